@@ -4,23 +4,37 @@
 package api
 
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
-	"log"
+	"iter"
 	"net/http"
 	"net/url"
+	"path"
+	"reflect"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"golang.org/x/time/rate"
 )
 
 const (
-	// API endpoint definitions
-	endpoint_get_hash_chain_info = "/data_accounting/get_hash_chain_info/"
-	endpoint_get_revision_hashes = "/data_accounting/get_revision_hashes/"
-	endpoint_get_revision        = "/data_accounting/get_revision/"
-	endpoint_get_server_info     = "/data_accounting/get_server_info"
+	// API endpoint definitions, relative to the api endpoint and base path
+	endpoint_get_hash_chain_info = "data_accounting/get_hash_chain_info/"
+	endpoint_get_revision_hashes = "data_accounting/get_revision_hashes/"
+	endpoint_get_revision        = "data_accounting/get_revision/"
+	endpoint_get_revision_by_id  = "data_accounting/get_revision_by_rev_id/"
+	endpoint_get_server_info     = "data_accounting/get_server_info"
+	endpoint_store_revision      = "data_accounting/write/store_revision"
 	timestamp_layout             = "20060102150405"
 
 	// etherscan endpoint regular expression and seperator for scraping output
@@ -37,21 +51,113 @@ var (
 		"kovan":   "https://kovan.etherscan.io/tx",
 		"rinkeby": "https://rinkeby.etherscan.io/tx",
 		"goerli":  "https://goerli.etherscan.io/tx",
+		"sepolia": "https://sepolia.etherscan.io/tx",
+		"polygon": "https://polygonscan.com/tx",
 	}
 	re = regexp.MustCompile(etherscanRegexp)
+
+	// ErrRevisionExists is returned by StoreRevision when the server already has the revision
+	ErrRevisionExists = errors.New("Revision already exists")
+	// ErrETagChanged is returned by GetRevision when the server returns a
+	// different ETag for a revision that was fetched before
+	ErrETagChanged = errors.New("ETag of immutable revision changed")
+	// ErrNotModified is returned by GetHashChainInfoIfChanged when the hash
+	// chain info is unchanged
+	ErrNotModified = errors.New("Not modified")
+	// ErrClosed is returned for requests made after Close
+	ErrClosed = errors.New("Client is closed")
+	// ErrNotSupported is returned when the server lacks the endpoint of a request
+	ErrNotSupported = errors.New("Request not supported by the server")
+	// ErrAuthRequired is returned when the server answers a request with 401
+	// Unauthorized, i.e. it requires an authentication token or rejects the
+	// one that was sent
+	ErrAuthRequired = errors.New("Authentication required")
+	// ErrTruncatedResponse is returned when a response body ends before the
+	// JSON value it holds, e.g. because the connection dropped. Unlike
+	// malformed JSON it is worth retrying, and requests are retried against
+	// the fallback endpoints when it occurs.
+	ErrTruncatedResponse = errors.New("Response is truncated")
 )
 
 // AquaProtocol holds the endpoint specific parameters and authentication token for an API session
+//
+// An AquaProtocol is safe for concurrent use by multiple goroutines once it is
+// created, and is meant to be shared rather than created per request. Its
+// mutable state, i.e. the ETag and namespace caches and the endpoint updated
+// by Discover, is guarded by locks. The options must not be changed after
+// NewAPI returned, e.g. the http.Client passed to WithHTTPClient.
+//
+// Requests reuse the connections kept alive by the transport of the
+// http.Client, so sharing one AquaProtocol also shares its connections. Close
+// releases the idle connections once the client is no longer needed.
 type AquaProtocol struct {
-	apiClient   http.Client
+	apiClient *http.Client
+	// ownTransport is set if the transport of apiClient was created for this
+	// AquaProtocol, rather than passed with WithHTTPClient
+	ownTransport bool
+	// mu guards apiEndpoint and serverInfo
+	mu          sync.RWMutex
 	apiEndpoint string
 	authToken   string
 	server      string
+	etags       *etagCache
+	tracer      Tracer
+	// fallbackEndpoints are tried in order when apiEndpoint fails
+	fallbackEndpoints []string
+	// basePath is the path of the data accounting api below every endpoint
+	basePath         string
+	observer         Observer
+	namespaces       *namespaceCache
+	maxResponseBytes int64
+	limiter          *rate.Limiter
+	requestTimeout   time.Duration
+	tokenProvider    TokenProvider
+	strictDecoding   bool
+	tlsConfig        *tls.Config
+	closed           atomic.Bool
+	stats            clientStats
+	responseRecorder func(endpoint string, body []byte)
+	// serverInfo is the server info Connect or CachedServerInfo fetched
+	serverInfo *ServerInfo
 }
 
-// ServerInfo holds the api response to
+// ServerInfo holds the api response to endpoint_get_server_info
 type ServerInfo struct {
 	ApiVersion string `json:"api_version"`
+	// ExtensionVersion is the version of the data accounting extension
+	ExtensionVersion string `json:"extension_version,omitempty"`
+	MediaWikiVersion string `json:"mediawiki_version,omitempty"`
+	// SiteInfo is the site info of the wiki, if the server sends it, from
+	// which Connect and CachedServerInfo learn the namespaces of the server
+	SiteInfo *SiteInfo `json:"site_info,omitempty"`
+	// Extra holds the fields of the response that are not known to this
+	// package, so that the full server environment can be logged
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON decodes a ServerInfo, keeping unknown fields in Extra. Like
+// the revision types, it accepts camelCase field names.
+func (s *ServerInfo) UnmarshalJSON(data []byte) error {
+	data, err := snakeCaseFields(data)
+	if err != nil {
+		return err
+	}
+	type serverInfo ServerInfo
+	if err := json.Unmarshal(data, (*serverInfo)(s)); err != nil {
+		return err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	for _, known := range []string{"api_version", "extension_version", "mediawiki_version", "site_info"} {
+		delete(fields, known)
+	}
+	s.Extra = nil
+	if len(fields) > 0 {
+		s.Extra = fields
+	}
+	return nil
 }
 
 // Namespace holdes the namestace field of a SiteInfo
@@ -80,6 +186,34 @@ type HashChainInfo struct {
 	Title                  string    `json:"title"`
 	Namespace              int       `json:"namespace"`
 	ChainHeight            int       `json:"chain_height"`
+	// CurrentRevision is the verification hash of the current revision of
+	// the page, if sent by the server. It is the same revision as
+	// LatestVerificationHash unless the server's metadata is stale.
+	CurrentRevision string `json:"current_revision,omitempty"`
+	// ETag and LastModified hold the validators the hash chain info was
+	// served with, if any, see GetHashChainInfoIfChanged
+	ETag         string `json:"-"`
+	LastModified string `json:"-"`
+}
+
+// Validate returns an error if mandatory fields of the hash chain info are
+// missing or inconsistent, e.g. because a server returned partial JSON
+func (ri *HashChainInfo) Validate() error {
+	switch {
+	case ri.GenesisHash == "":
+		return errors.New("Hash chain info has no genesis_hash")
+	case ri.LatestVerificationHash == "":
+		return errors.New("Hash chain info has no latest_verification_hash")
+	case ri.ChainHeight <= 0:
+		return fmt.Errorf("Hash chain info has invalid chain_height %d", ri.ChainHeight)
+	case ri.ChainHeight == 1 && ri.GenesisHash != ri.LatestVerificationHash:
+		return errors.New("Hash chain info of height 1 has a latest_verification_hash other than its genesis_hash")
+	case ri.ChainHeight > 1 && ri.GenesisHash == ri.LatestVerificationHash:
+		return fmt.Errorf("Hash chain info of height %d has its genesis_hash as latest_verification_hash", ri.ChainHeight)
+	case ri.CurrentRevision != "" && ri.CurrentRevision != ri.LatestVerificationHash:
+		return fmt.Errorf("Hash chain info has current_revision %s other than its latest_verification_hash %s", ri.CurrentRevision, ri.LatestVerificationHash)
+	}
+	return nil
 }
 
 // HashChain is the same as HashChainInfo but has a map of Revision keyed by revision hash
@@ -110,6 +244,8 @@ type RevisionContent struct {
 	Content     map[string]string `json:"content"`
 	ContentHash string            `json:"content_hash"`
 	File        *FileContent      `json:"file"`
+	// Salt is hashed together with Content by deployments that salt content
+	Salt string `json:"salt,omitempty"`
 }
 
 // Timestamp holds a timestamp in ??? format
@@ -131,6 +267,11 @@ func (p *Timestamp) UnmarshalJSON(bytes []byte) error {
 	return nil
 }
 
+// MarshalJSON marshals the timestamp in the same layout the api returns it
+func (p Timestamp) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.Format(timestamp_layout))
+}
+
 func (p *Timestamp) String() string {
 	return p.Format(timestamp_layout)
 }
@@ -159,16 +300,16 @@ type RevisionSignature struct {
 
 // MerkleNode holds the entries for the structured merkle proof
 type MerkleNode struct {
-	WitnessEventId int `json:"witness_event_id"`
-	Depth     int `json:"depth"`
-	LeftLeaf  string `json:"left_leaf"`
-	RightLeaf string `json:"right_leaf"`
-	Successor string `json:"successor"`
+	WitnessEventId int    `json:"witness_event_id"`
+	Depth          int    `json:"depth"`
+	LeftLeaf       string `json:"left_leaf"`
+	RightLeaf      string `json:"right_leaf"`
+	Successor      string `json:"successor"`
 }
 
 // RevisionWitness holds the Witness data in a Revision
 type RevisionWitness struct {
-	WitnessEventId               int        `json:"witness_event_id"`
+	WitnessEventId               int           `json:"witness_event_id"`
 	DomainId                     string        `json:"domain_id"`
 	DomainSnapshotTitle          string        `json:"domain_snapshot_title"`
 	WitnessHash                  string        `json:"witness_hash"`
@@ -190,176 +331,592 @@ type Revision struct {
 	Metadata  *RevisionMetadata    `json:"metadata"`
 	Signature *RevisionSignature   `json:"signature"`
 	Witness   *RevisionWitness     `json:"witness"`
+	// Signatures holds the signatures of a revision signed by several
+	// signers, served as an array in the signature field. Signature is then
+	// the first of them, so that code handling a single signature keeps
+	// working. It is nil for revisions with a single signature.
+	Signatures []*RevisionSignature `json:"-"`
 }
 
 // OfflineData holds the deserialized json-encoded export from PKC
 type OfflineData struct {
-	Pages    []*HashChain
-	SiteInfo *SiteInfo
+	Pages    []*HashChain `json:"pages"`
+	SiteInfo *SiteInfo    `json:"siteInfo"`
 }
 
 // GetHashChainInfo returns you all context for the requested hash_chain.
 func (a *AquaProtocol) GetHashChainInfo(id_type, id string) (*HashChainInfo, error) {
+	return a.GetHashChainInfoContext(context.Background(), id_type, id)
+}
+
+// GetHashChainInfoContext is GetHashChainInfo, made with ctx
+func (a *AquaProtocol) GetHashChainInfoContext(ctx context.Context, id_type, id string) (*HashChainInfo, error) {
+	return a.getHashChainInfo(ctx, id_type, id, nil)
+}
+
+// GetHashChainInfoIfChanged is like GetHashChainInfo but makes a conditional
+// request with the ETag and LastModified validators of prev, a hash chain info
+// fetched before. If the server reports the hash chain info as not modified,
+// prev is returned together with ErrNotModified, which makes polling an
+// unchanged page cheap. A nil prev makes an unconditional request.
+func (a *AquaProtocol) GetHashChainInfoIfChanged(ctx context.Context, id_type, id string, prev *HashChainInfo) (*HashChainInfo, error) {
+	header := http.Header{}
+	if prev != nil && prev.ETag != "" {
+		header.Set("If-None-Match", prev.ETag)
+	}
+	if prev != nil && prev.LastModified != "" {
+		header.Set("If-Modified-Since", prev.LastModified)
+	}
+	r, err := a.getHashChainInfo(ctx, id_type, id, header)
+	if err == ErrNotModified {
+		return prev, err
+	}
+	return r, err
+}
+
+func (a *AquaProtocol) getHashChainInfo(ctx context.Context, id_type, id string, header http.Header) (*HashChainInfo, error) {
 	if id_type != "genesis_hash" && id_type != "title" {
 		return nil, errors.New("id_type must be genesis_hash or title")
 	}
-	u, err := a.GetApiURL(endpoint_get_hash_chain_info + id_type + "?identifier=" + url.QueryEscape(id))
+	path := endpoint_get_hash_chain_info + id_type + "?identifier=" + url.QueryEscape(id)
+	r, resp, err := fetchJSON[HashChainInfo](a, ctx, path, header)
+	if resp != nil && resp.StatusCode == http.StatusNotModified {
+		return nil, ErrNotModified
+	}
 	if err != nil {
 		return nil, err
 	}
-	resp, err := a.fetch(u)
+	r.ETag = resp.Header.Get("ETag")
+	r.LastModified = resp.Header.Get("Last-Modified")
+	a.namespaces.learn(r.SiteInfo)
+
+	return r, nil
+}
+
+// GetRevisionHashes returns the revision requested if it exists and or a list of
+// any newer revision then the one requested.
+func (a *AquaProtocol) GetRevisionHashes(verification_hash string) ([]*RevisionHash, error) {
+	return a.GetRevisionHashesContext(context.Background(), verification_hash)
+}
+
+// GetRevisionHashesContext is GetRevisionHashes, made with ctx
+func (a *AquaProtocol) GetRevisionHashesContext(ctx context.Context, verification_hash string) ([]*RevisionHash, error) {
+	return a.getRevisionHashes(ctx, endpoint_get_revision_hashes+url.PathEscape(verification_hash))
+}
+
+// GetRevisionHashesSince is like GetRevisionHashes but returns at most limit
+// hashes, starting with the revision requested. A limit <= 0 returns all hashes.
+func (a *AquaProtocol) GetRevisionHashesSince(ctx context.Context, verification_hash string, limit int) ([]*RevisionHash, error) {
+	path := endpoint_get_revision_hashes + url.PathEscape(verification_hash)
+	if limit > 0 {
+		path += "?limit=" + strconv.Itoa(limit)
+	}
+	r, err := a.getRevisionHashes(ctx, path)
 	if err != nil {
 		return nil, err
 	}
+	// servers that do not support the limit parameter return the full list
+	if limit > 0 && len(r) > limit {
+		r = r[:limit]
+	}
+	return r, nil
+}
 
-	d := json.NewDecoder(resp.Body)
-	r := new(HashChainInfo)
-	err = d.Decode(r)
+// GetOrderedRevisionHashes is like GetRevisionHashes, but returns the hashes in
+// chain order, from the revision requested to the latest revision, whatever
+// order the server lists them in. The order is resolved by following the
+// previous verification hash of every revision, which are fetched with
+// GetRevision. It returns an error if the hashes don't form a single chain
+// starting at the revision requested.
+func (a *AquaProtocol) GetOrderedRevisionHashes(ctx context.Context, verification_hash string) ([]string, error) {
+	hashes, err := a.GetRevisionHashesContext(ctx, verification_hash)
 	if err != nil {
-		log.Println(err)
 		return nil, err
 	}
+	// next maps the verification hash of a revision to the one of the revision
+	// following it
+	next := make(map[string]string, len(hashes))
+	listed := make(map[string]bool, len(hashes))
+	for _, h := range hashes {
+		listed[string(*h)] = true
+	}
+	for _, h := range hashes {
+		r, err := a.GetRevisionContext(ctx, string(*h))
+		if err != nil {
+			return nil, fmt.Errorf("Failure getting revision %s: %w", *h, err)
+		}
+		prev := r.PreviousVerificationHash()
+		if string(*h) == verification_hash || !listed[prev] {
+			continue
+		}
+		if other, ok := next[prev]; ok {
+			return nil, fmt.Errorf("Revisions %s and %s both follow revision %s", other, *h, prev)
+		}
+		next[prev] = string(*h)
+	}
+	if !listed[verification_hash] {
+		return nil, fmt.Errorf("Revision %s is not listed in its revision hashes", verification_hash)
+	}
+	ordered := make([]string, 0, len(hashes))
+	for h, ok := verification_hash, true; ok; h, ok = next[h] {
+		ordered = append(ordered, h)
+	}
+	if len(ordered) != len(listed) {
+		return nil, fmt.Errorf("Revision hashes of %s don't form a single chain", verification_hash)
+	}
+	return ordered, nil
+}
 
-	return r, nil
+// NewerRevisions holds the revision hashes newer than a known revision
+type NewerRevisions struct {
+	// Hashes are the verification hashes of the newer revisions, oldest
+	// first, without the known revision.
+	Hashes []*RevisionHash
+	// Count is the number of newer revisions
+	Count int
+	// IsLatest is true if the known revision is the latest revision, i.e.
+	// nothing was added since
+	IsLatest bool
 }
 
-// GetRevisionHashes returns the revision requested if it exists and or a list of
-// any newer revision then the one requested.
-func (a *AquaProtocol) GetRevisionHashes(verification_hash string) ([]*RevisionHash, error) {
-	u, err := a.GetApiURL(endpoint_get_revision_hashes + verification_hash)
+// GetNewerRevisions returns the revision hashes newer than the revision with
+// the given verification hash, so that pollers can skip fetching revisions of
+// pages that didn't change. It uses a single request to
+// endpoint_get_revision_hashes, subject to the request timeout.
+func (a *AquaProtocol) GetNewerRevisions(ctx context.Context, verification_hash string) (*NewerRevisions, error) {
+	hashes, err := a.GetRevisionHashesContext(ctx, verification_hash)
 	if err != nil {
 		return nil, err
 	}
+	// the list starts with the revision requested
+	if len(hashes) > 0 && string(*hashes[0]) == NormalizeHash(verification_hash) {
+		hashes = hashes[1:]
+	}
+	return &NewerRevisions{Hashes: hashes, Count: len(hashes), IsLatest: len(hashes) == 0}, nil
+}
 
-	resp, err := a.fetch(u)
+func (a *AquaProtocol) getRevisionHashes(ctx context.Context, path string) ([]*RevisionHash, error) {
+	r, err := getJSON[[]*RevisionHash](a, ctx, path)
 	if err != nil {
 		return nil, err
 	}
+	return *r, nil
+}
 
-	d := json.NewDecoder(resp.Body)
-	r := make([]*RevisionHash, 0)
-	err = d.Decode(&r)
+// getJSON fetches path and decodes its JSON response into a new T
+func getJSON[T any](a *AquaProtocol, ctx context.Context, path string) (*T, error) {
+	v, _, err := fetchJSON[T](a, ctx, path, nil)
+	return v, err
+}
+
+// fetchJSON fetches path with header and decodes its JSON response into a new
+// T. The response body is closed before fetchJSON returns, also if the request
+// or decoding fails. The response, if any, is returned for its status and
+// headers.
+func fetchJSON[T any](a *AquaProtocol, ctx context.Context, path string, header http.Header) (*T, *http.Response, error) {
+	v := new(T)
+	resp, err := a.fetchInto(ctx, http.MethodGet, path, nil, header, v)
+	if resp != nil {
+		defer closeBody(resp)
+	}
 	if err != nil {
-		return nil, err
+		return nil, resp, err
 	}
+	return v, resp, nil
+}
 
-	return r, nil
+// fetch makes a request for path with the Authorization token initialized for
+// this api session and returns an *http.Response or error. Without a token the
+// request is sent anonymously, and a 401 response returns ErrAuthRequired. A
+// non-nil body is sent as the JSON-encoded request body and header is added to
+// the request headers. If a GET, HEAD or OPTIONS request fails with a network
+// error or a 5xx status, it is retried against the fallback endpoints in order.
+// Writes are only sent to the api endpoint, since a write that failed after the
+// server committed it would be replayed on a mirror, and carry an
+// IdempotencyKeyHeader so that the caller can retry them.
+func (a *AquaProtocol) fetch(ctx context.Context, method, path string, body []byte, header http.Header) (*http.Response, error) {
+	return a.fetchInto(ctx, method, path, body, header, nil)
+}
+
+// fetchInto is fetch, decoding the JSON response into v unless v is nil. A
+// response that fails to decode with ErrTruncatedResponse is retried against
+// the fallback endpoints like a 5xx status; one that fails to decode for
+// another reason is not.
+func (a *AquaProtocol) fetchInto(ctx context.Context, method, path string, body []byte, header http.Header, v interface{}) (*http.Response, error) {
+	header = withIdempotencyKey(method, header)
+	endpoints := []string{a.Endpoint()}
+	if isIdempotent(method) {
+		endpoints = append(endpoints, a.fallbackEndpoints...)
+	}
+	var resp *http.Response
+	var err error
+	for i, endpoint := range endpoints {
+		resp, err = a.fetchFrom(ctx, endpoint, method, path, body, header)
+		truncated := false
+		if err == nil && v != nil && resp.StatusCode != http.StatusNotModified {
+			// drop what was decoded from a truncated response before
+			reflect.ValueOf(v).Elem().SetZero()
+			err = a.decode(resp.Body, v)
+			truncated = errors.Is(err, ErrTruncatedResponse)
+		}
+		if err == nil || (resp != nil && resp.StatusCode < 500 && !truncated) || ctx.Err() != nil {
+			break
+		}
+		if resp != nil && i < len(endpoints)-1 {
+			closeBody(resp)
+		}
+	}
+	return resp, err
+}
+
+// maxDrainBytes bounds what closeBody reads of the rest of a response body
+const maxDrainBytes = 64 << 10
+
+// closeBody reads what is left of the body of resp, up to maxDrainBytes, and
+// closes it. A body closed before it was read to its end, e.g. after a JSON
+// value followed by trailing whitespace or an error page that wasn't read,
+// makes the transport close the connection instead of returning it to the
+// pool for the next request.
+func closeBody(resp *http.Response) {
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrainBytes))
+	resp.Body.Close()
+}
+
+// Endpoint returns the api endpoint requests are made against, which may have
+// been updated by Discover
+func (a *AquaProtocol) Endpoint() string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.apiEndpoint
 }
 
-// fetch makes a request with the Authorization token initialized for this api
-// session and returns an *http.Response or error
-func (a *AquaProtocol) fetch(u *url.URL) (*http.Response, error) {
-	req, err := http.NewRequest("GET", u.String(), nil)
+// fetchFrom makes a single request for path against the api endpoint
+func (a *AquaProtocol) fetchFrom(ctx context.Context, endpoint, method, path string, body []byte, header http.Header) (*http.Response, error) {
+	if a.closed.Load() {
+		return nil, ErrClosed
+	}
+	u, err := joinURL(endpoint, a.basePath, path)
 	if err != nil {
 		return nil, err
 	}
+	if a.limiter != nil {
+		if err := a.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+	token, err := a.token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := a.requestContext(ctx)
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	ctx, span := StartSpan(ctx, a.tracer, "aqua.fetch",
+		Attr("http.method", method),
+		Attr("http.url", u.String()))
+	defer span.End()
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), r)
+	if err != nil {
+		cancel()
+		span.RecordError(err)
+		return nil, err
+	}
 
+	for k, v := range header {
+		req.Header[k] = v
+	}
 	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("Authorization", "Bearer"+a.authToken)
+	if token != "" {
+		req.Header.Add("Authorization", "Bearer "+token)
+	}
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+	start := time.Now()
+	a.stats.requests.Add(1)
 	resp, err := a.apiClient.Do(req)
 	if err != nil {
+		cancel()
+		span.RecordError(err)
+		a.observe(path, start, 0, err)
 		return nil, err
 	}
-	if resp.StatusCode != http.StatusOK {
-		return resp, errors.New("Request Not 200 OK")
+	resp.Body = &cancelBody{ReadCloser: &statsBody{ReadCloser: resp.Body, stats: &a.stats}, cancel: cancel}
+	if err = decompressBody(resp); err == nil {
+		err = limitBody(resp, a.maxResponseBytes)
+	}
+	if err != nil {
+		resp.Body.Close()
+		span.RecordError(err)
+		a.observe(path, start, resp.StatusCode, err)
+		return nil, err
+	}
+	if a.responseRecorder != nil {
+		resp.Body = &recordingBody{ReadCloser: resp.Body, endpoint: req.URL.String(), record: a.responseRecorder}
 	}
+	span.SetAttributes(Attr("http.status_code", resp.StatusCode))
+	switch {
+	case resp.StatusCode == http.StatusOK, resp.StatusCode == http.StatusCreated:
+	// a revalidation of a cached response succeeded
+	case resp.StatusCode == http.StatusNotModified && (req.Header.Get("If-None-Match") != "" || req.Header.Get("If-Modified-Since") != ""):
+	case resp.StatusCode == http.StatusUnauthorized:
+		err = ErrAuthRequired
+	default:
+		err = errors.New("Request Not 200 OK")
+	}
+	if err != nil {
+		span.RecordError(err)
+	}
+	a.observe(path, start, resp.StatusCode, err)
 	return resp, err
 }
 
+// observe reports a request for path to the observer of the client, if any
+func (a *AquaProtocol) observe(path string, start time.Time, status int, err error) {
+	if a.observer != nil {
+		a.observer.ObserveRequest(endpointName(path), time.Since(start), status, err)
+	}
+}
+
 // GetRevision returns all data revision and revision verification data.
 func (a *AquaProtocol) GetRevision(verification_hash string) (*Revision, error) {
-	u, err := a.GetApiURL(endpoint_get_revision + verification_hash)
-	if err != nil {
-		return nil, err
+	return a.GetRevisionContext(context.Background(), verification_hash)
+}
+
+// GetRevisionContext is GetRevision, made with ctx.
+//
+// If the client was created with WithETagCache, the ETag of the response is
+// recorded and sent as If-None-Match when the revision is fetched again. A 304
+// Not Modified response returns a copy of the cached revision. As revisions
+// are immutable, a different ETag for the same verification hash is
+// suspicious: the freshly fetched revision is returned together with
+// ErrETagChanged. The cache is updated with every revision served in full,
+// and a revision served without an ETag is dropped from it. Revisions are
+// cached by their normalized verification hash, see NormalizeHash.
+func (a *AquaProtocol) GetRevisionContext(ctx context.Context, verification_hash string) (*Revision, error) {
+	path := endpoint_get_revision + url.PathEscape(verification_hash)
+	var header http.Header
+	key := NormalizeHash(verification_hash)
+	cached := a.etags.get(key)
+	if cached != nil {
+		header = http.Header{"If-None-Match": {cached.etag}}
 	}
-	resp, err := a.fetch(u)
+	r, resp, err := fetchJSON[Revision](a, ctx, path, header)
 	if err != nil {
 		return nil, err
 	}
-	d := json.NewDecoder(resp.Body)
+	// only a revalidation of the cached revision is answered with a 304
+	if resp.StatusCode == http.StatusNotModified {
+		return cached.revision.Copy(), nil
+	}
+
+	if a.etags == nil {
+		return r, nil
+	}
+	// the cache holds what the server served last, so that a changed ETag
+	// is reported once and then revalidated against
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		a.etags.remove(key)
+		return r, nil
+	}
+	a.etags.put(key, etag, r.Copy())
+	if cached != nil && cached.etag != etag {
+		return r, ErrETagChanged
+	}
+	return r, nil
+}
+
+// GetRevisionByRevId returns the revision with the MediaWiki revision id
+// revId. If the server has no such endpoint, which is told apart from an
+// unknown revision by the MediaWiki REST router reporting that no route
+// matched and confirmed with a server info check, ErrNotSupported is
+// returned.
+func (a *AquaProtocol) GetRevisionByRevId(ctx context.Context, revId int) (*Revision, error) {
 	r := new(Revision)
-	err = d.Decode(r)
+	resp, err := a.fetchInto(ctx, http.MethodGet, endpoint_get_revision_by_id+strconv.Itoa(revId), nil, nil, r)
+	if resp != nil {
+		defer closeBody(resp)
+	}
 	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound && isNoRouteResponse(resp) {
+			if s, e := a.GetServerInfoContext(ctx); e == nil {
+				return nil, fmt.Errorf("%w: revision by rev_id with api version %s", ErrNotSupported, s.ApiVersion)
+			}
+		}
 		return nil, err
 	}
-
 	return r, nil
 }
 
-// GetApiURL returns the api endpoint base URL given a server hostname
+// isNoRouteResponse reports whether resp is the error of the MediaWiki REST
+// router for a path without a handler
+func isNoRouteResponse(resp *http.Response) bool {
+	var e struct {
+		ErrorKey string `json:"errorKey"`
+	}
+	return json.NewDecoder(resp.Body).Decode(&e) == nil && e.ErrorKey == "rest-no-match"
+}
+
+// StoreRevision publishes a revision to the server. It returns
+// ErrRevisionExists if the server already has a revision with the same
+// verification hash. The verification hash is sent as the idempotency key, so
+// that calling StoreRevision again for a write that may have failed is
+// de-duplicated by servers supporting IdempotencyKeyHeader.
+func (a *AquaProtocol) StoreRevision(ctx context.Context, r *Revision) error {
+	body, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	var header http.Header
+	if vh := r.VerificationHash(); vh != "" {
+		header = withIdempotencyKeyValue(nil, vh)
+	}
+	resp, err := a.fetch(ctx, http.MethodPost, endpoint_store_revision, body, header)
+	if resp != nil {
+		defer closeBody(resp)
+	}
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusConflict {
+			return ErrRevisionExists
+		}
+		return err
+	}
+	return nil
+}
+
+// GetApiURL returns the api endpoint base URL given a server hostname. Ids in
+// path must be escaped with url.PathEscape, or url.QueryEscape in the query.
 func (a *AquaProtocol) GetApiURL(path string) (*url.URL, error) {
-	u, err := url.Parse(a.apiEndpoint + path)
+	return joinURL(a.Endpoint(), a.basePath, path)
+}
+
+// joinURL returns the URL of path, which may include a query, below the
+// endpoint base URL and the base path, which may be empty, regardless of
+// trailing or leading slashes. path must be escaped, i.e. ids in path segments
+// escaped with url.PathEscape and ids in the query with url.QueryEscape.
+func joinURL(endpoint, basePath, path string) (*url.URL, error) {
+	u, err := url.Parse(endpoint)
 	if err != nil {
 		return nil, err
 	}
+	path, query, _ := strings.Cut(path, "?")
+	u = u.JoinPath(basePath, path)
+	u.RawQuery = query
 	return u, nil
 }
 
 // GetServerInfo returns a serverInfo from the endpoint endpoint_get_server_info
 func (a *AquaProtocol) GetServerInfo() (*ServerInfo, error) {
-	u, err := a.GetApiURL(endpoint_get_server_info)
-	if err != nil {
-		return nil, err
+	return a.GetServerInfoContext(context.Background())
+}
+
+// GetServerInfoContext is GetServerInfo, made with ctx
+func (a *AquaProtocol) GetServerInfoContext(ctx context.Context) (*ServerInfo, error) {
+	return getJSON[ServerInfo](a, ctx, endpoint_get_server_info)
+}
+
+// ErrNotAquaServer is returned by Discover if the endpoint doesn't serve the
+// data accounting api
+var ErrNotAquaServer = errors.New("Endpoint is not an Aqua server")
+
+// Discover probes endpoint_get_server_info to confirm that the endpoint is an
+// Aqua server, returning an error wrapping ErrNotAquaServer if it isn't. If
+// the request was redirected, the endpoint the redirect led to is used for
+// all further requests. Discover is meant to be called before any other
+// request of the client is made.
+func (a *AquaProtocol) Discover(ctx context.Context) error {
+	_, err := a.discover(ctx)
+	return err
+}
+
+// discover is Discover, returning the server info of the Aqua server
+func (a *AquaProtocol) discover(ctx context.Context) (*ServerInfo, error) {
+	resp, err := a.fetch(ctx, http.MethodGet, endpoint_get_server_info, nil, nil)
+	if resp != nil {
+		defer closeBody(resp)
 	}
-	resp, err := a.fetch(u)
 	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("%w: get_server_info returned %s", ErrNotAquaServer, resp.Status)
+		}
 		return nil, err
 	}
-	d := json.NewDecoder(resp.Body)
 	s := new(ServerInfo)
-	err = d.Decode(s)
-	if err != nil {
-		return nil, err
+	if err := json.NewDecoder(resp.Body).Decode(s); err != nil || s.ApiVersion == "" {
+		return nil, fmt.Errorf("%w: get_server_info returned no api_version", ErrNotAquaServer)
+	}
+	// the request that led to the response was caused by a redirect
+	if resp.Request != nil && resp.Request.Response != nil {
+		final := *resp.Request.URL
+		final.RawQuery = ""
+		suffix := path.Join("/", a.basePath, endpoint_get_server_info)
+		if canonical := strings.TrimSuffix(final.String(), suffix); canonical != final.String() {
+			a.mu.Lock()
+			a.apiEndpoint = canonical
+			a.mu.Unlock()
+		}
 	}
 	return s, nil
 }
 
-// CheckEtherscan scrapes etherscan.io to see if the expected eventHash exists for a given transaction.
-func CheckEtherscan(network, txHash, eventHash string) error {
-	n, ok := WitnessNetworkMap[network]
-	if !ok {
-		return errors.New("Invalid ethereum network specified")
+// pingTimeout bounds the time Ping waits for the server
+const pingTimeout = 5 * time.Second
+
+// Ping checks that the server is reachable and answers endpoint_get_server_info
+// with a server info, e.g. for a readiness probe. The request is sent without
+// the authentication token, so that an invalid token doesn't fail the check
+// on servers serving their server info anonymously, and is cancelled after
+// five seconds unless ctx is done earlier.
+func (a *AquaProtocol) Ping(ctx context.Context) error {
+	if a.closed.Load() {
+		return ErrClosed
 	}
-	u, err := url.Parse(n)
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+	u, err := joinURL(a.Endpoint(), a.basePath, endpoint_get_server_info)
 	if err != nil {
 		return err
 	}
-	u.Path = u.Path + "/" + txHash
-	req, err := http.NewRequest("GET", u.String(), nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
 	if err != nil {
 		return err
 	}
-	// do the verification
-	c := &http.Client{}
-	resp, err := c.Do(req)
+	a.stats.requests.Add(1)
+	resp, err := a.apiClient.Do(req)
 	if err != nil {
 		return err
 	}
+	resp.Body = &statsBody{ReadCloser: resp.Body, stats: &a.stats}
+	defer closeBody(resp)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Server info returned %s", resp.Status)
+	}
+	s := new(ServerInfo)
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(s); err != nil {
+		return fmt.Errorf("Server info is not valid JSON: %w", err)
+	}
+	if s.ApiVersion == "" {
+		return errors.New("Server info has no api_version")
+	}
+	return nil
+}
 
-	// To avoid IP banning by etherscan.io
-	time.Sleep(300 * time.Millisecond)
+// CheckEtherscan scrapes etherscan.io to see if the expected eventHash exists for a given transaction.
+func CheckEtherscan(network, txHash, eventHash string) error {
+	return CheckEtherscanContext(context.Background(), network, txHash, eventHash)
+}
 
-	// read response
-	buf, err := io.ReadAll(resp.Body)
+// CheckEtherscanContext is CheckEtherscan, made with ctx
+func CheckEtherscanContext(ctx context.Context, network, txHash, eventHash string) error {
+	witnessed, err := (&EtherscanResolver{Network: network}).LookupMerkleRoot(ctx, txHash)
 	if err != nil {
 		return err
 	}
-
-	// scrape output
-	match := re.FindStringSubmatch(string(buf))
-	if match == nil || len(match) < 2 { // no grouping match
-		return errors.New("No Match")
-	}
-	// the response is prefixed by methodId
-	d := strings.Split(match[1], ethMethodId)
-	if len(d) != 2 {
-		return errors.New("No Match")
-	} else {
-		if strings.ToLower(d[1]) != strings.ToLower(eventHash) {
-			return errors.New("eventHash Does NOT match")
-		}
-		return nil
+	if witnessed != strings.ToLower(eventHash) {
+		return errors.New("eventHash Does NOT match")
 	}
+	return nil
 }
 
 /*
@@ -368,11 +925,352 @@ func doPreliminaryAPICall(endpointName string, u *url.URL, token string) {
 */
 
 // NewAPI returns an initialized AquaProtocol using the server and authentication token
-func NewAPI(endpoint, token string) (*AquaProtocol, error) {
+func NewAPI(endpoint, token string, opts ...Option) (*AquaProtocol, error) {
 	_, e := url.Parse(endpoint)
 	if e != nil {
 		return nil, e
 	}
 	// TODO: validate that the token is the correct form/length/etc...
-	return &AquaProtocol{apiEndpoint: endpoint, authToken: token}, nil
+	endpoint = strings.TrimRight(endpoint, "/")
+	a := &AquaProtocol{
+		apiClient:    &http.Client{Transport: http.DefaultTransport.(*http.Transport).Clone()},
+		ownTransport: true,
+		apiEndpoint:  endpoint,
+		authToken:    token,
+		namespaces:   &namespaceCache{},
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	if e := a.applyTLSConfig(); e != nil {
+		return nil, e
+	}
+	return a, nil
+}
+
+// Connect returns an AquaProtocol like NewAPI, after checking with Discover
+// that the endpoint is a reachable Aqua server: an unreachable endpoint fails
+// with the request error, and an endpoint that is not an Aqua server with an
+// error wrapping ErrNotAquaServer. The server info is kept, see ServerInfo.
+func Connect(ctx context.Context, endpoint, token string, opts ...Option) (*AquaProtocol, error) {
+	a, err := NewAPI(endpoint, token, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if a.serverInfo, err = a.discover(ctx); err != nil {
+		return nil, err
+	}
+	a.namespaces.learn(a.serverInfo.SiteInfo)
+	return a, nil
+}
+
+// ServerInfo returns the server info fetched by Connect or CachedServerInfo,
+// nil if none was fetched yet
+func (a *AquaProtocol) ServerInfo() *ServerInfo {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.serverInfo
+}
+
+// CachedServerInfo returns the server info like GetServerInfo, fetching it
+// only if it wasn't fetched before by Connect or CachedServerInfo
+func (a *AquaProtocol) CachedServerInfo(ctx context.Context) (*ServerInfo, error) {
+	if s := a.ServerInfo(); s != nil {
+		return s, nil
+	}
+	s, err := a.GetServerInfoContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	a.namespaces.learn(s.SiteInfo)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.serverInfo == nil {
+		a.serverInfo = s
+	}
+	return a.serverInfo, nil
+}
+
+// Close closes the idle connections of the client's transport, e.g. during a
+// graceful shutdown. Requests made after Close fail with ErrClosed; create a
+// new AquaProtocol to reconnect, e.g. to a rotated endpoint. Connections still
+// in use by requests in flight are not closed, and neither are those of a
+// client passed to WithHTTPClient, which may be shared with other code. Close
+// may be called multiple times.
+func (a *AquaProtocol) Close() error {
+	a.closed.Store(true)
+	if a.ownTransport {
+		a.apiClient.CloseIdleConnections()
+	}
+	return nil
+}
+
+// GetHashChain returns the hash chain info of the requested hash chain
+// together with its revisions, following the revisions from the latest
+// revision up to depth revisions deep (-1 for all) towards the genesis
+// revision.
+func (a *AquaProtocol) GetHashChain(ctx context.Context, id_type, id string, depth int) (*HashChain, error) {
+	return FetchHashChain(ctx, a, id_type, id, depth)
+}
+
+// FetchHashChain is like AquaProtocol.GetHashChain, fetching the hash chain
+// from c, e.g. a client of another transport than the REST api
+func FetchHashChain(ctx context.Context, c AquaClient, id_type, id string, depth int) (*HashChain, error) {
+	ri, err := c.GetHashChainInfoContext(ctx, id_type, id)
+	if err != nil {
+		return nil, err
+	}
+	data := &HashChain{HashChainInfo: *ri, Revisions: make(map[string]*Revision)}
+	if depth == 0 {
+		return data, nil
+	}
+	cur := ri.LatestVerificationHash
+	for r, err := range walkChain(ctx, c, cur) {
+		if err != nil {
+			return nil, err
+		}
+		data.Revisions[cur] = r
+		cur = r.Metadata.PreviousVerificationHash
+		if depth != -1 && len(data.Revisions) >= depth {
+			break
+		}
+	}
+	return data, nil
+}
+
+// LatestHashMismatchError is returned by AssertLatestHash if the latest
+// verification hash of a page is not the expected one
+type LatestHashMismatchError struct {
+	Title    string
+	Expected string
+	Actual   string
+}
+
+func (e *LatestHashMismatchError) Error() string {
+	return fmt.Sprintf("Latest verification hash of %s is %s, expected %s", e.Title, e.Actual, e.Expected)
+}
+
+// AssertLatestHash returns a *LatestHashMismatchError if the latest
+// verification hash of the page with the given title is not expected, e.g.
+// because the page was altered since expected was recorded.
+func (a *AquaProtocol) AssertLatestHash(ctx context.Context, title, expected string) error {
+	ri, err := a.GetHashChainInfoContext(ctx, "title", title)
+	if err != nil {
+		return err
+	}
+	if ri.LatestVerificationHash != expected {
+		return &LatestHashMismatchError{Title: title, Expected: expected, Actual: ri.LatestVerificationHash}
+	}
+	return nil
+}
+
+// GenesisHashMismatchError is returned by VerifyGenesisPinned if the genesis
+// hash of a page is not the pinned one
+type GenesisHashMismatchError struct {
+	Title  string
+	Pinned string
+	Actual string
+}
+
+func (e *GenesisHashMismatchError) Error() string {
+	return fmt.Sprintf("Genesis hash of %s is %s, pinned %s", e.Title, e.Actual, e.Pinned)
+}
+
+// VerifyGenesisPinned returns a *GenesisHashMismatchError if the genesis hash
+// of the page with the given title is not pinnedGenesis. For trust on first
+// use, pin the genesis hash the first time a page is seen and check it on
+// every later visit: a changed genesis hash means the page was replaced by an
+// entirely different hash chain, which verifying the chain doesn't detect.
+func (a *AquaProtocol) VerifyGenesisPinned(ctx context.Context, title, pinnedGenesis string) error {
+	if pinnedGenesis == "" {
+		return fmt.Errorf("No genesis hash pinned for %s", title)
+	}
+	ri, err := a.GetHashChainInfoContext(ctx, "title", title)
+	if err != nil {
+		return err
+	}
+	if ri.GenesisHash != NormalizeHash(pinnedGenesis) {
+		return &GenesisHashMismatchError{Title: title, Pinned: pinnedGenesis, Actual: ri.GenesisHash}
+	}
+	return nil
+}
+
+// ChainHeightMismatchError is returned by VerifyChainHeight if the chain
+// height declared for a page differs from the number of its revisions
+type ChainHeightMismatchError struct {
+	Title    string
+	Declared int
+	Observed int
+}
+
+func (e *ChainHeightMismatchError) Error() string {
+	return fmt.Sprintf("Chain height of %s is declared as %d, but %d revisions were found", e.Title, e.Declared, e.Observed)
+}
+
+// VerifyChainHeight returns a *ChainHeightMismatchError if the chain height
+// declared in the hash chain info of the page with the given title differs
+// from the number of revision hashes served from its genesis revision on. A
+// server reporting fewer revisions than it serves, or more, may be hiding or
+// injecting revisions.
+func (a *AquaProtocol) VerifyChainHeight(ctx context.Context, title string) error {
+	ri, err := a.GetHashChainInfoContext(ctx, "title", title)
+	if err != nil {
+		return err
+	}
+	hashes, err := a.GetRevisionHashesContext(ctx, ri.GenesisHash)
+	if err != nil {
+		return err
+	}
+	if len(hashes) != ri.ChainHeight {
+		return &ChainHeightMismatchError{Title: title, Declared: ri.ChainHeight, Observed: len(hashes)}
+	}
+	return nil
+}
+
+// ResolveHashPrefix returns the verification hash of the revision of the page
+// with the given title that starts with prefix, e.g. a hash shortened for
+// display or copied in part. The prefix is normalized like a hash. It returns
+// an error if no revision or more than one revision of the page matches.
+func (a *AquaProtocol) ResolveHashPrefix(ctx context.Context, title, prefix string) (string, error) {
+	prefix = NormalizeHash(prefix)
+	if prefix == "" {
+		return "", errors.New("Empty verification hash prefix")
+	}
+	ri, err := a.GetHashChainInfoContext(ctx, "title", title)
+	if err != nil {
+		return "", err
+	}
+	hashes, err := a.GetRevisionHashesContext(ctx, ri.GenesisHash)
+	if err != nil {
+		return "", err
+	}
+	var matches []string
+	for _, h := range hashes {
+		if strings.HasPrefix(string(*h), prefix) {
+			matches = append(matches, string(*h))
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("No revision of %s has a verification hash starting with %s", title, prefix)
+	case 1:
+		return matches[0], nil
+	}
+	return "", fmt.Errorf("Verification hash prefix %s is ambiguous, %d revisions of %s match", prefix, len(matches), title)
+}
+
+// GetGenesisRevision returns the genesis revision of the page with the given
+// title. It returns an error if the revision served for the genesis hash is
+// not a genesis revision, i.e. it has a previous revision or commits to a
+// previous signature or witness.
+func (a *AquaProtocol) GetGenesisRevision(ctx context.Context, title string) (*Revision, error) {
+	ri, err := a.GetHashChainInfoContext(ctx, "title", title)
+	if err != nil {
+		return nil, err
+	}
+	if ri.GenesisHash == "" {
+		return nil, fmt.Errorf("Hash chain info of %s has no genesis_hash", title)
+	}
+	r, err := a.GetRevisionContext(ctx, ri.GenesisHash)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case r.Metadata == nil || r.Metadata.VerificationHash != ri.GenesisHash:
+		return nil, fmt.Errorf("Server returned another revision for genesis revision %s", ri.GenesisHash)
+	case r.Metadata.PreviousVerificationHash != "":
+		return nil, fmt.Errorf("Genesis revision %s has previous revision %s", ri.GenesisHash, r.Metadata.PreviousVerificationHash)
+	case r.Context != nil && (r.Context.HasPreviousSignature || r.Context.HasPreviousWitness):
+		return nil, fmt.Errorf("Genesis revision %s has a previous signature or witness", ri.GenesisHash)
+	}
+	return r, nil
+}
+
+// WalkChain yields the revision with verification hash startHash and then
+// each of its previous revisions, from the latest towards the genesis
+// revision. Iteration stops after the genesis revision, at the first error,
+// or when ctx is cancelled, in which case the error of ctx is yielded. A
+// revision whose previous revisions lead back to itself yields an error
+// rather than walking the loop forever.
+func (a *AquaProtocol) WalkChain(ctx context.Context, startHash string) iter.Seq2[*Revision, error] {
+	return walkChain(ctx, a, startHash)
+}
+
+func walkChain(ctx context.Context, c AquaClient, startHash string) iter.Seq2[*Revision, error] {
+	return func(yield func(*Revision, error) bool) {
+		visited := make(map[string]bool)
+		for cur := startHash; cur != ""; {
+			if err := ctx.Err(); err != nil {
+				yield(nil, err)
+				return
+			}
+			if visited[NormalizeHash(cur)] {
+				yield(nil, fmt.Errorf("Chain of revision %s loops at revision %s", startHash, cur))
+				return
+			}
+			visited[NormalizeHash(cur)] = true
+			r, err := c.GetRevisionContext(ctx, cur)
+			if err == nil && r.Metadata == nil {
+				err = errors.New("Revision has no metadata")
+			}
+			if err != nil {
+				yield(nil, fmt.Errorf("Failure getting revision %s: %w", cur, err))
+				return
+			}
+			if !yield(r, nil) {
+				return
+			}
+			cur = r.Metadata.PreviousVerificationHash
+		}
+	}
+}
+
+// GetRevisionRange returns the revisions from the revision with verification
+// hash fromHash up to the one with verification hash toHash, both included,
+// ordered from oldest to newest. It returns an error if fromHash is not
+// toHash or one of its previous revisions, and like IsAncestor if the range
+// is more than MaxAncestorDepth revisions long.
+func (a *AquaProtocol) GetRevisionRange(ctx context.Context, fromHash, toHash string) ([]*Revision, error) {
+	from := NormalizeHash(fromHash)
+	var revisions []*Revision
+	for r, err := range a.WalkChain(ctx, toHash) {
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, r)
+		if NormalizeHash(r.Metadata.VerificationHash) == from {
+			slices.Reverse(revisions)
+			return revisions, nil
+		}
+		if len(revisions) > MaxAncestorDepth {
+			return nil, fmt.Errorf("Chain of revision %s is more than %d revisions deep", toHash, MaxAncestorDepth)
+		}
+	}
+	return nil, fmt.Errorf("Revision %s is not an ancestor of revision %s", fromHash, toHash)
+}
+
+// MaxAncestorDepth is the number of revisions IsAncestor walks back at most
+const MaxAncestorDepth = 100000
+
+// IsAncestor returns whether the revision with verification hash ancestorHash
+// is the revision with verification hash descendantHash or one of its previous
+// revisions, i.e. whether both are revisions of the same document. It walks
+// back from descendantHash towards the genesis revision, and returns an error
+// if the chain is more than MaxAncestorDepth revisions deep or loops.
+func (a *AquaProtocol) IsAncestor(ctx context.Context, ancestorHash, descendantHash string) (bool, error) {
+	ancestorHash = NormalizeHash(ancestorHash)
+	depth := 0
+	for r, err := range a.WalkChain(ctx, descendantHash) {
+		if err != nil {
+			return false, err
+		}
+		if NormalizeHash(r.Metadata.VerificationHash) == ancestorHash {
+			return true, nil
+		}
+		if depth == MaxAncestorDepth {
+			return false, fmt.Errorf("Chain of revision %s is more than %d revisions deep", descendantHash, MaxAncestorDepth)
+		}
+		depth++
+	}
+	return false, nil
 }
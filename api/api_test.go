@@ -1,6 +1,15 @@
 package api
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -110,6 +119,22 @@ func TestGetApiURL(t *testing.T) {
 	u, e := a.GetApiURL("")
 	require.NoError(e)
 	require.NotEqual(u.String(), "")
+
+	for _, endpoint := range []string{"http://localhost:9352", "http://localhost:9352/"} {
+		a, e = NewAPI(endpoint, testToken)
+		require.NoError(e)
+		u, e = a.GetApiURL(endpoint_get_revision + "abc")
+		require.NoError(e)
+		require.Equal("http://localhost:9352/data_accounting/get_revision/abc", u.String())
+	}
+
+	for _, endpoint := range []string{"http://localhost:9352/rest.php", "http://localhost:9352/rest.php/"} {
+		a, e = NewAPI(endpoint, testToken)
+		require.NoError(e)
+		u, e = a.GetApiURL(endpoint_get_hash_chain_info + "title?identifier=Main+Page")
+		require.NoError(e)
+		require.Equal("http://localhost:9352/rest.php/data_accounting/get_hash_chain_info/title?identifier=Main+Page", u.String())
+	}
 }
 
 func TestGetServerInfo(t *testing.T) {
@@ -118,7 +143,7 @@ func TestGetServerInfo(t *testing.T) {
 	require.NoError(e)
 	info, e := a.GetServerInfo()
 	require.NoError(e)
-	t.Logf("%s", info)
+	t.Logf("%+v", info)
 }
 
 func TestEtherscan(t *testing.T) {
@@ -129,3 +154,493 @@ func TestEtherscan(t *testing.T) {
 	e := CheckEtherscan("goerli", txHash, eventHash)
 	require.NoError(e)
 }
+
+func TestStoreRevision(t *testing.T) {
+	require := require.New(t)
+	rev := &Revision{
+		Content:  &RevisionContent{RevId: 1, Content: map[string]string{"main": "Hello"}},
+		Metadata: &RevisionMetadata{DomainId: "5e5a1ec586", VerificationHash: "abc"},
+	}
+	var stored = map[string]bool{}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(http.MethodPost, r.Method)
+		require.Equal("/rest.php/"+endpoint_store_revision, r.URL.Path)
+		require.Equal("Bearer secret", r.Header.Get("Authorization"))
+		got := new(Revision)
+		require.NoError(json.NewDecoder(r.Body).Decode(got))
+		require.Equal(rev.Content.Content, got.Content.Content)
+		require.Equal(rev.Metadata.VerificationHash, got.Metadata.VerificationHash)
+		if stored[got.Metadata.VerificationHash] {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		stored[got.Metadata.VerificationHash] = true
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer s.Close()
+
+	a, e := NewAPI(s.URL+"/rest.php", "secret")
+	require.NoError(e)
+	require.NoError(a.StoreRevision(context.Background(), rev))
+	require.ErrorIs(a.StoreRevision(context.Background(), rev), ErrRevisionExists)
+}
+
+func TestGetRevisionHashesSince(t *testing.T) {
+	require := require.New(t)
+	hashes := []string{"a", "b", "c", "d"}
+	var limit string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal("/rest.php/"+endpoint_get_revision_hashes+"a", r.URL.Path)
+		limit = r.URL.Query().Get("limit")
+		// ignore the limit to also exercise the client side truncation
+		json.NewEncoder(w).Encode(hashes)
+	}))
+	defer s.Close()
+
+	a, e := NewAPI(s.URL+"/rest.php", testToken)
+	require.NoError(e)
+	revHashes, e := a.GetRevisionHashesSince(context.Background(), "a", 2)
+	require.NoError(e)
+	require.Equal("2", limit)
+	require.Len(revHashes, 2)
+	require.Equal(RevisionHash("a"), *revHashes[0])
+	require.Equal(RevisionHash("b"), *revHashes[1])
+
+	revHashes, e = a.GetRevisionHashesSince(context.Background(), "a", 0)
+	require.NoError(e)
+	require.Equal("", limit)
+	require.Len(revHashes, 4)
+}
+
+func TestGetNewerRevisions(t *testing.T) {
+	require := require.New(t)
+	hashes := []string{"a", "b", "c", "d"}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested := strings.TrimPrefix(r.URL.Path, "/"+endpoint_get_revision_hashes)
+		for i, hash := range hashes {
+			if hash == requested {
+				json.NewEncoder(w).Encode(hashes[i:])
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer s.Close()
+
+	a, e := NewAPI(s.URL, testToken)
+	require.NoError(e)
+
+	// nothing newer
+	newer, e := a.GetNewerRevisions(context.Background(), "d")
+	require.NoError(e)
+	require.True(newer.IsLatest)
+	require.Zero(newer.Count)
+	require.Empty(newer.Hashes)
+
+	// three newer
+	newer, e = a.GetNewerRevisions(context.Background(), "a")
+	require.NoError(e)
+	require.False(newer.IsLatest)
+	require.Equal(3, newer.Count)
+	require.Len(newer.Hashes, 3)
+	require.Equal(RevisionHash("b"), *newer.Hashes[0])
+	require.Equal(RevisionHash("d"), *newer.Hashes[2])
+
+	_, e = a.GetNewerRevisions(context.Background(), "unknown")
+	require.Error(e)
+}
+
+func TestGetServerInfoFields(t *testing.T) {
+	require := require.New(t)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{
+			"api_version": "0.3.0",
+			"extension_version": "1.0.0-alpha",
+			"mediawiki_version": "1.37.1",
+			"php_version": "8.0.14",
+			"witness_networks": ["goerli", "mainnet"]
+		}`))
+	}))
+	defer s.Close()
+
+	a, e := NewAPI(s.URL, testToken)
+	require.NoError(e)
+	info, e := a.GetServerInfo()
+	require.NoError(e)
+	require.Equal("0.3.0", info.ApiVersion)
+	require.Equal("1.0.0-alpha", info.ExtensionVersion)
+	require.Equal("1.37.1", info.MediaWikiVersion)
+	require.Equal(map[string]json.RawMessage{
+		"php_version":      json.RawMessage(`"8.0.14"`),
+		"witness_networks": json.RawMessage(`["goerli", "mainnet"]`),
+	}, info.Extra)
+
+	info = new(ServerInfo)
+	require.NoError(json.Unmarshal([]byte(`{"api_version":"0.3.0"}`), info))
+	require.Nil(info.Extra)
+}
+
+func TestGetServerInfoAuthRequired(t *testing.T) {
+	require := require.New(t)
+	const token = "secret"
+	var anonymous bool
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth, sent := r.Header["Authorization"]
+		switch {
+		case anonymous && sent:
+			w.WriteHeader(http.StatusBadRequest)
+		case !anonymous && (!sent || auth[0] != "Bearer "+token):
+			w.WriteHeader(http.StatusUnauthorized)
+		default:
+			w.Write([]byte(`{"api_version":"` + Version + `"}`))
+		}
+	}))
+	defer s.Close()
+
+	// anonymous servers get no Authorization header without a token
+	anonymous = true
+	a, e := NewAPI(s.URL, "")
+	require.NoError(e)
+	info, e := a.GetServerInfo()
+	require.NoError(e)
+	require.Equal(Version, info.ApiVersion)
+
+	anonymous = false
+	_, e = a.GetServerInfo()
+	require.ErrorIs(e, ErrAuthRequired)
+	_, e = a.GetRevision("abc")
+	require.ErrorIs(e, ErrAuthRequired)
+
+	a, e = NewAPI(s.URL, "invalid")
+	require.NoError(e)
+	_, e = a.GetServerInfo()
+	require.ErrorIs(e, ErrAuthRequired)
+
+	a, e = NewAPI(s.URL, token)
+	require.NoError(e)
+	_, e = a.GetServerInfo()
+	require.NoError(e)
+}
+
+func TestHashChainInfoValidate(t *testing.T) {
+	require := require.New(t)
+	valid := HashChainInfo{GenesisHash: "genesis", LatestVerificationHash: "latest", ChainHeight: 2}
+	require.NoError(valid.Validate())
+	single := HashChainInfo{GenesisHash: "genesis", LatestVerificationHash: "genesis", ChainHeight: 1}
+	require.NoError(single.Validate())
+	valid.CurrentRevision = "latest"
+	require.NoError(valid.Validate())
+
+	for expected, ri := range map[string]HashChainInfo{
+		"Hash chain info has no genesis_hash":                                                    {LatestVerificationHash: "latest", ChainHeight: 2},
+		"Hash chain info has no latest_verification_hash":                                        {GenesisHash: "genesis", ChainHeight: 2},
+		"Hash chain info has invalid chain_height 0":                                             {GenesisHash: "genesis", LatestVerificationHash: "latest"},
+		"Hash chain info has invalid chain_height -1":                                            {GenesisHash: "genesis", LatestVerificationHash: "latest", ChainHeight: -1},
+		"Hash chain info of height 1 has a latest_verification_hash other than its genesis_hash": {GenesisHash: "genesis", LatestVerificationHash: "latest", ChainHeight: 1},
+		"Hash chain info of height 3 has its genesis_hash as latest_verification_hash":           {GenesisHash: "genesis", LatestVerificationHash: "genesis", ChainHeight: 3},
+		// a stale current revision after a failed write
+		"Hash chain info has current_revision previous other than its latest_verification_hash latest": {GenesisHash: "genesis", LatestVerificationHash: "latest", ChainHeight: 3, CurrentRevision: "previous"},
+	} {
+		require.EqualError(ri.Validate(), expected)
+	}
+}
+
+func TestAssertLatestHash(t *testing.T) {
+	require := require.New(t)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+endpoint_get_hash_chain_info+"title" || r.URL.Query().Get("identifier") != "Main Page" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(&HashChainInfo{GenesisHash: "genesis", LatestVerificationHash: "latest", ChainHeight: 2})
+	}))
+	defer s.Close()
+
+	a, e := NewAPI(s.URL, testToken)
+	require.NoError(e)
+	require.NoError(a.AssertLatestHash(context.Background(), "Main Page", "latest"))
+
+	e = a.AssertLatestHash(context.Background(), "Main Page", "genesis")
+	var mismatch *LatestHashMismatchError
+	require.True(errors.As(e, &mismatch))
+	require.Equal(&LatestHashMismatchError{Title: "Main Page", Expected: "genesis", Actual: "latest"}, mismatch)
+	require.EqualError(e, "Latest verification hash of Main Page is latest, expected genesis")
+
+	e = a.AssertLatestHash(context.Background(), "Unknown", "latest")
+	require.Error(e)
+	require.False(errors.As(e, &mismatch))
+}
+
+func TestEscapedIds(t *testing.T) {
+	require := require.New(t)
+	var paths, titles []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.EscapedPath())
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/"+endpoint_get_hash_chain_info):
+			titles = append(titles, r.URL.Query().Get("identifier"))
+			json.NewEncoder(w).Encode(&HashChainInfo{Title: r.URL.Query().Get("identifier")})
+		case strings.HasPrefix(r.URL.Path, "/"+endpoint_get_revision_hashes):
+			json.NewEncoder(w).Encode([]string{strings.TrimPrefix(r.URL.Path, "/"+endpoint_get_revision_hashes)})
+		case strings.HasPrefix(r.URL.Path, "/"+endpoint_get_revision):
+			json.NewEncoder(w).Encode(&Revision{Metadata: &RevisionMetadata{VerificationHash: strings.TrimPrefix(r.URL.Path, "/"+endpoint_get_revision)}})
+		}
+	}))
+	defer s.Close()
+	a, e := NewAPI(s.URL, testToken)
+	require.NoError(e)
+
+	for _, title := range []string{"My Document", "Projects/Plan A", "Ünïcödé 文書", "Q&A?x=1#top"} {
+		info, e := a.GetHashChainInfo("title", title)
+		require.NoError(e)
+		require.Equal(title, info.Title)
+	}
+	require.Equal([]string{"My Document", "Projects/Plan A", "Ünïcödé 文書", "Q&A?x=1#top"}, titles)
+
+	for _, id := range []string{"a b", "a/b", "文書", "a?b#c"} {
+		hashes, e := a.GetRevisionHashes(id)
+		require.NoError(e)
+		require.Equal([]*RevisionHash{(*RevisionHash)(&id)}, hashes)
+		r, e := a.GetRevision(id)
+		require.NoError(e)
+		require.Equal(id, r.Metadata.VerificationHash)
+	}
+	require.Contains(paths, "/"+endpoint_get_revision+"a%20b")
+	require.Contains(paths, "/"+endpoint_get_revision+"a%2Fb")
+	require.Contains(paths, "/"+endpoint_get_revision_hashes+"a%3Fb%23c")
+
+	u, e := a.GetApiURL(endpoint_get_revision + url.PathEscape("a/b"))
+	require.NoError(e)
+	require.Equal(s.URL+"/"+endpoint_get_revision+"a%2Fb", u.String())
+}
+
+func TestVerifyGenesisPinned(t *testing.T) {
+	require := require.New(t)
+	genesis := strings.Repeat("ab01", 32)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+endpoint_get_hash_chain_info+"title" || r.URL.Query().Get("identifier") != "Main Page" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(&HashChainInfo{GenesisHash: genesis, LatestVerificationHash: "latest", ChainHeight: 2})
+	}))
+	defer s.Close()
+
+	a, e := NewAPI(s.URL, testToken)
+	require.NoError(e)
+	require.NoError(a.VerifyGenesisPinned(context.Background(), "Main Page", genesis))
+	require.NoError(a.VerifyGenesisPinned(context.Background(), "Main Page", "0x"+strings.ToUpper(genesis)))
+
+	// the page was replaced by another chain
+	replaced := genesis
+	genesis = strings.Repeat("cd02", 32)
+	e = a.VerifyGenesisPinned(context.Background(), "Main Page", replaced)
+	var mismatch *GenesisHashMismatchError
+	require.True(errors.As(e, &mismatch))
+	require.Equal(&GenesisHashMismatchError{Title: "Main Page", Pinned: replaced, Actual: genesis}, mismatch)
+	require.EqualError(e, "Genesis hash of Main Page is "+genesis+", pinned "+replaced)
+
+	require.EqualError(a.VerifyGenesisPinned(context.Background(), "Main Page", ""), "No genesis hash pinned for Main Page")
+	e = a.VerifyGenesisPinned(context.Background(), "Unknown", genesis)
+	require.Error(e)
+	require.False(errors.As(e, &mismatch))
+}
+
+func TestVerifyChainHeight(t *testing.T) {
+	require := require.New(t)
+	hashes := []string{"abc", "def", "ghi"}
+	height := 3
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + endpoint_get_hash_chain_info + "title":
+			if r.URL.Query().Get("identifier") != "Main Page" {
+				http.NotFound(w, r)
+				return
+			}
+			json.NewEncoder(w).Encode(&HashChainInfo{GenesisHash: "abc", LatestVerificationHash: hashes[len(hashes)-1], ChainHeight: height})
+		case "/" + endpoint_get_revision_hashes + "abc":
+			json.NewEncoder(w).Encode(hashes)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer s.Close()
+
+	a, e := NewAPI(s.URL, testToken)
+	require.NoError(e)
+	require.NoError(a.VerifyChainHeight(context.Background(), "Main Page"))
+
+	// the server under-reports the height
+	height = 2
+	e = a.VerifyChainHeight(context.Background(), "Main Page")
+	var mismatch *ChainHeightMismatchError
+	require.True(errors.As(e, &mismatch))
+	require.Equal(&ChainHeightMismatchError{Title: "Main Page", Declared: 2, Observed: 3}, mismatch)
+	require.EqualError(e, "Chain height of Main Page is declared as 2, but 3 revisions were found")
+
+	// or over-reports it
+	height = 4
+	e = a.VerifyChainHeight(context.Background(), "Main Page")
+	require.True(errors.As(e, &mismatch))
+	require.Equal(4, mismatch.Declared)
+	require.Equal(3, mismatch.Observed)
+
+	e = a.VerifyChainHeight(context.Background(), "Unknown")
+	require.Error(e)
+	require.False(errors.As(e, &mismatch))
+}
+
+func TestResolveHashPrefix(t *testing.T) {
+	require := require.New(t)
+	hashes := []string{"ab12", "ab34", "cd56"}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + endpoint_get_hash_chain_info + "title":
+			json.NewEncoder(w).Encode(&HashChainInfo{GenesisHash: hashes[0], LatestVerificationHash: hashes[2], ChainHeight: 3})
+		case "/" + endpoint_get_revision_hashes + hashes[0]:
+			json.NewEncoder(w).Encode(hashes)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer s.Close()
+	a, e := NewAPI(s.URL, testToken)
+	require.NoError(e)
+
+	// unique
+	for prefix, expected := range map[string]string{"ab1": "ab12", "ab3": "ab34", "0xAB3": "ab34", "cd": "cd56", "cd56": "cd56"} {
+		hash, e := a.ResolveHashPrefix(context.Background(), "Main Page", prefix)
+		require.NoError(e, prefix)
+		require.Equal(expected, hash, prefix)
+	}
+
+	// ambiguous
+	_, e = a.ResolveHashPrefix(context.Background(), "Main Page", "ab")
+	require.EqualError(e, "Verification hash prefix ab is ambiguous, 2 revisions of Main Page match")
+
+	// absent
+	_, e = a.ResolveHashPrefix(context.Background(), "Main Page", "ef")
+	require.EqualError(e, "No revision of Main Page has a verification hash starting with ef")
+	_, e = a.ResolveHashPrefix(context.Background(), "Main Page", "")
+	require.Error(e)
+}
+
+func TestGetGenesisRevision(t *testing.T) {
+	require := require.New(t)
+	genesis := &Revision{
+		Context:  &VerificationContext{},
+		Metadata: &RevisionMetadata{VerificationHash: "abc"},
+	}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + endpoint_get_hash_chain_info + "title":
+			json.NewEncoder(w).Encode(&HashChainInfo{GenesisHash: "abc", LatestVerificationHash: "def", ChainHeight: 2})
+		case "/" + endpoint_get_revision + "abc":
+			json.NewEncoder(w).Encode(genesis)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer s.Close()
+	a, e := NewAPI(s.URL, testToken)
+	require.NoError(e)
+
+	r, e := a.GetGenesisRevision(context.Background(), "Main Page")
+	require.NoError(e)
+	require.Equal("abc", r.Metadata.VerificationHash)
+
+	genesis.Context.HasPreviousWitness = true
+	_, e = a.GetGenesisRevision(context.Background(), "Main Page")
+	require.EqualError(e, "Genesis revision abc has a previous signature or witness")
+
+	genesis.Metadata.PreviousVerificationHash = "012"
+	_, e = a.GetGenesisRevision(context.Background(), "Main Page")
+	require.EqualError(e, "Genesis revision abc has previous revision 012")
+
+	genesis.Metadata.VerificationHash = "def"
+	_, e = a.GetGenesisRevision(context.Background(), "Main Page")
+	require.EqualError(e, "Server returned another revision for genesis revision abc")
+}
+
+func TestGetRevisionByRevId(t *testing.T) {
+	require := require.New(t)
+	supported := true
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/"+endpoint_get_server_info:
+			w.Write([]byte(`{"api_version":"` + Version + `"}`))
+		case !supported:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"messageTranslations":{"en":"The requested relative path did not match any known handler"},"httpCode":404,"httpReason":"Not Found","errorKey":"rest-no-match"}`))
+		case r.URL.Path == "/"+endpoint_get_revision_by_id+"42":
+			w.Write([]byte(`{"content":{"rev_id":42},"metadata":{"verification_hash":"abc"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message":"Revision not found"}`))
+		}
+	}))
+	defer ts.Close()
+	a, e := NewAPI(ts.URL, testToken)
+	require.NoError(e)
+
+	r, e := a.GetRevisionByRevId(context.Background(), 42)
+	require.NoError(e)
+	require.Equal(42, r.Content.RevId)
+	require.Equal("abc", r.Metadata.VerificationHash)
+
+	_, e = a.GetRevisionByRevId(context.Background(), 43)
+	require.EqualError(e, "Request Not 200 OK")
+
+	supported = false
+	_, e = a.GetRevisionByRevId(context.Background(), 42)
+	require.True(errors.Is(e, ErrNotSupported))
+	require.EqualError(e, "Request not supported by the server: revision by rev_id with api version "+Version)
+}
+
+func TestConcurrentUse(t *testing.T) {
+	require := require.New(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/"+endpoint_get_server_info:
+			w.Write([]byte(`{"api_version":"` + Version + `"}`))
+		case strings.HasPrefix(r.URL.Path, "/"+endpoint_get_revision):
+			hash := strings.TrimPrefix(r.URL.Path, "/"+endpoint_get_revision)
+			w.Header().Set("ETag", `"`+hash+`"`)
+			if r.Header.Get("If-None-Match") == `"`+hash+`"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Write([]byte(`{"metadata":{"verification_hash":"` + hash + `"}}`))
+		case strings.HasPrefix(r.URL.Path, "/"+endpoint_get_hash_chain_info):
+			w.Write([]byte(`{"genesis_hash":"abc","site_info":{"namespaces":{"0":{"case":true,"title":""}}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+	a, e := NewAPI(ts.URL, testToken, WithETagCache())
+	require.NoError(e)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 64*3)
+	for i := 0; i < 64; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			hash := fmt.Sprintf("%02x", i%8)
+			r, e := a.GetRevision(hash)
+			if e == nil && r.Metadata.VerificationHash != hash {
+				e = fmt.Errorf("got revision %s instead of %s", r.Metadata.VerificationHash, hash)
+			}
+			errs <- e
+			_, e = a.GetHashChainInfo("title", "Main Page")
+			errs <- e
+			errs <- a.Discover(context.Background())
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for e := range errs {
+		require.NoError(e)
+	}
+}
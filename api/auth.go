@@ -0,0 +1,58 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// TokenProvider returns the authentication token to send with a request,
+// e.g. a short-lived token of an OIDC provider
+type TokenProvider func(ctx context.Context) (string, error)
+
+// WithTokenProvider makes the client ask p for the authentication token of
+// every request instead of using the token passed to NewAPI. A request fails
+// without being sent if p returns an error. Wrap p with CachedTokenProvider
+// to avoid fetching a new token for every request.
+func WithTokenProvider(p TokenProvider) Option {
+	return func(a *AquaProtocol) {
+		a.tokenProvider = p
+	}
+}
+
+// CachedTokenProvider returns a TokenProvider that reuses the token returned
+// by p until shortly before the expiry p returned along with it. It is safe
+// for concurrent use.
+func CachedTokenProvider(p func(ctx context.Context) (token string, expiry time.Time, err error)) TokenProvider {
+	// tokens are renewed early in case the request takes a while
+	const margin = 10 * time.Second
+	var mu sync.Mutex
+	var token string
+	var expiry time.Time
+	return func(ctx context.Context) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if token != "" && time.Now().Add(margin).Before(expiry) {
+			return token, nil
+		}
+		t, e, err := p(ctx)
+		if err != nil {
+			return "", err
+		}
+		token, expiry = t, e
+		return token, nil
+	}
+}
+
+// token returns the authentication token of the next request
+func (a *AquaProtocol) token(ctx context.Context) (string, error) {
+	if a.tokenProvider == nil {
+		return a.authToken, nil
+	}
+	token, err := a.tokenProvider(ctx)
+	if err != nil {
+		return "", fmt.Errorf("Failure getting authentication token: %w", err)
+	}
+	return token, nil
+}
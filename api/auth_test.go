@@ -0,0 +1,74 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithTokenProvider(t *testing.T) {
+	require := require.New(t)
+	var tokens []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokens = append(tokens, r.Header.Get("Authorization"))
+		w.Write([]byte(`{"api_version":"` + Version + `"}`))
+	}))
+	defer ts.Close()
+
+	var calls int
+	provider := func(ctx context.Context) (string, error) {
+		calls++
+		return fmt.Sprintf("token%d", calls), nil
+	}
+	a, e := NewAPI(ts.URL, "static", WithTokenProvider(provider))
+	require.NoError(e)
+	for i := 0; i < 2; i++ {
+		_, e = a.GetServerInfo()
+		require.NoError(e)
+	}
+	require.Equal([]string{"Bearer token1", "Bearer token2"}, tokens)
+
+	// a failing provider aborts the request
+	failure := errors.New("token endpoint down")
+	a, e = NewAPI(ts.URL, "static", WithTokenProvider(func(ctx context.Context) (string, error) {
+		return "", failure
+	}))
+	require.NoError(e)
+	_, e = a.GetServerInfo()
+	require.True(errors.Is(e, failure))
+	require.EqualError(e, "Failure getting authentication token: token endpoint down")
+	require.Len(tokens, 2)
+}
+
+func TestCachedTokenProvider(t *testing.T) {
+	require := require.New(t)
+	var calls int
+	expiry := time.Now().Add(time.Hour)
+	p := CachedTokenProvider(func(ctx context.Context) (string, time.Time, error) {
+		calls++
+		return fmt.Sprintf("token%d", calls), expiry, nil
+	})
+	for i := 0; i < 3; i++ {
+		token, e := p(context.Background())
+		require.NoError(e)
+		require.Equal("token1", token)
+	}
+
+	// tokens about to expire are renewed
+	expiry = time.Now().Add(time.Second)
+	p = CachedTokenProvider(func(ctx context.Context) (string, time.Time, error) {
+		calls++
+		return fmt.Sprintf("token%d", calls), expiry, nil
+	})
+	for _, expected := range []string{"token2", "token3"} {
+		token, e := p(context.Background())
+		require.NoError(e)
+		require.Equal(expected, token)
+	}
+}
@@ -0,0 +1,47 @@
+package api
+
+import "sync"
+
+// etagEntry holds a revision and the ETag it was served with
+type etagEntry struct {
+	etag     string
+	revision *Revision
+}
+
+// etagCache maps verification hashes to the ETag of the revision. It is safe
+// for concurrent use, and a nil *etagCache is a disabled cache.
+type etagCache struct {
+	sync.Mutex
+	entries map[string]*etagEntry
+}
+
+func newETagCache() *etagCache {
+	return &etagCache{entries: make(map[string]*etagEntry)}
+}
+
+func (c *etagCache) get(verification_hash string) *etagEntry {
+	if c == nil {
+		return nil
+	}
+	c.Lock()
+	defer c.Unlock()
+	return c.entries[verification_hash]
+}
+
+func (c *etagCache) put(verification_hash, etag string, r *Revision) {
+	if c == nil {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	c.entries[verification_hash] = &etagEntry{etag: etag, revision: r}
+}
+
+func (c *etagCache) remove(verification_hash string) {
+	if c == nil {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	delete(c.entries, verification_hash)
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetRevisionETag(t *testing.T) {
+	require := require.New(t)
+	etag := `"v1"`
+	var requests, notModified int
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == etag {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		json.NewEncoder(w).Encode(&Revision{
+			Metadata: &RevisionMetadata{VerificationHash: "abc"},
+		})
+	}))
+	defer s.Close()
+
+	o := &testObserver{}
+	a, e := NewAPI(s.URL, testToken, WithETagCache(), WithObserver(o))
+	require.NoError(e)
+	first, e := a.GetRevision("abc")
+	require.NoError(e)
+	require.Equal("abc", first.Metadata.VerificationHash)
+
+	// the server confirms the cached revision is unchanged, which is looked
+	// up by its normalized hash and returned as a copy
+	first.Metadata.VerificationHash = "mutated"
+	second, e := a.GetRevision("0xABC")
+	require.NoError(e)
+	require.Equal(1, notModified)
+	require.NotSame(first, second)
+	require.Equal("abc", second.Metadata.VerificationHash)
+	second.Metadata.VerificationHash = "mutated"
+	// the revalidation is not reported as a failed request
+	require.Len(o.observations, 2)
+	require.Equal(http.StatusNotModified, o.observations[1].status)
+	require.NoError(o.observations[1].err)
+
+	// the server serves the immutable revision with a different ETag
+	etag = `"v2"`
+	third, e := a.GetRevision("abc")
+	require.ErrorIs(e, ErrETagChanged)
+	require.NotNil(third)
+	require.Equal(3, requests)
+
+	// and the new ETag and revision are revalidated from then on
+	fourth, e := a.GetRevision("abc")
+	require.NoError(e)
+	require.Equal(2, notModified)
+	require.Equal(third, fourth)
+	require.NotSame(third, fourth)
+	require.Equal(1, len(a.etags.entries))
+
+	// a revision served without an ETag is not kept
+	etag = ""
+	_, e = a.GetRevision("abc")
+	require.NoError(e)
+	require.Nil(a.etags.get("abc"))
+	require.Equal(5, requests)
+
+	// without the cache no conditional requests are made
+	a, e = NewAPI(s.URL, testToken)
+	require.NoError(e)
+	etag = `"v3"`
+	_, e = a.GetRevision("abc")
+	require.NoError(e)
+	_, e = a.GetRevision("abc")
+	require.NoError(e)
+	require.Equal(2, notModified)
+}
+
+func TestGetHashChainInfoIfChanged(t *testing.T) {
+	require := require.New(t)
+	etag, lastModified := `"v1"`, "Wed, 14 Oct 2026 10:00:00 GMT"
+	var requests, notModified int
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if (etag != "" && r.Header.Get("If-None-Match") == etag) || r.Header.Get("If-Modified-Since") == lastModified {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		if etag != "" {
+			w.Header().Set("ETag", etag)
+		}
+		w.Header().Set("Last-Modified", lastModified)
+		json.NewEncoder(w).Encode(&HashChainInfo{Title: "Main Page", LatestVerificationHash: etag})
+	}))
+	defer s.Close()
+
+	a, e := NewAPI(s.URL, testToken)
+	require.NoError(e)
+	first, e := a.GetHashChainInfo("title", "Main Page")
+	require.NoError(e)
+	require.Equal(etag, first.ETag)
+	require.Equal(lastModified, first.LastModified)
+
+	// the page is unchanged
+	second, e := a.GetHashChainInfoIfChanged(context.Background(), "title", "Main Page", first)
+	require.ErrorIs(e, ErrNotModified)
+	require.Same(first, second)
+	require.Equal(1, notModified)
+
+	// the page got a new revision
+	etag, lastModified = `"v2"`, "Wed, 14 Oct 2026 10:01:00 GMT"
+	third, e := a.GetHashChainInfoIfChanged(context.Background(), "title", "Main Page", first)
+	require.NoError(e)
+	require.Equal(`"v2"`, third.LatestVerificationHash)
+	require.Equal(etag, third.ETag)
+	require.Equal(1, notModified)
+
+	// servers without ETags are polled with If-Modified-Since
+	etag = ""
+	fourth, e := a.GetHashChainInfoIfChanged(context.Background(), "title", "Main Page", &HashChainInfo{LastModified: lastModified})
+	require.ErrorIs(e, ErrNotModified)
+	require.Equal(lastModified, fourth.LastModified)
+
+	// without validators the request is unconditional
+	_, e = a.GetHashChainInfoIfChanged(context.Background(), "title", "Main Page", nil)
+	require.NoError(e)
+	require.Equal(2, notModified)
+	require.Equal(5, requests)
+}
@@ -0,0 +1,17 @@
+package api
+
+import "context"
+
+// AquaClient is the read side of the data accounting api. It is implemented
+// by AquaProtocol and lets callers substitute other sources of revisions,
+// e.g. to compare several mirrors, or backends of other transports, e.g. a
+// GraphQL endpoint. The verify package verifies hash chains fetched from any
+// AquaClient.
+type AquaClient interface {
+	GetHashChainInfoContext(ctx context.Context, id_type, id string) (*HashChainInfo, error)
+	GetRevisionHashesContext(ctx context.Context, verification_hash string) ([]*RevisionHash, error)
+	GetRevisionContext(ctx context.Context, verification_hash string) (*Revision, error)
+	GetServerInfoContext(ctx context.Context) (*ServerInfo, error)
+}
+
+var _ AquaClient = (*AquaProtocol)(nil)
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClose(t *testing.T) {
+	require := require.New(t)
+	var mu sync.Mutex
+	states := map[http.ConnState]int{}
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"api_version":"` + Version + `"}`))
+	}))
+	ts.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		mu.Lock()
+		defer mu.Unlock()
+		states[state]++
+	}
+	ts.Start()
+	defer ts.Close()
+	count := func(state http.ConnState) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return states[state]
+	}
+
+	a, e := NewAPI(ts.URL, testToken)
+	require.NoError(e)
+	for i := 0; i < 3; i++ {
+		_, e = a.GetServerInfo()
+		require.NoError(e)
+	}
+	// the requests reuse the connection
+	require.Equal(1, count(http.StateNew))
+	require.Zero(count(http.StateClosed))
+
+	require.NoError(a.Close())
+	require.NoError(a.Close())
+	require.Eventually(func() bool { return count(http.StateClosed) == 1 }, time.Second, 10*time.Millisecond)
+
+	_, e = a.GetServerInfo()
+	require.ErrorIs(e, ErrClosed)
+	require.ErrorIs(a.Ping(context.Background()), ErrClosed)
+	require.Equal(1, count(http.StateNew))
+
+	// a new client reconnects
+	a, e = NewAPI(ts.URL, testToken)
+	require.NoError(e)
+	require.NoError(a.Ping(context.Background()))
+	require.Equal(2, count(http.StateNew))
+}
+
+func TestResponseConnectionsReused(t *testing.T) {
+	require := require.New(t)
+	var mu sync.Mutex
+	var conns int
+	padding := strings.Repeat(" ", 16<<10)
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + endpoint_get_server_info:
+			w.Write([]byte(`{"api_version":"` + Version + `"}` + padding))
+		case "/" + endpoint_get_revision + "abc":
+			w.Write([]byte(`{"metadata":{"verification_hash":"abc"}}` + padding))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("<html>Not found</html>" + padding))
+		}
+	}))
+	ts.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			mu.Lock()
+			defer mu.Unlock()
+			conns++
+		}
+	}
+	ts.Start()
+	defer ts.Close()
+
+	a, e := NewAPI(ts.URL, testToken)
+	require.NoError(e)
+	for i := 0; i < 3; i++ {
+		_, e = a.GetServerInfo()
+		require.NoError(e)
+		_, e = a.GetRevision("abc")
+		require.NoError(e)
+		_, e = a.GetRevision("unknown")
+		require.Error(e)
+		_, e = a.GetRevisionHashes("unknown")
+		require.Error(e)
+	}
+	// trailing data and error pages don't cost the connection
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(1, conns)
+}
+
+func TestCloseOwnTransport(t *testing.T) {
+	require := require.New(t)
+	var mu sync.Mutex
+	states := map[http.ConnState]int{}
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"api_version":"` + Version + `"}`))
+	}))
+	ts.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		mu.Lock()
+		defer mu.Unlock()
+		states[state]++
+	}
+	ts.Start()
+	defer ts.Close()
+	count := func(state http.ConnState) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return states[state]
+	}
+
+	// each client has its own connections, and closing one keeps the other's
+	a, e := NewAPI(ts.URL, testToken)
+	require.NoError(e)
+	b, e := NewAPI(ts.URL, testToken)
+	require.NoError(e)
+	_, e = a.GetServerInfo()
+	require.NoError(e)
+	_, e = b.GetServerInfo()
+	require.NoError(e)
+	require.Equal(2, count(http.StateNew))
+	require.NoError(a.Close())
+	require.Eventually(func() bool { return count(http.StateClosed) == 1 }, time.Second, 10*time.Millisecond)
+	_, e = b.GetServerInfo()
+	require.NoError(e)
+	require.Equal(2, count(http.StateNew))
+	require.NoError(b.Close())
+	require.Eventually(func() bool { return count(http.StateClosed) == 2 }, time.Second, 10*time.Millisecond)
+
+	// the connections of a client passed in are left open
+	shared := &http.Client{Transport: http.DefaultTransport.(*http.Transport).Clone()}
+	defer shared.CloseIdleConnections()
+	a, e = NewAPI(ts.URL, testToken, WithHTTPClient(shared))
+	require.NoError(e)
+	_, e = a.GetServerInfo()
+	require.NoError(e)
+	require.NoError(a.Close())
+	b, e = NewAPI(ts.URL, testToken, WithHTTPClient(shared))
+	require.NoError(e)
+	_, e = b.GetServerInfo()
+	require.NoError(e)
+	require.Equal(3, count(http.StateNew))
+	require.Equal(2, count(http.StateClosed))
+}
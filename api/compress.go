@@ -0,0 +1,60 @@
+package api
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// acceptEncoding is sent with every request. Setting it disables the
+// transparent gzip support of http.Transport, so that decompressBody handles
+// both encodings the same way.
+const acceptEncoding = "gzip, deflate"
+
+// decompressedBody closes both the decompressor and the response body
+type decompressedBody struct {
+	io.Reader
+	decompressor io.Closer
+	body         io.Closer
+}
+
+func (d *decompressedBody) Close() error {
+	d.decompressor.Close()
+	return d.body.Close()
+}
+
+// decompressBody replaces the body of resp with its decompressed content if it
+// is gzip or deflate encoded
+func decompressBody(resp *http.Response) error {
+	var r io.ReadCloser
+	var err error
+	switch strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))) {
+	case "gzip", "x-gzip":
+		r, err = gzip.NewReader(resp.Body)
+	case "deflate":
+		// deflate is meant to be zlib wrapped, but some servers send raw
+		// deflate data
+		b := bufio.NewReader(resp.Body)
+		header, _ := b.Peek(2)
+		if len(header) == 2 && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+			r, err = zlib.NewReader(b)
+		} else {
+			r = flate.NewReader(b)
+		}
+	default:
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body = &decompressedBody{Reader: r, decompressor: r, body: resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return nil
+}
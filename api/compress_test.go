@@ -0,0 +1,61 @@
+package api
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressedResponses(t *testing.T) {
+	require := require.New(t)
+	body := []byte(`{"api_version":"` + Version + `"}`)
+	compress := map[string]func(io.Writer) io.WriteCloser{
+		"gzip":    func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) },
+		"deflate": func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) },
+		// raw deflate as sent by some servers
+		"Deflate": func(w io.Writer) io.WriteCloser {
+			f, _ := flate.NewWriter(w, flate.DefaultCompression)
+			return f
+		},
+		"": nil,
+	}
+	var encoding string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(acceptEncoding, r.Header.Get("Accept-Encoding"))
+		c := compress[encoding]
+		if c == nil {
+			w.Write(body)
+			return
+		}
+		var b bytes.Buffer
+		cw := c(&b)
+		cw.Write(body)
+		cw.Close()
+		w.Header().Set("Content-Encoding", encoding)
+		w.Write(b.Bytes())
+	}))
+	defer s.Close()
+
+	a, e := NewAPI(s.URL, testToken)
+	require.NoError(e)
+	for encoding = range compress {
+		info, e := a.GetServerInfo()
+		require.NoError(e, encoding)
+		require.Equal(Version, info.ApiVersion, encoding)
+	}
+
+	// a body that claims to be gzipped but isn't fails
+	s.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(body)
+	})
+	_, e = a.GetServerInfo()
+	require.Error(e)
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiscover(t *testing.T) {
+	require := require.New(t)
+	aqua := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest.php/"+endpoint_get_server_info {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"api_version":"` + Version + `"}`))
+	}))
+	defer aqua.Close()
+	redirect := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, aqua.URL+"/rest.php"+r.URL.Path, http.StatusMovedPermanently)
+	}))
+	defer redirect.Close()
+	html := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><body>Welcome</body></html>"))
+	}))
+	defer html.Close()
+
+	a, e := NewAPI(aqua.URL+"/rest.php", testToken)
+	require.NoError(e)
+	require.NoError(a.Discover(context.Background()))
+	require.Equal(aqua.URL+"/rest.php", a.apiEndpoint)
+
+	// the endpoint the redirect leads to is used from then on
+	a, e = NewAPI(redirect.URL, testToken)
+	require.NoError(e)
+	require.NoError(a.Discover(context.Background()))
+	require.Equal(aqua.URL+"/rest.php", a.apiEndpoint)
+
+	for _, endpoint := range []string{html.URL, aqua.URL} {
+		a, e = NewAPI(endpoint, testToken)
+		require.NoError(e)
+		e = a.Discover(context.Background())
+		require.True(errors.Is(e, ErrNotAquaServer), endpoint)
+		require.Equal(endpoint, a.apiEndpoint)
+	}
+}
+
+func TestPing(t *testing.T) {
+	require := require.New(t)
+	body := `{"api_version":"` + Version + `"}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" || r.URL.Path != "/"+endpoint_get_server_info {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	defer ts.Close()
+
+	// the token is not sent
+	a, e := NewAPI(ts.URL, "invalid")
+	require.NoError(e)
+	require.NoError(a.Ping(context.Background()))
+
+	body = "<html>Maintenance</html>"
+	e = a.Ping(context.Background())
+	require.Error(e)
+	require.Contains(e.Error(), "Server info is not valid JSON")
+
+	body = `{"status":"ok"}`
+	require.EqualError(a.Ping(context.Background()), "Server info has no api_version")
+
+	a, e = NewAPI(ts.URL+"/wrong", "")
+	require.NoError(e)
+	require.EqualError(a.Ping(context.Background()), "Server info returned 401 Unauthorized")
+
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+	a, e = NewAPI(unreachable.URL, "")
+	require.NoError(e)
+	require.Error(a.Ping(context.Background()))
+}
+
+func TestConnect(t *testing.T) {
+	require := require.New(t)
+	aqua := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+endpoint_get_server_info {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"api_version":"` + Version + `"}`))
+	}))
+	defer aqua.Close()
+	html := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><body>Welcome</body></html>"))
+	}))
+	defer html.Close()
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+
+	a, e := Connect(context.Background(), aqua.URL, testToken)
+	require.NoError(e)
+	require.Equal(Version, a.ServerInfo().ApiVersion)
+	_, e = a.GetServerInfo()
+	require.NoError(e)
+
+	_, e = Connect(context.Background(), html.URL, testToken)
+	require.True(errors.Is(e, ErrNotAquaServer))
+
+	_, e = Connect(context.Background(), unreachable.URL, testToken)
+	require.Error(e)
+	require.False(errors.Is(e, ErrNotAquaServer))
+
+	// NewAPI doesn't fetch the server info
+	a, e = NewAPI(aqua.URL, testToken)
+	require.NoError(e)
+	require.Nil(a.ServerInfo())
+}
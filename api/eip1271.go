@@ -0,0 +1,37 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// eip1271MagicValue is returned by isValidSignature for valid signatures
+var eip1271MagicValue = crypto.Keccak256([]byte("isValidSignature(bytes32,bytes)"))[:4]
+
+// CheckEIP1271Signature calls isValidSignature of the smart contract wallet
+// using the ethereum JSON-RPC endpoint at rpcURL and reports whether the
+// contract accepts signature as a signature of hash.
+func CheckEIP1271Signature(ctx context.Context, rpcURL, wallet string, hash, signature []byte) (bool, error) {
+	if !common.IsHexAddress(wallet) {
+		return false, errors.New("Invalid wallet address")
+	}
+	if len(hash) != 32 {
+		return false, errors.New("Invalid hash length")
+	}
+	data := append([]byte{}, eip1271MagicValue...)
+	data = append(data, hash...)
+	data = append(data, common.LeftPadBytes([]byte{0x40}, 32)...)
+	data = append(data, common.LeftPadBytes(big.NewInt(int64(len(signature))).Bytes(), 32)...)
+	data = append(data, common.RightPadBytes(signature, (len(signature)+31)/32*32)...)
+
+	out, err := ethCall(ctx, rpcURL, common.HexToAddress(wallet), data)
+	if err != nil {
+		return false, err
+	}
+	return len(out) >= 4 && bytes.Equal(out[:4], eip1271MagicValue), nil
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckEIP1271Signature(t *testing.T) {
+	require := require.New(t)
+	hash := make([]byte, 32)
+	signature := []byte("contract signature")
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Params []json.RawMessage `json:"params"`
+		}
+		require.NoError(json.NewDecoder(r.Body).Decode(&req))
+		var call struct {
+			To   string `json:"to"`
+			Data string `json:"data"`
+		}
+		require.NoError(json.Unmarshal(req.Params[0], &call))
+		data, err := hex.DecodeString(strings.TrimPrefix(call.Data, "0x"))
+		require.NoError(err)
+		require.Equal(eip1271MagicValue, data[:4])
+		require.Equal(hash, data[4:36])
+		// the contract only accepts signature
+		result := common.RightPadBytes([]byte{0xff, 0xff, 0xff, 0xff}, 32)
+		if strings.Contains(string(data[100:]), string(signature)) {
+			result = common.RightPadBytes(eip1271MagicValue, 32)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"jsonrpc": "2.0", "id": 1, "result": "0x" + hex.EncodeToString(result),
+		})
+	}))
+	defer s.Close()
+
+	ok, err := CheckEIP1271Signature(context.Background(), s.URL, testWallet, hash, signature)
+	require.NoError(err)
+	require.True(ok)
+	ok, err = CheckEIP1271Signature(context.Background(), s.URL, testWallet, hash, []byte("forged"))
+	require.NoError(err)
+	require.False(ok)
+	_, err = CheckEIP1271Signature(context.Background(), s.URL, "wrong", hash, signature)
+	require.Error(err)
+}
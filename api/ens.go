@@ -0,0 +1,166 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ensRegistry is the address of the ENS registry on mainnet
+const ensRegistry = "0x00000000000C2E074eC69A0dFb2997BA6C7d2e1e"
+
+var (
+	ensResolverSelector = crypto.Keccak256([]byte("resolver(bytes32)"))[:4]
+	ensNameSelector     = crypto.Keccak256([]byte("name(bytes32)"))[:4]
+	ensAddrSelector     = crypto.Keccak256([]byte("addr(bytes32)"))[:4]
+)
+
+// ResolveENS performs an ENS reverse resolution of address using the ethereum
+// JSON-RPC endpoint at rpcURL and returns the primary ENS name. The name is
+// only returned if it resolves back to address. If no name is set, address is
+// returned unchanged. On RPC errors address is returned together with the
+// error, so that callers can fall back to the hex address.
+func ResolveENS(ctx context.Context, rpcURL, address string) (string, error) {
+	if !common.IsHexAddress(address) {
+		return address, errors.New("Invalid wallet address")
+	}
+	addr := common.HexToAddress(address)
+	reverseNode := ensNamehash(strings.ToLower(hex.EncodeToString(addr.Bytes())) + ".addr.reverse")
+
+	resolver, err := ensResolver(ctx, rpcURL, reverseNode)
+	if err != nil || resolver == (common.Address{}) {
+		return address, err
+	}
+	out, err := ethCall(ctx, rpcURL, resolver, append(ensNameSelector, reverseNode...))
+	if err != nil {
+		return address, err
+	}
+	name, err := decodeABIString(out)
+	if err != nil || name == "" {
+		return address, err
+	}
+
+	// the reverse record is set by the owner of the address, so it has to be
+	// checked against the forward resolution of the name
+	node := ensNamehash(name)
+	resolver, err = ensResolver(ctx, rpcURL, node)
+	if err != nil || resolver == (common.Address{}) {
+		return address, err
+	}
+	out, err = ethCall(ctx, rpcURL, resolver, append(ensAddrSelector, node...))
+	if err != nil {
+		return address, err
+	}
+	if len(out) < 32 || common.BytesToAddress(out[:32]) != addr {
+		return address, nil
+	}
+	return name, nil
+}
+
+// ensNamehash implements the ENS namehash algorithm (EIP-137)
+func ensNamehash(name string) []byte {
+	node := make([]byte, 32)
+	if name == "" {
+		return node
+	}
+	labels := strings.Split(name, ".")
+	for i := len(labels) - 1; i >= 0; i-- {
+		node = crypto.Keccak256(node, crypto.Keccak256([]byte(labels[i])))
+	}
+	return node
+}
+
+// ensResolver returns the resolver of node from the ENS registry
+func ensResolver(ctx context.Context, rpcURL string, node []byte) (common.Address, error) {
+	out, err := ethCall(ctx, rpcURL, common.HexToAddress(ensRegistry), append(ensResolverSelector, node...))
+	if err != nil {
+		return common.Address{}, err
+	}
+	if len(out) < 32 {
+		return common.Address{}, nil
+	}
+	return common.BytesToAddress(out[:32]), nil
+}
+
+// decodeABIString decodes an ABI encoded string return value
+func decodeABIString(out []byte) (string, error) {
+	if len(out) == 0 {
+		return "", nil
+	}
+	if len(out) < 64 {
+		return "", errors.New("Invalid ABI encoded string")
+	}
+	offset := new(big.Int).SetBytes(out[:32])
+	if !offset.IsInt64() || offset.Int64()+32 > int64(len(out)) {
+		return "", errors.New("Invalid ABI encoded string")
+	}
+	start := offset.Int64() + 32
+	length := new(big.Int).SetBytes(out[start-32 : start])
+	if !length.IsInt64() || start+length.Int64() > int64(len(out)) {
+		return "", errors.New("Invalid ABI encoded string")
+	}
+	return string(out[start : start+length.Int64()]), nil
+}
+
+// ethCall performs an eth_call JSON-RPC request against the latest block
+func ethCall(ctx context.Context, rpcURL string, to common.Address, data []byte) ([]byte, error) {
+	var result string
+	err := rpcCall(ctx, rpcURL, "eth_call", []interface{}{
+		map[string]string{"to": to.Hex(), "data": "0x" + hex.EncodeToString(data)},
+		"latest",
+	}, &result)
+	if err != nil {
+		return nil, err
+	}
+	return hex.DecodeString(strings.TrimPrefix(result, "0x"))
+}
+
+// rpcCall performs a JSON-RPC request and decodes its result into result
+func rpcCall(ctx context.Context, rpcURL, method string, params []interface{}, result interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  method,
+		"params":  params,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, rpcURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var r struct {
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return err
+	}
+	if r.Error != nil {
+		return fmt.Errorf("RPC error %d: %s", r.Error.Code, r.Error.Message)
+	}
+	if len(r.Result) == 0 {
+		return errors.New("RPC response has no result")
+	}
+	return json.Unmarshal(r.Result, result)
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testWallet   = "0x1ad5da43de60aa7d311f9b4e9c3342c155e6d2e0"
+	testResolver = "0x4976fb03C32e5B8cfe2b6cCB31c09Ba78EBaBa41"
+)
+
+// ensServer returns a fake JSON-RPC server which resolves testWallet to name,
+// and name to forward
+func ensServer(t *testing.T, name, forward string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Params []json.RawMessage `json:"params"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		var call struct {
+			To   string `json:"to"`
+			Data string `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(req.Params[0], &call))
+		data, err := hex.DecodeString(strings.TrimPrefix(call.Data, "0x"))
+		require.NoError(t, err)
+
+		word := func(b []byte) []byte {
+			return common.LeftPadBytes(b, 32)
+		}
+		var out []byte
+		switch string(data[:4]) {
+		case string(ensResolverSelector):
+			out = word(common.HexToAddress(testResolver).Bytes())
+		case string(ensNameSelector):
+			padded := common.RightPadBytes([]byte(name), (len(name)+31)/32*32)
+			out = append(append(word([]byte{32}), word([]byte{byte(len(name))})...), padded...)
+		case string(ensAddrSelector):
+			out = word(common.HexToAddress(forward).Bytes())
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"jsonrpc": "2.0", "id": 1, "result": "0x" + hex.EncodeToString(out),
+		})
+	}))
+}
+
+func TestResolveENS(t *testing.T) {
+	require := require.New(t)
+	s := ensServer(t, "alice.eth", testWallet)
+	defer s.Close()
+	name, err := ResolveENS(context.Background(), s.URL, testWallet)
+	require.NoError(err)
+	require.Equal("alice.eth", name)
+}
+
+func TestResolveENSNoName(t *testing.T) {
+	require := require.New(t)
+	s := ensServer(t, "", testWallet)
+	defer s.Close()
+	name, err := ResolveENS(context.Background(), s.URL, testWallet)
+	require.NoError(err)
+	require.Equal(testWallet, name)
+
+	// the name does not resolve back to the wallet
+	s = ensServer(t, "mallory.eth", testResolver)
+	defer s.Close()
+	name, err = ResolveENS(context.Background(), s.URL, testWallet)
+	require.NoError(err)
+	require.Equal(testWallet, name)
+}
+
+func TestResolveENSRPCError(t *testing.T) {
+	require := require.New(t)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"rate limited"}}`))
+	}))
+	defer s.Close()
+	name, err := ResolveENS(context.Background(), s.URL, testWallet)
+	require.EqualError(err, "RPC error -32000: rate limited")
+	require.Equal(testWallet, name)
+
+	s.Close()
+	name, err = ResolveENS(context.Background(), s.URL, testWallet)
+	require.Error(err)
+	require.Equal(testWallet, name)
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Environment variables read by NewAPIFromEnv
+const (
+	EnvEndpoint = "AQUA_ENDPOINT"
+	EnvToken    = "AQUA_TOKEN"
+	// EnvTimeout is optional and holds a time.ParseDuration duration, e.g. 30s
+	EnvTimeout = "AQUA_TIMEOUT"
+)
+
+// EnvLocalEndpoint overrides the endpoint of NewLocalAPI
+const EnvLocalEndpoint = "AQUA_LOCAL_ENDPOINT"
+
+// LocalEndpoint is the endpoint of the MediaWiki of a local development setup
+const LocalEndpoint = "http://localhost:9352/rest.php"
+
+// NewAPIFromEnv returns an AquaProtocol configured from the AQUA_ENDPOINT,
+// AQUA_TOKEN and optional AQUA_TIMEOUT environment variables. The options are
+// applied after the environment, so that they take precedence.
+func NewAPIFromEnv(opts ...Option) (*AquaProtocol, error) {
+	endpoint := os.Getenv(EnvEndpoint)
+	token := os.Getenv(EnvToken)
+	var missing []string
+	if endpoint == "" {
+		missing = append(missing, EnvEndpoint)
+	}
+	if token == "" {
+		missing = append(missing, EnvToken)
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("Missing environment variables: %s", strings.Join(missing, ", "))
+	}
+	if err := validateEndpoint(EnvEndpoint, endpoint); err != nil {
+		return nil, err
+	}
+	if s := os.Getenv(EnvTimeout); s != "" {
+		timeout, err := time.ParseDuration(s)
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("Invalid %s %q: expected a positive duration such as 30s", EnvTimeout, s)
+		}
+		// set on the client created by NewAPI, so that Close still closes
+		// its connections
+		opts = append([]Option{func(a *AquaProtocol) { a.apiClient.Timeout = timeout }}, opts...)
+	}
+	return NewAPI(endpoint, token, opts...)
+}
+
+// NewLocalAPI returns an AquaProtocol for the MediaWiki of a local development
+// setup, at LocalEndpoint unless the AQUA_LOCAL_ENDPOINT environment variable
+// is set
+func NewLocalAPI(token string, opts ...Option) (*AquaProtocol, error) {
+	endpoint := LocalEndpoint
+	if e := os.Getenv(EnvLocalEndpoint); e != "" {
+		endpoint = e
+		if err := validateEndpoint(EnvLocalEndpoint, endpoint); err != nil {
+			return nil, err
+		}
+	}
+	return NewAPI(endpoint, token, opts...)
+}
+
+// validateEndpoint checks that the endpoint read from the environment
+// variable name is an http(s) URL
+func validateEndpoint(name, endpoint string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("Invalid %s %q: expected an http(s) URL", name, endpoint)
+	}
+	return nil
+}
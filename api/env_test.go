@@ -0,0 +1,60 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAPIFromEnv(t *testing.T) {
+	require := require.New(t)
+	t.Setenv(EnvEndpoint, "")
+	t.Setenv(EnvToken, "")
+	t.Setenv(EnvTimeout, "")
+
+	_, e := NewAPIFromEnv()
+	require.EqualError(e, "Missing environment variables: AQUA_ENDPOINT, AQUA_TOKEN")
+
+	t.Setenv(EnvToken, "secret")
+	_, e = NewAPIFromEnv()
+	require.EqualError(e, "Missing environment variables: AQUA_ENDPOINT")
+
+	t.Setenv(EnvEndpoint, "localhost:9352")
+	_, e = NewAPIFromEnv()
+	require.EqualError(e, `Invalid AQUA_ENDPOINT "localhost:9352": expected an http(s) URL`)
+
+	t.Setenv(EnvEndpoint, "http://localhost:9352/rest.php/")
+	a, e := NewAPIFromEnv()
+	require.NoError(e)
+	require.Equal("http://localhost:9352/rest.php", a.apiEndpoint)
+	require.Equal("secret", a.authToken)
+	require.Zero(a.apiClient.Timeout)
+
+	t.Setenv(EnvTimeout, "soon")
+	_, e = NewAPIFromEnv()
+	require.EqualError(e, `Invalid AQUA_TIMEOUT "soon": expected a positive duration such as 30s`)
+
+	t.Setenv(EnvTimeout, "30s")
+	a, e = NewAPIFromEnv()
+	require.NoError(e)
+	require.Equal(30*time.Second, a.apiClient.Timeout)
+}
+
+func TestNewLocalAPI(t *testing.T) {
+	require := require.New(t)
+	t.Setenv(EnvLocalEndpoint, "")
+	a, e := NewLocalAPI("secret")
+	require.NoError(e)
+	require.Equal("http://localhost:9352/rest.php", a.apiEndpoint)
+	require.Equal("secret", a.authToken)
+
+	t.Setenv(EnvLocalEndpoint, "http://127.0.0.1:8080/rest.php/")
+	a, e = NewLocalAPI("")
+	require.NoError(e)
+	require.Equal("http://127.0.0.1:8080/rest.php", a.apiEndpoint)
+
+	t.Setenv(EnvLocalEndpoint, "localhost:8080")
+	_, e = NewLocalAPI("")
+	require.EqualError(e, `Invalid AQUA_LOCAL_ENDPOINT "localhost:8080": expected an http(s) URL`)
+}
@@ -0,0 +1,21 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+)
+
+// ExportChain writes the hash chain of the page with the given title to w in
+// the offline export format read by verify.VerifyExport, i.e. as OfflineData
+// holding the page with all of its revisions keyed by verification hash and
+// the site info of the server.
+func (a *AquaProtocol) ExportChain(ctx context.Context, w io.Writer, title string) error {
+	data, err := a.GetHashChain(ctx, "title", title, -1)
+	if err != nil {
+		return err
+	}
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(&OfflineData{Pages: []*HashChain{data}, SiteInfo: data.SiteInfo})
+}
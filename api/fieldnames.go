@@ -0,0 +1,91 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"unicode"
+)
+
+// snakeCaseFields returns the JSON object data with its camelCase keys, e.g.
+// verificationHash as served by some alternative implementations and
+// reverse proxies, renamed to the snake_case names of the protocol, e.g.
+// verification_hash. Only the keys of the object itself are renamed, not the
+// keys of nested objects, which are renamed by the UnmarshalJSON methods of
+// their own types; the content slots of a revision keep their names. A key
+// that is also present in snake_case is dropped. Other JSON values and
+// objects without camelCase keys are returned unchanged.
+func snakeCaseFields(data []byte) ([]byte, error) {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) == 0 || trimmed[0] != '{' || !bytes.ContainsFunc(trimmed, unicode.IsUpper) {
+		return data, nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	renamed := make(map[string]json.RawMessage, len(fields))
+	changed := false
+	for name, value := range fields {
+		snake := camelToSnake(name)
+		if snake == name {
+			renamed[name] = value
+			continue
+		}
+		changed = true
+		if _, ok := fields[snake]; !ok {
+			renamed[snake] = value
+		}
+	}
+	if !changed {
+		return data, nil
+	}
+	return json.Marshal(renamed)
+}
+
+// camelToSnake returns the snake_case form of the camelCase name, e.g.
+// verification_hash for verificationHash and rev_id for revID
+func camelToSnake(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			// a word starts at an upper case letter following a lower case
+			// letter or digit, or at the last letter of an acronym
+			if i > 0 && runes[i-1] != '_' && (!unicode.IsUpper(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// UnmarshalJSON decodes a Revision, accepting camelCase field names and the
+// array form of the signature field of revisions with several signatures
+func (r *Revision) UnmarshalJSON(data []byte) error {
+	data, err := snakeCaseFields(data)
+	if err != nil {
+		return err
+	}
+	type revision Revision
+	v := struct {
+		*revision
+		Signature json.RawMessage `json:"signature"`
+	}{revision: (*revision)(r)}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	return r.unmarshalSignatures(v.Signature)
+}
+
+// UnmarshalJSON decodes a VerificationContext, accepting camelCase field
+// names
+func (c *VerificationContext) UnmarshalJSON(data []byte) error {
+	data, err := snakeCaseFields(data)
+	if err != nil {
+		return err
+	}
+	type verificationContext VerificationContext
+	return json.Unmarshal(data, (*verificationContext)(c))
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCamelCaseFields(t *testing.T) {
+	require := require.New(t)
+	hash := "9dab72c7635043452958c4cc2902f48ef7c4ae437058280197c6a2736ab9635f"
+	snake := `{
+		"verification_context": {"has_previous_signature": true, "has_previous_witness": false},
+		"content": {"rev_id": 42, "content": {"main": "Hello", "transclusion-hashes": "[]"}, "content_hash": "` + hash + `"},
+		"metadata": {"domain_id": "5e5a1ec586", "time_stamp": "20220101120000", "previous_verification_hash": "", "metadata_hash": "` + hash + `", "verification_hash": "0x` + hash + `"},
+		"signature": {"signature": "0x01", "public_key": "0x02", "wallet_address": "0x03", "signature_hash": "` + hash + `"},
+		"witness": {"witness_event_id": 1, "witness_network": "sepolia", "witness_event_transaction_hash": "0x04", "structured_merkle_proof": [{"left_leaf": "` + hash + `", "witness_event_id": 1}]}
+	}`
+	camel := `{
+		"verificationContext": {"hasPreviousSignature": true, "hasPreviousWitness": false},
+		"content": {"revID": 42, "content": {"main": "Hello", "transclusion-hashes": "[]"}, "contentHash": "` + hash + `"},
+		"metadata": {"domainId": "5e5a1ec586", "timeStamp": "20220101120000", "previousVerificationHash": "", "metadataHash": "` + hash + `", "verificationHash": "0x` + hash + `"},
+		"signature": {"signature": "0x01", "publicKey": "0x02", "walletAddress": "0x03", "signatureHash": "` + hash + `"},
+		"witness": {"witnessEventId": 1, "witnessNetwork": "sepolia", "witnessEventTransactionHash": "0x04", "structuredMerkleProof": [{"leftLeaf": "` + hash + `", "witnessEventId": 1}]}
+	}`
+	var fromSnake, fromCamel Revision
+	require.NoError(json.Unmarshal([]byte(snake), &fromSnake))
+	require.NoError(json.Unmarshal([]byte(camel), &fromCamel))
+	require.Equal(fromSnake, fromCamel)
+	require.Equal(hash, fromCamel.Metadata.VerificationHash)
+	require.True(fromCamel.Context.HasPreviousSignature)
+	require.Equal(42, fromCamel.Content.RevId)
+	require.Equal("0x03", fromCamel.Signature.WalletAddress)
+	require.Equal(hash, fromCamel.Witness.MerkleProof[0].LeftLeaf)
+	// content slots keep their names
+	require.Equal("[]", fromCamel.Content.Content["transclusion-hashes"])
+
+	var info HashChainInfo
+	require.NoError(json.Unmarshal([]byte(`{"genesisHash": "0x`+hash+`", "latestVerificationHash": "`+hash+`", "chainHeight": 3}`), &info))
+	require.Equal(HashChainInfo{GenesisHash: hash, LatestVerificationHash: hash, ChainHeight: 3}, info)
+	var h RevisionHash
+	require.NoError(json.Unmarshal([]byte(`{"verificationHash": "0x`+hash+`"}`), &h))
+	require.Equal(RevisionHash(hash), h)
+
+	// snake_case names take precedence
+	var m RevisionMetadata
+	require.NoError(json.Unmarshal([]byte(`{"domain_id": "snake", "domainId": "camel"}`), &m))
+	require.Equal("snake", m.DomainId)
+
+	// and camelCase names are known fields with strict decoding
+	transport := &cannedTransport{responses: map[string]string{
+		"/rest.php/" + endpoint_get_revision + "camel": camel,
+		"/rest.php/" + endpoint_get_server_info:        `{"apiVersion": "` + Version + `"}`,
+	}}
+	a, e := NewAPI("http://aqua.invalid/rest.php", testToken, WithHTTPClient(&http.Client{Transport: transport}), WithStrictDecoding())
+	require.NoError(e)
+	r, e := a.GetRevision("camel")
+	require.NoError(e)
+	require.Equal(fromSnake, *r)
+	s, e := a.GetServerInfo()
+	require.NoError(e)
+	require.Equal(Version, s.ApiVersion)
+	require.Nil(s.Extra)
+}
+
+func TestCamelToSnake(t *testing.T) {
+	require := require.New(t)
+	for camel, snake := range map[string]string{
+		"verificationHash":            "verification_hash",
+		"witnessEventTransactionHash": "witness_event_transaction_hash",
+		"revID":                       "rev_id",
+		"HTTPStatus":                  "http_status",
+		"verification_hash":           "verification_hash",
+		"main":                        "main",
+	} {
+		require.Equal(snake, camelToSnake(camel), camel)
+	}
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// FSClient is an AquaClient reading revisions from a file system instead of
+// the network, e.g. a directory of exported revisions or an embed.FS of test
+// revisions. Every revision is stored as <verification hash>.json. Hash chain
+// info is derived from the revisions when looked up by genesis hash, while
+// lookups by title need the hash chain info stored as titles/<title>.json,
+// with the spaces of the title replaced by underscores.
+type FSClient struct {
+	fsys fs.FS
+	// next maps the verification hash of every revision to that of its next
+	// revision, indexed once by NewFSClient
+	next map[string]string
+}
+
+var _ AquaClient = (*FSClient)(nil)
+
+// NewFSClient returns an FSClient reading from fsys. The revisions are indexed
+// once, so revisions added to fsys afterwards are only found by
+// GetRevisionContext, not as part of their chain; create a new FSClient to
+// pick them up. It fails if a revision can't be read or has two next revisions.
+func NewFSClient(fsys fs.FS) (*FSClient, error) {
+	c := &FSClient{fsys: fsys, next: make(map[string]string)}
+	names, err := fs.Glob(fsys, "*.json")
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		other := new(Revision)
+		if err := c.readJSON(name, other); err != nil {
+			return nil, err
+		}
+		if other.Metadata == nil || other.Metadata.PreviousVerificationHash == "" {
+			continue
+		}
+		prev := other.Metadata.PreviousVerificationHash
+		if n, ok := c.next[prev]; ok && n != other.Metadata.VerificationHash {
+			return nil, fmt.Errorf("Revision %s has two next revisions %s and %s", prev, n, other.Metadata.VerificationHash)
+		}
+		c.next[prev] = other.Metadata.VerificationHash
+	}
+	return c, nil
+}
+
+// GetHashChainInfoContext returns the hash chain info of the chain with the
+// given title or genesis hash
+func (c *FSClient) GetHashChainInfoContext(ctx context.Context, id_type, id string) (*HashChainInfo, error) {
+	switch id_type {
+	case "title":
+		name := strings.ReplaceAll(id, " ", "_")
+		if strings.Contains(name, "/") {
+			return nil, fmt.Errorf("Invalid title %s", id)
+		}
+		ri := new(HashChainInfo)
+		if err := c.readJSON(path.Join("titles", name+".json"), ri); err != nil {
+			return nil, err
+		}
+		return ri, nil
+	case "genesis_hash":
+		genesis, err := c.GetRevisionContext(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if genesis.Metadata.PreviousVerificationHash != "" {
+			return nil, fmt.Errorf("Revision %s is not a genesis revision", id)
+		}
+		hashes, err := c.GetRevisionHashesContext(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return &HashChainInfo{
+			GenesisHash:            genesis.Metadata.VerificationHash,
+			DomainId:               genesis.Metadata.DomainId,
+			LatestVerificationHash: string(*hashes[len(hashes)-1]),
+			ChainHeight:            len(hashes),
+		}, nil
+	}
+	return nil, errors.New("id_type must be genesis_hash or title")
+}
+
+// GetRevisionHashesContext returns the verification hash of the given revision
+// and of all newer revisions of its chain, oldest first
+func (c *FSClient) GetRevisionHashesContext(ctx context.Context, verification_hash string) ([]*RevisionHash, error) {
+	r, err := c.GetRevisionContext(ctx, verification_hash)
+	if err != nil {
+		return nil, err
+	}
+	hash := RevisionHash(r.Metadata.VerificationHash)
+	hashes := []*RevisionHash{&hash}
+	for cur := string(hash); c.next[cur] != ""; cur = c.next[cur] {
+		if len(hashes) > len(c.next) {
+			return nil, fmt.Errorf("Revision %s is part of a cycle", cur)
+		}
+		h := RevisionHash(c.next[cur])
+		hashes = append(hashes, &h)
+	}
+	return hashes, nil
+}
+
+// GetRevisionContext returns the revision with the given verification hash
+func (c *FSClient) GetRevisionContext(ctx context.Context, verification_hash string) (*Revision, error) {
+	hash := NormalizeHash(verification_hash)
+	if hash == "" || strings.Contains(hash, "/") {
+		return nil, fmt.Errorf("Invalid verification hash %s", verification_hash)
+	}
+	r := new(Revision)
+	if err := c.readJSON(hash+".json", r); err != nil {
+		return nil, err
+	}
+	if r.Metadata == nil || r.Metadata.VerificationHash != hash {
+		return nil, fmt.Errorf("File %s.json doesn't hold revision %s", hash, hash)
+	}
+	return r, nil
+}
+
+// GetServerInfoContext returns the server info of the api version implemented by
+// the client
+func (c *FSClient) GetServerInfoContext(ctx context.Context) (*ServerInfo, error) {
+	return &ServerInfo{ApiVersion: Version}, nil
+}
+
+func (c *FSClient) readJSON(name string, v interface{}) error {
+	b, err := fs.ReadFile(c.fsys, name)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("Failure decoding %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFSClient(t *testing.T) {
+	require := require.New(t)
+	fsys := fstest.MapFS{}
+	put := func(name string, v interface{}) {
+		b, err := json.Marshal(v)
+		require.NoError(err)
+		fsys[name] = &fstest.MapFile{Data: b}
+	}
+	prev := ""
+	for _, hash := range []string{"aa", "bb", "cc"} {
+		put(hash+".json", &Revision{Metadata: &RevisionMetadata{DomainId: "domain", VerificationHash: hash, PreviousVerificationHash: prev}})
+		prev = hash
+	}
+	put("titles/Main_Page.json", &HashChainInfo{GenesisHash: "aa", LatestVerificationHash: "cc", Title: "Main Page", ChainHeight: 3})
+	c, e := NewFSClient(fsys)
+	require.NoError(e)
+	ctx := context.Background()
+
+	r, e := c.GetRevisionContext(ctx, "0xBB")
+	require.NoError(e)
+	require.Equal("aa", r.Metadata.PreviousVerificationHash)
+	_, e = c.GetRevisionContext(ctx, "dd")
+	require.True(errors.Is(e, fs.ErrNotExist))
+	_, e = c.GetRevisionContext(ctx, "../aa")
+	require.Error(e)
+
+	hashes, e := c.GetRevisionHashesContext(ctx, "bb")
+	require.NoError(e)
+	require.Len(hashes, 2)
+	require.Equal(RevisionHash("bb"), *hashes[0])
+	require.Equal(RevisionHash("cc"), *hashes[1])
+
+	info, e := c.GetHashChainInfoContext(ctx, "genesis_hash", "aa")
+	require.NoError(e)
+	require.Equal(&HashChainInfo{GenesisHash: "aa", DomainId: "domain", LatestVerificationHash: "cc", ChainHeight: 3}, info)
+	require.NoError(info.Validate())
+	_, e = c.GetHashChainInfoContext(ctx, "genesis_hash", "bb")
+	require.EqualError(e, "Revision bb is not a genesis revision")
+
+	info, e = c.GetHashChainInfoContext(ctx, "title", "Main Page")
+	require.NoError(e)
+	require.Equal("cc", info.LatestVerificationHash)
+	_, e = c.GetHashChainInfoContext(ctx, "title", "Other Page")
+	require.True(errors.Is(e, fs.ErrNotExist))
+
+	s, e := c.GetServerInfoContext(ctx)
+	require.NoError(e)
+	require.Equal(Version, s.ApiVersion)
+
+	// the directory is indexed once, by NewFSClient
+	put("dd.json", &Revision{Metadata: &RevisionMetadata{VerificationHash: "dd", PreviousVerificationHash: "cc"}})
+	hashes, e = c.GetRevisionHashesContext(ctx, "bb")
+	require.NoError(e)
+	require.Len(hashes, 2)
+	c, e = NewFSClient(fsys)
+	require.NoError(e)
+	hashes, e = c.GetRevisionHashesContext(ctx, "bb")
+	require.NoError(e)
+	require.Len(hashes, 3)
+
+	// a fork can't be followed
+	put("ee.json", &Revision{Metadata: &RevisionMetadata{VerificationHash: "ee", PreviousVerificationHash: "bb"}})
+	_, e = NewFSClient(fsys)
+	require.Error(e)
+}
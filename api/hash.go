@@ -0,0 +1,209 @@
+package api
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// NormalizeHash returns a hex encoded hash in lower case without a 0x prefix,
+// as server versions differ in how they format hashes. Strings that are not
+// hex encoded, e.g. base64 encoded hashes, are returned unchanged.
+func NormalizeHash(s string) string {
+	h := s
+	if len(h) > 2 && h[0] == '0' && (h[1] == 'x' || h[1] == 'X') {
+		h = h[2:]
+	}
+	if h == "" || strings.Trim(h, "0123456789abcdefABCDEF") != "" {
+		return s
+	}
+	return strings.ToLower(h)
+}
+
+// normalizeHashes normalizes the hashes hs points to in place
+func normalizeHashes(hs ...*string) {
+	for _, h := range hs {
+		*h = NormalizeHash(*h)
+	}
+}
+
+// ComputeHash returns the signature hash of s, the SHA3-512 hash of the
+// signature and the public key as declared by the server. Verifiers compare it
+// to s.SignatureHash before recovering the signer, as signature hashes are
+// chained into the verification hash of the next revision. It returns an error
+// if s has no signature or public key.
+func (s *RevisionSignature) ComputeHash() (string, error) {
+	if s.Signature == "" || s.PublicKey == "" {
+		return "", errors.New("Signature has no signature or public key")
+	}
+	sum := sha3.Sum512([]byte(s.Signature + s.PublicKey))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// ComputeHash returns the witness hash of w, the SHA3-512 hash of the domain
+// snapshot genesis hash, the merkle root, the network and the transaction hash
+// in that order. It returns an error if any of them is missing.
+func (w *RevisionWitness) ComputeHash() (string, error) {
+	if w.DomainSnapshotGenesisHash == "" || w.MerkleRoot == "" || w.WitnessNetwork == "" || w.WitnessEventTransactionHash == "" {
+		return "", errors.New("Witness is missing fields of its hash")
+	}
+	sum := sha3.Sum512([]byte(w.DomainSnapshotGenesisHash + w.MerkleRoot + w.WitnessNetwork + w.WitnessEventTransactionHash))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// ComputeHash returns the metadata hash of m, the SHA3-512 hash of the domain
+// id, the timestamp in the layout the api serves it in and the previous
+// verification hash in that order. The previous verification hash of a
+// genesis revision is empty and contributes nothing to the hash; it is
+// neither omitted from the layout nor hashed as "null". It returns an error if
+// the domain id or the timestamp is missing.
+func (m *RevisionMetadata) ComputeHash() (string, error) {
+	if m.DomainId == "" || m.Timestamp.IsZero() {
+		return "", errors.New("Metadata is missing fields of its hash")
+	}
+	sum := sha3.Sum512([]byte(m.DomainId + m.Timestamp.String() + m.PreviousVerificationHash))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// UnmarshalJSON decodes a RevisionHash, normalizing it with NormalizeHash.
+// Newer server versions return objects with a verification_hash field instead
+// of plain strings, both forms are accepted.
+func (h *RevisionHash) UnmarshalJSON(data []byte) error {
+	var s string
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		var obj struct {
+			VerificationHash *string `json:"verification_hash"`
+		}
+		trimmed, err := snakeCaseFields(trimmed)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(trimmed, &obj); err != nil {
+			return err
+		}
+		if obj.VerificationHash == nil {
+			return errors.New("Revision hash object has no verification_hash")
+		}
+		s = *obj.VerificationHash
+	} else if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*h = RevisionHash(NormalizeHash(s))
+	return nil
+}
+
+// UnmarshalJSON decodes a HashChainInfo, normalizing its hashes
+func (ri *HashChainInfo) UnmarshalJSON(data []byte) error {
+	data, err := snakeCaseFields(data)
+	if err != nil {
+		return err
+	}
+	type hashChainInfo HashChainInfo
+	if err := json.Unmarshal(data, (*hashChainInfo)(ri)); err != nil {
+		return err
+	}
+	normalizeHashes(&ri.GenesisHash, &ri.LatestVerificationHash, &ri.CurrentRevision)
+	return nil
+}
+
+// UnmarshalJSON decodes a HashChain. It is needed as the method of the
+// embedded HashChainInfo would otherwise skip the revisions.
+func (c *HashChain) UnmarshalJSON(data []byte) error {
+	data, err := snakeCaseFields(data)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &c.HashChainInfo); err != nil {
+		return err
+	}
+	var revisions struct {
+		Revisions map[string]*Revision `json:"revisions"`
+	}
+	if err := json.Unmarshal(data, &revisions); err != nil {
+		return err
+	}
+	c.Revisions = nil
+	if revisions.Revisions != nil {
+		c.Revisions = make(map[string]*Revision, len(revisions.Revisions))
+		for hash, r := range revisions.Revisions {
+			c.Revisions[NormalizeHash(hash)] = r
+		}
+	}
+	return nil
+}
+
+// UnmarshalJSON decodes a RevisionContent, normalizing its hashes
+func (c *RevisionContent) UnmarshalJSON(data []byte) error {
+	data, err := snakeCaseFields(data)
+	if err != nil {
+		return err
+	}
+	type revisionContent RevisionContent
+	if err := json.Unmarshal(data, (*revisionContent)(c)); err != nil {
+		return err
+	}
+	normalizeHashes(&c.ContentHash)
+	return nil
+}
+
+// UnmarshalJSON decodes a RevisionMetadata, normalizing its hashes
+func (m *RevisionMetadata) UnmarshalJSON(data []byte) error {
+	data, err := snakeCaseFields(data)
+	if err != nil {
+		return err
+	}
+	type revisionMetadata RevisionMetadata
+	if err := json.Unmarshal(data, (*revisionMetadata)(m)); err != nil {
+		return err
+	}
+	normalizeHashes(&m.PreviousVerificationHash, &m.MetadataHash, &m.VerificationHash)
+	return nil
+}
+
+// UnmarshalJSON decodes a RevisionSignature, normalizing its signature hash.
+// The signature, public key and wallet address keep their 0x prefix.
+func (s *RevisionSignature) UnmarshalJSON(data []byte) error {
+	data, err := snakeCaseFields(data)
+	if err != nil {
+		return err
+	}
+	type revisionSignature RevisionSignature
+	if err := json.Unmarshal(data, (*revisionSignature)(s)); err != nil {
+		return err
+	}
+	normalizeHashes(&s.SignatureHash)
+	return nil
+}
+
+// UnmarshalJSON decodes a RevisionWitness, normalizing its hashes. The
+// transaction hash and addresses keep their 0x prefix.
+func (w *RevisionWitness) UnmarshalJSON(data []byte) error {
+	data, err := snakeCaseFields(data)
+	if err != nil {
+		return err
+	}
+	type revisionWitness RevisionWitness
+	if err := json.Unmarshal(data, (*revisionWitness)(w)); err != nil {
+		return err
+	}
+	normalizeHashes(&w.WitnessHash, &w.DomainSnapshotGenesisHash, &w.MerkleRoot, &w.WitnessEventVerificationHash)
+	return nil
+}
+
+// UnmarshalJSON decodes a MerkleNode, normalizing its hashes
+func (n *MerkleNode) UnmarshalJSON(data []byte) error {
+	data, err := snakeCaseFields(data)
+	if err != nil {
+		return err
+	}
+	type merkleNode MerkleNode
+	if err := json.Unmarshal(data, (*merkleNode)(n)); err != nil {
+		return err
+	}
+	normalizeHashes(&n.LeftLeaf, &n.RightLeaf, &n.Successor)
+	return nil
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeHash(t *testing.T) {
+	require := require.New(t)
+	hash := strings.Repeat("ab01", 32)
+	require.Equal(hash, NormalizeHash(hash))
+	require.Equal(hash, NormalizeHash("0x"+hash))
+	require.Equal(hash, NormalizeHash("0X"+strings.ToUpper(hash)))
+	require.Equal("", NormalizeHash(""))
+	require.Equal("0x", NormalizeHash("0x"))
+	// hashes in other encodings are left alone
+	require.Equal("q80BqwGrAQ==", NormalizeHash("q80BqwGrAQ=="))
+}
+
+func TestHashesNormalizedOnDecode(t *testing.T) {
+	require := require.New(t)
+	hash := strings.Repeat("ab01", 32)
+	prefixed := "0x" + strings.ToUpper(hash)
+
+	var r Revision
+	require.NoError(json.Unmarshal([]byte(`{
+		"content": {"content": {"main": "0xAB"}, "content_hash": "`+prefixed+`"},
+		"metadata": {"time_stamp": "20220101000000", "previous_verification_hash": "`+prefixed+`", "metadata_hash": "`+hash+`", "verification_hash": "`+prefixed+`"},
+		"signature": {"signature": "0xAB", "public_key": "0x04", "wallet_address": "0x1AD5", "signature_hash": "`+prefixed+`"},
+		"witness": {"merkle_root": "`+prefixed+`", "witness_event_transaction_hash": "0x17CB", "structured_merkle_proof": [{"left_leaf": "`+prefixed+`"}]}
+	}`), &r))
+	require.Equal(hash, r.Content.ContentHash)
+	require.Equal(hash, r.Metadata.PreviousVerificationHash)
+	require.Equal(hash, r.Metadata.MetadataHash)
+	require.Equal(hash, r.Metadata.VerificationHash)
+	require.Equal(hash, r.Signature.SignatureHash)
+	require.Equal(hash, r.Witness.MerkleRoot)
+	require.Equal(hash, r.Witness.MerkleProof[0].LeftLeaf)
+	require.Equal("20220101000000", r.Metadata.Timestamp.String())
+	// content, signatures, addresses and transaction hashes are kept verbatim
+	require.Equal("0xAB", r.Content.Content["main"])
+	require.Equal("0xAB", r.Signature.Signature)
+	require.Equal("0x1AD5", r.Signature.WalletAddress)
+	require.Equal("0x17CB", r.Witness.WitnessEventTransactionHash)
+
+	var c HashChain
+	require.NoError(json.Unmarshal([]byte(`{"genesis_hash": "`+prefixed+`", "title": "Main Page", "revisions": {"`+prefixed+`": {}}}`), &c))
+	require.Equal(hash, c.GenesisHash)
+	require.Equal("Main Page", c.Title)
+	require.Contains(c.Revisions, hash)
+
+	var hashes []*RevisionHash
+	require.NoError(json.Unmarshal([]byte(`["`+prefixed+`"]`), &hashes))
+	require.Equal(RevisionHash(hash), *hashes[0])
+}
+
+func TestRevisionHashObjects(t *testing.T) {
+	require := require.New(t)
+	hash := strings.Repeat("ab01", 32)
+	transport := &cannedTransport{responses: map[string]string{
+		"/rest.php/" + endpoint_get_revision_hashes + "strings": `["` + hash + `","def"]`,
+		"/rest.php/" + endpoint_get_revision_hashes + "objects": `[{"verification_hash":"0x` + hash + `","rev_id":1},{"verification_hash":"def"}]`,
+		"/rest.php/" + endpoint_get_revision_hashes + "missing": `[{"rev_id":1}]`,
+	}}
+	a, e := NewAPI("http://aqua.invalid/rest.php", "secret", WithHTTPClient(&http.Client{Transport: transport}))
+	require.NoError(e)
+
+	for _, form := range []string{"strings", "objects"} {
+		hashes, e := a.GetRevisionHashes(form)
+		require.NoError(e, form)
+		require.Len(hashes, 2, form)
+		require.Equal(RevisionHash(hash), *hashes[0], form)
+		require.Equal(RevisionHash("def"), *hashes[1], form)
+	}
+	_, e = a.GetRevisionHashes("missing")
+	require.Error(e)
+	require.Contains(e.Error(), "Revision hash object has no verification_hash")
+}
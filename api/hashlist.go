@@ -0,0 +1,31 @@
+package api
+
+import "fmt"
+
+// ValidateHashList returns an error if hashes, as returned by
+// GetRevisionHashes, are not an unbroken sequence of revisions: every hash
+// must have its revision in revisions, keyed by verification hash, and every
+// revision but the first must have the revision of the previous hash as its
+// parent. A hash whose revision's parent is missing from the list indicates
+// that the server left out revisions when enumerating the chain.
+func ValidateHashList(hashes []*RevisionHash, revisions map[string]*Revision) error {
+	seen := make(map[string]bool, len(hashes))
+	for i, h := range hashes {
+		hash := string(*h)
+		if seen[hash] {
+			return fmt.Errorf("Revision %s is listed more than once", hash)
+		}
+		seen[hash] = true
+		r, ok := revisions[hash]
+		if !ok || r.Metadata == nil {
+			return fmt.Errorf("Revision %s is listed but missing", hash)
+		}
+		if i == 0 {
+			continue
+		}
+		if parent, expected := r.Metadata.PreviousVerificationHash, string(*hashes[i-1]); parent != expected {
+			return fmt.Errorf("Revision %s has parent %s instead of the previous listed revision %s", hash, parent, expected)
+		}
+	}
+	return nil
+}
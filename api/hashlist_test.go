@@ -0,0 +1,37 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateHashList(t *testing.T) {
+	require := require.New(t)
+	revisions := map[string]*Revision{}
+	var hashes []*RevisionHash
+	prev := ""
+	for _, h := range []string{"genesis", "second", "third", "latest"} {
+		revisions[h] = &Revision{Metadata: &RevisionMetadata{VerificationHash: h, PreviousVerificationHash: prev}}
+		hash := RevisionHash(h)
+		hashes = append(hashes, &hash)
+		prev = h
+	}
+
+	require.NoError(ValidateHashList(hashes, revisions))
+	// lists may start anywhere in the chain
+	require.NoError(ValidateHashList(hashes[2:], revisions))
+	require.NoError(ValidateHashList(nil, revisions))
+
+	// the middle revision is left out of the list
+	gap := []*RevisionHash{hashes[0], hashes[1], hashes[3]}
+	require.EqualError(ValidateHashList(gap, revisions),
+		"Revision latest has parent third instead of the previous listed revision second")
+
+	// or its revision is missing
+	delete(revisions, "third")
+	require.EqualError(ValidateHashList(hashes, revisions), "Revision third is listed but missing")
+
+	require.EqualError(ValidateHashList([]*RevisionHash{hashes[0], hashes[0]}, revisions),
+		"Revision genesis is listed more than once")
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// IdempotencyKeyHeader is the request header identifying a write, so that a
+// server can de-duplicate the retries of a write that may already have been
+// applied. Servers that don't support it ignore the header.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// isIdempotent reports whether requests with method may be retried, e.g.
+// against the fallback endpoints
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// withIdempotencyKey returns header with a random idempotency key added for
+// writes that don't have one
+func withIdempotencyKey(method string, header http.Header) http.Header {
+	if isIdempotent(method) || header.Get(IdempotencyKeyHeader) != "" {
+		return header
+	}
+	return withIdempotencyKeyValue(header, newIdempotencyKey())
+}
+
+// withIdempotencyKeyValue returns a copy of header with the idempotency key
+// set to key
+func withIdempotencyKeyValue(header http.Header, key string) http.Header {
+	header = header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	header.Set(IdempotencyKeyHeader, key)
+	return header
+}
+
+// newIdempotencyKey returns a random idempotency key
+func newIdempotencyKey() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
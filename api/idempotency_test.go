@@ -0,0 +1,61 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotencyKey(t *testing.T) {
+	require := require.New(t)
+	var keys []string
+	// the server doesn't de-duplicate writes
+	stored := map[string]bool{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get(IdempotencyKeyHeader))
+		if r.Method == http.MethodPost && stored[r.URL.Path] {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		stored[r.URL.Path] = true
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer ts.Close()
+
+	a, e := NewAPI(ts.URL, testToken)
+	require.NoError(e)
+	ctx := context.Background()
+	rev := &Revision{Metadata: &RevisionMetadata{VerificationHash: "abc"}}
+	require.NoError(a.StoreRevision(ctx, rev))
+	require.Equal([]string{"abc"}, keys)
+	// the key is stable across calls for the same revision
+	keys = nil
+	require.ErrorIs(a.StoreRevision(ctx, rev), ErrRevisionExists)
+	require.Equal([]string{"abc"}, keys)
+
+	// other writes get a random key
+	keys = nil
+	resp, e := a.fetch(ctx, http.MethodPost, "write", []byte(`{}`), nil)
+	require.NoError(e)
+	closeBody(resp)
+	resp, e = a.fetch(ctx, http.MethodPut, "write", []byte(`{}`), nil)
+	require.NoError(e)
+	closeBody(resp)
+	require.Len(keys, 2)
+	require.NotEmpty(keys[0])
+	require.NotEmpty(keys[1])
+	require.NotEqual(keys[0], keys[1])
+
+	// a key of the caller is kept, and reads are sent without one
+	keys = nil
+	resp, e = a.fetch(ctx, http.MethodPost, "other", nil, http.Header{IdempotencyKeyHeader: {"mine"}})
+	require.NoError(e)
+	closeBody(resp)
+	resp, e = a.fetch(ctx, http.MethodGet, "other", nil, nil)
+	require.NoError(e)
+	closeBody(resp)
+	require.Equal([]string{"mine", ""}, keys)
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ErrResponseTooLarge is returned when a response body exceeds the limit set
+// with WithMaxResponseBytes
+var ErrResponseTooLarge = errors.New("Response body too large")
+
+// WithMaxResponseBytes limits the size of the (decompressed) response bodies
+// the client accepts to n bytes. Reading past the limit fails with
+// ErrResponseTooLarge, and responses announcing a larger Content-Length are
+// rejected before their body is read. By default the size is not limited.
+func WithMaxResponseBytes(n int64) Option {
+	return func(a *AquaProtocol) {
+		a.maxResponseBytes = n
+	}
+}
+
+// limitedBody fails reads once more than max bytes were read from the body
+type limitedBody struct {
+	body io.ReadCloser
+	max  int64
+	read int64
+}
+
+func (l *limitedBody) Read(p []byte) (int, error) {
+	if l.read > l.max {
+		return 0, l.err()
+	}
+	// read at most one byte past the limit to detect exceeding it
+	if left := l.max - l.read + 1; int64(len(p)) > left {
+		p = p[:left]
+	}
+	n, err := l.body.Read(p)
+	l.read += int64(n)
+	if l.read > l.max {
+		return n - int(l.read-l.max), l.err()
+	}
+	return n, err
+}
+
+func (l *limitedBody) Close() error {
+	return l.body.Close()
+}
+
+func (l *limitedBody) err() error {
+	return fmt.Errorf("%w: more than %d bytes", ErrResponseTooLarge, l.max)
+}
+
+// limitBody limits the body of resp to max bytes, if max is positive
+func limitBody(resp *http.Response, max int64) error {
+	if max <= 0 {
+		return nil
+	}
+	l := &limitedBody{body: resp.Body, max: max}
+	if resp.ContentLength > max {
+		return l.err()
+	}
+	resp.Body = l
+	return nil
+}
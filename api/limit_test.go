@@ -0,0 +1,73 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// endlessBody is a never ending revision, counting the bytes read from it
+type endlessBody struct {
+	read int
+}
+
+func (e *endlessBody) Read(p []byte) (int, error) {
+	n := copy(p, []byte(`{"content":{"content":{"main":"`))
+	if e.read > 0 {
+		n = 0
+	}
+	for ; n < len(p); n++ {
+		p[n] = 'a'
+	}
+	e.read += n
+	return n, nil
+}
+
+func (e *endlessBody) Close() error { return nil }
+
+type endlessTransport struct {
+	body *endlessBody
+}
+
+func (t *endlessTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode:    http.StatusOK,
+		Header:        http.Header{},
+		Body:          t.body,
+		ContentLength: -1,
+		Request:       req,
+	}, nil
+}
+
+func TestWithMaxResponseBytes(t *testing.T) {
+	require := require.New(t)
+	const limit = 1 << 16
+	transport := &endlessTransport{body: &endlessBody{}}
+	a, e := NewAPI("http://aqua.invalid", testToken, WithHTTPClient(&http.Client{Transport: transport}), WithMaxResponseBytes(limit))
+	require.NoError(e)
+	_, e = a.GetRevision("abc")
+	require.True(errors.Is(e, ErrResponseTooLarge), e)
+	require.LessOrEqual(transport.body.read, limit+1)
+
+	// a too large Content-Length is rejected upfront
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"api_version":"` + Version + `"}` + strings.Repeat(" ", 100)))
+	}))
+	defer ts.Close()
+	a, e = NewAPI(ts.URL, testToken, WithMaxResponseBytes(100))
+	require.NoError(e)
+	_, e = a.GetServerInfo()
+	require.EqualError(e, "Response body too large: more than 100 bytes")
+
+	// bodies within the limit are read as usual
+	a, e = NewAPI(ts.URL, testToken, WithMaxResponseBytes(200))
+	require.NoError(e)
+	info, e := a.GetServerInfo()
+	require.NoError(e)
+	require.Equal(Version, info.ApiVersion)
+
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// defaultNamespaces are the namespaces every MediaWiki has, used until the
+// namespaces of the server are known
+var defaultNamespaces = map[int]*Namespace{
+	-2: {Case: true, Title: "Media"},
+	-1: {Case: true, Title: "Special"},
+	0:  {Case: true, Title: ""},
+	1:  {Case: true, Title: "Talk"},
+	2:  {Case: true, Title: "User"},
+	3:  {Case: true, Title: "User talk"},
+	6:  {Case: true, Title: "File"},
+	7:  {Case: true, Title: "File talk"},
+	8:  {Case: true, Title: "MediaWiki"},
+	9:  {Case: true, Title: "MediaWiki talk"},
+	10: {Case: true, Title: "Template"},
+	11: {Case: true, Title: "Template talk"},
+	12: {Case: true, Title: "Help"},
+	13: {Case: true, Title: "Help talk"},
+	14: {Case: true, Title: "Category"},
+	15: {Case: true, Title: "Category talk"},
+}
+
+// namespaceCache holds the namespaces of the server, learned from the site
+// info of the server info at Connect and of hash chain info responses. It is safe for concurrent use, and a nil
+// *namespaceCache only knows the default namespaces.
+type namespaceCache struct {
+	sync.Mutex
+	namespaces map[int]*Namespace
+}
+
+func (c *namespaceCache) get(namespace int) (*Namespace, bool) {
+	if c != nil {
+		c.Lock()
+		ns, ok := c.namespaces[namespace]
+		c.Unlock()
+		if ok {
+			return ns, true
+		}
+	}
+	ns, ok := defaultNamespaces[namespace]
+	return ns, ok
+}
+
+func (c *namespaceCache) learn(s *SiteInfo) {
+	if c == nil || s == nil || len(s.Namespaces) == 0 {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	c.namespaces = s.Namespaces
+}
+
+// NamespacedTitle returns the full title of the page with the given title in
+// namespace, e.g. "Data Accounting:Main Page". The namespaces of the server
+// are known after Connect or CachedServerInfo if the server info carries the
+// site info, and otherwise once any hash chain info has been fetched; before
+// that only the default MediaWiki namespaces are.
+func (a *AquaProtocol) NamespacedTitle(namespace int, title string) (string, error) {
+	ns, ok := a.namespaces.get(namespace)
+	if !ok {
+		return "", fmt.Errorf("Unknown namespace %d", namespace)
+	}
+	title = strings.ReplaceAll(strings.TrimSpace(title), "_", " ")
+	// namespaces that are case insensitive in the first letter capitalize it
+	if ns.Case {
+		if r, n := utf8.DecodeRuneInString(title); r != utf8.RuneError {
+			title = string(unicode.ToUpper(r)) + title[n:]
+		}
+	}
+	if ns.Title == "" {
+		return title, nil
+	}
+	return ns.Title + ":" + title, nil
+}
+
+// GetHashChainInfoByTitle returns the hash chain info of the page with the
+// given title in namespace, see NamespacedTitle
+func (a *AquaProtocol) GetHashChainInfoByTitle(ctx context.Context, namespace int, title string) (*HashChainInfo, error) {
+	t, err := a.NamespacedTitle(namespace, title)
+	if err != nil {
+		return nil, err
+	}
+	return a.GetHashChainInfoContext(ctx, "title", t)
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetHashChainInfoByTitle(t *testing.T) {
+	require := require.New(t)
+	site := &SiteInfo{Namespaces: map[int]*Namespace{
+		0:    {Case: true, Title: ""},
+		6942: {Case: true, Title: "Data Accounting"},
+		2302: {Case: false, Title: "Gadget definition"},
+	}}
+	pages := map[string]*HashChainInfo{
+		"Main Page":              {GenesisHash: "a", SiteInfo: site},
+		"Data Accounting:Config": {GenesisHash: "b", SiteInfo: site},
+		"Gadget definition:foo":  {GenesisHash: "c", SiteInfo: site},
+	}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ri, ok := pages[r.URL.Query().Get("identifier")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(ri)
+	}))
+	defer s.Close()
+
+	a, e := NewAPI(s.URL, testToken)
+	require.NoError(e)
+	// the custom namespace is not known before the site info was fetched
+	_, e = a.GetHashChainInfoByTitle(context.Background(), 6942, "Config")
+	require.EqualError(e, "Unknown namespace 6942")
+
+	ri, e := a.GetHashChainInfoByTitle(context.Background(), 0, "main_Page")
+	require.NoError(e)
+	require.Equal("a", ri.GenesisHash)
+
+	ri, e = a.GetHashChainInfoByTitle(context.Background(), 6942, "config")
+	require.NoError(e)
+	require.Equal("b", ri.GenesisHash)
+
+	// case sensitive namespaces keep the first letter
+	ri, e = a.GetHashChainInfoByTitle(context.Background(), 2302, "foo")
+	require.NoError(e)
+	require.Equal("c", ri.GenesisHash)
+
+	title, e := a.NamespacedTitle(1, "main Page")
+	require.NoError(e)
+	require.Equal("Talk:Main Page", title)
+}
+
+func TestConnectNamespaces(t *testing.T) {
+	require := require.New(t)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + endpoint_get_server_info:
+			w.Write([]byte(`{"api_version":"0.3.0","site_info":{"namespaces":{"0":{"case":true,"title":""},"6942":{"case":true,"title":"Data Accounting"}}}}`))
+		case "/" + endpoint_get_hash_chain_info + "title":
+			require.Equal("Data Accounting:Config", r.URL.Query().Get("identifier"))
+			w.Write([]byte(`{"genesis_hash":"b"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer s.Close()
+
+	a, e := Connect(context.Background(), s.URL, testToken)
+	require.NoError(e)
+	require.Nil(a.ServerInfo().Extra)
+	// the custom namespace is known without fetching a hash chain info first
+	ri, e := a.GetHashChainInfoByTitle(context.Background(), 6942, "config")
+	require.NoError(e)
+	require.Equal("b", ri.GenesisHash)
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"strings"
+	"time"
+)
+
+// Observer is notified after every request the client makes, e.g. to export
+// latency and status code metrics. It is an interface so that this package
+// doesn't depend on a metrics library.
+type Observer interface {
+	// ObserveRequest is called with the name of the api endpoint, e.g.
+	// "get_revision", the time the request took, the HTTP status code (0 if
+	// no response was received) and the error of the request, if any.
+	ObserveRequest(endpoint string, elapsed time.Duration, status int, err error)
+}
+
+// WithObserver makes the client report every request to o
+func WithObserver(o Observer) Option {
+	return func(a *AquaProtocol) {
+		a.observer = o
+	}
+}
+
+// endpointName returns the name of the api endpoint path belongs to
+func endpointName(path string) string {
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+	for _, e := range []string{
+		endpoint_get_hash_chain_info,
+		endpoint_get_revision_hashes,
+		endpoint_get_revision,
+		endpoint_get_server_info,
+		endpoint_store_revision,
+	} {
+		if strings.HasPrefix(path, e) {
+			path = e
+			break
+		}
+	}
+	path = strings.TrimSuffix(path, "/")
+	return path[strings.LastIndexByte(path, '/')+1:]
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type observation struct {
+	endpoint string
+	elapsed  time.Duration
+	status   int
+	err      error
+}
+
+type testObserver struct {
+	observations []observation
+}
+
+func (o *testObserver) ObserveRequest(endpoint string, elapsed time.Duration, status int, err error) {
+	o.observations = append(o.observations, observation{endpoint, elapsed, status, err})
+}
+
+func TestWithObserver(t *testing.T) {
+	require := require.New(t)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/"+endpoint_get_server_info {
+			time.Sleep(10 * time.Millisecond)
+			w.Write([]byte(`{"api_version":"` + Version + `"}`))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer s.Close()
+
+	o := &testObserver{}
+	a, e := NewAPI(s.URL, testToken, WithObserver(o))
+	require.NoError(e)
+	_, e = a.GetServerInfo()
+	require.NoError(e)
+	_, e = a.GetRevision("abc")
+	require.Error(e)
+	_, e = a.GetHashChainInfo("title", "Main Page")
+	require.Error(e)
+
+	require.Len(o.observations, 3)
+	require.Equal("get_server_info", o.observations[0].endpoint)
+	require.Equal(http.StatusOK, o.observations[0].status)
+	require.NoError(o.observations[0].err)
+	require.GreaterOrEqual(o.observations[0].elapsed, 10*time.Millisecond)
+	require.Equal("get_revision", o.observations[1].endpoint)
+	require.Equal(http.StatusNotFound, o.observations[1].status)
+	require.Error(o.observations[1].err)
+	require.Equal("get_hash_chain_info", o.observations[2].endpoint)
+
+	s.Close()
+	_, e = a.GetServerInfo()
+	require.Error(e)
+	require.Len(o.observations, 4)
+	require.Equal(0, o.observations[3].status)
+	require.Error(o.observations[3].err)
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"net/http"
+
+	"golang.org/x/time/rate"
+)
+
+// Option configures an AquaProtocol created by NewAPI
+type Option func(*AquaProtocol)
+
+// WithETagCache makes the client remember the ETag of every fetched revision
+// and revalidate it with If-None-Match on the next fetch, see GetRevision.
+func WithETagCache() Option {
+	return func(a *AquaProtocol) {
+		a.etags = newETagCache()
+	}
+}
+
+// WithHTTPClient makes the client send its requests using c, e.g. to route
+// them through a proxy or a recording http.RoundTripper. Close leaves the
+// connections of c open.
+func WithHTTPClient(c *http.Client) Option {
+	return func(a *AquaProtocol) {
+		a.apiClient = c
+		a.ownTransport = false
+	}
+}
+
+// WithFallbackEndpoints makes the client retry reads that fail with a
+// network error, a 5xx status or a truncated response against each of the
+// mirror endpoints in turn. Writes are never retried against the mirrors.
+// The mirrors are expected to serve identical data.
+func WithFallbackEndpoints(endpoints []string) Option {
+	return func(a *AquaProtocol) {
+		a.fallbackEndpoints = append([]string{}, endpoints...)
+	}
+}
+
+// WithBasePath makes the client send its requests below basePath of the
+// endpoint and the fallback endpoints, for deployments that mount the api
+// under a prefix, e.g. /wiki/rest.php behind a reverse proxy. The prefix may
+// also be part of the endpoint passed to NewAPI; WithBasePath keeps it when
+// Discover follows a redirect to another host.
+func WithBasePath(basePath string) Option {
+	return func(a *AquaProtocol) {
+		a.basePath = basePath
+	}
+}
+
+// WithRateLimit limits the client to requestsPerSecond requests, counting
+// every request made, including those against fallback endpoints. Requests
+// exceeding the rate wait for their turn, or fail with the error of their
+// context if it is done first. A requestsPerSecond <= 0 removes the limit, like
+// not passing WithRateLimit.
+func WithRateLimit(requestsPerSecond float64) Option {
+	return func(a *AquaProtocol) {
+		if requestsPerSecond <= 0 {
+			a.limiter = nil
+			return
+		}
+		a.limiter = rate.NewLimiter(rate.Limit(requestsPerSecond), 1)
+	}
+}
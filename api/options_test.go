@@ -0,0 +1,352 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// cannedTransport replays canned response bodies keyed by request path
+type cannedTransport struct {
+	responses map[string]string
+	requests  []*http.Request
+}
+
+func (c *cannedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.requests = append(c.requests, req)
+	body, ok := c.responses[req.URL.Path]
+	status := http.StatusOK
+	if !ok {
+		status = http.StatusNotFound
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+// closeCountingTransport counts the response bodies of transport that are
+// opened and closed
+type closeCountingTransport struct {
+	transport      http.RoundTripper
+	opened, closed int
+}
+
+func (c *closeCountingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := c.transport.RoundTrip(req)
+	if err == nil {
+		c.opened++
+		resp.Body = &countingBody{ReadCloser: resp.Body, closed: &c.closed}
+	}
+	return resp, err
+}
+
+type countingBody struct {
+	io.ReadCloser
+	closed *int
+}
+
+func (b *countingBody) Close() error {
+	*b.closed++
+	return b.ReadCloser.Close()
+}
+
+func TestResponseBodiesClosed(t *testing.T) {
+	require := require.New(t)
+	transport := &closeCountingTransport{transport: &cannedTransport{responses: map[string]string{
+		"/rest.php/" + endpoint_get_server_info:                      `{`,
+		"/rest.php/" + endpoint_get_revision_hashes + "abc":          `{"not":"a list"}`,
+		"/rest.php/" + endpoint_get_revision + "abc":                 `[`,
+		"/rest.php/" + endpoint_get_hash_chain_info + "title":        `"info"`,
+		"/rest.php/" + endpoint_get_revision_hashes + "def":          `["def"]`,
+		"/rest.php/" + endpoint_get_revision + "def":                 `{"metadata":{"verification_hash":"def"}}`,
+		"/rest.php/" + endpoint_get_hash_chain_info + "genesis_hash": `{"genesis_hash":"def"}`,
+	}}}
+	a, e := NewAPI("http://aqua.invalid/rest.php", testToken, WithHTTPClient(&http.Client{Transport: transport}))
+	require.NoError(e)
+	ctx := context.Background()
+
+	// decode errors
+	_, e = a.GetServerInfoContext(ctx)
+	require.Error(e)
+	_, e = a.GetRevisionHashesContext(ctx, "abc")
+	require.Error(e)
+	_, e = a.GetRevisionContext(ctx, "abc")
+	require.Error(e)
+	_, e = a.GetHashChainInfoContext(ctx, "title", "Main Page")
+	require.Error(e)
+	// request errors
+	_, e = a.GetRevisionContext(ctx, "unknown")
+	require.Error(e)
+	_, e = a.GetRevisionHashesContext(ctx, "unknown")
+	require.Error(e)
+	// and successful requests
+	_, e = a.GetRevisionHashesContext(ctx, "def")
+	require.NoError(e)
+	_, e = a.GetRevisionContext(ctx, "def")
+	require.NoError(e)
+	_, e = a.GetHashChainInfoContext(ctx, "genesis_hash", "def")
+	require.NoError(e)
+
+	require.Equal(9, transport.opened)
+	require.Equal(transport.opened, transport.closed)
+}
+
+func TestWithHTTPClient(t *testing.T) {
+	require := require.New(t)
+	transport := &cannedTransport{responses: map[string]string{
+		"/rest.php/" + endpoint_get_server_info:             `{"api_version":"` + Version + `"}`,
+		"/rest.php/" + endpoint_get_revision_hashes + "abc": `["abc","def"]`,
+	}}
+	a, e := NewAPI("http://aqua.invalid/rest.php", "secret", WithHTTPClient(&http.Client{Transport: transport}))
+	require.NoError(e)
+
+	info, e := a.GetServerInfo()
+	require.NoError(e)
+	require.Equal(Version, info.ApiVersion)
+	hashes, e := a.GetRevisionHashes("abc")
+	require.NoError(e)
+	require.Len(hashes, 2)
+	_, e = a.GetRevision("abc")
+	require.Error(e)
+
+	require.Len(transport.requests, 3)
+	require.Equal("aqua.invalid", transport.requests[0].URL.Host)
+	require.Equal("Bearer secret", transport.requests[0].Header.Get("Authorization"))
+}
+
+func TestWithFallbackEndpoints(t *testing.T) {
+	require := require.New(t)
+	var failed int
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		failed++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/"+endpoint_get_server_info {
+			w.Write([]byte(`{"api_version":"` + Version + `"}`))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer mirror.Close()
+
+	a, e := NewAPI(down.URL, testToken, WithFallbackEndpoints([]string{unreachable.URL, mirror.URL}))
+	require.NoError(e)
+	info, e := a.GetServerInfo()
+	require.NoError(e)
+	require.Equal(Version, info.ApiVersion)
+	require.Equal(1, failed)
+
+	// client errors are not retried against the mirrors
+	a, e = NewAPI(mirror.URL, testToken, WithFallbackEndpoints([]string{down.URL}))
+	require.NoError(e)
+	_, e = a.GetRevision("abc")
+	require.Error(e)
+	require.Equal(1, failed)
+
+	// all endpoints failing returns the last error
+	a, e = NewAPI(down.URL, testToken, WithFallbackEndpoints([]string{unreachable.URL}))
+	require.NoError(e)
+	_, e = a.GetServerInfo()
+	require.Error(e)
+	require.Equal(2, failed)
+
+	// writes are not replayed on the mirrors
+	var mirrored int
+	written := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mirrored++
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer written.Close()
+	a, e = NewAPI(down.URL, testToken, WithFallbackEndpoints([]string{written.URL}))
+	require.NoError(e)
+	rev := &Revision{Metadata: &RevisionMetadata{VerificationHash: "abc"}}
+	require.Error(a.StoreRevision(context.Background(), rev))
+	require.Equal(3, failed)
+	require.Zero(mirrored)
+}
+
+func TestWithBasePath(t *testing.T) {
+	require := require.New(t)
+	const basePath = "/wiki/rest.php"
+	var paths []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		switch r.URL.Path {
+		case basePath + "/" + endpoint_get_server_info:
+			w.Write([]byte(`{"api_version":"` + Version + `"}`))
+		case basePath + "/" + endpoint_get_revision_hashes + "abc":
+			w.Write([]byte(`["abc"]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	for _, tc := range []struct {
+		endpoint string
+		opts     []Option
+	}{
+		{ts.URL, []Option{WithBasePath(basePath)}},
+		{ts.URL + "/", []Option{WithBasePath("wiki/rest.php/")}},
+		{ts.URL + basePath, nil},
+	} {
+		a, e := NewAPI(tc.endpoint, testToken, tc.opts...)
+		require.NoError(e)
+		info, e := a.GetServerInfo()
+		require.NoError(e)
+		require.Equal(Version, info.ApiVersion)
+		hashes, e := a.GetRevisionHashes("abc")
+		require.NoError(e)
+		require.Len(hashes, 1)
+		u, e := a.GetApiURL(endpoint_get_revision + "abc")
+		require.NoError(e)
+		require.Equal(ts.URL+basePath+"/"+endpoint_get_revision+"abc", u.String())
+	}
+	require.Equal(basePath+"/"+endpoint_get_server_info, paths[0])
+
+	// the base path applies to the fallback endpoints
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+	a, e := NewAPI(down.URL, testToken, WithBasePath(basePath), WithFallbackEndpoints([]string{ts.URL}))
+	require.NoError(e)
+	_, e = a.GetServerInfo()
+	require.NoError(e)
+
+	// and is kept when Discover follows a redirect
+	redirect := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, ts.URL+r.URL.Path, http.StatusMovedPermanently)
+	}))
+	defer redirect.Close()
+	a, e = NewAPI(redirect.URL, testToken, WithBasePath(basePath))
+	require.NoError(e)
+	require.NoError(a.Discover(context.Background()))
+	require.Equal(ts.URL, a.Endpoint())
+	_, e = a.GetRevisionHashes("abc")
+	require.NoError(e)
+}
+
+func TestWithRateLimit(t *testing.T) {
+	require := require.New(t)
+	var requests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"api_version":"` + Version + `"}`))
+	}))
+	defer ts.Close()
+
+	const rate, n = 50, 10
+	a, e := NewAPI(ts.URL, testToken, WithRateLimit(rate))
+	require.NoError(e)
+	start := time.Now()
+	for i := 0; i < n; i++ {
+		_, e = a.GetServerInfo()
+		require.NoError(e)
+	}
+	// the first request is not delayed
+	require.GreaterOrEqual(time.Since(start), (n-1)*time.Second/rate)
+	require.Equal(n, requests)
+
+	// waiting for a token is cancelled with the context
+	a, e = NewAPI(ts.URL, testToken, WithRateLimit(0.1))
+	require.NoError(e)
+	_, e = a.GetServerInfo()
+	require.NoError(e)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	_, e = a.GetServerInfoContext(ctx)
+	require.Error(e)
+	require.Less(time.Since(start), time.Second)
+	require.Equal(n+1, requests)
+
+	// a rate <= 0 doesn't limit the requests
+	for _, rate := range []float64{0, -1} {
+		a, e = NewAPI(ts.URL, testToken, WithRateLimit(rate))
+		require.NoError(e)
+		require.Nil(a.limiter)
+		start = time.Now()
+		for i := 0; i < n; i++ {
+			_, e = a.GetServerInfo()
+			require.NoError(e)
+		}
+		require.Less(time.Since(start), time.Second)
+	}
+}
+
+func TestTruncatedResponses(t *testing.T) {
+	require := require.New(t)
+	body := `{"api_version":"` + Version + `"}`
+	var flakyRequests int
+	// the connection drops after half of the body
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flakyRequests++
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.Write([]byte(body[:len(body)/2]))
+	}))
+	defer flaky.Close()
+	// the body is complete, but the JSON value in it isn't
+	incomplete := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body[:len(body)/2]))
+	}))
+	defer incomplete.Close()
+	var malformedRequests int
+	malformed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		malformedRequests++
+		w.Write([]byte(`{"api_version":` + Version + `}`))
+	}))
+	defer malformed.Close()
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer mirror.Close()
+	ctx := context.Background()
+
+	for _, endpoint := range []string{flaky.URL, incomplete.URL} {
+		a, e := NewAPI(endpoint, testToken)
+		require.NoError(e)
+		_, e = a.GetServerInfoContext(ctx)
+		require.ErrorIs(e, ErrTruncatedResponse)
+		require.ErrorIs(e, io.ErrUnexpectedEOF)
+		_, e = a.GetRevisionHashesContext(ctx, "abc")
+		require.ErrorIs(e, ErrTruncatedResponse)
+
+		// truncated responses are retried against the mirrors
+		a, e = NewAPI(endpoint, testToken, WithFallbackEndpoints([]string{mirror.URL}))
+		require.NoError(e)
+		info, e := a.GetServerInfoContext(ctx)
+		require.NoError(e)
+		require.Equal(Version, info.ApiVersion)
+
+		// also with strict decoding
+		a, e = NewAPI(endpoint, testToken, WithStrictDecoding(), WithFallbackEndpoints([]string{mirror.URL}))
+		require.NoError(e)
+		_, e = a.GetServerInfoContext(ctx)
+		require.NoError(e)
+	}
+	require.Equal(4, flakyRequests)
+
+	// malformed JSON is not
+	a, e := NewAPI(malformed.URL, testToken, WithFallbackEndpoints([]string{mirror.URL}))
+	require.NoError(e)
+	_, e = a.GetServerInfoContext(ctx)
+	require.Error(e)
+	require.False(errors.Is(e, ErrTruncatedResponse))
+	require.Equal(1, malformedRequests)
+}
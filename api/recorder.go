@@ -0,0 +1,43 @@
+package api
+
+import (
+	"bytes"
+	"io"
+)
+
+// WithResponseRecorder makes the client pass the body of every response it
+// receives to record, e.g. to keep the exact payload of a failed verification
+// as evidence. record is called with the URL of the request and the bytes of
+// the body, after decompression, when the body is closed. The body is teed to
+// the recorder as it is decoded, so a body the client doesn't read to its end,
+// e.g. one exceeding the response size limit, is recorded up to where reading
+// stopped.
+func WithResponseRecorder(record func(endpoint string, body []byte)) Option {
+	return func(a *AquaProtocol) {
+		a.responseRecorder = record
+	}
+}
+
+// recordingBody keeps the bytes read from a response body for the response
+// recorder of a client
+type recordingBody struct {
+	io.ReadCloser
+	endpoint string
+	record   func(endpoint string, body []byte)
+	buf      bytes.Buffer
+	closed   bool
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.Write(p[:n])
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	if !b.closed {
+		b.closed = true
+		b.record(b.endpoint, b.buf.Bytes())
+	}
+	return b.ReadCloser.Close()
+}
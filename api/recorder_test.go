@@ -0,0 +1,52 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithResponseRecorder(t *testing.T) {
+	require := require.New(t)
+	responses := map[string]string{
+		"/" + endpoint_get_server_info:             `{"api_version": "` + Version + `", "extra": [1, 2]}` + "\n\n",
+		"/" + endpoint_get_revision_hashes + "abc": `[ "abc",  "def" ]`,
+		"/" + endpoint_get_revision + "abc":        `{"metadata": {"verification_hash": "abc"}, "unknown": "field"}`,
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := responses[r.URL.Path]
+		if !ok {
+			http.Error(w, "no such revision", http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	defer ts.Close()
+
+	recorded := map[string]string{}
+	a, e := NewAPI(ts.URL, testToken, WithResponseRecorder(func(endpoint string, body []byte) {
+		recorded[endpoint] = string(body)
+	}))
+	require.NoError(e)
+	ctx := context.Background()
+	info, e := a.GetServerInfoContext(ctx)
+	require.NoError(e)
+	require.Equal(Version, info.ApiVersion)
+	hashes, e := a.GetRevisionHashesContext(ctx, "abc")
+	require.NoError(e)
+	require.Len(hashes, 2)
+	r, e := a.GetRevisionContext(ctx, "abc")
+	require.NoError(e)
+	require.Equal("abc", r.Metadata.VerificationHash)
+	_, e = a.GetRevisionContext(ctx, "missing")
+	require.Error(e)
+
+	require.Len(recorded, 4)
+	for path, body := range responses {
+		require.Equal(body, recorded[ts.URL+path], path)
+	}
+	require.Equal("no such revision\n", recorded[ts.URL+"/"+endpoint_get_revision+"missing"])
+}
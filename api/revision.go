@@ -0,0 +1,126 @@
+package api
+
+// The accessors of Revision are safe to call on revisions missing any of
+// their parts, as served for revisions that are not signed or witnessed, or
+// that were fetched only partially, and on a nil *Revision.
+
+// HasSignature returns whether the revision is signed
+func (r *Revision) HasSignature() bool {
+	return r != nil && r.Signature != nil && r.Signature.Signature != ""
+}
+
+// HasWitness returns whether the revision is witnessed
+func (r *Revision) HasWitness() bool {
+	return r != nil && r.Witness != nil
+}
+
+// HasPreviousSignature returns whether the verification context of the
+// revision claims that its previous revision is signed
+func (r *Revision) HasPreviousSignature() bool {
+	return r != nil && r.Context != nil && r.Context.HasPreviousSignature
+}
+
+// HasPreviousWitness returns whether the verification context of the
+// revision claims that its previous revision is witnessed
+func (r *Revision) HasPreviousWitness() bool {
+	return r != nil && r.Context != nil && r.Context.HasPreviousWitness
+}
+
+// VerificationHash returns the verification hash of the revision, empty if it
+// has no metadata
+func (r *Revision) VerificationHash() string {
+	if r == nil || r.Metadata == nil {
+		return ""
+	}
+	return r.Metadata.VerificationHash
+}
+
+// PreviousVerificationHash returns the verification hash of the previous
+// revision, empty for a genesis revision or a revision without metadata
+func (r *Revision) PreviousVerificationHash() string {
+	if r == nil || r.Metadata == nil {
+		return ""
+	}
+	return r.Metadata.PreviousVerificationHash
+}
+
+// SignatureHash returns the signature hash of the revision, empty if it is not
+// signed
+func (r *Revision) SignatureHash() string {
+	if r == nil || r.Signature == nil {
+		return ""
+	}
+	return r.Signature.SignatureHash
+}
+
+// WitnessHash returns the witness hash of the revision, empty if it is not
+// witnessed
+func (r *Revision) WitnessHash() string {
+	if !r.HasWitness() {
+		return ""
+	}
+	return r.Witness.WitnessHash
+}
+
+// Copy returns a deep copy of the revision, which shares no part with it, or
+// nil for a nil *Revision
+func (r *Revision) Copy() *Revision {
+	if r == nil {
+		return nil
+	}
+	c := *r
+	if r.Context != nil {
+		context := *r.Context
+		c.Context = &context
+	}
+	if r.Content != nil {
+		content := *r.Content
+		if r.Content.Content != nil {
+			content.Content = make(map[string]string, len(r.Content.Content))
+			for k, v := range r.Content.Content {
+				content.Content[k] = v
+			}
+		}
+		if r.Content.File != nil {
+			file := *r.Content.File
+			content.File = &file
+		}
+		c.Content = &content
+	}
+	if r.Metadata != nil {
+		metadata := *r.Metadata
+		c.Metadata = &metadata
+	}
+	if r.Signature != nil {
+		signature := *r.Signature
+		c.Signature = &signature
+	}
+	if r.Signatures != nil {
+		c.Signatures = make([]*RevisionSignature, len(r.Signatures))
+		for i, s := range r.Signatures {
+			switch {
+			case s == nil:
+			case s == r.Signature:
+				// Signature is the first of Signatures
+				c.Signatures[i] = c.Signature
+			default:
+				signature := *s
+				c.Signatures[i] = &signature
+			}
+		}
+	}
+	if r.Witness != nil {
+		witness := *r.Witness
+		if r.Witness.MerkleProof != nil {
+			witness.MerkleProof = make([]*MerkleNode, len(r.Witness.MerkleProof))
+			for i, n := range r.Witness.MerkleProof {
+				if n != nil {
+					node := *n
+					witness.MerkleProof[i] = &node
+				}
+			}
+		}
+		c.Witness = &witness
+	}
+	return &c
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevisionAccessors(t *testing.T) {
+	require := require.New(t)
+	r := &Revision{
+		Context:   &VerificationContext{HasPreviousSignature: true, HasPreviousWitness: true},
+		Metadata:  &RevisionMetadata{VerificationHash: "abc", PreviousVerificationHash: "def"},
+		Signature: &RevisionSignature{Signature: "0x01", SignatureHash: "sig"},
+		Witness:   &RevisionWitness{WitnessHash: "wit"},
+	}
+	require.True(r.HasSignature())
+	require.True(r.HasWitness())
+	require.True(r.HasPreviousSignature())
+	require.True(r.HasPreviousWitness())
+	require.Equal("abc", r.VerificationHash())
+	require.Equal("def", r.PreviousVerificationHash())
+	require.Equal("sig", r.SignatureHash())
+	require.Equal("wit", r.WitnessHash())
+
+	// a signature without the signature itself, as served for unsigned
+	// revisions by some servers
+	r.Signature = &RevisionSignature{}
+	require.False(r.HasSignature())
+
+	var nilRevision *Revision
+	for _, r := range []*Revision{{}, nilRevision} {
+		require.False(r.HasSignature())
+		require.False(r.HasWitness())
+		require.False(r.HasPreviousSignature())
+		require.False(r.HasPreviousWitness())
+		require.Empty(r.VerificationHash())
+		require.Empty(r.PreviousVerificationHash())
+		require.Empty(r.SignatureHash())
+		require.Empty(r.WitnessHash())
+	}
+}
+
+func TestRevisionCopy(t *testing.T) {
+	require := require.New(t)
+	require.Nil((*Revision)(nil).Copy())
+
+	sig := &RevisionSignature{Signature: "0x01", WalletAddress: "0xa"}
+	r := &Revision{
+		Context:    &VerificationContext{HasPreviousSignature: true},
+		Content:    &RevisionContent{Content: map[string]string{"main": "a"}, File: &FileContent{Data: "b"}},
+		Metadata:   &RevisionMetadata{VerificationHash: "abc"},
+		Signature:  sig,
+		Signatures: []*RevisionSignature{sig, {Signature: "0x02", WalletAddress: "0xb"}},
+		Witness:    &RevisionWitness{MerkleProof: []*MerkleNode{{LeftLeaf: "l"}}},
+	}
+	c := r.Copy()
+	require.Equal(r, c)
+	require.Same(c.Signature, c.Signatures[0])
+
+	c.Context.HasPreviousSignature = false
+	c.Content.Content["main"] = "changed"
+	c.Content.File.Data = "changed"
+	c.Metadata.VerificationHash = "changed"
+	c.Signature.Signature = "changed"
+	c.Signatures[1].Signature = "changed"
+	c.Witness.MerkleProof[0].LeftLeaf = "changed"
+	require.True(r.Context.HasPreviousSignature)
+	require.Equal("a", r.Content.Content["main"])
+	require.Equal("b", r.Content.File.Data)
+	require.Equal("abc", r.Metadata.VerificationHash)
+	require.Equal("0x01", r.Signature.Signature)
+	require.Equal("0x02", r.Signatures[1].Signature)
+	require.Equal("l", r.Witness.MerkleProof[0].LeftLeaf)
+}
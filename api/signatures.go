@@ -0,0 +1,53 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// unmarshalSignatures decodes the signature field of a revision, a single
+// signature or an array of them
+func (r *Revision) unmarshalSignatures(data json.RawMessage) error {
+	data = bytes.TrimSpace(data)
+	r.Signature, r.Signatures = nil, nil
+	switch {
+	case len(data) == 0 || bytes.Equal(data, []byte("null")):
+		return nil
+	case data[0] == '[':
+		if err := json.Unmarshal(data, &r.Signatures); err != nil {
+			return err
+		}
+		if len(r.Signatures) > 0 {
+			r.Signature = r.Signatures[0]
+		}
+		return nil
+	}
+	return json.Unmarshal(data, &r.Signature)
+}
+
+// MarshalJSON encodes a Revision, with the signature field in the array form
+// if the revision has Signatures
+func (r Revision) MarshalJSON() ([]byte, error) {
+	type revision Revision
+	if r.Signatures == nil {
+		return json.Marshal(revision(r))
+	}
+	return json.Marshal(struct {
+		revision
+		Signature []*RevisionSignature `json:"signature"`
+	}{revision(r), r.Signatures})
+}
+
+// AllSignatures returns the signatures of the revision: its Signatures if it
+// has several signers, its Signature otherwise, or none if it isn't signed
+func (r *Revision) AllSignatures() []*RevisionSignature {
+	switch {
+	case r == nil:
+		return nil
+	case r.Signatures != nil:
+		return r.Signatures
+	case r.HasSignature():
+		return []*RevisionSignature{r.Signature}
+	}
+	return nil
+}
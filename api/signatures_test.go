@@ -0,0 +1,40 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevisionSignatures(t *testing.T) {
+	require := require.New(t)
+
+	// the single signature form is unchanged
+	var single Revision
+	require.NoError(json.Unmarshal([]byte(`{"signature": {"signature": "0x01", "wallet_address": "0x02"}}`), &single))
+	require.Equal("0x01", single.Signature.Signature)
+	require.Nil(single.Signatures)
+	require.Equal([]*RevisionSignature{single.Signature}, single.AllSignatures())
+	data, err := json.Marshal(&single)
+	require.NoError(err)
+	require.Contains(string(data), `"signature":{"signature":"0x01"`)
+
+	// co-signed revisions list their signatures
+	var multi Revision
+	require.NoError(json.Unmarshal([]byte(`{"signature": [{"signature": "0x01", "wallet_address": "0x02"}, {"signature": "0x03", "walletAddress": "0x04"}]}`), &multi))
+	require.Len(multi.Signatures, 2)
+	require.Same(multi.Signatures[0], multi.Signature)
+	require.Equal("0x04", multi.Signatures[1].WalletAddress)
+	require.Equal(multi.Signatures, multi.AllSignatures())
+	data, err = json.Marshal(&multi)
+	require.NoError(err)
+	var decoded Revision
+	require.NoError(json.Unmarshal(data, &decoded))
+	require.Equal(multi.Signatures, decoded.Signatures)
+
+	var unsigned Revision
+	require.NoError(json.Unmarshal([]byte(`{"signature": null}`), &unsigned))
+	require.Nil(unsigned.Signature)
+	require.Empty(unsigned.AllSignatures())
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"io"
+	"sync/atomic"
+)
+
+// ClientStats holds the transfer accounting of a client, e.g. to audit the
+// usage of a metered api
+type ClientStats struct {
+	// Requests is the number of requests sent to the server
+	Requests int64
+	// BytesRead is the number of response body bytes read from the
+	// network, before decompression
+	BytesRead int64
+}
+
+// clientStats are the counters behind ClientStats
+type clientStats struct {
+	requests  atomic.Int64
+	bytesRead atomic.Int64
+}
+
+// Stats returns the number of requests the client sent and the response
+// bytes it read so far. It is safe to call while requests are in flight.
+func (a *AquaProtocol) Stats() ClientStats {
+	return ClientStats{
+		Requests:  a.stats.requests.Load(),
+		BytesRead: a.stats.bytesRead.Load(),
+	}
+}
+
+// statsBody counts the bytes read from a response body into the stats of
+// a client
+type statsBody struct {
+	io.ReadCloser
+	stats *clientStats
+}
+
+func (b *statsBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.stats.bytesRead.Add(int64(n))
+	return n, err
+}
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStats(t *testing.T) {
+	require := require.New(t)
+	body := `{"api_version":"` + Version + `"}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer ts.Close()
+
+	a, e := NewAPI(ts.URL, testToken)
+	require.NoError(e)
+	require.Equal(ClientStats{}, a.Stats())
+	_, e = a.GetServerInfo()
+	require.NoError(e)
+	require.Equal(ClientStats{Requests: 1, BytesRead: int64(len(body))}, a.Stats())
+
+	// concurrent requests are all accounted for
+	const n = 10
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.GetServerInfo()
+			a.Stats()
+		}()
+	}
+	wg.Wait()
+	require.Equal(ClientStats{Requests: n + 1, BytesRead: (n + 1) * int64(len(body))}, a.Stats())
+}
@@ -0,0 +1,161 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// GetRevisionContentTo streams the file of the revision with verification hash
+// verification_hash to w, decoding it from base64 as it is read from the
+// response, and returns the SHA3-512 hash of the file computed on the way, to
+// be compared with the file_hash content slot of the revision. Only the fields
+// leading up to the file data are decoded, so the memory used doesn't grow
+// with the size of the file. It returns an error if the revision has no inline
+// file.
+func (a *AquaProtocol) GetRevisionContentTo(ctx context.Context, verification_hash string, w io.Writer) (contentHash string, err error) {
+	resp, err := a.fetch(ctx, http.MethodGet, endpoint_get_revision+url.PathEscape(verification_hash), nil, nil)
+	if resp != nil {
+		defer closeBody(resp)
+	}
+	if err != nil {
+		return "", err
+	}
+	dec := json.NewDecoder(resp.Body)
+	if err := findField(dec, "content", "file", "data"); err != nil {
+		return "", err
+	}
+	data := &jsonStringReader{r: bufio.NewReader(io.MultiReader(dec.Buffered(), resp.Body))}
+	if err := data.open(); err != nil {
+		return "", err
+	}
+	h := sha3.New512()
+	if _, err := io.Copy(io.MultiWriter(w, h), base64.NewDecoder(base64.StdEncoding, data)); err != nil {
+		return "", fmt.Errorf("Failure reading file data: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// findField advances dec to the value of the field at path, a list of field
+// names of nested objects, so that the value is the next input of dec
+func findField(dec *json.Decoder, path ...string) error {
+	for i, name := range path {
+		if t, err := dec.Token(); err != nil {
+			return err
+		} else if t != json.Delim('{') {
+			if i == 0 {
+				return errors.New("Revision is not a JSON object")
+			}
+			return fmt.Errorf("Revision has no %s", path[i-1])
+		}
+		for {
+			t, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			if t == json.Delim('}') {
+				return fmt.Errorf("Revision has no %s", name)
+			}
+			if t == name {
+				break
+			}
+			if err := skipValue(dec); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// skipValue reads the next value of dec, including nested values
+func skipValue(dec *json.Decoder) error {
+	depth := 0
+	for {
+		t, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch t {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+// jsonStringReader reads the contents of a JSON string value, unescaped, from
+// r, which is positioned before the colon preceding the value
+type jsonStringReader struct {
+	r    *bufio.Reader
+	done bool
+}
+
+// open reads up to the opening quote of the string
+func (s *jsonStringReader) open() error {
+	sawColon := false
+	for {
+		c, err := s.r.ReadByte()
+		if err != nil {
+			return err
+		}
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+		case c == ':' && !sawColon:
+			sawColon = true
+		case c == '"' && sawColon:
+			return nil
+		default:
+			return errors.New("Revision file data is not a string")
+		}
+	}
+}
+
+func (s *jsonStringReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) && !s.done {
+		c, err := s.r.ReadByte()
+		if err == io.EOF {
+			return n, io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return n, err
+		}
+		switch c {
+		case '"':
+			s.done = true
+			continue
+		case '\\':
+			if c, err = s.r.ReadByte(); err != nil {
+				return n, io.ErrUnexpectedEOF
+			}
+			switch c {
+			case '"', '\\', '/':
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			default:
+				return n, fmt.Errorf("Unsupported escape \\%c in file data", c)
+			}
+		}
+		p[n] = c
+		n++
+	}
+	if n == 0 && s.done {
+		return 0, io.EOF
+	}
+	return n, nil
+}
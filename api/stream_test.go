@@ -0,0 +1,81 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/sha3"
+)
+
+func TestGetRevisionContentTo(t *testing.T) {
+	require := require.New(t)
+	file := make([]byte, 4<<20)
+	_, err := rand.Read(file)
+	require.NoError(err)
+	sum := sha3.Sum512(file)
+	fileHash := hex.EncodeToString(sum[:])
+	revisions := map[string]*Revision{
+		"file": {
+			Context: &VerificationContext{},
+			Content: &RevisionContent{
+				Content: map[string]string{"main": "A \"file\"", "file_hash": fileHash},
+				File:    &FileContent{Data: base64.StdEncoding.EncodeToString(file), Filename: "big.bin", Size: len(file)},
+			},
+			Metadata: &RevisionMetadata{VerificationHash: "file"},
+		},
+		"page": {Content: &RevisionContent{Content: map[string]string{"main": "Hello"}}},
+	}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rev, ok := revisions[strings.TrimPrefix(r.URL.Path, "/"+endpoint_get_revision)]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(rev)
+	}))
+	defer s.Close()
+	a, e := NewAPI(s.URL, testToken)
+	require.NoError(e)
+
+	var b bytes.Buffer
+	hash, e := a.GetRevisionContentTo(context.Background(), "file", &b)
+	require.NoError(e)
+	require.Equal(fileHash, hash)
+	require.True(bytes.Equal(file, b.Bytes()))
+
+	_, e = a.GetRevisionContentTo(context.Background(), "page", &b)
+	require.EqualError(e, "Revision has no file")
+	_, e = a.GetRevisionContentTo(context.Background(), "missing", &b)
+	require.Error(e)
+}
+
+func TestJSONStringReader(t *testing.T) {
+	require := require.New(t)
+	var b bytes.Buffer
+	r := &jsonStringReader{r: bufioReader(` : "a\/b\\c\"d\n",`)}
+	require.NoError(r.open())
+	_, err := b.ReadFrom(r)
+	require.NoError(err)
+	require.Equal("a/b\\c\"d\n", b.String())
+
+	r = &jsonStringReader{r: bufioReader(`: "abc`)}
+	require.NoError(r.open())
+	_, err = b.ReadFrom(r)
+	require.Error(err)
+	r = &jsonStringReader{r: bufioReader(`: null`)}
+	require.EqualError(r.open(), "Revision file data is not a string")
+}
+
+func bufioReader(s string) *bufio.Reader {
+	return bufio.NewReader(strings.NewReader(s))
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// WithStrictDecoding makes the client reject responses with fields that the
+// types of this package don't model, e.g. to detect protocol drift in
+// conformance tests. By default unknown fields are ignored, so that the client
+// keeps working against newer servers.
+func WithStrictDecoding() Option {
+	return func(a *AquaProtocol) {
+		a.strictDecoding = true
+	}
+}
+
+// decode decodes the JSON value read from r into v. With strict decoding it
+// fails on unknown fields, also of the types that have their own UnmarshalJSON
+// method, which can't be checked by json.Decoder.DisallowUnknownFields.
+func (a *AquaProtocol) decode(r io.Reader, v interface{}) error {
+	if !a.strictDecoding {
+		return truncationError(json.NewDecoder(r).Decode(v))
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return truncationError(err)
+	}
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.DisallowUnknownFields()
+	if err := d.Decode(v); err != nil {
+		return truncationError(err)
+	}
+	return checkUnknownFields(data, reflect.TypeOf(v), "")
+}
+
+// truncationError returns err wrapped in ErrTruncatedResponse if the body
+// ended unexpectedly, either because the connection dropped before the body
+// was complete or because the JSON value in it ended early
+func truncationError(err error) error {
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("%w: %w", ErrTruncatedResponse, err)
+	}
+	return err
+}
+
+// checkUnknownFields returns an error for the first object key in data that
+// has no field in the type t it is decoded into. path is the location of data
+// in the response, for the error message.
+func checkUnknownFields(data []byte, t reflect.Type, path string) error {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil
+	}
+	switch {
+	case t.Kind() == reflect.Struct && data[0] == '{':
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return err
+		}
+		known := jsonFields(t)
+		for name, value := range fields {
+			// camelCase names are accepted by the UnmarshalJSON methods
+			ft, ok := known[strings.ToLower(name)]
+			if !ok {
+				ft, ok = known[camelToSnake(name)]
+			}
+			if !ok {
+				return fmt.Errorf("Unknown field %s in response", path+name)
+			}
+			if err := checkUnknownFields(value, ft, path+name+"."); err != nil {
+				return err
+			}
+		}
+	case t.Kind() == reflect.Map && data[0] == '{':
+		var entries map[string]json.RawMessage
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return err
+		}
+		for key, value := range entries {
+			if err := checkUnknownFields(value, t.Elem(), path+key+"."); err != nil {
+				return err
+			}
+		}
+	case (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && data[0] == '[':
+		var elems []json.RawMessage
+		if err := json.Unmarshal(data, &elems); err != nil {
+			return err
+		}
+		for i, value := range elems {
+			if err := checkUnknownFields(value, t.Elem(), fmt.Sprintf("%s%d.", path, i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// jsonFields returns the types of the fields of the struct type t by their
+// lower case JSON name, as encoding/json matches names case-insensitively
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			for n, t := range jsonFields(ft) {
+				fields[n] = t
+			}
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[strings.ToLower(name)] = f.Type
+	}
+	return fields
+}
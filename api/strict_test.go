@@ -0,0 +1,62 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithStrictDecoding(t *testing.T) {
+	require := require.New(t)
+	hash := strings.Repeat("ab01", 32)
+	known, err := json.Marshal(&Revision{
+		Context:   &VerificationContext{},
+		Content:   &RevisionContent{Content: map[string]string{"main": "hello"}, ContentHash: hash},
+		Metadata:  &RevisionMetadata{VerificationHash: hash},
+		Signature: &RevisionSignature{},
+		Witness:   &RevisionWitness{MerkleProof: []*MerkleNode{{LeftLeaf: hash}}},
+	})
+	require.NoError(err)
+	transport := &cannedTransport{responses: map[string]string{
+		"/rest.php/" + endpoint_get_revision + "known":        string(known),
+		"/rest.php/" + endpoint_get_revision + "top":          `{"metadata": {"verification_hash": "` + hash + `"}, "extra": 1}`,
+		"/rest.php/" + endpoint_get_revision + "nested":       `{"content": {"content_hash": "` + hash + `", "content_salt": "abc"}}`,
+		"/rest.php/" + endpoint_get_revision + "proof":        `{"witness": {"structured_merkle_proof": [{"left_leaf": "a"}, {"left_leaf": "b", "side": "left"}]}}`,
+		"/rest.php/" + endpoint_get_hash_chain_info + "title": `{"genesis_hash": "` + hash + `", "site_info": {"sitename": "Wiki", "logo": "x.png"}}`,
+		"/rest.php/" + endpoint_get_server_info:               `{"api_version": "` + Version + `", "php_version": "8.1"}`,
+	}}
+	client := &http.Client{Transport: transport}
+
+	// unknown fields are ignored by default
+	a, e := NewAPI("http://aqua.invalid/rest.php", "secret", WithHTTPClient(client))
+	require.NoError(e)
+	for _, hash := range []string{"known", "top", "nested", "proof"} {
+		_, e = a.GetRevision(hash)
+		require.NoError(e, hash)
+	}
+	_, e = a.GetHashChainInfo("title", "Main Page")
+	require.NoError(e)
+	_, e = a.GetServerInfo()
+	require.NoError(e)
+
+	a, e = NewAPI("http://aqua.invalid/rest.php", "secret", WithHTTPClient(client), WithStrictDecoding())
+	require.NoError(e)
+	r, e := a.GetRevision("known")
+	require.NoError(e)
+	require.Equal(hash, r.Metadata.VerificationHash)
+	for hash, expected := range map[string]string{
+		"top":    "Unknown field extra in response",
+		"nested": "Unknown field content.content_salt in response",
+		"proof":  "Unknown field witness.structured_merkle_proof.1.side in response",
+	} {
+		_, e = a.GetRevision(hash)
+		require.EqualError(e, expected, hash)
+	}
+	_, e = a.GetHashChainInfo("title", "Main Page")
+	require.EqualError(e, "Unknown field site_info.logo in response")
+	_, e = a.GetServerInfo()
+	require.EqualError(e, "Unknown field php_version in response")
+}
@@ -0,0 +1,41 @@
+package api
+
+import "time"
+
+// RevisionSummary holds the fields of a revision used to list and log it
+type RevisionSummary struct {
+	RevId            int       `json:"rev_id,omitempty"`
+	Timestamp        time.Time `json:"time_stamp"`
+	VerificationHash string    `json:"verification_hash"`
+	// Signer is the wallet address of the signer, empty if the revision is
+	// not signed
+	Signer string `json:"signer,omitempty"`
+	// WitnessNetwork and WitnessTxHash are empty if the revision is not
+	// witnessed
+	WitnessNetwork string `json:"witness_network,omitempty"`
+	WitnessTxHash  string `json:"witness_tx_hash,omitempty"`
+}
+
+// Summary returns the summary of the revision. Fields of parts the revision
+// doesn't have, e.g. a revision fetched without its content, are left empty.
+func (r *Revision) Summary() RevisionSummary {
+	var s RevisionSummary
+	if r == nil {
+		return s
+	}
+	if r.Content != nil {
+		s.RevId = r.Content.RevId
+	}
+	if r.Metadata != nil {
+		s.Timestamp = r.Metadata.Timestamp.Time
+		s.VerificationHash = r.Metadata.VerificationHash
+	}
+	if r.Signature != nil {
+		s.Signer = r.Signature.WalletAddress
+	}
+	if r.Witness != nil {
+		s.WitnessNetwork = r.Witness.WitnessNetwork
+		s.WitnessTxHash = r.Witness.WitnessEventTransactionHash
+	}
+	return s
+}
@@ -0,0 +1,36 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevisionSummary(t *testing.T) {
+	require := require.New(t)
+	ts := time.Date(2022, 1, 1, 12, 0, 0, 0, time.UTC)
+	r := &Revision{
+		Context:   &VerificationContext{},
+		Content:   &RevisionContent{RevId: 42},
+		Metadata:  &RevisionMetadata{Timestamp: Timestamp{Time: ts}, VerificationHash: "abc"},
+		Signature: &RevisionSignature{WalletAddress: "0xa2026582b94feb9124231fbf7b052c39218954c2"},
+		Witness:   &RevisionWitness{WitnessNetwork: "goerli", WitnessEventTransactionHash: "0xdef"},
+	}
+	require.Equal(RevisionSummary{
+		RevId:            42,
+		Timestamp:        ts,
+		VerificationHash: "abc",
+		Signer:           "0xa2026582b94feb9124231fbf7b052c39218954c2",
+		WitnessNetwork:   "goerli",
+		WitnessTxHash:    "0xdef",
+	}, r.Summary())
+
+	// a revision with metadata only
+	r = &Revision{Metadata: &RevisionMetadata{VerificationHash: "abc"}}
+	require.Equal(RevisionSummary{VerificationHash: "abc"}, r.Summary())
+
+	r = nil
+	require.Equal(RevisionSummary{}, (&Revision{}).Summary())
+	require.Equal(RevisionSummary{}, r.Summary())
+}
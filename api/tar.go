@@ -0,0 +1,68 @@
+package api
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	// TarChainInfoName is the name of the hash chain info file in a chain tar
+	TarChainInfoName = "chain_info.json"
+	// TarRevisionsDir is the directory holding the revisions in a chain tar
+	TarRevisionsDir = "revisions/"
+)
+
+// ExportChainTar writes a tar archive of the requested hash chain to w. The
+// archive holds the hash chain info as TarChainInfoName followed by one file
+// per revision in TarRevisionsDir, ordered from the genesis revision to the
+// latest. Entries have fixed timestamps and permissions so that exporting the
+// same chain twice gives identical archives.
+func (a *AquaProtocol) ExportChainTar(ctx context.Context, id_type, id string, w io.Writer) error {
+	data, err := a.GetHashChain(ctx, id_type, id, -1)
+	if err != nil {
+		return err
+	}
+
+	// order revisions from genesis to latest
+	revisions := make([]*Revision, 0, len(data.Revisions))
+	for cur := data.LatestVerificationHash; cur != ""; {
+		r := data.Revisions[cur]
+		revisions = append([]*Revision{r}, revisions...)
+		cur = r.Metadata.PreviousVerificationHash
+	}
+
+	tw := tar.NewWriter(w)
+	if err := writeTarJSON(tw, TarChainInfoName, &data.HashChainInfo); err != nil {
+		return err
+	}
+	for i, r := range revisions {
+		name := fmt.Sprintf("%s%04d_%s.json", TarRevisionsDir, i+1, r.Metadata.VerificationHash)
+		if err := writeTarJSON(tw, name, r); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+func writeTarJSON(tw *tar.Writer, name string, v interface{}) error {
+	j, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(j)),
+		ModTime: time.Unix(0, 0),
+		Format:  tar.FormatPAX,
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err = tw.Write(j)
+	return err
+}
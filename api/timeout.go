@@ -0,0 +1,39 @@
+package api
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// WithPerRequestTimeout gives every HTTP request of the client its own
+// deadline of d, derived from the context of the call, so that a single slow
+// request fails on its own instead of using up the deadline of a whole chain
+// walk. The deadline covers reading the response body. Requests retried
+// against fallback endpoints get a fresh deadline, and the deadline of the
+// call's context still applies if it is earlier.
+func WithPerRequestTimeout(d time.Duration) Option {
+	return func(a *AquaProtocol) {
+		a.requestTimeout = d
+	}
+}
+
+// requestContext returns the context of a single request derived from ctx
+func (a *AquaProtocol) requestContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if a.requestTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, a.requestTimeout)
+}
+
+// cancelBody releases the context of a request once its body is closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// deadlineTransport records the deadline of every request
+type deadlineTransport struct {
+	sync.Mutex
+	deadlines []time.Time
+}
+
+func (d *deadlineTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	deadline, _ := req.Context().Deadline()
+	d.Lock()
+	d.deadlines = append(d.deadlines, deadline)
+	d.Unlock()
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestWithPerRequestTimeout(t *testing.T) {
+	require := require.New(t)
+	s, _ := newChainServer("genesis", "second", "third", "latest")
+	var delay atomic.Int64
+	delay.Store(int64(60 * time.Millisecond))
+	handler := s.Config.Handler
+	s.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Duration(delay.Load()))
+		handler.ServeHTTP(w, r)
+	})
+	defer s.Close()
+
+	transport := &deadlineTransport{}
+	const timeout = 200 * time.Millisecond
+	a, e := NewAPI(s.URL, testToken, WithPerRequestTimeout(timeout), WithHTTPClient(&http.Client{Transport: transport}))
+	require.NoError(e)
+
+	// the walk takes longer than a single request may
+	start := time.Now()
+	var walked int
+	for _, e := range a.WalkChain(context.Background(), "latest") {
+		require.NoError(e)
+		walked++
+	}
+	require.Equal(4, walked)
+	require.Greater(time.Since(start), timeout)
+	require.Len(transport.deadlines, 4)
+	for i, deadline := range transport.deadlines {
+		require.False(deadline.IsZero())
+		if i > 0 {
+			require.True(deadline.After(transport.deadlines[i-1]))
+		}
+	}
+
+	// a single slow request fails
+	delay.Store(int64(2 * timeout))
+	_, e = a.GetRevision("latest")
+	require.True(errors.Is(e, context.DeadlineExceeded), e)
+
+	// an earlier deadline of the caller still applies
+	delay.Store(0)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout/2)
+	defer cancel()
+	_, e = a.GetRevisionContext(ctx, "latest")
+	require.NoError(e)
+	callerDeadline, _ := ctx.Deadline()
+	require.Equal(callerDeadline, transport.deadlines[len(transport.deadlines)-1])
+}
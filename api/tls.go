@@ -0,0 +1,42 @@
+package api
+
+import (
+	"crypto/tls"
+	"errors"
+	"net/http"
+)
+
+// WithTLSConfig makes the client connect to the endpoints using cfg, e.g. to
+// trust the private CA of an on-premise server with cfg.RootCAs or to
+// authenticate with a client certificate with cfg.Certificates. It applies to
+// the transport of the client passed to WithHTTPClient as well, which must
+// then be an *http.Transport or nil.
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(a *AquaProtocol) {
+		a.tlsConfig = cfg
+	}
+}
+
+// applyTLSConfig replaces the client of a with one whose transport uses the
+// TLS config of a, leaving the client passed to WithHTTPClient unchanged. The
+// new transport is owned by a, so Close closes its connections.
+func (a *AquaProtocol) applyTLSConfig() error {
+	if a.tlsConfig == nil {
+		return nil
+	}
+	var transport *http.Transport
+	switch t := a.apiClient.Transport.(type) {
+	case nil:
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		transport = t.Clone()
+	default:
+		return errors.New("TLS config can't be applied to a custom http.RoundTripper")
+	}
+	transport.TLSClientConfig = a.tlsConfig.Clone()
+	client := *a.apiClient
+	client.Transport = transport
+	a.apiClient = &client
+	a.ownTransport = true
+	return nil
+}
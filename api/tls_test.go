@@ -0,0 +1,57 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithTLSConfig(t *testing.T) {
+	require := require.New(t)
+	var clientCerts int
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientCerts = len(r.TLS.PeerCertificates)
+		w.Write([]byte(`{"api_version":"` + Version + `"}`))
+	}))
+	ts.TLS = &tls.Config{ClientAuth: tls.VerifyClientCertIfGiven}
+	ts.StartTLS()
+	defer ts.Close()
+	pool := x509.NewCertPool()
+	pool.AddCert(ts.Certificate())
+
+	// the server's certificate is not trusted by default
+	a, e := NewAPI(ts.URL, testToken)
+	require.NoError(e)
+	_, e = a.GetServerInfo()
+	require.Error(e)
+
+	a, e = NewAPI(ts.URL, testToken, WithTLSConfig(&tls.Config{RootCAs: pool}))
+	require.NoError(e)
+	info, e := a.GetServerInfo()
+	require.NoError(e)
+	require.Equal(Version, info.ApiVersion)
+	require.Zero(clientCerts)
+
+	// client certificates are presented, also using the client passed to
+	// WithHTTPClient
+	ts.TLS.ClientAuth = tls.RequireAnyClientCert
+	transport := &http.Transport{}
+	client := &http.Client{Transport: transport}
+	cfg := &tls.Config{RootCAs: pool, Certificates: ts.TLS.Certificates}
+	a, e = NewAPI(ts.URL, testToken, WithHTTPClient(client), WithTLSConfig(cfg))
+	require.NoError(e)
+	_, e = a.GetServerInfo()
+	require.NoError(e)
+	require.Equal(1, clientCerts)
+	require.Same(transport, client.Transport)
+	if transport.TLSClientConfig != nil {
+		require.Nil(transport.TLSClientConfig.RootCAs)
+	}
+
+	_, e = NewAPI(ts.URL, testToken, WithHTTPClient(&http.Client{Transport: &cannedTransport{}}), WithTLSConfig(cfg))
+	require.Error(e)
+}
@@ -0,0 +1,55 @@
+package api
+
+import "context"
+
+// Attribute is a key/value pair attached to a Span
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Attr returns an Attribute with the given key and value
+func Attr(key string, value interface{}) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Span is the subset of an OpenTelemetry span used by this package
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	RecordError(err error)
+	End()
+}
+
+// Tracer creates spans around api requests and verification steps. It is kept
+// minimal so that an OpenTelemetry tracer can be adapted to it without this
+// package depending on OpenTelemetry.
+type Tracer interface {
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+}
+
+// WithTracer makes the client create a span for every api request
+func WithTracer(t Tracer) Option {
+	return func(a *AquaProtocol) {
+		a.tracer = t
+	}
+}
+
+// Tracer returns the Tracer the client was created with, or nil
+func (a *AquaProtocol) Tracer() Tracer {
+	return a.tracer
+}
+
+// StartSpan starts a span using t. If t is nil a span that does nothing is
+// returned.
+func StartSpan(ctx context.Context, t Tracer, name string, attrs ...Attribute) (context.Context, Span) {
+	if t == nil {
+		return ctx, nopSpan{}
+	}
+	return t.Start(ctx, name, attrs...)
+}
+
+type nopSpan struct{}
+
+func (nopSpan) SetAttributes(attrs ...Attribute) {}
+func (nopSpan) RecordError(err error)            {}
+func (nopSpan) End()                             {}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testSpan struct {
+	name  string
+	attrs map[string]interface{}
+	errs  []error
+	ended bool
+}
+
+func (s *testSpan) SetAttributes(attrs ...Attribute) {
+	for _, a := range attrs {
+		s.attrs[a.Key] = a.Value
+	}
+}
+
+func (s *testSpan) RecordError(err error) {
+	s.errs = append(s.errs, err)
+}
+
+func (s *testSpan) End() {
+	s.ended = true
+}
+
+// testTracer records all spans it has started
+type testTracer struct {
+	spans []*testSpan
+}
+
+func (t *testTracer) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	s := &testSpan{name: name, attrs: map[string]interface{}{}}
+	s.SetAttributes(attrs...)
+	t.spans = append(t.spans, s)
+	return ctx, s
+}
+
+func TestWithTracer(t *testing.T) {
+	require := require.New(t)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/"+endpoint_get_server_info {
+			w.Write([]byte(`{"api_version":"` + Version + `"}`))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer s.Close()
+
+	tracer := &testTracer{}
+	a, e := NewAPI(s.URL, testToken, WithTracer(tracer))
+	require.NoError(e)
+	require.Equal(tracer, a.Tracer())
+	_, e = a.GetServerInfo()
+	require.NoError(e)
+	_, e = a.GetRevision("abc")
+	require.Error(e)
+
+	require.Len(tracer.spans, 2)
+	span := tracer.spans[0]
+	require.Equal("aqua.fetch", span.name)
+	require.True(span.ended)
+	require.Equal(http.MethodGet, span.attrs["http.method"])
+	require.Equal(s.URL+"/"+endpoint_get_server_info, span.attrs["http.url"])
+	require.Equal(http.StatusOK, span.attrs["http.status_code"])
+	require.Empty(span.errs)
+
+	span = tracer.spans[1]
+	require.Equal(s.URL+"/"+endpoint_get_revision+"abc", span.attrs["http.url"])
+	require.Equal(http.StatusNotFound, span.attrs["http.status_code"])
+	require.Len(span.errs, 1)
+}
+
+func TestStartSpanWithoutTracer(t *testing.T) {
+	ctx := context.Background()
+	got, span := StartSpan(ctx, nil, "noop", Attr("key", "value"))
+	require.Equal(t, ctx, got)
+	span.SetAttributes(Attr("key", "value"))
+	span.RecordError(nil)
+	span.End()
+}
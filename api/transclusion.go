@@ -0,0 +1,44 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// transclusionHashesSlot is the content slot listing the transcluded pages
+const transclusionHashesSlot = "transclusion-hashes"
+
+// Transclusion is a page transcluded by a revision, as listed in the
+// transclusion-hashes content slot of the revision
+type Transclusion struct {
+	DbKey     string `json:"dbkey"`
+	Namespace int    `json:"ns"`
+	// RevId and GenesisHash are only served by newer servers
+	RevId       int    `json:"revid,omitempty"`
+	GenesisHash string `json:"genesis_hash,omitempty"`
+	// VerificationHash is the verification hash of the transcluded revision,
+	// empty if the transcluded page doesn't exist
+	VerificationHash string `json:"verification_hash"`
+}
+
+// ParseTransclusions returns the pages transcluded by the content, in the
+// order they are listed in its transclusion-hashes slot. Content without the
+// slot transcludes no pages. An error is returned if the slot is not a list
+// of transclusions, or a transclusion has no dbkey.
+func (c *RevisionContent) ParseTransclusions() ([]Transclusion, error) {
+	raw := strings.TrimSpace(c.Content[transclusionHashesSlot])
+	if raw == "" {
+		return nil, nil
+	}
+	var transclusions []Transclusion
+	if err := json.Unmarshal([]byte(raw), &transclusions); err != nil {
+		return nil, fmt.Errorf("Malformed transclusion hashes: %w", err)
+	}
+	for i, t := range transclusions {
+		if t.DbKey == "" {
+			return nil, fmt.Errorf("Transclusion %d has no dbkey", i)
+		}
+	}
+	return transclusions, nil
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTransclusions(t *testing.T) {
+	require := require.New(t)
+	parse := func(hashes string) ([]Transclusion, error) {
+		c := &RevisionContent{Content: map[string]string{"main": "text", transclusionHashesSlot: hashes}}
+		return c.ParseTransclusions()
+	}
+
+	transclusions, e := (&RevisionContent{Content: map[string]string{"main": "text"}}).ParseTransclusions()
+	require.NoError(e)
+	require.Empty(transclusions)
+	for _, empty := range []string{"", "[]", " [] "} {
+		transclusions, e = parse(empty)
+		require.NoError(e)
+		require.Empty(transclusions)
+	}
+
+	transclusions, e = parse(`[{"dbkey":"Interactive_Tutorial","ns":0,"revid":9,"genesis_hash":"g","verification_hash":"v"}]`)
+	require.NoError(e)
+	require.Equal([]Transclusion{{DbKey: "Interactive_Tutorial", RevId: 9, GenesisHash: "g", VerificationHash: "v"}}, transclusions)
+
+	transclusions, e = parse(`[{"dbkey":"A","ns":0,"verification_hash":"a"},{"dbkey":"B","ns":10,"verification_hash":null},{"dbkey":"C","ns":6,"verification_hash":"c"}]`)
+	require.NoError(e)
+	require.Equal([]Transclusion{
+		{DbKey: "A", VerificationHash: "a"},
+		{DbKey: "B", Namespace: 10},
+		{DbKey: "C", Namespace: 6, VerificationHash: "c"},
+	}, transclusions)
+
+	_, e = parse(`{"dbkey":"A"}`)
+	require.Error(e)
+	require.Contains(e.Error(), "Malformed transclusion hashes: ")
+	_, e = parse(`[{"dbkey":"A",`)
+	require.Error(e)
+	_, e = parse(`[{"dbkey":"A","ns":"main"}]`)
+	require.Error(e)
+	_, e = parse(`[{"dbkey":"A"},{"ns":0,"verification_hash":"b"}]`)
+	require.EqualError(e, "Transclusion 1 has no dbkey")
+}
@@ -0,0 +1,263 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newChainServer serves a chain of revisions with the given verification
+// hashes, ordered from genesis to latest
+func newChainServer(hashes ...string) (*httptest.Server, *int) {
+	revisions := map[string]*Revision{}
+	prev := ""
+	for _, h := range hashes {
+		revisions[h] = &Revision{Metadata: &RevisionMetadata{VerificationHash: h, PreviousVerificationHash: prev}}
+		prev = h
+	}
+	var requests int
+	var mu sync.Mutex
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		rev, ok := revisions[strings.TrimPrefix(r.URL.Path, "/"+endpoint_get_revision)]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(rev)
+	}))
+	return s, &requests
+}
+
+func TestWalkChain(t *testing.T) {
+	require := require.New(t)
+	s, requests := newChainServer("genesis", "second", "latest")
+	defer s.Close()
+	a, e := NewAPI(s.URL, testToken)
+	require.NoError(e)
+
+	var walked []string
+	for r, e := range a.WalkChain(context.Background(), "latest") {
+		require.NoError(e)
+		walked = append(walked, r.Metadata.VerificationHash)
+	}
+	require.Equal([]string{"latest", "second", "genesis"}, walked)
+	require.Equal(3, *requests)
+
+	// stopping early doesn't fetch the remaining revisions
+	walked = nil
+	for r := range a.WalkChain(context.Background(), "latest") {
+		walked = append(walked, r.Metadata.VerificationHash)
+		break
+	}
+	require.Equal([]string{"latest"}, walked)
+	require.Equal(4, *requests)
+
+	// a cancelled context stops the walk
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	walked = nil
+	var errs []error
+	for r, e := range a.WalkChain(ctx, "latest") {
+		if e != nil {
+			errs = append(errs, e)
+			continue
+		}
+		walked = append(walked, r.Metadata.VerificationHash)
+		cancel()
+	}
+	require.Equal([]string{"latest"}, walked)
+	require.Equal([]error{context.Canceled}, errs)
+
+	errs = nil
+	for _, e := range a.WalkChain(context.Background(), "missing") {
+		errs = append(errs, e)
+	}
+	require.Len(errs, 1)
+	require.EqualError(errs[0], "Failure getting revision missing: Request Not 200 OK")
+}
+
+func TestGetRevisionRange(t *testing.T) {
+	require := require.New(t)
+	s, _ := newChainServer("genesis", "second", "third", "latest")
+	defer s.Close()
+	a, e := NewAPI(s.URL, testToken)
+	require.NoError(e)
+	hashes := func(revisions []*Revision) []string {
+		var h []string
+		for _, r := range revisions {
+			h = append(h, r.Metadata.VerificationHash)
+		}
+		return h
+	}
+
+	revisions, e := a.GetRevisionRange(context.Background(), "second", "latest")
+	require.NoError(e)
+	require.Equal([]string{"second", "third", "latest"}, hashes(revisions))
+
+	revisions, e = a.GetRevisionRange(context.Background(), "genesis", "second")
+	require.NoError(e)
+	require.Equal([]string{"genesis", "second"}, hashes(revisions))
+
+	revisions, e = a.GetRevisionRange(context.Background(), "third", "third")
+	require.NoError(e)
+	require.Equal([]string{"third"}, hashes(revisions))
+
+	// the range is not followed forward
+	_, e = a.GetRevisionRange(context.Background(), "latest", "second")
+	require.EqualError(e, "Revision latest is not an ancestor of revision second")
+
+	_, e = a.GetRevisionRange(context.Background(), "genesis", "unknown")
+	require.Error(e)
+
+	// fromHash is compared normalized, like the served hashes
+	hex, _ := newChainServer("aa", "bb", "cc")
+	defer hex.Close()
+	a, e = NewAPI(hex.URL, testToken)
+	require.NoError(e)
+	revisions, e = a.GetRevisionRange(context.Background(), "0xBB", "cc")
+	require.NoError(e)
+	require.Equal([]string{"bb", "cc"}, hashes(revisions))
+}
+
+func TestIsAncestor(t *testing.T) {
+	require := require.New(t)
+	s, _ := newChainServer("genesis", "second", "latest")
+	defer s.Close()
+	other, _ := newChainServer("other")
+	defer other.Close()
+	a, e := NewAPI(s.URL, testToken)
+	require.NoError(e)
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		ancestor, descendant string
+		expected             bool
+	}{
+		{"genesis", "latest", true},
+		{"second", "latest", true},
+		// a descendant is not an ancestor
+		{"latest", "second", false},
+		{"other", "latest", false},
+	} {
+		ok, e := a.IsAncestor(ctx, tc.ancestor, tc.descendant)
+		require.NoError(e)
+		require.Equal(tc.expected, ok, tc.ancestor+" "+tc.descendant)
+	}
+	_, e = a.IsAncestor(ctx, "genesis", "unknown")
+	require.Error(e)
+
+	// a chain looping back to itself
+	loop := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hash := strings.TrimPrefix(r.URL.Path, "/"+endpoint_get_revision)
+		prev := map[string]string{"a": "b", "b": "a"}[hash]
+		json.NewEncoder(w).Encode(&Revision{Metadata: &RevisionMetadata{VerificationHash: hash, PreviousVerificationHash: prev}})
+	}))
+	defer loop.Close()
+	a, e = NewAPI(loop.URL, testToken)
+	require.NoError(e)
+	_, e = a.IsAncestor(ctx, "genesis", "a")
+	require.EqualError(e, "Chain of revision a loops at revision a")
+}
+
+func TestWalkChainLoops(t *testing.T) {
+	require := require.New(t)
+	for _, tc := range []struct {
+		name     string
+		prev     map[string]string
+		walked   []string
+		expected string
+	}{
+		{"self-referential", map[string]string{"a": "a"}, []string{"a"}, "Chain of revision a loops at revision a"},
+		{"cycle", map[string]string{"a": "b", "b": "c", "c": "b"}, []string{"a", "b", "c"}, "Chain of revision a loops at revision b"},
+	} {
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasPrefix(r.URL.Path, "/"+endpoint_get_hash_chain_info) {
+				json.NewEncoder(w).Encode(&HashChainInfo{GenesisHash: "a", LatestVerificationHash: "a", Title: "a"})
+				return
+			}
+			hash := strings.TrimPrefix(r.URL.Path, "/"+endpoint_get_revision)
+			json.NewEncoder(w).Encode(&Revision{Metadata: &RevisionMetadata{VerificationHash: hash, PreviousVerificationHash: tc.prev[hash]}})
+		}))
+		a, e := NewAPI(s.URL, testToken)
+		require.NoError(e)
+
+		var walked []string
+		for r, e := range a.WalkChain(context.Background(), "a") {
+			if e != nil {
+				require.EqualError(e, tc.expected, tc.name)
+				break
+			}
+			walked = append(walked, r.Metadata.VerificationHash)
+		}
+		require.Equal(tc.walked, walked, tc.name)
+
+		// fetching the whole chain ends too
+		_, e = FetchHashChain(context.Background(), a, "title", "a", -1)
+		require.EqualError(e, tc.expected, tc.name)
+		s.Close()
+	}
+}
+
+func TestGetOrderedRevisionHashes(t *testing.T) {
+	require := require.New(t)
+	chain := []string{"genesis", "second", "third", "latest"}
+	revisions := map[string]*Revision{}
+	prev := ""
+	for _, h := range chain {
+		revisions[h] = &Revision{Metadata: &RevisionMetadata{VerificationHash: h, PreviousVerificationHash: prev}}
+		prev = h
+	}
+	var listed []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/"+endpoint_get_revision_hashes) {
+			json.NewEncoder(w).Encode(listed)
+			return
+		}
+		rev, ok := revisions[strings.TrimPrefix(r.URL.Path, "/"+endpoint_get_revision)]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(rev)
+	}))
+	defer s.Close()
+	a, e := NewAPI(s.URL, testToken)
+	require.NoError(e)
+	ctx := context.Background()
+
+	listed = []string{"third", "latest", "genesis", "second"}
+	hashes, e := a.GetOrderedRevisionHashes(ctx, "genesis")
+	require.NoError(e)
+	require.Equal(chain, hashes)
+	listed = []string{"latest", "second", "third"}
+	hashes, e = a.GetOrderedRevisionHashes(ctx, "second")
+	require.NoError(e)
+	require.Equal(chain[1:], hashes)
+
+	// a gap in the listed hashes
+	listed = []string{"latest", "genesis", "second"}
+	_, e = a.GetOrderedRevisionHashes(ctx, "genesis")
+	require.EqualError(e, "Revision hashes of genesis don't form a single chain")
+	// a fork
+	revisions["fork"] = &Revision{Metadata: &RevisionMetadata{VerificationHash: "fork", PreviousVerificationHash: "second"}}
+	listed = []string{"fork", "third", "second"}
+	_, e = a.GetOrderedRevisionHashes(ctx, "second")
+	require.EqualError(e, "Revisions fork and third both follow revision second")
+	// the requested revision is missing
+	listed = []string{"third", "latest"}
+	_, e = a.GetOrderedRevisionHashes(ctx, "second")
+	require.EqualError(e, "Revision second is not listed in its revision hashes")
+	// a revision that can't be fetched
+	listed = []string{"second", "missing"}
+	_, e = a.GetOrderedRevisionHashes(ctx, "second")
+	require.Error(e)
+}
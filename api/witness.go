@@ -0,0 +1,187 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrTransactionNotFound is returned by a WitnessResolver if the witness
+// transaction doesn't exist
+var ErrTransactionNotFound = errors.New("Transaction hash not found")
+
+// WitnessResolver looks up witness transactions on the chain of a witness
+// network
+type WitnessResolver interface {
+	// LookupMerkleRoot returns the hash witnessed by the transaction, i.e.
+	// the witness event verification hash stored in its input data, as hex
+	// without 0x prefix.
+	LookupMerkleRoot(ctx context.Context, txHash string) (string, error)
+}
+
+// BlockTimeResolver is implemented by WitnessResolvers that can look up when a
+// witness transaction was included in a block, so that backdated witnesses can
+// be detected
+type BlockTimeResolver interface {
+	// LookupBlockTime returns the timestamp of the block of the transaction
+	LookupBlockTime(ctx context.Context, txHash string) (time.Time, error)
+}
+
+// WitnessEvidence is what a witness transaction recorded on chain, imported
+// e.g. from an archive node or light client, so that witnesses can be
+// verified without access to the witness network
+type WitnessEvidence struct {
+	// Network is the witness network of the transaction
+	Network string `json:"network"`
+	// MerkleRoot is the hash witnessed by the transaction, like the result of
+	// LookupMerkleRoot
+	MerkleRoot string `json:"merkle_root"`
+	// BlockTime, if known, is the timestamp of the block of the transaction
+	BlockTime time.Time `json:"block_time,omitempty"`
+}
+
+// EVMResolver looks up witness transactions through the JSON-RPC api of an
+// EVM compatible chain
+type EVMResolver struct {
+	RPCURL string
+	// MethodID is the selector of the witness contract method called by the
+	// transaction, the one of the Aqua witness contract if empty
+	MethodID string
+	// Contract, if set, is the only address witness transactions may be sent
+	// to
+	Contract string
+}
+
+// NewEthereumResolver returns a WitnessResolver for transactions of the Aqua
+// witness contract on an ethereum network, using the JSON-RPC endpoint rpcURL
+func NewEthereumResolver(rpcURL string) *EVMResolver {
+	return &EVMResolver{RPCURL: rpcURL, MethodID: ethMethodId}
+}
+
+// LookupMerkleRoot implements WitnessResolver
+func (e *EVMResolver) LookupMerkleRoot(ctx context.Context, txHash string) (string, error) {
+	var tx *struct {
+		To    string `json:"to"`
+		Input string `json:"input"`
+	}
+	if err := rpcCall(ctx, e.RPCURL, "eth_getTransactionByHash", []interface{}{txHash}, &tx); err != nil {
+		return "", err
+	}
+	if tx == nil {
+		return "", ErrTransactionNotFound
+	}
+	if e.Contract != "" && !strings.EqualFold(tx.To, e.Contract) {
+		return "", errors.New("Transaction is not sent to the witness contract")
+	}
+	methodID := e.MethodID
+	if methodID == "" {
+		methodID = ethMethodId
+	}
+	input := strings.ToLower(tx.Input)
+	if !strings.HasPrefix(input, strings.ToLower(methodID)) {
+		return "", errors.New("Transaction doesn't call the witness method")
+	}
+	return strings.TrimPrefix(input, strings.ToLower(methodID)), nil
+}
+
+// LookupBlockTime implements BlockTimeResolver
+func (e *EVMResolver) LookupBlockTime(ctx context.Context, txHash string) (time.Time, error) {
+	var tx *struct {
+		BlockNumber *string `json:"blockNumber"`
+	}
+	if err := rpcCall(ctx, e.RPCURL, "eth_getTransactionByHash", []interface{}{txHash}, &tx); err != nil {
+		return time.Time{}, err
+	}
+	if tx == nil {
+		return time.Time{}, ErrTransactionNotFound
+	}
+	if tx.BlockNumber == nil {
+		return time.Time{}, errors.New("Transaction is not included in a block yet")
+	}
+	var block *struct {
+		Timestamp string `json:"timestamp"`
+	}
+	if err := rpcCall(ctx, e.RPCURL, "eth_getBlockByNumber", []interface{}{*tx.BlockNumber, false}, &block); err != nil {
+		return time.Time{}, err
+	}
+	if block == nil {
+		return time.Time{}, fmt.Errorf("Block %s not found", *tx.BlockNumber)
+	}
+	seconds, err := strconv.ParseInt(strings.TrimPrefix(block.Timestamp, "0x"), 16, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Invalid block timestamp %q", block.Timestamp)
+	}
+	return time.Unix(seconds, 0).UTC(), nil
+}
+
+// ExplorerTxURL returns the URL of the witness transaction on the block
+// explorer of the witness network in WitnessNetworkMap. It returns an error
+// for networks without a known explorer rather than a link to another
+// network, and for witnesses without a transaction hash.
+func (w *RevisionWitness) ExplorerTxURL() (string, error) {
+	explorer, ok := WitnessNetworkMap[w.WitnessNetwork]
+	if !ok {
+		return "", fmt.Errorf("No block explorer known for witness network %q", w.WitnessNetwork)
+	}
+	if w.WitnessEventTransactionHash == "" {
+		return "", errors.New("Witness has no transaction hash")
+	}
+	return explorer + "/" + w.WitnessEventTransactionHash, nil
+}
+
+// EtherscanResolver looks up witness transactions by scraping the etherscan
+// page of a network in WitnessNetworkMap
+type EtherscanResolver struct {
+	Network string
+}
+
+// LookupMerkleRoot implements WitnessResolver
+func (e *EtherscanResolver) LookupMerkleRoot(ctx context.Context, txHash string) (string, error) {
+	n, ok := WitnessNetworkMap[e.Network]
+	if !ok {
+		return "", errors.New("Invalid ethereum network specified")
+	}
+	u, err := url.Parse(n)
+	if err != nil {
+		return "", err
+	}
+	u.Path = u.Path + "/" + txHash
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	// do the verification
+	c := &http.Client{}
+	resp, err := c.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	// To avoid IP banning by etherscan.io
+	time.Sleep(300 * time.Millisecond)
+
+	// read response
+	buf, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	// scrape output
+	match := re.FindStringSubmatch(string(buf))
+	if match == nil || len(match) < 2 { // no grouping match
+		return "", errors.New("No Match")
+	}
+	// the response is prefixed by methodId
+	d := strings.Split(match[1], ethMethodId)
+	if len(d) != 2 {
+		return "", errors.New("No Match")
+	}
+	return strings.ToLower(d[1]), nil
+}
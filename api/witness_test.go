@@ -0,0 +1,104 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testWitnessContract = "0x45f59310add88e6d23ca58a0fa7a55bee6d2a611"
+	testWitnessedHash   = "9dab72c7635043452958c4cc2902f48ef7c4ae437058280197c6a2736ab9635f"
+	// testBlockTime is the time of the block including transaction "0x1"
+	testBlockTime = 1640995200
+)
+
+// witnessServer returns a fake JSON-RPC server that knows the transaction
+// "0x1" calling the witness contract with testWitnessedHash in block "0x10",
+// and the pending transaction "0x3"
+func witnessServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Method string        `json:"method"`
+			Params []interface{} `json:"params"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		var result interface{}
+		switch {
+		case req.Method == "eth_getTransactionByHash" && req.Params[0] == "0x1":
+			result = map[string]string{"to": testWitnessContract, "input": ethMethodId + testWitnessedHash, "blockNumber": "0x10"}
+		case req.Method == "eth_getTransactionByHash" && req.Params[0] == "0x3":
+			result = map[string]interface{}{"to": testWitnessContract, "input": ethMethodId + testWitnessedHash, "blockNumber": nil}
+		case req.Method == "eth_getBlockByNumber" && req.Params[0] == "0x10":
+			require.Equal(t, false, req.Params[1])
+			result = map[string]string{"number": "0x10", "timestamp": fmt.Sprintf("0x%x", testBlockTime)}
+		case req.Method != "eth_getTransactionByHash" && req.Method != "eth_getBlockByNumber":
+			require.Fail(t, "unexpected method", req.Method)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "result": result})
+	}))
+}
+
+func TestEVMResolver(t *testing.T) {
+	require := require.New(t)
+	s := witnessServer(t)
+	defer s.Close()
+
+	for _, r := range []*EVMResolver{
+		NewEthereumResolver(s.URL),
+		{RPCURL: s.URL, Contract: "0x45F59310ADD88E6D23CA58A0FA7A55BEE6D2A611"},
+	} {
+		root, err := r.LookupMerkleRoot(context.Background(), "0x1")
+		require.NoError(err)
+		require.Equal(testWitnessedHash, root)
+	}
+
+	_, err := NewEthereumResolver(s.URL).LookupMerkleRoot(context.Background(), "0x2")
+	require.Equal(ErrTransactionNotFound, err)
+
+	_, err = (&EVMResolver{RPCURL: s.URL, Contract: testWallet}).LookupMerkleRoot(context.Background(), "0x1")
+	require.EqualError(err, "Transaction is not sent to the witness contract")
+	_, err = (&EVMResolver{RPCURL: s.URL, MethodID: "0x12345678"}).LookupMerkleRoot(context.Background(), "0x1")
+	require.EqualError(err, "Transaction doesn't call the witness method")
+}
+
+func TestEVMResolverBlockTime(t *testing.T) {
+	require := require.New(t)
+	s := witnessServer(t)
+	defer s.Close()
+	var r BlockTimeResolver = NewEthereumResolver(s.URL)
+
+	blockTime, err := r.LookupBlockTime(context.Background(), "0x1")
+	require.NoError(err)
+	require.Equal(time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC), blockTime)
+
+	_, err = r.LookupBlockTime(context.Background(), "0x2")
+	require.Equal(ErrTransactionNotFound, err)
+	_, err = r.LookupBlockTime(context.Background(), "0x3")
+	require.EqualError(err, "Transaction is not included in a block yet")
+}
+
+func TestExplorerTxURL(t *testing.T) {
+	require := require.New(t)
+	const tx = "0x5d4e2f3c7bcbba1e2b10cb23c2d5f3ea3b2b2e9e3978c37d49aa3e60c4e35471"
+	for network, expected := range map[string]string{
+		"mainnet": "https://etherscan.io/tx/" + tx,
+		"sepolia": "https://sepolia.etherscan.io/tx/" + tx,
+		"polygon": "https://polygonscan.com/tx/" + tx,
+	} {
+		u, e := (&RevisionWitness{WitnessNetwork: network, WitnessEventTransactionHash: tx}).ExplorerTxURL()
+		require.NoError(e)
+		require.Equal(expected, u)
+	}
+
+	_, e := (&RevisionWitness{WitnessNetwork: "unknown", WitnessEventTransactionHash: tx}).ExplorerTxURL()
+	require.EqualError(e, `No block explorer known for witness network "unknown"`)
+	_, e = (&RevisionWitness{WitnessNetwork: "mainnet"}).ExplorerTxURL()
+	require.Error(e)
+}
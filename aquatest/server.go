@@ -0,0 +1,197 @@
+// Package aquatest provides an in-memory Aqua data accounting server for
+// testing code that uses the api and verify packages.
+//
+// A Server is preloaded with hash chains, e.g. an export of a PKC, and serves
+// them over the four GET endpoints of the data accounting api:
+//
+//	s, err := aquatest.NewServerFromFile("testdata/Main_Page.json")
+//	...
+//	defer s.Close()
+//	ap, err := api.NewAPI(s.URL, "")
+//
+// Tamper changes a served revision to exercise verification failures.
+package aquatest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/inblockio/aqua-verifier-go/api"
+)
+
+const prefix = "/data_accounting/"
+
+// Server is an Aqua data accounting server serving hash chains from memory.
+// It is safe for concurrent use.
+type Server struct {
+	*httptest.Server
+
+	mu         sync.RWMutex
+	chains     []*api.HashChain
+	serverInfo api.ServerInfo
+}
+
+// NewServer starts a Server serving copies of chains, so that tampering with
+// the served revisions leaves chains unchanged. It reports the api.Version of
+// this module as its api version. The caller must call Close when finished.
+func NewServer(chains ...*api.HashChain) (*Server, error) {
+	s := &Server{serverInfo: api.ServerInfo{ApiVersion: api.Version}}
+	for _, c := range chains {
+		if err := s.AddChain(c); err != nil {
+			return nil, err
+		}
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(prefix+"get_server_info", s.handleServerInfo)
+	mux.HandleFunc(prefix+"get_hash_chain_info/", s.handleHashChainInfo)
+	mux.HandleFunc(prefix+"get_revision_hashes/", s.handleRevisionHashes)
+	mux.HandleFunc(prefix+"get_revision/", s.handleRevision)
+	s.Server = httptest.NewServer(mux)
+	return s, nil
+}
+
+// NewServerFromFile starts a Server serving the pages of the JSON export of a
+// PKC in the given file
+func NewServerFromFile(filename string) (*Server, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	data := &api.OfflineData{}
+	if err := json.Unmarshal(b, data); err != nil {
+		return nil, err
+	}
+	return NewServer(data.Pages...)
+}
+
+// AddChain adds a copy of c to the served hash chains
+func (s *Server) AddChain(c *api.HashChain) error {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	chain := &api.HashChain{}
+	if err := json.Unmarshal(b, chain); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.chains = append(s.chains, chain)
+	return nil
+}
+
+// SetServerInfo sets the server info the server responds with, e.g. to test
+// api version checks
+func (s *Server) SetServerInfo(info api.ServerInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.serverInfo = info
+}
+
+// Tamper calls f with the served revision with the given verification hash,
+// so that it can be changed in place, e.g. to serve tampered content. The
+// revision is still served under its original verification hash.
+func (s *Server) Tamper(verificationHash string, f func(r *api.Revision)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, r := s.findRevision(verificationHash)
+	if r == nil {
+		return fmt.Errorf("No revision %s", verificationHash)
+	}
+	f(r)
+	return nil
+}
+
+// TamperContent replaces the main content of the served revision with the
+// given verification hash, leaving its hashes unchanged
+func (s *Server) TamperContent(verificationHash, content string) error {
+	return s.Tamper(verificationHash, func(r *api.Revision) {
+		if r.Content == nil {
+			r.Content = &api.RevisionContent{}
+		}
+		if r.Content.Content == nil {
+			r.Content.Content = make(map[string]string)
+		}
+		r.Content.Content["main"] = content
+	})
+}
+
+// findRevision returns the revision with the given verification hash and its
+// chain. s.mu must be held.
+func (s *Server) findRevision(verificationHash string) (*api.HashChain, *api.Revision) {
+	for _, c := range s.chains {
+		if r, ok := c.Revisions[verificationHash]; ok {
+			return c, r
+		}
+	}
+	return nil, nil
+}
+
+func (s *Server) handleServerInfo(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	writeJSON(w, &s.serverInfo)
+}
+
+func (s *Server) handleHashChainInfo(w http.ResponseWriter, r *http.Request) {
+	idType := strings.TrimPrefix(r.URL.Path, prefix+"get_hash_chain_info/")
+	id := r.URL.Query().Get("identifier")
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, c := range s.chains {
+		switch {
+		case idType == "title" && strings.ReplaceAll(c.Title, "_", " ") == strings.ReplaceAll(id, "_", " "),
+			idType == "genesis_hash" && c.GenesisHash == id:
+			writeJSON(w, &c.HashChainInfo)
+			return
+		}
+	}
+	http.NotFound(w, r)
+}
+
+func (s *Server) handleRevisionHashes(w http.ResponseWriter, r *http.Request) {
+	hash := strings.TrimPrefix(r.URL.Path, prefix+"get_revision_hashes/")
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	c, rev := s.findRevision(hash)
+	if rev == nil {
+		http.NotFound(w, r)
+		return
+	}
+	// the requested revision and all newer ones, oldest first
+	hashes := []string{}
+	for cur := c.LatestVerificationHash; cur != ""; {
+		hashes = append([]string{cur}, hashes...)
+		prev, ok := c.Revisions[cur]
+		if cur == hash || !ok {
+			break
+		}
+		cur = prev.Metadata.PreviousVerificationHash
+	}
+	if limit, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && limit > 0 && limit < len(hashes) {
+		hashes = hashes[:limit]
+	}
+	writeJSON(w, hashes)
+}
+
+func (s *Server) handleRevision(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, rev := s.findRevision(strings.TrimPrefix(r.URL.Path, prefix+"get_revision/"))
+	if rev == nil {
+		http.NotFound(w, r)
+		return
+	}
+	writeJSON(w, rev)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,97 @@
+package aquatest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/inblockio/aqua-verifier-go/api"
+	"github.com/inblockio/aqua-verifier-go/verify"
+	"github.com/stretchr/testify/require"
+)
+
+const fixture = "../verify/test_fixtures/5e5a1ec586_Main_Page.json"
+
+func TestServer(t *testing.T) {
+	require := require.New(t)
+	s, err := NewServerFromFile(fixture)
+	require.NoError(err)
+	defer s.Close()
+	ap, err := api.NewAPI(s.URL, "")
+	require.NoError(err)
+	ctx := context.Background()
+
+	info, err := ap.GetServerInfoContext(ctx)
+	require.NoError(err)
+	require.Equal(api.Version, info.ApiVersion)
+
+	chain, err := ap.GetHashChainInfoContext(ctx, "title", "Main_Page")
+	require.NoError(err)
+	require.Equal("Main_Page", chain.Title)
+	byGenesis, err := ap.GetHashChainInfoContext(ctx, "genesis_hash", chain.GenesisHash)
+	require.NoError(err)
+	require.Equal(chain.LatestVerificationHash, byGenesis.LatestVerificationHash)
+	_, err = ap.GetHashChainInfoContext(ctx, "title", "Unknown")
+	require.Error(err)
+
+	hashes, err := ap.GetRevisionHashesContext(ctx, chain.GenesisHash)
+	require.NoError(err)
+	require.Len(hashes, chain.ChainHeight)
+	require.Equal(chain.GenesisHash, string(*hashes[0]))
+	require.Equal(chain.LatestVerificationHash, string(*hashes[len(hashes)-1]))
+	hashes, err = ap.GetRevisionHashesSince(ctx, chain.GenesisHash, 2)
+	require.NoError(err)
+	require.Len(hashes, 2)
+
+	r, err := ap.GetRevisionContext(ctx, chain.LatestVerificationHash)
+	require.NoError(err)
+	require.Equal(chain.LatestVerificationHash, r.Metadata.VerificationHash)
+	_, err = ap.GetRevisionContext(ctx, "unknown")
+	require.Error(err)
+
+	result, err := verify.VerifyChain(ctx, ap, chain.Title, false, -1, verify.WithOnChainChecks(false))
+	require.NoError(err)
+	require.True(result.Valid())
+}
+
+func TestTamper(t *testing.T) {
+	require := require.New(t)
+	s, err := NewServerFromFile(fixture)
+	require.NoError(err)
+	defer s.Close()
+	ap, err := api.NewAPI(s.URL, "")
+	require.NoError(err)
+	ctx := context.Background()
+	chain, err := ap.GetHashChainInfoContext(ctx, "title", "Main Page")
+	require.NoError(err)
+
+	require.NoError(s.TamperContent(chain.LatestVerificationHash, "tampered"))
+	result, err := verify.VerifyChain(ctx, ap, chain.Title, false, -1, verify.WithOnChainChecks(false))
+	require.NoError(err)
+	require.False(result.Valid())
+	require.True(errors.Is(result.Err(), verify.ErrContentHashMismatch))
+
+	require.NoError(s.Tamper(chain.GenesisHash, func(r *api.Revision) {
+		r.Metadata.DomainId = "tampered"
+	}))
+	result, err = verify.VerifyChain(ctx, ap, chain.Title, false, -1, verify.WithOnChainChecks(false))
+	require.NoError(err)
+	require.True(errors.Is(result.Err(), verify.ErrMetadataHashMismatch))
+
+	require.EqualError(s.TamperContent("unknown", "tampered"), "No revision unknown")
+
+	// the tampering doesn't leak into other servers of the same chain
+	other, err := NewServerFromFile(fixture)
+	require.NoError(err)
+	defer other.Close()
+	ap, err = api.NewAPI(other.URL, "")
+	require.NoError(err)
+	result, err = verify.VerifyChain(ctx, ap, chain.Title, false, -1, verify.WithOnChainChecks(false))
+	require.NoError(err)
+	require.True(result.Valid())
+
+	other.SetServerInfo(api.ServerInfo{ApiVersion: "0.0.1"})
+	info, err := ap.GetServerInfoContext(ctx)
+	require.NoError(err)
+	require.Equal("0.0.1", info.ApiVersion)
+}
@@ -3,10 +3,13 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
+	"time"
 
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/inblockio/aqua-verifier-go/api"
 	"github.com/inblockio/aqua-verifier-go/verify"
 )
@@ -18,6 +21,9 @@ var (
 	authToken         = flag.String("token", "", "(Optional) OAuth2 access token to access the API")
 	dataFile          = flag.String("file", "", "(If present) The file to read from for the data")
 	depth             = flag.Int("depth", -1, "(Optional) Depth to follow verification chain. By default, verifies all revisions")
+	certificate       = flag.String("certificate", "", "(Optional) Write a signed PDF certificate of the verification of a page to this file")
+	certificateKey    = flag.String("certificate-key", "", "The file holding the hex encoded private key to sign the certificate with")
+	export            = flag.String("export", "", "(Optional) Write the hash chain of a page to this file in the offline export format")
 	ap                *api.AquaProtocol
 )
 
@@ -47,7 +53,17 @@ func main() {
 			fmt.Println("Failed to get api endpoint", e)
 			os.Exit(-1)
 		}
-		if verify.VerifyPage(a, title, true, *depth) {
+		if *export != "" {
+			if e := writeExport(a, title); e != nil {
+				fmt.Println("Failed to export", e)
+				os.Exit(-1)
+			}
+		} else if *certificate != "" {
+			if e := writeCertificate(a, title); e != nil {
+				fmt.Println("Failed to write certificate", e)
+				os.Exit(-1)
+			}
+		} else if verify.VerifyPage(a, title, true, *depth) {
 			fmt.Println("Verified:", title)
 		} else {
 			fmt.Println("Failed to verify:", title)
@@ -55,6 +71,59 @@ func main() {
 	}
 }
 
+// writeCertificate verifies the page with the given title and writes the
+// signed certificate of the verification
+func writeCertificate(a *api.AquaProtocol, title string) error {
+	key, e := crypto.LoadECDSA(*certificateKey)
+	if e != nil {
+		return e
+	}
+	data, e := a.GetHashChain(context.Background(), "title", title, *depth)
+	if e != nil {
+		return e
+	}
+	result, e := verify.VerifyHashChain(data, !*ignoreMerkleProof, *depth)
+	if e != nil {
+		return e
+	}
+	f, e := os.Create(*certificate)
+	if e != nil {
+		return e
+	}
+	e = verify.WriteCertificatePDF(f, verify.NewCertificate(data, result, time.Now()), key)
+	if cerr := f.Close(); e == nil {
+		e = cerr
+	}
+	if e != nil {
+		return e
+	}
+	if result.Valid() {
+		fmt.Println("Verified:", title)
+	} else {
+		fmt.Println("Failed to verify:", title)
+	}
+	fmt.Println("Certificate written to", *certificate)
+	return nil
+}
+
+// writeExport writes the hash chain of the page with the given title to the
+// export file
+func writeExport(a *api.AquaProtocol, title string) error {
+	f, e := os.Create(*export)
+	if e != nil {
+		return e
+	}
+	e = a.ExportChain(context.Background(), f, title)
+	if cerr := f.Close(); e == nil {
+		e = cerr
+	}
+	if e != nil {
+		return e
+	}
+	fmt.Println("Exported", title, "to", *export)
+	return nil
+}
+
 func usage() {
 	fmt.Printf(`Usage:
 verify [OPTIONS] <page title>
@@ -0,0 +1,32 @@
+// Package conformance checks the hashing of the api and verify packages
+// against test vectors, so that it stays correct as the protocol evolves.
+//
+// The vectors are JSON files below testdata, one directory per hash function:
+// content, metadata, signature, witness and verification. Every file holds the
+// input of the function and the hash it must return:
+//
+//	{"input": {...}, "expected_hash": "..."}
+//
+// The inputs are the api types the function takes, a RevisionContent,
+// RevisionMetadata, RevisionSignature or RevisionWitness, and for
+// verification hashes a Revision with the revision and its previous
+// revision. New vectors, e.g. from the reference spec, are added by dropping
+// files into the directories. The initial vectors are generated from the
+// revisions of an export of the reference implementation, with the hashes it
+// computed, by running go test with -generate.
+package conformance
+
+import "github.com/inblockio/aqua-verifier-go/api"
+
+// Vector is a test vector of a hash function
+type Vector[T any] struct {
+	Input        T      `json:"input"`
+	ExpectedHash string `json:"expected_hash"`
+}
+
+// Revision is the input of a verification hash vector, a revision and its
+// previous revision, which is nil for a genesis revision
+type Revision struct {
+	Revision *api.Revision `json:"revision"`
+	Previous *api.Revision `json:"previous"`
+}
@@ -0,0 +1,145 @@
+package conformance
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/inblockio/aqua-verifier-go/api"
+	"github.com/inblockio/aqua-verifier-go/verify"
+	"github.com/stretchr/testify/require"
+)
+
+var generate = flag.Bool("generate", false, "Generate the test vectors from the export of the reference implementation")
+
+// export is the export the vectors are generated from
+const export = "../verify/test_fixtures/5e5a1ec586_Main_Page.json"
+
+// runVectors runs check on every vector in testdata/dir
+func runVectors[T any](t *testing.T, dir string, check func(input T) (string, error)) {
+	files, err := filepath.Glob(filepath.Join("testdata", dir, "*.json"))
+	require.NoError(t, err)
+	require.NotEmpty(t, files, "no %s vectors", dir)
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".json")
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile(file)
+			require.NoError(t, err)
+			var v Vector[T]
+			require.NoError(t, json.Unmarshal(data, &v), "vector %s/%s is malformed", dir, name)
+			hash, err := check(v.Input)
+			require.NoError(t, err, "vector %s/%s", dir, name)
+			require.Equal(t, v.ExpectedHash, hash, "vector %s/%s: %s hash doesn't match", dir, name, dir)
+		})
+	}
+}
+
+func TestConformance(t *testing.T) {
+	if *generate {
+		require.NoError(t, generateVectors())
+	}
+	t.Run("content", func(t *testing.T) {
+		runVectors(t, "content", func(c *api.RevisionContent) (string, error) {
+			_, hash := verify.ExplainContentHash(c)
+			return hash, nil
+		})
+	})
+	t.Run("metadata", func(t *testing.T) {
+		runVectors(t, "metadata", func(m *api.RevisionMetadata) (string, error) {
+			return m.ComputeHash()
+		})
+	})
+	t.Run("signature", func(t *testing.T) {
+		runVectors(t, "signature", func(s *api.RevisionSignature) (string, error) {
+			return s.ComputeHash()
+		})
+	})
+	t.Run("witness", func(t *testing.T) {
+		runVectors(t, "witness", func(w *api.RevisionWitness) (string, error) {
+			return w.ComputeHash()
+		})
+	})
+	t.Run("verification", func(t *testing.T) {
+		runVectors(t, "verification", func(r Revision) (string, error) {
+			return verify.ComputeVerificationHash(r.Revision, r.Previous)
+		})
+	})
+}
+
+// generateVectors writes the vectors of the revisions of export to testdata,
+// named after the position of the revision in its chain, with the hashes the
+// reference implementation computed rather than the ones of this module
+func generateVectors() error {
+	data, err := os.ReadFile(export)
+	if err != nil {
+		return err
+	}
+	var offline api.OfflineData
+	if err := json.Unmarshal(data, &offline); err != nil {
+		return err
+	}
+	for _, page := range offline.Pages {
+		var chain []*api.Revision
+		for cur := page.LatestVerificationHash; cur != ""; {
+			r, ok := page.Revisions[cur]
+			if !ok {
+				return fmt.Errorf("Revision %s of %s is missing", cur, page.Title)
+			}
+			chain = append([]*api.Revision{r}, chain...)
+			cur = r.Metadata.PreviousVerificationHash
+		}
+		prefix := strings.ToLower(strings.ReplaceAll(page.Title, " ", "_"))
+		for i, r := range chain {
+			name := fmt.Sprintf("%s_%d", prefix, i+1)
+			content := *r.Content
+			content.ContentHash = ""
+			metadata := *r.Metadata
+			metadata.MetadataHash, metadata.VerificationHash = "", ""
+			if err := writeVector("content", name, &content, r.Content.ContentHash); err != nil {
+				return err
+			}
+			if err := writeVector("metadata", name, &metadata, r.Metadata.MetadataHash); err != nil {
+				return err
+			}
+			if s := r.Signature; r.HasSignature() && s.SignatureHash != "" {
+				signature := *s
+				signature.SignatureHash = ""
+				if err := writeVector("signature", name, &signature, s.SignatureHash); err != nil {
+					return err
+				}
+			}
+			if w := r.Witness; r.HasWitness() && w.WitnessHash != "" {
+				witness := *w
+				witness.WitnessHash = ""
+				if err := writeVector("witness", name, &witness, w.WitnessHash); err != nil {
+					return err
+				}
+			}
+			input := Revision{Revision: &api.Revision{Context: r.Context, Content: &content, Metadata: &metadata}}
+			if i > 0 {
+				input.Previous = chain[i-1]
+			}
+			if err := writeVector("verification", name, input, r.Metadata.VerificationHash); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeVector writes the vector of input and expectedHash to
+// testdata/dir/name.json
+func writeVector[T any](dir, name string, input T, expectedHash string) error {
+	data, err := json.MarshalIndent(Vector[T]{Input: input, ExpectedHash: expectedHash}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Join("testdata", dir), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join("testdata", dir, name+".json"), append(data, '\n'), 0o644)
+}
@@ -0,0 +1,26 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+
+	"github.com/inblockio/aqua-verifier-go/api"
+)
+
+// AttachmentFetcher returns the raw bytes of the file of content, for
+// revisions of file pages whose file is stored as an attachment instead of
+// inline in content.File.Data. The file hash in the file_hash content slot is
+// checked against the bytes.
+type AttachmentFetcher func(ctx context.Context, content *api.RevisionContent) ([]byte, error)
+
+// FSAttachmentFetcher returns an AttachmentFetcher reading the attachments
+// from fsys, by the file name of the revision's file
+func FSAttachmentFetcher(fsys fs.FS) AttachmentFetcher {
+	return func(ctx context.Context, content *api.RevisionContent) ([]byte, error) {
+		if content.File == nil || content.File.Filename == "" {
+			return nil, errors.New("Revision has no file name")
+		}
+		return fs.ReadFile(fsys, content.File.Filename)
+	}
+}
@@ -0,0 +1,65 @@
+package verify
+
+import (
+	"encoding/base64"
+	"os"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/inblockio/aqua-verifier-go/api"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileAttachment(t *testing.T) {
+	require := require.New(t)
+	png, err := os.ReadFile("test_fixtures/attachment.png")
+	require.NoError(err)
+
+	content := &api.RevisionContent{
+		Content: map[string]string{"main": "[[File:Attachment.png]]", "file_hash": getHashSum(string(png))},
+		File:    &api.FileContent{Filename: "attachment.png", Size: len(png)},
+	}
+	content.ContentHash = calculateContentHash(nil, content)
+	metadata := &api.RevisionMetadata{DomainId: "5e5a1ec586", Timestamp: api.Timestamp{Time: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)}}
+	_, metadata.MetadataHash = ExplainMetadataHash(metadata)
+	r := &api.Revision{Context: &api.VerificationContext{}, Content: content, Metadata: metadata}
+	metadata.VerificationHash, err = ComputeVerificationHash(r, nil)
+	require.NoError(err)
+
+	// the attachment can't be checked without a fetcher
+	isCorrect, result := verifyRevision(r, nil, GlobalDoVerifyMerkleProof, WithOnChainChecks(false))
+	require.False(isCorrect)
+	require.Equal("INVALID", result.Status.File)
+	require.EqualError(result.Error, "Revision contains a file attachment, but no attachment fetcher to check it")
+
+	isCorrect, result = verifyRevision(r, nil, GlobalDoVerifyMerkleProof, WithOnChainChecks(false),
+		WithAttachmentFetcher(FSAttachmentFetcher(os.DirFS("test_fixtures"))))
+	require.True(isCorrect)
+	require.NoError(result.Err())
+	require.Equal("VERIFIED", result.Status.File)
+	require.Equal(content.Content["file_hash"], result.FileHash)
+
+	// a single flipped byte of the attachment
+	tampered := append([]byte{}, png...)
+	tampered[len(tampered)/2] ^= 1
+	isCorrect, result = verifyRevision(r, nil, GlobalDoVerifyMerkleProof, WithOnChainChecks(false),
+		WithAttachmentFetcher(FSAttachmentFetcher(fstest.MapFS{"attachment.png": {Data: tampered}})))
+	require.False(isCorrect)
+	require.Equal("INVALID", result.Status.File)
+	require.EqualError(result.Error, "File content hash does not match")
+
+	_, result = verifyRevision(r, nil, GlobalDoVerifyMerkleProof, WithOnChainChecks(false),
+		WithAttachmentFetcher(FSAttachmentFetcher(fstest.MapFS{})))
+	require.Equal("INVALID", result.Status.File)
+	require.Contains(result.Error.Error(), "Failure fetching file attachment")
+
+	// inline files are checked without fetching them
+	content.File.Data = base64.StdEncoding.EncodeToString(png)
+	isCorrect, result = verifyRevision(r, nil, GlobalDoVerifyMerkleProof, WithOnChainChecks(false))
+	require.True(isCorrect)
+	require.Equal("VERIFIED", result.Status.File)
+	content.File.Data = base64.StdEncoding.EncodeToString(tampered)
+	_, result = verifyRevision(r, nil, GlobalDoVerifyMerkleProof, WithOnChainChecks(false))
+	require.Equal("INVALID", result.Status.File)
+}
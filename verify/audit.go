@@ -0,0 +1,65 @@
+package verify
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+)
+
+// auditRecord is the JSON artifact written by Save. Unlike MarshalJSON,
+// which produces the output of the JavaScript aqua-verifier, it holds every
+// field of the result.
+type auditRecord struct {
+	*chainResult
+	RequireAnchor bool `json:"require_anchor,omitempty"`
+}
+
+type chainResult ChainVerificationResult
+
+// Save writes the result, including when it was verified and the server it
+// was fetched from, as a JSON artifact to path, e.g. to keep a record of
+// verification runs for audits. LoadChainVerificationResult reads it back.
+func (c *ChainVerificationResult) Save(path string) error {
+	b, err := json.MarshalIndent(&auditRecord{(*chainResult)(c), c.requireAnchor}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0644)
+}
+
+// LoadChainVerificationResult reads a result written by Save. The errors of
+// the revision results are restored with their messages only, so they don't
+// wrap the errors of this package anymore.
+func LoadChainVerificationResult(path string) (*ChainVerificationResult, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &ChainVerificationResult{}
+	record := &auditRecord{chainResult: (*chainResult)(c)}
+	if err := json.Unmarshal(b, record); err != nil {
+		return nil, err
+	}
+	c.requireAnchor = record.RequireAnchor
+	return c, nil
+}
+
+// UnmarshalJSON deserializes a result serialized by MarshalJSON, restoring
+// Error from its message
+func (r *RevisionVerificationResult) UnmarshalJSON(data []byte) error {
+	type result RevisionVerificationResult
+	var msg struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(data, (*result)(r)); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return err
+	}
+	r.Error = nil
+	if msg.Error != "" {
+		r.Error = errors.New(msg.Error)
+	}
+	return nil
+}
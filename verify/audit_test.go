@@ -0,0 +1,75 @@
+package verify
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/inblockio/aqua-verifier-go/api"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSaveLoadChainVerificationResult(t *testing.T) {
+	require := require.New(t)
+	data, err := jsonDecodeFixture(fixture)
+	require.NoError(err)
+	s := newFixtureServer(data)
+	defer s.Close()
+	ap, err := api.NewAPI(s.URL, "")
+	require.NoError(err)
+	page := data.Pages[0]
+	page.Revisions[page.LatestVerificationHash].Content.Content["main"] = "tampered"
+
+	start := time.Now()
+	result, err := VerifyChain(context.Background(), ap, page.Title, true, -1, WithOnChainChecks(false))
+	require.NoError(err)
+	require.False(result.Valid())
+	require.False(result.VerifiedAt.Before(start.Add(-time.Second)))
+	require.Equal(s.URL, result.Endpoint)
+	require.Equal(strings.TrimPrefix(s.URL, "http://"), result.Server)
+
+	path := filepath.Join(t.TempDir(), "result.json")
+	require.NoError(result.Save(path))
+	loaded, err := LoadChainVerificationResult(path)
+	require.NoError(err)
+	require.True(result.VerifiedAt.Equal(loaded.VerifiedAt))
+	require.Equal(result.Endpoint, loaded.Endpoint)
+	require.Equal(result.Server, loaded.Server)
+	require.Equal(result.GenesisHash, loaded.GenesisHash)
+	require.Equal(result.SignatureSchemes, loaded.SignatureSchemes)
+	require.False(loaded.Valid())
+	require.Len(loaded.Revisions, len(result.Revisions))
+	for i, r := range loaded.Revisions {
+		expected := result.Revisions[i]
+		require.Equal(expected.VerificationHash, r.VerificationHash)
+		require.Equal(expected.Status, r.Status)
+		require.Equal(expected.WitnessResult, r.WitnessResult)
+		require.Equal(expected.Signer, r.Signer)
+		require.Equal(expected.Elapsed, r.Elapsed)
+		require.True(expected.Timestamp.Equal(r.Timestamp))
+		require.Equal(expected.Valid(), r.Valid())
+	}
+	last := loaded.Revisions[len(loaded.Revisions)-1]
+	require.EqualError(last.Error, "Content hash doesn't match")
+	require.NotNil(loaded.Revisions[0].WitnessResult)
+
+	// a chain that is only valid with a trust anchor stays invalid
+	data, err = jsonDecodeFixture(fixture)
+	require.NoError(err)
+	result, err = VerifyHashChain(data.Pages[0], GlobalDoVerifyMerkleProof, -1, WithOnChainChecks(false), WithIndependentRoots([]string{"unknown"}))
+	require.NoError(err)
+	require.False(result.Valid())
+	require.NoError(result.Save(path))
+	loaded, err = LoadChainVerificationResult(path)
+	require.NoError(err)
+	require.False(loaded.Valid())
+	require.Empty(loaded.Endpoint)
+	for _, r := range loaded.Revisions {
+		require.True(r.Valid())
+	}
+
+	_, err = LoadChainVerificationResult(filepath.Join(t.TempDir(), "missing.json"))
+	require.Error(err)
+}
@@ -0,0 +1,46 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/inblockio/aqua-verifier-go/api"
+)
+
+// VerifyTitles verifies the whole hash chains of the pages with the given
+// titles like VerifyChain, verifying up to concurrency pages at once. A page
+// that fails to be fetched doesn't abort the others: the results of the
+// pages that could be verified are returned together with an error joining
+// the failures of the others, each prefixed with its title.
+func VerifyTitles(ctx context.Context, ap api.AquaClient, titles []string, concurrency int, opts ...Option) (map[string]*ChainVerificationResult, error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	results := make(map[string]*ChainVerificationResult, len(titles))
+	var errs []error
+	var mu sync.Mutex
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for _, title := range titles {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			result, err := VerifyChain(ctx, ap, title, true, -1, opts...)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", title, err))
+				return
+			}
+			results[title] = result
+		}()
+	}
+	wg.Wait()
+	return results, errors.Join(errs...)
+}
@@ -0,0 +1,54 @@
+package verify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/inblockio/aqua-verifier-go/api"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyTitles(t *testing.T) {
+	require := require.New(t)
+	data, err := jsonDecodeFixture(fixture)
+	require.NoError(err)
+	fixtureServer := newFixtureServer(data)
+	defer fixtureServer.Close()
+	var inFlight, maxInFlight atomic.Int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for m := maxInFlight.Load(); n > m && !maxInFlight.CompareAndSwap(m, n); m = maxInFlight.Load() {
+		}
+		time.Sleep(5 * time.Millisecond)
+		fixtureServer.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer s.Close()
+	ap, err := api.NewAPI(s.URL, "")
+	require.NoError(err)
+	page := data.Pages[0]
+
+	titles := []string{page.Title}
+	for i := 0; i < 9; i++ {
+		titles = append(titles, fmt.Sprintf("Missing %d", i))
+	}
+	const concurrency = 3
+	results, err := VerifyTitles(context.Background(), ap, titles, concurrency, WithOnChainChecks(false))
+	// the missing pages don't abort the batch
+	require.Error(err)
+	require.Contains(err.Error(), "Missing 0: ")
+	require.Contains(err.Error(), "Missing 8: ")
+	require.Len(results, 1)
+	require.True(results[page.Title].Valid())
+	require.Len(results[page.Title].Revisions, page.ChainHeight)
+	require.LessOrEqual(maxInFlight.Load(), int32(concurrency))
+
+	results, err = VerifyTitles(context.Background(), ap, []string{page.Title}, 0, WithOnChainChecks(false))
+	require.NoError(err)
+	require.Len(results, 1)
+}
@@ -0,0 +1,168 @@
+package verify
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/inblockio/aqua-verifier-go/api"
+)
+
+// ResultCache remembers revisions that verified successfully, so that
+// verifying them again, e.g. the shared ancestors of overlapping chains,
+// doesn't recompute their hashes. It is safe for concurrent use, and a nil
+// *ResultCache is a disabled cache.
+//
+// A cached result is only reused for a revision identical to the one it was
+// computed for, verified against the same previous revision and with the same
+// options. Options holding callbacks or lookups, such as witness resolvers,
+// signature verifiers or a content normalizer, can't be compared, so results
+// verified with them are neither cached nor looked up. As a
+// cache used with WithIndependentRoots must not vouch for revisions confirmed
+// under other roots, it is emptied when the roots change.
+type ResultCache struct {
+	mu      sync.Mutex
+	entries map[resultKey]*resultEntry
+	roots   string
+}
+
+// resultKey identifies the verification of a revision
+type resultKey struct {
+	verificationHash    string
+	prevHash            string
+	doVerifyMerkleProof bool
+	settings            resultSettings
+}
+
+// resultSettings are the options that affect the result of verifying a
+// revision and can be compared. Options that can't be compared disable the
+// cache, see uncomparable.
+type resultSettings struct {
+	onChain              bool
+	hashEncoding         HashEncoding
+	strict               bool
+	witnessTimeTolerance time.Duration
+	hashAlgorithm        string
+	ethSign              bool
+	mode                 VerifyMode
+	requireWitness       bool
+	requireSignature     bool
+}
+
+// resultEntry holds a copy of a valid revision and its verification result
+type resultEntry struct {
+	revision *api.Revision
+	result   *RevisionVerificationResult
+}
+
+// NewResultCache returns an empty ResultCache
+func NewResultCache() *ResultCache {
+	return &ResultCache{entries: make(map[resultKey]*resultEntry)}
+}
+
+// Len returns the number of cached results
+func (c *ResultCache) Len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// get returns a copy of the cached result of verifying r, or nil
+func (c *ResultCache) get(r, prev *api.Revision, doVerifyMerkleProof bool, o *options) *RevisionVerificationResult {
+	if c == nil || r.Metadata == nil || o.uncomparable() {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.useRoots(o.independentRoots)
+	e, ok := c.entries[newResultKey(r, prev, doVerifyMerkleProof, o)]
+	if !ok || !reflect.DeepEqual(e.revision, r) {
+		return nil
+	}
+	return copyResult(e.result)
+}
+
+// put caches result of verifying r if it is valid
+func (c *ResultCache) put(r, prev *api.Revision, doVerifyMerkleProof bool, o *options, result *RevisionVerificationResult) {
+	if c == nil || r.Metadata == nil || !result.Valid() || o.uncomparable() {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.useRoots(o.independentRoots)
+	c.entries[newResultKey(r, prev, doVerifyMerkleProof, o)] = &resultEntry{
+		revision: r.Copy(),
+		result:   copyResult(result),
+	}
+}
+
+// useRoots empties the cache if roots differ from the roots of the cached
+// results. c.mu must be held.
+func (c *ResultCache) useRoots(roots map[string]bool) {
+	var keys []string
+	for root := range roots {
+		keys = append(keys, root)
+	}
+	slices.Sort(keys)
+	fingerprint := strings.Join(keys, ",")
+	if roots != nil {
+		fingerprint = "roots:" + fingerprint
+	}
+	if fingerprint != c.roots {
+		c.entries = make(map[resultKey]*resultEntry)
+		c.roots = fingerprint
+	}
+}
+
+func newResultKey(r, prev *api.Revision, doVerifyMerkleProof bool, o *options) resultKey {
+	k := resultKey{
+		verificationHash:    r.Metadata.VerificationHash,
+		doVerifyMerkleProof: doVerifyMerkleProof,
+		settings: resultSettings{
+			onChain:              o.onChainChecks(),
+			hashEncoding:         o.hashEncoding,
+			strict:               o.strict,
+			witnessTimeTolerance: o.witnessTimeTolerance,
+			hashAlgorithm:        o.hashAlgorithm,
+			ethSign:              o.ethSign,
+			mode:                 o.mode,
+			requireWitness:       o.requireWitness,
+			requireSignature:     o.requireSignature,
+		},
+	}
+	if prev != nil && prev.Metadata != nil {
+		k.prevHash = prev.Metadata.VerificationHash
+	}
+	return k
+}
+
+// uncomparable reports whether o has options affecting the result of a
+// verification that can't be compared, so that its results can't be shared
+// with verifications that might verify differently
+func (o *options) uncomparable() bool {
+	if o.contractSignatureChecker != nil || o.contentReconstructor != nil || o.contentNormalizer != nil ||
+		len(o.witnessResolvers) > 0 || len(o.witnessEvidence) > 0 || len(o.signatureVerifiers) > 0 ||
+		o.attachmentFetcher != nil || reflect.ValueOf(o.clock).Pointer() != reflect.ValueOf(time.Now).Pointer() {
+		return true
+	}
+	return false
+}
+
+// copyResult returns a copy of result that doesn't share its status
+func copyResult(result *RevisionVerificationResult) *RevisionVerificationResult {
+	c := *result
+	if result.Status != nil {
+		status := *result.Status
+		c.Status = &status
+	}
+	if result.WitnessResult != nil {
+		witnessResult := *result.WitnessResult
+		c.WitnessResult = &witnessResult
+	}
+	return &c
+}
@@ -0,0 +1,111 @@
+package verify
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/inblockio/aqua-verifier-go/api"
+	"github.com/stretchr/testify/require"
+)
+
+// countHashing makes sum512 count its invocations until the test ends
+func countHashing(t *testing.T) *int {
+	var n int
+	orig := sum512
+	sum512 = func(b []byte) [64]byte {
+		n++
+		return orig(b)
+	}
+	t.Cleanup(func() { sum512 = orig })
+	return &n
+}
+
+func TestWithResultCache(t *testing.T) {
+	require := require.New(t)
+	data, err := jsonDecodeFixture(fixture)
+	require.NoError(err)
+	page := data.Pages[0]
+	hashed := countHashing(t)
+	cache := NewResultCache()
+	opts := []Option{WithOnChainChecks(false), WithResultCache(cache)}
+
+	first, err := VerifyHashChain(page, GlobalDoVerifyMerkleProof, -1, opts...)
+	require.NoError(err)
+	require.True(first.Valid())
+	require.NotZero(*hashed)
+	require.Equal(page.ChainHeight, cache.Len())
+
+	// the cached revisions are not hashed again
+	*hashed = 0
+	second, err := VerifyHashChain(page, GlobalDoVerifyMerkleProof, -1, opts...)
+	require.NoError(err)
+	require.True(second.Valid())
+	require.Zero(*hashed)
+	for i, r := range second.Revisions {
+		require.Equal(first.Revisions[i].VerificationHash, r.VerificationHash)
+		require.Equal(first.Revisions[i].Status, r.Status)
+	}
+
+	// other checks don't reuse the results
+	_, err = VerifyHashChain(page, GlobalDoVerifyMerkleProof, -1, WithResultCache(cache))
+	require.NoError(err)
+	require.NotZero(*hashed)
+
+	// nor do verifications with options that can't be compared, even if
+	// they are verified the same way twice, and their results are not cached
+	cached := cache.Len()
+	for _, opt := range []Option{
+		WithContentNormalizer(strings.TrimSpace),
+		WithWitnessEvidence(map[string]api.WitnessEvidence{"0x01": {Network: "goerli"}}),
+		WithWitnessResolver("goerli", fakeResolver{}),
+		WithClock(func() time.Time { return time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC) }),
+	} {
+		for i := 0; i < 2; i++ {
+			*hashed = 0
+			_, err = VerifyHashChain(page, GlobalDoVerifyMerkleProof, -1, append(opts, opt)...)
+			require.NoError(err)
+			require.NotZero(*hashed)
+			require.Equal(cached, cache.Len())
+		}
+	}
+	*hashed = 0
+	_, err = VerifyHashChain(page, GlobalDoVerifyMerkleProof, -1, append(opts, WithHashEncoding(HashEncodingAuto))...)
+	require.NoError(err)
+	require.NotZero(*hashed)
+
+	// a tampered revision claiming a cached verification hash is verified
+	latest := page.Revisions[page.LatestVerificationHash]
+	main := latest.Content.Content["main"]
+	latest.Content.Content["main"] = "tampered"
+	*hashed = 0
+	result, err := VerifyHashChain(page, GlobalDoVerifyMerkleProof, -1, opts...)
+	require.NoError(err)
+	require.False(result.Valid())
+	require.NotZero(*hashed)
+	latest.Content.Content["main"] = main
+
+	// changing the independent roots invalidates the cached results. The
+	// trust anchor is looked up with the revision hashes either way.
+	genesis := page.Revisions[page.GenesisHash]
+	roots := WithIndependentRoots([]string{genesis.Witness.MerkleRoot})
+	*hashed = 0
+	_, err = VerifyHashChain(page, GlobalDoVerifyMerkleProof, -1, append(opts, roots)...)
+	require.NoError(err)
+	uncached := *hashed
+	*hashed = 0
+	result, err = VerifyHashChain(page, GlobalDoVerifyMerkleProof, -1, append(opts, roots)...)
+	require.NoError(err)
+	require.True(result.Valid())
+	require.Less(*hashed, uncached)
+	other := WithIndependentRoots([]string{page.GenesisHash})
+	*hashed = 0
+	_, err = VerifyHashChain(page, GlobalDoVerifyMerkleProof, -1, WithOnChainChecks(false), WithResultCache(NewResultCache()), other)
+	require.NoError(err)
+	uncached = *hashed
+	*hashed = 0
+	result, err = VerifyHashChain(page, GlobalDoVerifyMerkleProof, -1, append(opts, other)...)
+	require.NoError(err)
+	require.True(result.Valid())
+	require.Equal(uncached, *hashed)
+}
@@ -0,0 +1,217 @@
+package verify
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/inblockio/aqua-verifier-go/api"
+)
+
+// certificateSignaturePrefix starts the PDF comment that holds the signature
+// of a certificate. It follows the %%EOF marker so that PDF readers ignore it.
+const certificateSignaturePrefix = "%AquaCertificateSignature: "
+
+// Certificate summarizes the verification of a hash chain
+type Certificate struct {
+	Title       string
+	ChainHead   string
+	GenesisHash string
+	Revisions   int
+	Valid       bool
+	// Signers are the wallet addresses of the valid revision signatures
+	Signers   []string
+	Witnesses []CertificateWitness
+	// VerifiedAt is the time of the verification
+	VerifiedAt time.Time
+}
+
+// CertificateWitness is a witness transaction of a verified chain
+type CertificateWitness struct {
+	Network         string
+	TransactionHash string
+	// URL links to the transaction on a block explorer, if the network is known
+	URL string
+}
+
+// NewCertificate returns the certificate of the verification of data
+func NewCertificate(data *api.HashChain, result *ChainVerificationResult, verifiedAt time.Time) *Certificate {
+	c := &Certificate{
+		Title:       data.Title,
+		ChainHead:   data.LatestVerificationHash,
+		GenesisHash: data.GenesisHash,
+		Revisions:   len(result.Revisions),
+		Valid:       result.Valid(),
+		VerifiedAt:  verifiedAt.UTC(),
+	}
+	signers := map[string]bool{}
+	witnesses := map[string]bool{}
+	for _, rr := range result.Revisions {
+		r := data.Revisions[rr.VerificationHash]
+		if r == nil {
+			continue
+		}
+		if rr.Status.Signature == "VALID" && !signers[r.Signature.WalletAddress] {
+			signers[r.Signature.WalletAddress] = true
+			c.Signers = append(c.Signers, r.Signature.WalletAddress)
+		}
+		if rr.Status.Witness == "VALID" && !witnesses[r.Witness.WitnessEventTransactionHash] {
+			witnesses[r.Witness.WitnessEventTransactionHash] = true
+			w := CertificateWitness{Network: r.Witness.WitnessNetwork, TransactionHash: r.Witness.WitnessEventTransactionHash}
+			w.URL = explorerURL(w.Network, w.TransactionHash)
+			c.Witnesses = append(c.Witnesses, w)
+		}
+	}
+	return c
+}
+
+// lines returns the text of the certificate
+func (c *Certificate) lines(signer string) []string {
+	result := "INVALID"
+	if c.Valid {
+		result = "VALID"
+	}
+	lines := []string{
+		"Aqua Verification Certificate",
+		"",
+		"Title: " + c.Title,
+		"Result: " + result,
+		fmt.Sprintf("Revisions verified: %d", c.Revisions),
+		"Verified at: " + c.VerifiedAt.Format(time.RFC3339),
+		"Chain head:",
+		"  " + c.ChainHead,
+		"Genesis hash:",
+		"  " + c.GenesisHash,
+		"",
+		"Signers:",
+	}
+	for _, s := range c.Signers {
+		lines = append(lines, "  "+s)
+	}
+	lines = append(lines, "", "Witness transactions:")
+	for _, w := range c.Witnesses {
+		lines = append(lines, "  "+w.Network+" "+w.TransactionHash)
+		if w.URL != "" {
+			lines = append(lines, "  "+w.URL)
+		}
+	}
+	return append(lines, "", "Certified by: "+signer)
+}
+
+// WriteCertificatePDF writes c as a PDF document to w, signed by key. The
+// signature covers every byte of the document preceding it and can be checked
+// with VerifyCertificatePDF.
+func WriteCertificatePDF(w io.Writer, c *Certificate, key *ecdsa.PrivateKey) error {
+	signer := strings.ToLower(crypto.PubkeyToAddress(key.PublicKey).Hex())
+	doc := renderPDF(c.lines(signer))
+	signature, err := crypto.Sign(crypto.Keccak256(doc), key)
+	if err != nil {
+		return err
+	}
+	doc = append(doc, certificateSignaturePrefix+hexutil.Encode(signature)+"\n"...)
+	_, err = w.Write(doc)
+	return err
+}
+
+// VerifyCertificatePDF checks that a certificate written by WriteCertificatePDF
+// is signed by the wallet address signer, compared case-insensitively. A
+// certificate that is tampered with or signed by another key fails with an
+// error wrapping ErrSignatureInvalid, an unsigned one with an error wrapping
+// ErrSignatureMissing.
+func VerifyCertificatePDF(pdf []byte, signer string) error {
+	i := bytes.LastIndex(pdf, []byte(certificateSignaturePrefix))
+	if i < 0 {
+		return newVerificationError(ErrSignatureMissing, "Certificate is not signed")
+	}
+	signature, err := hexutil.Decode(strings.TrimSpace(string(pdf[i+len(certificateSignaturePrefix):])))
+	if err != nil {
+		return newVerificationError(ErrSignatureInvalid, "Malformed certificate signature: %s", err)
+	}
+	pub, err := crypto.SigToPub(crypto.Keccak256(pdf[:i]), signature)
+	if err != nil {
+		return newVerificationError(ErrSignatureInvalid, "Certificate signature is invalid")
+	}
+	if recovered := crypto.PubkeyToAddress(*pub).Hex(); !strings.EqualFold(recovered, signer) {
+		return newVerificationError(ErrSignatureInvalid, "Certificate is not signed by %s", signer)
+	}
+	return nil
+}
+
+const (
+	pdfPageHeight   = 842
+	pdfMargin       = 40
+	pdfLeading      = 10
+	pdfLinesPerPage = (pdfPageHeight - 2*pdfMargin) / pdfLeading
+)
+
+// renderPDF returns a minimal A4 PDF document showing lines in a monospaced
+// font, as a chain head alone is 128 characters long
+func renderPDF(lines []string) []byte {
+	var pages [][]string
+	for len(lines) > pdfLinesPerPage {
+		pages = append(pages, lines[:pdfLinesPerPage])
+		lines = lines[pdfLinesPerPage:]
+	}
+	pages = append(pages, lines)
+
+	// objects 1 and 2 are the catalog and the page tree, 3 is the font, and
+	// every page is followed by its content stream
+	var objects []string
+	var kids []string
+	for i, page := range pages {
+		var content strings.Builder
+		fmt.Fprintf(&content, "BT /F1 7 Tf %d TL %d %d Td\n", pdfLeading, pdfMargin, pdfPageHeight-pdfMargin)
+		for _, l := range page {
+			fmt.Fprintf(&content, "(%s) Tj T*\n", pdfEscape(l))
+		}
+		content.WriteString("ET")
+		n := 4 + 2*i
+		kids = append(kids, fmt.Sprintf("%d 0 R", n))
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 %d] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", pdfPageHeight, n+1),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.String()))
+	}
+	objects = append([]string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier >>",
+	}, objects...)
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, o := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, o)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return b.Bytes()
+}
+
+// pdfEscape escapes s for use in a PDF string literal. Characters outside of
+// printable ASCII are replaced, as the standard fonts can't show them.
+func pdfEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < ' ' || r > '~':
+			b.WriteByte('?')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,96 @@
+package verify
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/inblockio/aqua-verifier-go/api"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCertificatePDF(t *testing.T) {
+	require := require.New(t)
+	data, err := jsonDecodeFixture(fixture)
+	require.NoError(err)
+	page := data.Pages[0]
+	verifiedAt := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	// witnesses whose transaction wasn't looked up are not certified
+	result, err := VerifyHashChain(page, GlobalDoVerifyMerkleProof, -1, WithOnChainChecks(false))
+	require.NoError(err)
+	c := NewCertificate(page, result, verifiedAt)
+	require.True(c.Valid)
+	require.Empty(c.Witnesses)
+
+	w := page.Revisions[page.GenesisHash].Witness
+	evidence := map[string]api.WitnessEvidence{
+		w.WitnessEventTransactionHash: {Network: w.WitnessNetwork, MerkleRoot: w.WitnessEventVerificationHash},
+	}
+	result, err = VerifyHashChain(page, GlobalDoVerifyMerkleProof, -1, WithWitnessEvidence(evidence))
+	require.NoError(err)
+	c = NewCertificate(page, result, verifiedAt)
+	require.True(c.Valid)
+	require.Equal(page.LatestVerificationHash, c.ChainHead)
+	require.Equal(page.ChainHeight, c.Revisions)
+	require.Equal([]string{"0x1ad5da43de60aa7d311f9b4e9c3342c155e6d2e0"}, c.Signers)
+	require.Len(c.Witnesses, 1)
+	require.Equal("goerli", c.Witnesses[0].Network)
+	require.Equal("https://goerli.etherscan.io/tx/"+c.Witnesses[0].TransactionHash, c.Witnesses[0].URL)
+
+	key, err := crypto.GenerateKey()
+	require.NoError(err)
+	var b bytes.Buffer
+	require.NoError(WriteCertificatePDF(&b, c, key))
+	pdf := b.Bytes()
+	require.True(bytes.HasPrefix(pdf, []byte("%PDF-1.4\n")))
+	require.Contains(string(pdf), "(  "+page.LatestVerificationHash+") Tj")
+	require.Contains(string(pdf), "(Verified at: 2022-03-01T12:00:00Z) Tj")
+
+	signer := crypto.PubkeyToAddress(key.PublicKey).Hex()
+	require.NoError(VerifyCertificatePDF(pdf, signer))
+	require.NoError(VerifyCertificatePDF(pdf, strings.ToLower(signer)))
+	require.Contains(string(pdf), "(Certified by: "+strings.ToLower(signer)+") Tj")
+
+	// the signature covers the contents
+	tampered := bytes.Replace(pdf, []byte("Result: VALID"), []byte("Result: FALSE"), 1)
+	err = VerifyCertificatePDF(tampered, signer)
+	require.True(errors.Is(err, ErrSignatureInvalid))
+
+	// a certificate re-signed by a forger
+	forger, err := crypto.GenerateKey()
+	require.NoError(err)
+	b.Reset()
+	require.NoError(WriteCertificatePDF(&b, c, forger))
+	err = VerifyCertificatePDF(b.Bytes(), signer)
+	require.True(errors.Is(err, ErrSignatureInvalid))
+	require.EqualError(err, "Certificate is not signed by "+signer)
+
+	err = VerifyCertificatePDF(pdf[:bytes.LastIndex(pdf, []byte(certificateSignaturePrefix))], signer)
+	require.True(errors.Is(err, ErrSignatureMissing))
+	require.EqualError(err, "Certificate is not signed")
+}
+
+func TestRenderPDF(t *testing.T) {
+	require := require.New(t)
+	lines := make([]string, 2*pdfLinesPerPage+1)
+	lines[0] = `a (parenthesized) \ line`
+	pdf := string(renderPDF(lines))
+	require.Contains(pdf, "/Count 3 >>")
+	require.Contains(pdf, `(a \(parenthesized\) \\ line) Tj`)
+	require.True(strings.HasSuffix(pdf, "%%EOF\n"))
+
+	// the cross-reference table points at the objects
+	xref := pdf[strings.Index(pdf, "xref\n"):]
+	entries := strings.Split(xref, "\n")[3:]
+	for i := 0; i < 3+2*3; i++ {
+		var offset int
+		_, err := fmt.Sscanf(entries[i], "%010d 00000 n", &offset)
+		require.NoError(err)
+		require.True(strings.HasPrefix(pdf[offset:], fmt.Sprintf("%d 0 obj\n", i+1)))
+	}
+}
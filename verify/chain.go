@@ -0,0 +1,527 @@
+package verify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/inblockio/aqua-verifier-go/api"
+)
+
+// ChainVerificationResult holds the verification result of every revision of
+// a hash chain, ordered from oldest to newest.
+type ChainVerificationResult struct {
+	GenesisHash string                        `json:"genesis_hash"`
+	Height      int                           `json:"height"`
+	Revisions   []*RevisionVerificationResult `json:"revisions"`
+	// SignatureSchemes counts the valid signatures of the chain by scheme
+	SignatureSchemes map[string]int `json:"signature_schemes,omitempty"`
+	// Disagreements lists the mirrors that disagreed with the majority, see
+	// VerifyChainQuorum
+	Disagreements []MirrorDisagreement `json:"disagreements,omitempty"`
+	// TrustAnchor is the root the chain is anchored in and AnchoredRevisions
+	// the number of oldest revisions it covers, see WithIndependentRoots
+	TrustAnchor       string `json:"trust_anchor,omitempty"`
+	AnchoredRevisions int    `json:"anchored_revisions,omitempty"`
+	// ActualGenesisHash is the verification hash of the genesis revision if
+	// it differs from the GenesisHash advertised for the chain
+	ActualGenesisHash string `json:"actual_genesis_hash,omitempty"`
+	// DomainIdConflicts lists the revisions whose domain id differs from the
+	// domain id of the chain
+	DomainIdConflicts []DomainIdConflict `json:"domain_id_conflicts,omitempty"`
+	// VerifiedAt is when the chain was verified, and Server and Endpoint the
+	// host and api endpoint it was fetched from, empty for offline chains
+	VerifiedAt time.Time `json:"verified_at"`
+	Server     string    `json:"server,omitempty"`
+	Endpoint   string    `json:"endpoint,omitempty"`
+	// ResumedFrom is the checkpoint the verification was resumed from, see
+	// WithCheckpoint; Revisions only holds the revisions newer than it
+	ResumedFrom *Checkpoint `json:"resumed_from,omitempty"`
+	// requireAnchor is set if the chain is only valid with a TrustAnchor
+	requireAnchor bool
+}
+
+// Valid returns true if every revision of the chain verified successfully
+func (c *ChainVerificationResult) Valid() bool {
+	if (len(c.Revisions) == 0 && c.ResumedFrom == nil) || (c.requireAnchor && c.TrustAnchor == "") || len(c.DomainIdConflicts) > 0 || c.ActualGenesisHash != "" {
+		return false
+	}
+	for _, r := range c.Revisions {
+		if !r.Valid() {
+			return false
+		}
+	}
+	return true
+}
+
+// Valid returns true if the revision verified successfully
+func (r *RevisionVerificationResult) Valid() bool {
+	return r.Error == nil &&
+		r.Status.Verification == VERIFIED_VERIFICATION_STATUS &&
+		r.Status.Signature != "INVALID" &&
+		r.Status.Witness != "INVALID"
+}
+
+// MarshalJSON serializes the result with Error as its message, as error values
+// have no JSON representation of their own.
+func (r *RevisionVerificationResult) MarshalJSON() ([]byte, error) {
+	type result RevisionVerificationResult
+	var msg string
+	if r.Error != nil {
+		msg = r.Error.Error()
+	}
+	return json.Marshal(&struct {
+		*result
+		Error string `json:"error,omitempty"`
+	}{(*result)(r), msg})
+}
+
+// VerifyHashChain verifies the revisions of an offline hash chain up to depth
+// revisions deep (-1 for all) and returns the result of every revision.
+// Unlike VerifyData it does not stop at the first invalid revision.
+func VerifyHashChain(data *api.HashChain, doVerifyMerkleProof bool, depth int, opts ...Option) (*ChainVerificationResult, error) {
+	return verifyHashChain(context.Background(), nil, data, doVerifyMerkleProof, depth, opts)
+}
+
+// VerifyChain fetches the revisions of the page with the given title, up to
+// depth revisions deep (-1 for all), and verifies them like VerifyHashChain.
+// The revisions may come from any backend implementing api.AquaClient, e.g.
+// an *api.AquaProtocol. If ap was created with api.WithTracer, the chain and
+// every revision verification are traced as spans. Chains higher than
+// DefaultMaxChainHeight fail with ErrChainTooHigh, see WithMaxChainHeight.
+// With WithCheckpoint only the revisions newer than the checkpoint are
+// fetched and verified, regardless of depth.
+func VerifyChain(ctx context.Context, ap api.AquaClient, title string, doVerifyMerkleProof bool, depth int, opts ...Option) (*ChainVerificationResult, error) {
+	ctx, span := api.StartSpan(ctx, tracerOf(ap), "aqua.verify_chain", api.Attr("aqua.title", title))
+	defer span.End()
+
+	o := newOptions(opts)
+	if err := checkAllowedAPIVersion(ctx, ap, o); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	opts, err := withServerHashAlgorithm(ctx, ap, opts)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	if o.checkpoint != nil {
+		c, err := verifyChainFromCheckpoint(ctx, ap, title, doVerifyMerkleProof, o.checkpoint, opts)
+		if err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+		span.SetAttributes(api.Attr("aqua.valid", c.Valid()))
+		return c, nil
+	}
+	max := o.maxChainHeight
+	bounded := max > 0 && (depth < 0 || depth > max)
+	fetchDepth := depth
+	if bounded {
+		// one revision more than allowed tells whether there are more
+		fetchDepth = max + 1
+	}
+	data, err := api.FetchHashChain(ctx, ap, "title", title, fetchDepth)
+	if err == nil && bounded && (data.ChainHeight > max || len(data.Revisions) > max) {
+		err = newVerificationError(ErrChainTooHigh, "Chain of %s has more than %d revisions", title, max)
+	}
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	c, err := verifyHashChain(ctx, tracerOf(ap), data, doVerifyMerkleProof, depth, opts)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	c.setSourceOf(ap)
+	span.SetAttributes(api.Attr("aqua.valid", c.Valid()))
+	return c, nil
+}
+
+// tracerOf returns the Tracer of ap if it has one, see api.WithTracer
+func tracerOf(ap api.AquaClient) api.Tracer {
+	if t, ok := ap.(interface{ Tracer() api.Tracer }); ok {
+		return t.Tracer()
+	}
+	return nil
+}
+
+// serverInfo returns the server info of ap, fetching it only once if ap keeps
+// it, see api.AquaProtocol.CachedServerInfo
+func serverInfo(ctx context.Context, ap api.AquaClient) (*api.ServerInfo, error) {
+	if c, ok := ap.(interface {
+		CachedServerInfo(context.Context) (*api.ServerInfo, error)
+	}); ok {
+		return c.CachedServerInfo(ctx)
+	}
+	return ap.GetServerInfoContext(ctx)
+}
+
+// checkAllowedAPIVersion checks that the api version of the server of ap is
+// allowed by WithAllowedAPIVersions, if given
+func checkAllowedAPIVersion(ctx context.Context, ap api.AquaClient, o *options) error {
+	if o.allowedAPIVersions == nil {
+		return nil
+	}
+	s, err := serverInfo(ctx, ap)
+	if err != nil {
+		return err
+	}
+	if !o.allowedAPIVersions[s.ApiVersion] {
+		return newVerificationError(ErrUnsupportedAPIVersion, "Server api version %s is not allowed", s.ApiVersion)
+	}
+	return nil
+}
+
+func verifyHashChain(ctx context.Context, t api.Tracer, data *api.HashChain, doVerifyMerkleProof bool, depth int, opts []Option) (*ChainVerificationResult, error) {
+	verificationSet, height, err := getVerificationSet(data, depth)
+	if err != nil {
+		return nil, err
+	}
+	return verifyVerificationSet(ctx, t, data, verificationSet, height, nil, doVerifyMerkleProof, opts)
+}
+
+// verifyVerificationSet verifies the revisions of verificationSet, ordered
+// from oldest to newest, of the chain data. prev is the revision previous to
+// the oldest revision, nil if the oldest revision is to be verified without
+// it.
+func verifyVerificationSet(ctx context.Context, t api.Tracer, data *api.HashChain, verificationSet []*api.Revision, height int, prev *api.Revision, doVerifyMerkleProof bool, opts []Option) (*ChainVerificationResult, error) {
+	var err error
+	opts = append(opts[:len(opts):len(opts)], withContext(ctx))
+	o := newOptions(opts)
+	if reconstruct := o.contentReconstructor; reconstruct != nil {
+		verificationSet, err = reconstructContent(verificationSet, reconstruct)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	c := &ChainVerificationResult{
+		GenesisHash: data.GenesisHash,
+		Height:      height,
+		Revisions:   make([]*RevisionVerificationResult, len(verificationSet)),
+		VerifiedAt:  o.clock().UTC(),
+	}
+	c.DomainIdConflicts = domainIdConflicts(data.DomainId, verificationSet)
+	// the advertised genesis hash can only be checked if the genesis
+	// revision is verified
+	if len(verificationSet) > 0 && verificationSet[0].PreviousVerificationHash() == "" && data.GenesisHash != "" &&
+		api.NormalizeHash(verificationSet[0].VerificationHash()) != api.NormalizeHash(data.GenesisHash) {
+		c.ActualGenesisHash = verificationSet[0].VerificationHash()
+	}
+	signatures := signatureSet{}
+	for i, revision := range verificationSet {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			prev = verificationSet[i-1]
+		}
+		_, span := api.StartSpan(ctx, t, "aqua.verify_revision",
+			api.Attr("aqua.verification_hash", revision.Metadata.VerificationHash))
+		isCorrect, result := verifyRevision(revision, prev, doVerifyMerkleProof, opts...)
+		if err := signatures.add(revision); err != nil {
+			// the result may be cached for the revision on its own
+			result = withReusedSignature(result, err)
+			isCorrect = false
+		}
+		span.SetAttributes(api.Attr("aqua.valid", isCorrect))
+		if result.Error != nil {
+			span.RecordError(result.Error)
+		}
+		span.End()
+		c.Revisions[i] = result
+		if result.SignatureScheme != "" {
+			if c.SignatureSchemes == nil {
+				c.SignatureSchemes = make(map[string]int)
+			}
+			c.SignatureSchemes[result.SignatureScheme]++
+		}
+		if o.progress != nil {
+			o.progress(i+1, len(verificationSet))
+		}
+		if o.strict && !result.Valid() {
+			c.Revisions = c.Revisions[:i+1]
+			break
+		}
+	}
+	if o.independentRoots != nil {
+		c.requireAnchor = true
+		c.TrustAnchor, c.AnchoredRevisions = findTrustAnchor(verificationSet, o.independentRoots, opts)
+	}
+	return c, nil
+}
+
+// setSourceOf records the api endpoint the chain was fetched from, if ap has
+// one
+func (c *ChainVerificationResult) setSourceOf(ap api.AquaClient) {
+	e, ok := ap.(interface{ Endpoint() string })
+	if !ok {
+		return
+	}
+	c.Endpoint = e.Endpoint()
+	if u, err := url.Parse(c.Endpoint); err == nil {
+		c.Server = u.Host
+	}
+}
+
+// findTrustAnchor returns the root of roots that the newest possible revision
+// of verificationSet is anchored in, and the number of revisions it covers.
+// Only hashes derived from the revision data, with the hash algorithm and
+// encoding of opts, are compared to the roots.
+func findTrustAnchor(verificationSet []*api.Revision, roots map[string]bool, opts []Option) (string, int) {
+	enc := newOptions(opts).hashEncoding
+	for i := len(verificationSet) - 1; i >= 0; i-- {
+		r := verificationSet[i]
+		var prev *api.Revision
+		if i > 0 {
+			prev = verificationSet[i-1]
+		}
+		verificationHash, err := ComputeVerificationHash(r, prev, opts...)
+		if err != nil {
+			continue
+		}
+		if w := enc.normalizeWitness(r.Witness); w != nil && verifyMerkleIntegrity(w.MerkleProof, verificationHash, w.MerkleRoot) {
+			if roots[w.MerkleRoot] {
+				return w.MerkleRoot, i + 1
+			}
+			if eventHash := getHashSum(w.DomainSnapshotGenesisHash + w.MerkleRoot); roots[eventHash] {
+				return eventHash, i + 1
+			}
+		}
+		if i == 0 && r.Metadata.PreviousVerificationHash == "" && roots[verificationHash] {
+			return verificationHash, 1
+		}
+	}
+	return "", 0
+}
+
+// reconstructContent returns copies of the revisions of verificationSet with
+// their full content, applying reconstruct from oldest to newest
+func reconstructContent(verificationSet []*api.Revision, reconstruct ContentReconstructor) ([]*api.Revision, error) {
+	if len(verificationSet) > 0 && verificationSet[0].Metadata.PreviousVerificationHash != "" {
+		return nil, errors.New("Reconstructing content requires verifying from the genesis revision")
+	}
+	full := make([]*api.Revision, len(verificationSet))
+	var prev *api.RevisionContent
+	for i, revision := range verificationSet {
+		content, err := reconstruct(prev, revision.Content)
+		if err != nil {
+			return nil, fmt.Errorf("Failure reconstructing content of revision %s: %w", revision.Metadata.VerificationHash, err)
+		}
+		r := *revision
+		r.Content = content
+		full[i] = &r
+		prev = content
+	}
+	return full, nil
+}
+
+// GetVerifiedRevision fetches the revision with the given verification hash,
+// and its previous revision if it has one, and verifies it. On-chain checks
+// are disabled unless re-enabled with WithOnChainChecks(true). The returned
+// error only reports failures to fetch the revision; the verification outcome
+// is reported by the RevisionVerificationResult.
+func GetVerifiedRevision(ctx context.Context, ap api.AquaClient, verification_hash string, opts ...Option) (*api.Revision, *RevisionVerificationResult, error) {
+	r, err := ap.GetRevisionContext(ctx, verification_hash)
+	if err != nil {
+		return nil, nil, err
+	}
+	var prev *api.Revision
+	if r.Metadata != nil && r.Metadata.PreviousVerificationHash != "" {
+		prev, err = ap.GetRevisionContext(ctx, r.Metadata.PreviousVerificationHash)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Failure getting previous revision %s: %w", r.Metadata.PreviousVerificationHash, err)
+		}
+	}
+	opts, err = withServerHashAlgorithm(ctx, ap, append([]Option{WithOnChainChecks(false), withContext(ctx)}, opts...))
+	if err != nil {
+		return nil, nil, err
+	}
+	_, result := verifyRevision(r, prev, true, opts...)
+	return r, result, nil
+}
+
+// VerifyLatest fetches the latest revision of the page with the given title
+// and verifies just that revision, including its link to its previous
+// revision, which is much cheaper than verifying the whole chain. Like
+// GetVerifiedRevision, on-chain checks are disabled unless re-enabled with
+// WithOnChainChecks(true), and the returned error only reports failures to
+// fetch the revisions.
+func VerifyLatest(ctx context.Context, ap api.AquaClient, title string, opts ...Option) (*RevisionVerificationResult, error) {
+	info, err := ap.GetHashChainInfoContext(ctx, "title", title)
+	if err != nil {
+		return nil, err
+	}
+	r, result, err := GetVerifiedRevision(ctx, ap, info.LatestVerificationHash, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if result.Error == nil && r.Metadata.VerificationHash != info.LatestVerificationHash {
+		result.Error = newVerificationError(ErrBrokenChain, "Served revision %s is not the latest revision %s", r.Metadata.VerificationHash, info.LatestVerificationHash)
+	}
+	return result, nil
+}
+
+// checkDuplicateHashes returns an error if hashes, the revision hashes of the
+// page with the given title, list a revision more than once, as a chain that
+// contains a revision twice loops
+func checkDuplicateHashes(title string, hashes []*api.RevisionHash) error {
+	listed := make(map[string]bool, len(hashes))
+	for _, h := range hashes {
+		if listed[string(*h)] {
+			return newVerificationError(ErrBrokenChain, "Revision hashes of %s list revision %s more than once", title, *h)
+		}
+		listed[string(*h)] = true
+	}
+	return nil
+}
+
+// checkChainHeight checks that the chain of the page with the given title,
+// whose revision hashes are hashes, is not higher than allowed by
+// WithMaxChainHeight
+func checkChainHeight(title string, info *api.HashChainInfo, hashes []*api.RevisionHash, opts []Option) error {
+	if max := newOptions(opts).maxChainHeight; max > 0 && (info.ChainHeight > max || len(hashes) > max) {
+		return newVerificationError(ErrChainTooHigh, "Chain of %s has more than %d revisions", title, max)
+	}
+	return nil
+}
+
+// maxConcurrentFetches limits the revisions GetAllRevisions fetches at once
+const maxConcurrentFetches = 8
+
+// GetAllRevisions fetches the whole hash chain of the page with the given
+// title, fetching its revisions concurrently, and verifies it like
+// VerifyHashChain. The revisions are returned ordered from the genesis
+// revision to the latest revision together with the verification result.
+//
+// The returned error reports failures to fetch the chain, and chains higher
+// than allowed by WithMaxChainHeight, which fail with ErrChainTooHigh before
+// any revision is fetched. With WithStrict(true) the verification stops at the first invalid revision, and
+// the revisions up to it are returned with the error of the result.
+func GetAllRevisions(ctx context.Context, ap api.AquaClient, title string, doVerifyMerkleProof bool, opts ...Option) ([]*api.Revision, *ChainVerificationResult, error) {
+	info, err := ap.GetHashChainInfoContext(ctx, "title", title)
+	if err != nil {
+		return nil, nil, err
+	}
+	hashes, err := ap.GetRevisionHashesContext(ctx, info.GenesisHash)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(hashes) == 0 || string(*hashes[len(hashes)-1]) != info.LatestVerificationHash {
+		return nil, nil, newVerificationError(ErrBrokenChain, "Revision hashes of %s don't end at the latest revision %s", title, info.LatestVerificationHash)
+	}
+	if err := checkChainHeight(title, info, hashes, opts); err != nil {
+		return nil, nil, err
+	}
+	if err := checkDuplicateHashes(title, hashes); err != nil {
+		return nil, nil, err
+	}
+
+	revisions := make([]*api.Revision, len(hashes))
+	errs := make([]error, len(hashes))
+	sem := make(chan struct{}, maxConcurrentFetches)
+	var wg sync.WaitGroup
+	for i, hash := range hashes {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			revisions[i], errs[i] = ap.GetRevisionContext(ctx, string(*hash))
+			if errs[i] != nil {
+				errs[i] = fmt.Errorf("Failure getting revision %s: %w", *hash, errs[i])
+			}
+		}()
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return nil, nil, err
+	}
+
+	data := &api.HashChain{HashChainInfo: *info, Revisions: make(map[string]*api.Revision, len(revisions))}
+	for i, r := range revisions {
+		data.Revisions[string(*hashes[i])] = r
+	}
+	if opts, err = withServerHashAlgorithm(ctx, ap, opts); err != nil {
+		return nil, nil, err
+	}
+	result, err := verifyHashChain(ctx, nil, data, doVerifyMerkleProof, -1, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	result.setSourceOf(ap)
+	if o := newOptions(opts); o.strict && !result.Valid() {
+		return revisions[:len(result.Revisions)], result, result.Err()
+	}
+	return revisions, result, nil
+}
+
+// VerifyChainStream fetches the revisions of the page with the given title one
+// by one, from the genesis revision to the latest revision, and yields the
+// result of each as soon as it is verified, e.g. to update a progress list.
+// Iteration stops when the caller stops consuming the sequence, at the first
+// failure to fetch a revision, or when ctx is cancelled, in which case the
+// error is yielded with a nil result. Like VerifyChain, chains higher than
+// allowed by WithMaxChainHeight fail with ErrChainTooHigh. Invalid revisions
+// are yielded like valid ones; the caller decides whether to go on.
+func VerifyChainStream(ctx context.Context, ap api.AquaClient, title string, doVerifyMerkleProof bool, opts ...Option) iter.Seq2[*RevisionVerificationResult, error] {
+	return func(yield func(*RevisionVerificationResult, error) bool) {
+		info, err := ap.GetHashChainInfoContext(ctx, "title", title)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		hashes, err := ap.GetRevisionHashesContext(ctx, info.GenesisHash)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		if len(hashes) == 0 || string(*hashes[len(hashes)-1]) != info.LatestVerificationHash {
+			yield(nil, newVerificationError(ErrBrokenChain, "Revision hashes of %s don't end at the latest revision %s", title, info.LatestVerificationHash))
+			return
+		}
+		if err := checkChainHeight(title, info, hashes, opts); err != nil {
+			yield(nil, err)
+			return
+		}
+		if err := checkDuplicateHashes(title, hashes); err != nil {
+			yield(nil, err)
+			return
+		}
+		opts, err := withServerHashAlgorithm(ctx, ap, append(opts[:len(opts):len(opts)], withContext(ctx)))
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		var prev *api.Revision
+		signatures := signatureSet{}
+		for _, hash := range hashes {
+			if err := ctx.Err(); err != nil {
+				yield(nil, err)
+				return
+			}
+			r, err := ap.GetRevisionContext(ctx, string(*hash))
+			if err != nil {
+				yield(nil, fmt.Errorf("Failure getting revision %s: %w", *hash, err))
+				return
+			}
+			_, result := verifyRevision(r, prev, doVerifyMerkleProof, opts...)
+			if err := signatures.add(r); err != nil {
+				result = withReusedSignature(result, err)
+			}
+			if !yield(result, nil) {
+				return
+			}
+			prev = r
+		}
+	}
+}
@@ -0,0 +1,772 @@
+package verify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+
+	"github.com/inblockio/aqua-verifier-go/api"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyHashChain(t *testing.T) {
+	require := require.New(t)
+	data, err := jsonDecodeFixture(fixture)
+	require.NoError(err)
+	page := data.Pages[0]
+	first := page.Revisions[page.GenesisHash]
+
+	// The witness of the first revision is tampered, but its content is not
+	first.Witness.WitnessEventVerificationHash = "wrong"
+	result, err := VerifyHashChain(page, GlobalDoVerifyMerkleProof, -1)
+	require.NoError(err)
+	require.Equal(page.GenesisHash, result.GenesisHash)
+	require.Equal(page.ChainHeight, result.Height)
+	require.Len(result.Revisions, page.ChainHeight)
+	require.False(result.Valid())
+
+	r := result.Revisions[0]
+	require.Equal(page.GenesisHash, r.VerificationHash)
+	require.False(r.Valid())
+	require.True(r.Status.Content)
+	require.True(r.Status.Metadata)
+	require.Equal("INVALID", r.Status.Witness)
+	require.False(r.WitnessResult.WitnessEventVHMatches)
+	for _, r := range result.Revisions[1:] {
+		require.NoError(r.Error)
+		require.True(r.Status.Content)
+	}
+
+	j, err := json.Marshal(result)
+	require.NoError(err)
+	decoded := map[string]interface{}{}
+	require.NoError(json.Unmarshal(j, &decoded))
+	require.Equal(INVALID_VERIFICATION_STATUS, decoded["verification_status"])
+	require.Equal(page.GenesisHash, decoded["verification_hashes"].([]interface{})[0])
+	revisions := decoded["revision_details"].([]interface{})
+	status := revisions[0].(map[string]interface{})["status"].(map[string]interface{})
+	require.Equal(true, status["content"])
+	require.Equal("INVALID", status["witness"])
+}
+
+func TestRevisionVerificationResultJSONError(t *testing.T) {
+	require := require.New(t)
+	first, _, err := get1st2ndFixtureVerStructure()
+	require.NoError(err)
+	first.Content.Content["main"] = "wrong"
+	result := expectErrorFirstRevision(require, first, "Content hash doesn't match")
+	j, err := json.Marshal(result)
+	require.NoError(err)
+	require.Contains(string(j), `"error":"Content hash doesn't match"`)
+}
+
+type testSpan struct {
+	name  string
+	attrs map[string]interface{}
+}
+
+func (s *testSpan) SetAttributes(attrs ...api.Attribute) {
+	for _, a := range attrs {
+		s.attrs[a.Key] = a.Value
+	}
+}
+
+func (s *testSpan) RecordError(err error) {}
+
+func (s *testSpan) End() {}
+
+type testTracer struct {
+	spans []*testSpan
+}
+
+func (t *testTracer) Start(ctx context.Context, name string, attrs ...api.Attribute) (context.Context, api.Span) {
+	s := &testSpan{name: name, attrs: map[string]interface{}{}}
+	s.SetAttributes(attrs...)
+	t.spans = append(t.spans, s)
+	return ctx, s
+}
+
+func TestVerifyChain(t *testing.T) {
+	require := require.New(t)
+	data, err := jsonDecodeFixture(fixture)
+	require.NoError(err)
+	s := newFixtureServer(data)
+	defer s.Close()
+
+	tracer := &testTracer{}
+	ap, err := api.NewAPI(s.URL, "", api.WithTracer(tracer))
+	require.NoError(err)
+	page := data.Pages[0]
+	result, err := VerifyChain(context.Background(), ap, "Main Page", GlobalDoVerifyMerkleProof, -1)
+	require.NoError(err)
+	require.Equal(page.GenesisHash, result.GenesisHash)
+	require.Len(result.Revisions, page.ChainHeight)
+	for _, r := range result.Revisions {
+		require.NoError(r.Error)
+		require.Equal(VERIFIED_VERIFICATION_STATUS, r.Status.Verification)
+	}
+
+	// one span for the chain, one for each fetch and revision verification
+	require.Len(tracer.spans, 1+1+2*page.ChainHeight)
+	require.Equal("aqua.verify_chain", tracer.spans[0].name)
+	require.Equal("Main Page", tracer.spans[0].attrs["aqua.title"])
+	var fetches, verifications int
+	for _, s := range tracer.spans[1:] {
+		switch s.name {
+		case "aqua.fetch":
+			fetches++
+		case "aqua.verify_revision":
+			verifications++
+			require.NotEmpty(s.attrs["aqua.verification_hash"])
+			require.Contains(s.attrs, "aqua.valid")
+		}
+	}
+	require.Equal(1+page.ChainHeight, fetches)
+	require.Equal(page.ChainHeight, verifications)
+	require.Equal(result.Revisions[len(result.Revisions)-1].VerificationHash,
+		tracer.spans[len(tracer.spans)-1].attrs["aqua.verification_hash"])
+
+	// depth limits the number of revisions fetched
+	result, err = VerifyChain(context.Background(), ap, "Main Page", GlobalDoVerifyMerkleProof, 2)
+	require.NoError(err)
+	require.Len(result.Revisions, 2)
+	require.Equal(page.LatestVerificationHash, result.Revisions[1].VerificationHash)
+}
+
+func TestWithMaxChainHeight(t *testing.T) {
+	require := require.New(t)
+	// a server claiming a huge height and serving an endless chain
+	var fetched atomic.Int64
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/data_accounting/get_hash_chain_info/") {
+			json.NewEncoder(w).Encode(&api.HashChainInfo{Title: "Endless", LatestVerificationHash: "0", ChainHeight: 1 << 40})
+			return
+		}
+		n, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/data_accounting/get_revision/"))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		fetched.Add(1)
+		json.NewEncoder(w).Encode(&api.Revision{Metadata: &api.RevisionMetadata{
+			VerificationHash:         strconv.Itoa(n),
+			PreviousVerificationHash: strconv.Itoa(n + 1),
+		}})
+	}))
+	defer s.Close()
+	ap, err := api.NewAPI(s.URL, "")
+	require.NoError(err)
+
+	_, err = VerifyChain(context.Background(), ap, "Endless", GlobalDoVerifyMerkleProof, -1, WithMaxChainHeight(10))
+	require.True(errors.Is(err, ErrChainTooHigh))
+	require.EqualError(err, "Chain of Endless has more than 10 revisions")
+	require.EqualValues(11, fetched.Load())
+
+	// shallow verifications are not affected by the limit
+	fetched.Store(0)
+	_, err = VerifyChain(context.Background(), ap, "Endless", GlobalDoVerifyMerkleProof, 3, WithMaxChainHeight(10))
+	require.False(errors.Is(err, ErrChainTooHigh))
+	require.EqualValues(3, fetched.Load())
+
+	// chains within the limit verify as before
+	data, err := jsonDecodeFixture(fixture)
+	require.NoError(err)
+	fs := newFixtureServer(data)
+	defer fs.Close()
+	ap, err = api.NewAPI(fs.URL, "")
+	require.NoError(err)
+	height := data.Pages[0].ChainHeight
+	result, err := VerifyChain(context.Background(), ap, "Main Page", GlobalDoVerifyMerkleProof, -1, WithMaxChainHeight(height))
+	require.NoError(err)
+	require.Len(result.Revisions, height)
+	_, err = VerifyChain(context.Background(), ap, "Main Page", GlobalDoVerifyMerkleProof, -1, WithMaxChainHeight(height-1))
+	require.True(errors.Is(err, ErrChainTooHigh))
+	_, err = VerifyChain(context.Background(), ap, "Main Page", GlobalDoVerifyMerkleProof, -1, WithMaxChainHeight(0))
+	require.NoError(err)
+
+	// the limit applies to GetAllRevisions and VerifyChainStream too
+	backend := &fakeBackend{pages: data.Pages, calls: map[string]int{}}
+	_, _, err = GetAllRevisions(context.Background(), backend, data.Pages[0].Title, GlobalDoVerifyMerkleProof, WithOnChainChecks(false), WithMaxChainHeight(height-1))
+	require.True(errors.Is(err, ErrChainTooHigh))
+	for result, err := range VerifyChainStream(context.Background(), backend, data.Pages[0].Title, GlobalDoVerifyMerkleProof, WithOnChainChecks(false), WithMaxChainHeight(height-1)) {
+		require.Nil(result)
+		require.True(errors.Is(err, ErrChainTooHigh))
+	}
+	require.Zero(backend.calls["revision"])
+	revisions, _, err := GetAllRevisions(context.Background(), backend, data.Pages[0].Title, GlobalDoVerifyMerkleProof, WithOnChainChecks(false), WithMaxChainHeight(height))
+	require.NoError(err)
+	require.Len(revisions, height)
+}
+
+func TestGetVerifiedRevision(t *testing.T) {
+	require := require.New(t)
+	data, err := jsonDecodeFixture(fixture)
+	require.NoError(err)
+	s := newFixtureServer(data)
+	defer s.Close()
+	ap, err := api.NewAPI(s.URL, "")
+	require.NoError(err)
+	page := data.Pages[0]
+
+	// the genesis revision is witnessed, but its transaction is not looked up
+	r, result, err := GetVerifiedRevision(context.Background(), ap, page.GenesisHash)
+	require.NoError(err)
+	require.Equal(page.GenesisHash, r.Metadata.VerificationHash)
+	require.True(result.Valid())
+	require.Equal(NOT_CHECKED_STATUS, result.Status.Witness)
+	require.Equal(ETHERSCAN_NOT_CHECKED, result.WitnessResult.EtherscanResult)
+
+	r, result, err = GetVerifiedRevision(context.Background(), ap, page.LatestVerificationHash)
+	require.NoError(err)
+	require.True(result.Valid())
+
+	page.Revisions[page.LatestVerificationHash].Content.Content["main"] = "tampered"
+	r, result, err = GetVerifiedRevision(context.Background(), ap, page.LatestVerificationHash)
+	require.NoError(err)
+	require.Equal("tampered", r.Content.Content["main"])
+	require.False(result.Valid())
+	require.EqualError(result.Error, "Content hash doesn't match")
+
+	_, _, err = GetVerifiedRevision(context.Background(), ap, "unknown")
+	require.Error(err)
+}
+
+func TestVerifyLatest(t *testing.T) {
+	require := require.New(t)
+	data, err := jsonDecodeFixture(fixture)
+	require.NoError(err)
+	s := newFixtureServer(data)
+	defer s.Close()
+	ap, err := api.NewAPI(s.URL, "")
+	require.NoError(err)
+	page := data.Pages[0]
+	latest := page.Revisions[page.LatestVerificationHash]
+	parent := page.Revisions[latest.Metadata.PreviousVerificationHash]
+
+	result, err := VerifyLatest(context.Background(), ap, page.Title)
+	require.NoError(err)
+	require.True(result.Valid())
+	require.Equal(page.LatestVerificationHash, result.VerificationHash)
+
+	// the link to the previous revision is checked
+	parentHash := parent.Metadata.VerificationHash
+	parent.Metadata.VerificationHash = "tampered"
+	result, err = VerifyLatest(context.Background(), ap, page.Title)
+	require.NoError(err)
+	require.False(result.Valid())
+	require.ErrorIs(result.Error, ErrBrokenChain)
+	parent.Metadata.VerificationHash = parentHash
+
+	latest.Content.Content["main"] = "tampered"
+	result, err = VerifyLatest(context.Background(), ap, page.Title)
+	require.NoError(err)
+	require.False(result.Valid())
+	require.ErrorIs(result.Error, ErrContentHashMismatch)
+
+	_, err = VerifyLatest(context.Background(), ap, "unknown")
+	require.Error(err)
+}
+
+func TestGetAllRevisions(t *testing.T) {
+	require := require.New(t)
+	data, err := jsonDecodeFixture(fixture)
+	require.NoError(err)
+	s := newFixtureServer(data)
+	defer s.Close()
+	ap, err := api.NewAPI(s.URL, "")
+	require.NoError(err)
+	page := data.Pages[0]
+	set, _, err := getVerificationSet(page, -1)
+	require.NoError(err)
+
+	revisions, result, err := GetAllRevisions(context.Background(), ap, page.Title, GlobalDoVerifyMerkleProof, WithOnChainChecks(false))
+	require.NoError(err)
+	require.True(result.Valid())
+	require.Len(revisions, page.ChainHeight)
+	require.Len(result.Revisions, page.ChainHeight)
+	for i, r := range revisions {
+		require.Equal(set[i].Metadata.VerificationHash, r.Metadata.VerificationHash)
+		require.Equal(r.Metadata.VerificationHash, result.Revisions[i].VerificationHash)
+	}
+	for _, merkle := range []bool{true, false} {
+		_, result, err = GetAllRevisions(context.Background(), ap, page.Title, merkle, WithOnChainChecks(false))
+		require.NoError(err)
+		require.Equal(merkle, result.Revisions[0].WitnessResult.DoVerifyMerkleProof)
+	}
+
+	// the whole chain is verified by default
+	tampered := set[1].Metadata.VerificationHash
+	page.Revisions[tampered].Content.Content["main"] = "tampered"
+	revisions, result, err = GetAllRevisions(context.Background(), ap, page.Title, GlobalDoVerifyMerkleProof, WithOnChainChecks(false))
+	require.NoError(err)
+	require.False(result.Valid())
+	require.Len(revisions, page.ChainHeight)
+	require.Len(result.Revisions, page.ChainHeight)
+
+	// and strict verification stops at the tampered revision
+	revisions, result, err = GetAllRevisions(context.Background(), ap, page.Title, GlobalDoVerifyMerkleProof, WithOnChainChecks(false), WithStrict(true))
+	require.True(errors.Is(err, ErrContentHashMismatch))
+	require.False(result.Valid())
+	require.Len(revisions, 2)
+	require.Len(result.Revisions, 2)
+	require.Equal(tampered, revisions[1].Metadata.VerificationHash)
+
+	_, _, err = GetAllRevisions(context.Background(), ap, "Unknown Page", GlobalDoVerifyMerkleProof)
+	require.Error(err)
+}
+
+func TestVerifyChainStream(t *testing.T) {
+	require := require.New(t)
+	data, err := jsonDecodeFixture(fixture)
+	require.NoError(err)
+	s := newFixtureServer(data)
+	defer s.Close()
+	var fetched atomic.Int64
+	handler := s.Config.Handler
+	s.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/data_accounting/get_revision/") {
+			fetched.Add(1)
+		}
+		handler.ServeHTTP(w, r)
+	})
+	ap, err := api.NewAPI(s.URL, "")
+	require.NoError(err)
+	page := data.Pages[0]
+	ctx := context.Background()
+
+	// fully, genesis first
+	var hashes []string
+	for result, err := range VerifyChainStream(ctx, ap, page.Title, GlobalDoVerifyMerkleProof, WithOnChainChecks(false)) {
+		require.NoError(err)
+		require.True(result.Valid())
+		if len(hashes) == 0 {
+			require.Equal(page.GenesisHash, result.VerificationHash)
+		}
+		hashes = append(hashes, result.VerificationHash)
+		// the result is yielded before the next revision is fetched
+		require.EqualValues(len(hashes), fetched.Load())
+	}
+	require.Len(hashes, page.ChainHeight)
+	require.Equal(page.LatestVerificationHash, hashes[len(hashes)-1])
+
+	// the merkle proofs are checked as requested
+	for _, merkle := range []bool{true, false} {
+		witnessed := 0
+		for result, err := range VerifyChainStream(ctx, ap, page.Title, merkle, WithOnChainChecks(false)) {
+			require.NoError(err)
+			if result.WitnessResult != nil && result.WitnessResult.TxHash != "" {
+				witnessed++
+				require.Equal(merkle, result.WitnessResult.DoVerifyMerkleProof)
+			}
+		}
+		require.NotZero(witnessed)
+	}
+
+	// partially, stopping at the first failure
+	fetched.Store(0)
+	page.Revisions[hashes[1]].Content.Content["main"] = "tampered"
+	var results []*RevisionVerificationResult
+	for result, err := range VerifyChainStream(ctx, ap, page.Title, GlobalDoVerifyMerkleProof, WithOnChainChecks(false)) {
+		require.NoError(err)
+		results = append(results, result)
+		if !result.Valid() {
+			break
+		}
+	}
+	require.Len(results, 2)
+	require.ErrorIs(results[1].Err(), ErrContentHashMismatch)
+	require.EqualValues(2, fetched.Load())
+
+	// cancelling the context stops the iteration with its error
+	cancelCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var errs []error
+	for result, err := range VerifyChainStream(cancelCtx, ap, page.Title, GlobalDoVerifyMerkleProof, WithOnChainChecks(false)) {
+		if err != nil {
+			require.Nil(result)
+			errs = append(errs, err)
+			continue
+		}
+		cancel()
+	}
+	require.Len(errs, 1)
+	require.ErrorIs(errs[0], context.Canceled)
+
+	for _, err := range VerifyChainStream(ctx, ap, "unknown", GlobalDoVerifyMerkleProof) {
+		require.Error(err)
+	}
+}
+
+func TestVerifyFromFS(t *testing.T) {
+	require := require.New(t)
+	data, err := jsonDecodeFixture(fixture)
+	require.NoError(err)
+	page := data.Pages[0]
+	fsys := fstest.MapFS{}
+	for hash, r := range page.Revisions {
+		b, err := json.Marshal(r)
+		require.NoError(err)
+		fsys[hash+".json"] = &fstest.MapFile{Data: b}
+	}
+	c, err := api.NewFSClient(fsys)
+	require.NoError(err)
+
+	_, result, err := GetVerifiedRevision(context.Background(), c, page.LatestVerificationHash)
+	require.NoError(err)
+	require.True(result.Valid())
+
+	store := &memStore{}
+	info, err := c.GetHashChainInfoContext(context.Background(), "genesis_hash", page.GenesisHash)
+	require.NoError(err)
+	b, err := json.Marshal(info)
+	require.NoError(err)
+	fsys["titles/Main_Page.json"] = &fstest.MapFile{Data: b}
+	added, err := SyncChain(context.Background(), c, store, "Main Page", WithOnChainChecks(false))
+	require.NoError(err)
+	require.Equal(page.ChainHeight, added)
+}
+
+var errNotFound = errors.New("Not found")
+
+// fakeBackend serves hash chains from memory, like a client of another
+// transport than the REST api would
+type fakeBackend struct {
+	pages []*api.HashChain
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (b *fakeBackend) count(call string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.calls[call]++
+}
+
+func (b *fakeBackend) GetHashChainInfoContext(ctx context.Context, id_type, id string) (*api.HashChainInfo, error) {
+	b.count("info")
+	for _, p := range b.pages {
+		if (id_type == "title" && p.Title == id) || (id_type == "genesis_hash" && p.GenesisHash == id) {
+			info := p.HashChainInfo
+			return &info, nil
+		}
+	}
+	return nil, errNotFound
+}
+
+func (b *fakeBackend) GetRevisionHashesContext(ctx context.Context, verification_hash string) ([]*api.RevisionHash, error) {
+	b.count("hashes")
+	for _, p := range b.pages {
+		var hashes []*api.RevisionHash
+		for cur := p.LatestVerificationHash; cur != ""; cur = p.Revisions[cur].Metadata.PreviousVerificationHash {
+			h := api.RevisionHash(cur)
+			hashes = append([]*api.RevisionHash{&h}, hashes...)
+			if cur == verification_hash {
+				return hashes, nil
+			}
+		}
+	}
+	return nil, errNotFound
+}
+
+func (b *fakeBackend) GetRevisionContext(ctx context.Context, verification_hash string) (*api.Revision, error) {
+	b.count("revision")
+	for _, p := range b.pages {
+		if r, ok := p.Revisions[verification_hash]; ok {
+			return r, nil
+		}
+	}
+	return nil, errNotFound
+}
+
+func (b *fakeBackend) GetServerInfoContext(ctx context.Context) (*api.ServerInfo, error) {
+	return &api.ServerInfo{ApiVersion: api.Version}, nil
+}
+
+func TestVerifyChainBackend(t *testing.T) {
+	require := require.New(t)
+	data, err := jsonDecodeFixture(fixture)
+	require.NoError(err)
+	page := data.Pages[0]
+	backend := &fakeBackend{pages: data.Pages, calls: map[string]int{}}
+	ctx := context.Background()
+	opts := []Option{WithOnChainChecks(false)}
+
+	expected, err := VerifyHashChain(page, true, -1, opts...)
+	require.NoError(err)
+	result, err := VerifyChain(ctx, backend, page.Title, true, -1, opts...)
+	require.NoError(err)
+	require.NoError(result.Err())
+	require.Len(result.Revisions, page.ChainHeight)
+	for i, r := range result.Revisions {
+		require.Equal(expected.Revisions[i].VerificationHash, r.VerificationHash)
+		require.Equal(expected.Revisions[i].Status, r.Status)
+	}
+	require.Empty(result.Endpoint)
+	require.Equal(1, backend.calls["info"])
+	require.Equal(page.ChainHeight, backend.calls["revision"])
+
+	results, err := VerifyTitles(ctx, backend, []string{page.Title, "unknown"}, 2, opts...)
+	require.Error(err)
+	require.True(results[page.Title].Valid())
+
+	var streamed int
+	for r, err := range VerifyChainStream(ctx, backend, page.Title, GlobalDoVerifyMerkleProof, opts...) {
+		require.NoError(err)
+		require.True(r.Valid())
+		streamed++
+	}
+	require.Equal(page.ChainHeight, streamed)
+
+	// tampering is detected the same way
+	page.Revisions[page.LatestVerificationHash].Content.Content["main"] = "tampered"
+	result, err = VerifyChain(ctx, backend, page.Title, true, -1, opts...)
+	require.NoError(err)
+	require.ErrorIs(result.Err(), ErrContentHashMismatch)
+}
+
+func TestWithObserver(t *testing.T) {
+	require := require.New(t)
+	data, err := jsonDecodeFixture(fixture)
+	require.NoError(err)
+	page := data.Pages[0]
+	page.Revisions[page.LatestVerificationHash].Content.Content["main"] = "tampered"
+
+	var observed []*RevisionVerificationResult
+	result, err := VerifyHashChain(page, GlobalDoVerifyMerkleProof, -1, WithOnChainChecks(false),
+		WithObserver(func(r *RevisionVerificationResult) {
+			observed = append(observed, r)
+		}))
+	require.NoError(err)
+	require.Equal(result.Revisions, observed)
+	require.True(observed[0].Valid())
+	last := observed[len(observed)-1]
+	require.Equal(page.LatestVerificationHash, last.VerificationHash)
+	require.EqualError(last.Error, "Content hash doesn't match")
+	require.NotZero(last.Elapsed)
+}
+
+func TestWithProgress(t *testing.T) {
+	require := require.New(t)
+	data, err := jsonDecodeFixture(fixture)
+	require.NoError(err)
+	s := newFixtureServer(data)
+	defer s.Close()
+	ap, err := api.NewAPI(s.URL, "")
+	require.NoError(err)
+	page := data.Pages[0]
+
+	var calls [][2]int
+	progress := WithProgress(func(done, total int) {
+		calls = append(calls, [2]int{done, total})
+	})
+	_, err = VerifyChain(context.Background(), ap, page.Title, GlobalDoVerifyMerkleProof, -1, WithOnChainChecks(false), progress)
+	require.NoError(err)
+	require.Len(calls, page.ChainHeight)
+	for i, call := range calls {
+		require.Equal([2]int{i + 1, page.ChainHeight}, call)
+	}
+
+	calls = nil
+	_, err = VerifyChain(context.Background(), ap, page.Title, GlobalDoVerifyMerkleProof, 2, WithOnChainChecks(false), progress)
+	require.NoError(err)
+	require.Equal([][2]int{{1, 2}, {2, 2}}, calls)
+}
+
+// forgeChain returns a made up but internally consistent copy of the chain
+// of page, as served by a malicious server
+func forgeChain(require *require.Assertions, page *api.HashChain) *api.HashChain {
+	set, _, err := getVerificationSet(page, -1)
+	require.NoError(err)
+	forged := &api.HashChain{HashChainInfo: page.HashChainInfo, Revisions: map[string]*api.Revision{}}
+	var prev *api.Revision
+	for _, r := range set {
+		content := &api.RevisionContent{Content: map[string]string{"main": "forged " + r.Content.Content["main"]}}
+		content.ContentHash = calculateContentHash(nil, content)
+		metadata := *r.Metadata
+		metadata.PreviousVerificationHash = ""
+		if prev != nil {
+			metadata.PreviousVerificationHash = prev.Metadata.VerificationHash
+		}
+		_, metadata.MetadataHash = ExplainMetadataHash(&metadata)
+		f := &api.Revision{Context: &api.VerificationContext{}, Content: content, Metadata: &metadata}
+		metadata.VerificationHash, err = ComputeVerificationHash(f, prev)
+		require.NoError(err)
+		forged.Revisions[metadata.VerificationHash] = f
+		if prev == nil {
+			forged.GenesisHash = metadata.VerificationHash
+		}
+		prev = f
+	}
+	forged.LatestVerificationHash = prev.Metadata.VerificationHash
+	return forged
+}
+
+func TestWithIndependentRoots(t *testing.T) {
+	require := require.New(t)
+	data, err := jsonDecodeFixture(fixture)
+	require.NoError(err)
+	page := data.Pages[0]
+	genesis := page.Revisions[page.GenesisHash]
+	merkleRoot := genesis.Witness.MerkleRoot
+
+	// the forged chain is internally consistent
+	forged := forgeChain(require, page)
+	result, err := VerifyHashChain(forged, GlobalDoVerifyMerkleProof, -1, WithOnChainChecks(false))
+	require.NoError(err)
+	require.True(result.Valid())
+	require.Empty(result.TrustAnchor)
+
+	for _, root := range []string{page.GenesisHash, merkleRoot, genesis.Witness.WitnessEventVerificationHash} {
+		result, err = VerifyHashChain(forged, GlobalDoVerifyMerkleProof, -1, WithOnChainChecks(false), WithIndependentRoots([]string{root}))
+		require.NoError(err)
+		require.False(result.Valid())
+		require.True(errors.Is(result.Err(), ErrNoTrustAnchor))
+
+		result, err = VerifyHashChain(page, GlobalDoVerifyMerkleProof, -1, WithOnChainChecks(false), WithIndependentRoots([]string{root}))
+		require.NoError(err)
+		require.True(result.Valid())
+		require.NoError(result.Err())
+		require.Equal(root, result.TrustAnchor)
+		require.Equal(1, result.AnchoredRevisions)
+	}
+
+	// roots are compared regardless of their format
+	result, err = VerifyHashChain(page, GlobalDoVerifyMerkleProof, -1, WithOnChainChecks(false), WithIndependentRoots([]string{"0x" + strings.ToUpper(merkleRoot)}))
+	require.NoError(err)
+	require.Equal(merkleRoot, result.TrustAnchor)
+
+	// a forged merkle proof doesn't anchor the chain
+	genesis.Witness.MerkleProof[len(genesis.Witness.MerkleProof)-1].Successor = "forged"
+	result, err = VerifyHashChain(page, GlobalDoVerifyMerkleProof, -1, WithOnChainChecks(false), WithIndependentRoots([]string{merkleRoot}))
+	require.NoError(err)
+	require.False(result.Valid())
+	require.Empty(result.TrustAnchor)
+
+	// no roots anchor nothing
+	result, err = VerifyHashChain(page, GlobalDoVerifyMerkleProof, -1, WithOnChainChecks(false), WithIndependentRoots(nil))
+	require.NoError(err)
+	require.False(result.Valid())
+}
+
+func TestGenesisHashMismatch(t *testing.T) {
+	require := require.New(t)
+	data, err := jsonDecodeFixture(fixture)
+	require.NoError(err)
+	page := data.Pages[0]
+
+	result, err := VerifyHashChain(page, GlobalDoVerifyMerkleProof, -1, WithOnChainChecks(false))
+	require.NoError(err)
+	require.True(result.Valid())
+	require.Empty(result.ActualGenesisHash)
+
+	// the server advertises a genesis hash of another chain
+	genesis := page.GenesisHash
+	page.GenesisHash = strings.Repeat("ab", 64)
+	result, err = VerifyHashChain(page, GlobalDoVerifyMerkleProof, -1, WithOnChainChecks(false))
+	require.NoError(err)
+	for _, r := range result.Revisions {
+		require.True(r.Valid())
+	}
+	require.False(result.Valid())
+	require.Equal(genesis, result.ActualGenesisHash)
+	require.True(errors.Is(result.Err(), ErrGenesisMismatch))
+	require.EqualError(result.Err(), "Genesis hash "+page.GenesisHash+" differs from the verification hash "+genesis+" of the genesis revision")
+
+	// which can't be checked without verifying the genesis revision
+	result, err = VerifyHashChain(page, GlobalDoVerifyMerkleProof, 2, WithOnChainChecks(false))
+	require.NoError(err)
+	require.Empty(result.ActualGenesisHash)
+	require.False(errors.Is(result.Err(), ErrGenesisMismatch))
+}
+
+// duplicateHashesBackend lists the revision hashes of its pages with the
+// genesis hash twice
+type duplicateHashesBackend struct {
+	*fakeBackend
+}
+
+func (b duplicateHashesBackend) GetRevisionHashesContext(ctx context.Context, verification_hash string) ([]*api.RevisionHash, error) {
+	hashes, err := b.fakeBackend.GetRevisionHashesContext(ctx, verification_hash)
+	if err != nil {
+		return nil, err
+	}
+	return append([]*api.RevisionHash{hashes[0]}, hashes...), nil
+}
+
+func TestDuplicateRevisions(t *testing.T) {
+	require := require.New(t)
+	data, err := jsonDecodeFixture(fixture)
+	require.NoError(err)
+	page := data.Pages[0]
+	set, _, err := getVerificationSet(page, -1)
+	require.NoError(err)
+	genesis := set[0].Metadata
+
+	// a backend listing a revision twice
+	backend := duplicateHashesBackend{&fakeBackend{pages: data.Pages, calls: map[string]int{}}}
+	_, _, err = GetAllRevisions(context.Background(), backend, page.Title, GlobalDoVerifyMerkleProof, WithOnChainChecks(false))
+	require.ErrorIs(err, ErrBrokenChain)
+	require.EqualError(err, "Revision hashes of "+page.Title+" list revision "+genesis.VerificationHash+" more than once")
+	for result, err := range VerifyChainStream(context.Background(), backend, page.Title, GlobalDoVerifyMerkleProof, WithOnChainChecks(false)) {
+		require.Nil(result)
+		require.ErrorIs(err, ErrBrokenChain)
+	}
+
+	// a self-referential revision
+	latest := set[len(set)-1].Metadata
+	latestPrev := latest.PreviousVerificationHash
+	latest.PreviousVerificationHash = latest.VerificationHash
+	_, err = VerifyHashChain(page, GlobalDoVerifyMerkleProof, -1, WithOnChainChecks(false))
+	require.ErrorIs(err, ErrBrokenChain)
+	require.EqualError(err, "Revision "+latest.VerificationHash+" occurs more than once in the chain")
+	latest.PreviousVerificationHash = latestPrev
+
+	// a chain looping back to a later revision
+	set[3].Metadata.PreviousVerificationHash = set[5].Metadata.VerificationHash
+	_, err = VerifyHashChain(page, GlobalDoVerifyMerkleProof, -1, WithOnChainChecks(false))
+	require.ErrorIs(err, ErrBrokenChain)
+	require.EqualError(err, "Revision "+set[5].Metadata.VerificationHash+" occurs more than once in the chain")
+}
+
+func TestWithAllowedAPIVersions(t *testing.T) {
+	require := require.New(t)
+	data, err := jsonDecodeFixture(fixture)
+	require.NoError(err)
+	s := newFixtureServer(data)
+	defer s.Close()
+
+	var serverInfoRequests int
+	ap, err := api.NewAPI(s.URL, "", api.WithResponseRecorder(func(endpoint string, body []byte) {
+		if strings.Contains(endpoint, "get_server_info") {
+			serverInfoRequests++
+		}
+	}))
+	require.NoError(err)
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		result, err := VerifyChain(ctx, ap, "Main Page", GlobalDoVerifyMerkleProof, -1, WithOnChainChecks(false), WithAllowedAPIVersions([]string{"0.1.0", api.Version}))
+		require.NoError(err)
+		require.NoError(result.Err())
+	}
+	require.Equal(1, serverInfoRequests)
+
+	_, err = VerifyChain(ctx, ap, "Main Page", GlobalDoVerifyMerkleProof, -1, WithAllowedAPIVersions([]string{"0.1.0"}))
+	require.True(errors.Is(err, ErrUnsupportedAPIVersion))
+	require.EqualError(err, "Server api version "+api.Version+" is not allowed")
+	require.Equal(1, serverInfoRequests)
+
+	// an empty allowlist refuses every server, also clients without a cached
+	// server info
+	backend := &fakeBackend{pages: data.Pages, calls: map[string]int{}}
+	_, err = VerifyChain(ctx, backend, "Main Page", GlobalDoVerifyMerkleProof, -1, WithAllowedAPIVersions(nil))
+	require.True(errors.Is(err, ErrUnsupportedAPIVersion))
+	require.Zero(backend.calls["info"])
+}
@@ -0,0 +1,91 @@
+package verify
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/inblockio/aqua-verifier-go/api"
+)
+
+// Checkpoint records how far the verification of a chain got, so that an
+// interrupted verification can be resumed with WithCheckpoint instead of
+// verifying the whole chain again. It is meant to be persisted, e.g. as JSON.
+type Checkpoint struct {
+	GenesisHash string `json:"genesis_hash"`
+	// VerificationHash is the verification hash of the last verified
+	// revision; it and all its previous revisions verified successfully
+	VerificationHash string `json:"verification_hash"`
+	// Verified is the number of revisions verified up to VerificationHash
+	Verified int `json:"verified"`
+}
+
+// Checkpoint returns the checkpoint after the newest revision of c up to
+// which every revision verified successfully, to resume verifying the chain
+// from there. It returns the checkpoint c was resumed from if no further
+// revision verified successfully, and nil if c wasn't verified from the
+// genesis revision or a checkpoint, e.g. for a limited depth.
+func (c *ChainVerificationResult) Checkpoint() *Checkpoint {
+	cp := c.ResumedFrom
+	if cp == nil && (len(c.Revisions) == 0 || c.Revisions[0].VerificationHash != c.GenesisHash) {
+		return nil
+	}
+	for _, r := range c.Revisions {
+		if !r.Valid() {
+			break
+		}
+		verified := 0
+		if cp != nil {
+			verified = cp.Verified
+		}
+		cp = &Checkpoint{GenesisHash: c.GenesisHash, VerificationHash: r.VerificationHash, Verified: verified + 1}
+	}
+	return cp
+}
+
+// verifyChainFromCheckpoint verifies the revisions of the page with the given
+// title that are newer than the revision of cp
+func verifyChainFromCheckpoint(ctx context.Context, ap api.AquaClient, title string, doVerifyMerkleProof bool, cp *Checkpoint, opts []Option) (*ChainVerificationResult, error) {
+	info, err := ap.GetHashChainInfoContext(ctx, "title", title)
+	if err != nil {
+		return nil, err
+	}
+	if info.GenesisHash != cp.GenesisHash {
+		return nil, newVerificationError(ErrBrokenChain, "Checkpoint of chain %s doesn't belong to the chain %s of %s", cp.GenesisHash, info.GenesisHash, title)
+	}
+	hashes, err := ap.GetRevisionHashesContext(ctx, cp.VerificationHash)
+	if err == nil && (len(hashes) == 0 || string(*hashes[0]) != cp.VerificationHash) {
+		err = fmt.Errorf("Revision hashes don't start with the requested revision")
+	}
+	if err != nil {
+		return nil, newVerificationError(ErrBrokenChain, "Checkpoint revision %s is not a revision of %s: %s", cp.VerificationHash, title, err)
+	}
+	if string(*hashes[len(hashes)-1]) != info.LatestVerificationHash {
+		return nil, newVerificationError(ErrBrokenChain, "Revision hashes of %s don't end at the latest revision %s", title, info.LatestVerificationHash)
+	}
+	if max := newOptions(opts).maxChainHeight; max > 0 && len(hashes)-1 > max {
+		return nil, newVerificationError(ErrChainTooHigh, "Chain of %s has more than %d revisions", title, max)
+	}
+
+	data := &api.HashChain{HashChainInfo: *info, Revisions: make(map[string]*api.Revision)}
+	verificationSet := make([]*api.Revision, len(hashes))
+	for i, h := range hashes {
+		r, err := ap.GetRevisionContext(ctx, string(*h))
+		if err == nil && r.VerificationHash() != string(*h) {
+			err = fmt.Errorf("Revision has verification hash %q", r.VerificationHash())
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Failure getting revision %s: %w", *h, err)
+		}
+		data.Revisions[string(*h)] = r
+		verificationSet[i] = r
+	}
+	// the checkpoint revision was verified already, and links the newer
+	// revisions to it
+	c, err := verifyVerificationSet(ctx, tracerOf(ap), data, verificationSet[1:], len(verificationSet)-1, verificationSet[0], doVerifyMerkleProof, opts)
+	if err != nil {
+		return nil, err
+	}
+	c.ResumedFrom = cp
+	c.setSourceOf(ap)
+	return c, nil
+}
@@ -0,0 +1,89 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/inblockio/aqua-verifier-go/api"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithCheckpoint(t *testing.T) {
+	require := require.New(t)
+	data, err := jsonDecodeFixture(fixture)
+	require.NoError(err)
+	page := data.Pages[0]
+	set, _, err := getVerificationSet(page, -1)
+	require.NoError(err)
+	n := len(set)
+	require.Greater(n, 3)
+	backend := &fakeBackend{pages: []*api.HashChain{page}, calls: map[string]int{}}
+	ctx := context.Background()
+
+	// a verification interrupted by a failing revision
+	saved := set[2].Content.Content["main"]
+	set[2].Content.Content["main"] = "tampered"
+	result, err := VerifyChain(ctx, backend, page.Title, GlobalDoVerifyMerkleProof, -1, WithOnChainChecks(false), WithStrict(true))
+	require.NoError(err)
+	require.False(result.Valid())
+	cp := result.Checkpoint()
+	require.Equal(&Checkpoint{GenesisHash: page.GenesisHash, VerificationHash: set[1].VerificationHash(), Verified: 2}, cp)
+	set[2].Content.Content["main"] = saved
+
+	// is resumed after the checkpoint revision
+	backend.calls = map[string]int{}
+	result, err = VerifyChain(ctx, backend, page.Title, GlobalDoVerifyMerkleProof, -1, WithOnChainChecks(false), WithCheckpoint(cp))
+	require.NoError(err)
+	require.NoError(result.Err())
+	require.True(result.Valid())
+	require.Len(result.Revisions, n-2)
+	require.Equal(set[2].VerificationHash(), result.Revisions[0].VerificationHash)
+	require.Equal(n-1, backend.calls["revision"])
+	require.Equal(cp, result.ResumedFrom)
+	latest := result.Checkpoint()
+	require.Equal(&Checkpoint{GenesisHash: page.GenesisHash, VerificationHash: page.LatestVerificationHash, Verified: n}, latest)
+
+	// a checkpoint at the latest revision has nothing left to verify
+	result, err = VerifyChain(ctx, backend, page.Title, GlobalDoVerifyMerkleProof, -1, WithOnChainChecks(false), WithCheckpoint(latest))
+	require.NoError(err)
+	require.True(result.Valid())
+	require.Empty(result.Revisions)
+	require.Equal(latest, result.Checkpoint())
+
+	// the checkpoint of a full verification
+	result, err = VerifyChain(ctx, backend, page.Title, GlobalDoVerifyMerkleProof, -1, WithOnChainChecks(false))
+	require.NoError(err)
+	require.Equal(latest, result.Checkpoint())
+	result, err = VerifyChain(ctx, backend, page.Title, GlobalDoVerifyMerkleProof, 2, WithOnChainChecks(false))
+	require.NoError(err)
+	require.Nil(result.Checkpoint())
+
+	// checkpoints of other chains are rejected
+	for _, other := range []*Checkpoint{
+		{GenesisHash: page.GenesisHash, VerificationHash: "unknown", Verified: 2},
+		{GenesisHash: "other", VerificationHash: set[1].VerificationHash(), Verified: 2},
+	} {
+		_, err = VerifyChain(ctx, backend, page.Title, GlobalDoVerifyMerkleProof, -1, WithOnChainChecks(false), WithCheckpoint(other))
+		require.True(errors.Is(err, ErrBrokenChain), err.Error())
+	}
+
+	// the revisions after the checkpoint must lead to the latest revision
+	_, err = VerifyChain(ctx, truncatedHashesBackend{backend}, page.Title, GlobalDoVerifyMerkleProof, -1, WithOnChainChecks(false), WithCheckpoint(cp))
+	require.ErrorIs(err, ErrBrokenChain)
+	require.Contains(err.Error(), "don't end at the latest revision")
+}
+
+// truncatedHashesBackend lists the revision hashes of its pages without the
+// latest revision
+type truncatedHashesBackend struct {
+	*fakeBackend
+}
+
+func (b truncatedHashesBackend) GetRevisionHashesContext(ctx context.Context, verification_hash string) ([]*api.RevisionHash, error) {
+	hashes, err := b.fakeBackend.GetRevisionHashesContext(ctx, verification_hash)
+	if err != nil {
+		return nil, err
+	}
+	return hashes[:len(hashes)-1], nil
+}
@@ -0,0 +1,89 @@
+package verify
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/inblockio/aqua-verifier-go/api"
+)
+
+// ContentReconstructor returns the full content of a revision whose content
+// is served as delta against prev, the full content of the previous revision.
+// prev is nil for the genesis revision.
+type ContentReconstructor func(prev, delta *api.RevisionContent) (*api.RevisionContent, error)
+
+// VerifyContentHash returns true if the content hash of content matches its
+// content
+func VerifyContentHash(content *api.RevisionContent, opts ...Option) bool {
+	o := newOptions(opts)
+	return verifyContent(normalizeContent(content, o.contentNormalizer), o.hashEncoding, o.hasher)
+}
+
+// ApplyRCSDelta is a ContentReconstructor for content served as RCS style
+// edit scripts, as produced by diff -n. Every content slot of delta holds the
+// edit script of the same slot of prev; slots without a script are kept
+// unchanged.
+func ApplyRCSDelta(prev, delta *api.RevisionContent) (*api.RevisionContent, error) {
+	full := *delta
+	full.Content = make(map[string]string)
+	if prev != nil {
+		for k, v := range prev.Content {
+			full.Content[k] = v
+		}
+	}
+	for k, script := range delta.Content {
+		c, err := applyRCSScript(full.Content[k], script)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid delta of %s: %w", k, err)
+		}
+		full.Content[k] = c
+	}
+	return &full, nil
+}
+
+// applyRCSScript applies the edit script to base. The script consists of
+// "d<line> <count>" commands, deleting count lines starting at line, and
+// "a<line> <count>" commands followed by count lines to add after line. Line
+// numbers refer to base and commands are in ascending order.
+func applyRCSScript(base, script string) (string, error) {
+	lines := splitLines(base)
+	commands := splitLines(script)
+	var out []string
+	next := 0
+	for i := 0; i < len(commands); i++ {
+		var op byte
+		var line, count int
+		if _, err := fmt.Sscanf(strings.TrimSuffix(commands[i], "\n"), "%c%d %d", &op, &line, &count); err != nil {
+			return "", fmt.Errorf("Invalid command %q", commands[i])
+		}
+		switch op {
+		case 'd':
+			if line-1 < next || line-1+count > len(lines) {
+				return "", fmt.Errorf("Deleting lines %d-%d out of order or range", line, line+count-1)
+			}
+			out = append(out, lines[next:line-1]...)
+			next = line - 1 + count
+		case 'a':
+			if line < next || line > len(lines) || i+count >= len(commands) {
+				return "", fmt.Errorf("Adding %d lines after line %d out of order or range", count, line)
+			}
+			out = append(out, lines[next:line]...)
+			next = line
+			out = append(out, commands[i+1:i+1+count]...)
+			i += count
+		default:
+			return "", fmt.Errorf("Invalid command %q", commands[i])
+		}
+	}
+	out = append(out, lines[next:]...)
+	return strings.Join(out, ""), nil
+}
+
+// splitLines splits s after every newline
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
@@ -0,0 +1,90 @@
+package verify
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/inblockio/aqua-verifier-go/api"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyRCSScript(t *testing.T) {
+	require := require.New(t)
+	base := "one\ntwo\nthree\nfour\n"
+	for _, tc := range []struct {
+		script, expected string
+	}{
+		{"", base},
+		{"a0 1\nzero\n", "zero\none\ntwo\nthree\nfour\n"},
+		{"d2 1\na2 1\nTWO\n", "one\nTWO\nthree\nfour\n"},
+		{"d1 2\na4 2\nfive\nsix", "three\nfour\nfive\nsix"},
+		{"d1 4", ""},
+	} {
+		actual, err := applyRCSScript(base, tc.script)
+		require.NoError(err, tc.script)
+		require.Equal(tc.expected, actual, tc.script)
+	}
+	for _, script := range []string{"x1 1\n", "d3 1\nd1 1\n", "d4 2\n", "a5 1\nfive\n", "a1 2\nonly one\n"} {
+		_, err := applyRCSScript(base, script)
+		require.Error(err, script)
+	}
+}
+
+// replacementDelta returns an edit script replacing all of base with content
+func replacementDelta(base, content string) string {
+	n := len(splitLines(base))
+	script := fmt.Sprintf("a%d %d\n%s", n, len(splitLines(content)), content)
+	if n > 0 {
+		script = fmt.Sprintf("d1 %d\n", n) + script
+	}
+	return script
+}
+
+func TestWithContentReconstructor(t *testing.T) {
+	require := require.New(t)
+	data, err := jsonDecodeFixture(fixture)
+	require.NoError(err)
+	page := data.Pages[0]
+	set, _, err := getVerificationSet(page, -1)
+	require.NoError(err)
+
+	// serve the content of the first two revisions as deltas
+	first, second := set[0], set[1]
+	delta := map[string]string{}
+	for k, v := range second.Content.Content {
+		delta[k] = replacementDelta(first.Content.Content[k], v)
+	}
+	for k, v := range first.Content.Content {
+		first.Content.Content[k] = replacementDelta("", v)
+	}
+	second.Content.Content = delta
+	require.False(VerifyContentHash(first.Content))
+	chain := &api.HashChain{
+		HashChainInfo: page.HashChainInfo,
+		Revisions: map[string]*api.Revision{
+			first.Metadata.VerificationHash:  first,
+			second.Metadata.VerificationHash: second,
+		},
+	}
+	chain.LatestVerificationHash = second.Metadata.VerificationHash
+
+	result, err := VerifyHashChain(chain, GlobalDoVerifyMerkleProof, -1, WithOnChainChecks(false))
+	require.NoError(err)
+	require.EqualError(result.Revisions[0].Error, "Content hash doesn't match")
+
+	result, err = VerifyHashChain(chain, GlobalDoVerifyMerkleProof, -1,
+		WithOnChainChecks(false), WithContentReconstructor(ApplyRCSDelta))
+	require.NoError(err)
+	require.Len(result.Revisions, 2)
+	require.True(result.Valid())
+	// the served revisions are left as they are
+	require.Equal(delta, second.Content.Content)
+
+	_, err = VerifyHashChain(chain, GlobalDoVerifyMerkleProof, 1, WithContentReconstructor(ApplyRCSDelta))
+	require.EqualError(err, "Reconstructing content requires verifying from the genesis revision")
+
+	second.Content.Content["main"] = "d1 1000\n"
+	_, err = VerifyHashChain(chain, GlobalDoVerifyMerkleProof, -1, WithContentReconstructor(ApplyRCSDelta))
+	require.Error(err)
+	require.Contains(err.Error(), "Failure reconstructing content of revision "+second.Metadata.VerificationHash)
+}
@@ -0,0 +1,90 @@
+package verify
+
+import (
+	"fmt"
+
+	"github.com/inblockio/aqua-verifier-go/api"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// DiffContent returns a unified diff of the main content of expected and
+// actual, or an empty string if they are identical.
+func DiffContent(expected, actual *api.RevisionContent) string {
+	var a, b string
+	if expected != nil {
+		a = expected.Content["main"]
+	}
+	if actual != nil {
+		b = actual.Content["main"]
+	}
+	if a == b {
+		return ""
+	}
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(a),
+		B:        difflib.SplitLines(b),
+		FromFile: "expected",
+		ToFile:   "actual",
+		Context:  3,
+	})
+	if err != nil {
+		return err.Error()
+	}
+	return diff
+}
+
+// ChainDiff describes how two copies of a hash chain relate to each other
+type ChainDiff struct {
+	// ForkPoint is the verification hash of the last revision both chains
+	// share, empty if they don't even share the genesis revision
+	ForkPoint string
+	// OnlyA and OnlyB are the revisions following ForkPoint in either chain,
+	// ordered from oldest to newest
+	OnlyA []*api.Revision
+	OnlyB []*api.Revision
+}
+
+// Forked returns true if both chains have revisions the other doesn't have,
+// i.e. neither chain is a prefix of the other one
+func (d *ChainDiff) Forked() bool {
+	return len(d.OnlyA) > 0 && len(d.OnlyB) > 0
+}
+
+// DiffChains compares the chains a and b, ordered from oldest to newest, by
+// their verification hashes. A chain that merely lags behind has no revisions
+// unique to it, while a chain whose history was rewritten forks off after
+// the last revision both chains share. The revisions themselves are not
+// verified. An error is returned if a or b is not a chain of revisions.
+func DiffChains(a, b []*api.Revision) (*ChainDiff, error) {
+	for _, chain := range [][]*api.Revision{a, b} {
+		if err := checkLinked(chain); err != nil {
+			return nil, err
+		}
+	}
+	d := &ChainDiff{}
+	i := 0
+	for ; i < len(a) && i < len(b); i++ {
+		if a[i].Metadata.VerificationHash != b[i].Metadata.VerificationHash {
+			break
+		}
+		d.ForkPoint = a[i].Metadata.VerificationHash
+	}
+	d.OnlyA = a[i:]
+	d.OnlyB = b[i:]
+	return d, nil
+}
+
+// checkLinked returns an error unless every revision of chain is the
+// previous revision of the one following it
+func checkLinked(chain []*api.Revision) error {
+	for i, r := range chain {
+		if r.Metadata == nil {
+			return fmt.Errorf("Revision %d has no metadata", i)
+		}
+		if i > 0 && r.Metadata.PreviousVerificationHash != chain[i-1].Metadata.VerificationHash {
+			return newVerificationError(ErrBrokenChain, "Revision %s doesn't follow revision %s",
+				r.Metadata.VerificationHash, chain[i-1].Metadata.VerificationHash)
+		}
+	}
+	return nil
+}
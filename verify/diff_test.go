@@ -0,0 +1,96 @@
+package verify
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/inblockio/aqua-verifier-go/api"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffContent(t *testing.T) {
+	require := require.New(t)
+	expected := &api.RevisionContent{Content: map[string]string{"main": "first line\nsecond line\nthird line"}}
+	require.Empty(DiffContent(expected, expected))
+
+	actual := &api.RevisionContent{Content: map[string]string{"main": "first line\nchanged line\nthird line"}}
+	require.Equal(`--- expected
++++ actual
+@@ -1,3 +1,3 @@
+ first line
+-second line
++changed line
+ third line
+`, DiffContent(expected, actual))
+}
+
+func TestVerifyRevisionWithExpectedContentDiff(t *testing.T) {
+	require := require.New(t)
+	first, _, err := get1st2ndFixtureVerStructure()
+	require.NoError(err)
+	original := *first.Content
+	original.Content = map[string]string{}
+	for k, v := range first.Content.Content {
+		original.Content[k] = v
+	}
+
+	result, err := VerifyRevisionWithExpected(first, ExpectedHashes{Content: &original})
+	require.NoError(err)
+	require.Empty(result.ContentDiff)
+
+	first.Content.Content["main"] = "tampered\n" + first.Content.Content["main"]
+	result, err = VerifyRevisionWithExpected(first, ExpectedHashes{Content: &original})
+	require.EqualError(err, "Content hash doesn't match")
+	require.Contains(result.ContentDiff, "+tampered\n")
+}
+
+func TestDiffChains(t *testing.T) {
+	require := require.New(t)
+	chain, err := getFixtureVerificationSet()
+	require.NoError(err)
+	latest := chain[len(chain)-1]
+
+	d, err := DiffChains(chain, chain)
+	require.NoError(err)
+	require.Equal(latest.Metadata.VerificationHash, d.ForkPoint)
+	require.Empty(d.OnlyA)
+	require.Empty(d.OnlyB)
+	require.False(d.Forked())
+
+	// b lags behind a
+	lagging := chain[:len(chain)-2]
+	d, err = DiffChains(chain, lagging)
+	require.NoError(err)
+	require.Equal(lagging[len(lagging)-1].Metadata.VerificationHash, d.ForkPoint)
+	require.Equal(chain[len(chain)-2:], d.OnlyA)
+	require.Empty(d.OnlyB)
+	require.False(d.Forked())
+
+	// the history of b was rewritten after the first revision
+	rewritten := make([]*api.Revision, len(chain))
+	prev := ""
+	for i, r := range chain {
+		metadata := *r.Metadata
+		if i > 0 {
+			metadata.PreviousVerificationHash = prev
+			metadata.VerificationHash = "rewritten" + metadata.VerificationHash
+		}
+		prev = metadata.VerificationHash
+		rewritten[i] = &api.Revision{Metadata: &metadata}
+	}
+	d, err = DiffChains(chain, rewritten)
+	require.NoError(err)
+	require.Equal(chain[0].Metadata.VerificationHash, d.ForkPoint)
+	require.Equal(chain[1:], d.OnlyA)
+	require.Equal(rewritten[1:], d.OnlyB)
+	require.True(d.Forked())
+
+	// chains with different genesis revisions share nothing
+	d, err = DiffChains(chain[:1], []*api.Revision{{Metadata: &api.RevisionMetadata{VerificationHash: "other"}}})
+	require.NoError(err)
+	require.Empty(d.ForkPoint)
+	require.True(d.Forked())
+
+	_, err = DiffChains([]*api.Revision{chain[0], chain[2]}, chain)
+	require.True(errors.Is(err, ErrBrokenChain))
+}
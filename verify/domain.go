@@ -0,0 +1,47 @@
+package verify
+
+import "github.com/inblockio/aqua-verifier-go/api"
+
+// DomainIdConflict is a revision whose domain id differs from the domain id
+// of its chain. All revisions of a page share the domain id of the wiki they
+// were created on, so a conflict hints at merged or spoofed histories.
+type DomainIdConflict struct {
+	VerificationHash string `json:"verification_hash"`
+	DomainId         string `json:"domain_id"`
+	ChainDomainId    string `json:"chain_domain_id"`
+}
+
+// CheckDomainId returns the revisions of data, ordered from oldest to newest,
+// whose domain id differs from the domain id the server reports for the
+// chain, or from the domain id of the genesis revision if the server doesn't
+// report one.
+func CheckDomainId(data *api.HashChain) ([]DomainIdConflict, error) {
+	verificationSet, _, err := getVerificationSet(data, -1)
+	if err != nil {
+		return nil, err
+	}
+	return domainIdConflicts(data.DomainId, verificationSet), nil
+}
+
+// domainIdConflicts returns the revisions of verificationSet whose domain id
+// differs from chainDomainId, or from the domain id of the oldest revision if
+// chainDomainId is empty
+func domainIdConflicts(chainDomainId string, verificationSet []*api.Revision) []DomainIdConflict {
+	var conflicts []DomainIdConflict
+	for _, r := range verificationSet {
+		if r.Metadata == nil {
+			continue
+		}
+		if chainDomainId == "" {
+			chainDomainId = r.Metadata.DomainId
+		}
+		if r.Metadata.DomainId != chainDomainId {
+			conflicts = append(conflicts, DomainIdConflict{
+				VerificationHash: r.Metadata.VerificationHash,
+				DomainId:         r.Metadata.DomainId,
+				ChainDomainId:    chainDomainId,
+			})
+		}
+	}
+	return conflicts
+}
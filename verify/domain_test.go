@@ -0,0 +1,82 @@
+package verify
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/inblockio/aqua-verifier-go/api"
+	"github.com/stretchr/testify/require"
+)
+
+// newDomainChain returns a consistent chain with a revision of every domain id
+func newDomainChain(require *require.Assertions, domainIds ...string) *api.HashChain {
+	page := &api.HashChain{HashChainInfo: api.HashChainInfo{Title: "Main Page"}, Revisions: map[string]*api.Revision{}}
+	var prev *api.Revision
+	for i, domainId := range domainIds {
+		content := &api.RevisionContent{Content: map[string]string{"main": "Revision of " + domainId}}
+		_, content.ContentHash = ExplainContentHash(content)
+		metadata := &api.RevisionMetadata{
+			DomainId:                 domainId,
+			Timestamp:                api.Timestamp{Time: time.Date(2022, 1, 1, 0, 0, i, 0, time.UTC)},
+			PreviousVerificationHash: prev.VerificationHash(),
+		}
+		_, metadata.MetadataHash = ExplainMetadataHash(metadata)
+		r := &api.Revision{Context: &api.VerificationContext{}, Content: content, Metadata: metadata}
+		var err error
+		metadata.VerificationHash, err = ComputeVerificationHash(r, prev)
+		require.NoError(err)
+		page.Revisions[metadata.VerificationHash] = r
+		if prev == nil {
+			page.GenesisHash = metadata.VerificationHash
+		}
+		prev = r
+	}
+	page.LatestVerificationHash = prev.VerificationHash()
+	page.ChainHeight = len(domainIds)
+	return page
+}
+
+func TestCheckDomainId(t *testing.T) {
+	require := require.New(t)
+	data, err := jsonDecodeFixture(fixture)
+	require.NoError(err)
+	conflicts, err := CheckDomainId(data.Pages[0])
+	require.NoError(err)
+	require.Empty(conflicts)
+
+	page := newDomainChain(require, "5e5a1ec586", "5e5a1ec586", "5e5a1ec586")
+	conflicts, err = CheckDomainId(page)
+	require.NoError(err)
+	require.Empty(conflicts)
+
+	// a middle revision of another domain
+	page = newDomainChain(require, "5e5a1ec586", "spoofed", "5e5a1ec586")
+	set, _, err := getVerificationSet(page, -1)
+	require.NoError(err)
+	conflicts, err = CheckDomainId(page)
+	require.NoError(err)
+	require.Equal([]DomainIdConflict{{VerificationHash: set[1].VerificationHash(), DomainId: "spoofed", ChainDomainId: "5e5a1ec586"}}, conflicts)
+
+	result, err := VerifyHashChain(page, GlobalDoVerifyMerkleProof, -1, WithOnChainChecks(false))
+	require.NoError(err)
+	for _, r := range result.Revisions {
+		require.True(r.Valid())
+	}
+	require.False(result.Valid())
+	require.True(errors.Is(result.Err(), ErrDomainIdMismatch))
+	require.EqualError(result.Err(), "Revision "+set[1].VerificationHash()+" has domain id spoofed, other than the domain id 5e5a1ec586 of the chain")
+
+	// the domain id reported by the server takes precedence over the genesis
+	page.DomainId = "spoofed"
+	conflicts, err = CheckDomainId(page)
+	require.NoError(err)
+	require.Len(conflicts, 2)
+	require.Equal("spoofed", conflicts[0].ChainDomainId)
+	require.Equal(set[0].VerificationHash(), conflicts[0].VerificationHash)
+
+	// a broken chain
+	delete(page.Revisions, set[1].VerificationHash())
+	_, err = CheckDomainId(page)
+	require.True(errors.Is(err, ErrBrokenChain))
+}
@@ -0,0 +1,119 @@
+package verify
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"strings"
+
+	"github.com/inblockio/aqua-verifier-go/api"
+)
+
+// HashEncoding is the encoding of the hashes served by an aqua server. Hashes
+// are decoded and normalized to lower case hex before they are compared to
+// the calculated ones.
+type HashEncoding string
+
+const (
+	// HashEncodingHex is the encoding defined by the protocol and the default
+	HashEncodingHex HashEncoding = "hex"
+	// HashEncodingBase64 is standard base64, with or without padding
+	HashEncodingBase64 HashEncoding = "base64"
+	// HashEncodingBase58 is base58 with the bitcoin alphabet. A base58 hash
+	// may be a SHA3-512 multihash.
+	HashEncodingBase58 HashEncoding = "base58"
+	// HashEncodingAuto detects the encoding of every hash
+	HashEncodingAuto HashEncoding = "auto"
+
+	hashLength = 64
+	// multihash code and digest length of SHA3-512
+	multihashSHA3_512 = 0x14
+)
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// Decode returns the digest encoded in s
+func (e HashEncoding) Decode(s string) ([]byte, error) {
+	switch e {
+	case "", HashEncodingHex:
+		return hex.DecodeString(s)
+	case HashEncodingBase64:
+		return base64.RawStdEncoding.DecodeString(strings.TrimRight(s, "="))
+	case HashEncodingBase58:
+		b, err := decodeBase58(s)
+		if err != nil {
+			return nil, err
+		}
+		if len(b) == hashLength+2 && b[0] == multihashSHA3_512 && b[1] == hashLength {
+			b = b[2:]
+		}
+		return b, nil
+	case HashEncodingAuto:
+		for _, enc := range []HashEncoding{HashEncodingHex, HashEncodingBase64, HashEncodingBase58} {
+			if b, err := enc.Decode(s); err == nil && len(b) == hashLength {
+				return b, nil
+			}
+		}
+		return nil, errors.New("Unknown hash encoding")
+	}
+	return nil, errors.New("Unsupported hash encoding " + string(e))
+}
+
+// normalize returns s as lower case hex. If s cannot be decoded it is returned
+// unchanged, so that it doesn't match any calculated hash.
+func (e HashEncoding) normalize(s string) string {
+	if s == "" || e == "" || e == HashEncodingHex {
+		return s
+	}
+	b, err := e.Decode(s)
+	if err != nil {
+		return s
+	}
+	return hex.EncodeToString(b)
+}
+
+// normalizeWitness returns w with its hashes and the nodes of its merkle proof
+// normalized, see normalize. w itself is returned if it is nil or the hashes
+// are hex, otherwise a copy. The transaction hash is not a hash of the
+// protocol and is kept as is.
+func (e HashEncoding) normalizeWitness(w *api.RevisionWitness) *api.RevisionWitness {
+	if w == nil || e == "" || e == HashEncodingHex {
+		return w
+	}
+	c := *w
+	c.DomainSnapshotGenesisHash = e.normalize(w.DomainSnapshotGenesisHash)
+	c.MerkleRoot = e.normalize(w.MerkleRoot)
+	c.WitnessEventVerificationHash = e.normalize(w.WitnessEventVerificationHash)
+	c.WitnessHash = e.normalize(w.WitnessHash)
+	if w.MerkleProof != nil {
+		c.MerkleProof = make([]*api.MerkleNode, len(w.MerkleProof))
+		for i, n := range w.MerkleProof {
+			if n == nil {
+				continue
+			}
+			node := *n
+			node.LeftLeaf = e.normalize(n.LeftLeaf)
+			node.RightLeaf = e.normalize(n.RightLeaf)
+			node.Successor = e.normalize(n.Successor)
+			c.MerkleProof[i] = &node
+		}
+	}
+	return &c
+}
+
+func decodeBase58(s string) ([]byte, error) {
+	n := new(big.Int)
+	radix := big.NewInt(58)
+	for _, c := range s {
+		i := strings.IndexRune(base58Alphabet, c)
+		if i < 0 {
+			return nil, errors.New("Invalid base58 character " + string(c))
+		}
+		n.Mul(n, radix)
+		n.Add(n, big.NewInt(int64(i)))
+	}
+	// leading zero bytes are encoded as leading '1's
+	zeros := len(s) - len(strings.TrimLeft(s, "1"))
+	return append(make([]byte, zeros), n.Bytes()...), nil
+}
@@ -0,0 +1,149 @@
+package verify
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/inblockio/aqua-verifier-go/api"
+	"github.com/stretchr/testify/require"
+)
+
+func encodeBase58(b []byte) string {
+	n := new(big.Int).SetBytes(b)
+	radix := big.NewInt(58)
+	mod := new(big.Int)
+	var out []byte
+	for n.Sign() > 0 {
+		n.DivMod(n, radix, mod)
+		out = append([]byte{base58Alphabet[mod.Int64()]}, out...)
+	}
+	for _, c := range b {
+		if c != 0 {
+			break
+		}
+		out = append([]byte{'1'}, out...)
+	}
+	return string(out)
+}
+
+func TestHashEncodingDecode(t *testing.T) {
+	require := require.New(t)
+	digest, err := hex.DecodeString(getHashSum("aqua"))
+	require.NoError(err)
+	multihash := append([]byte{multihashSHA3_512, hashLength}, digest...)
+
+	for _, tc := range []struct {
+		enc     HashEncoding
+		encoded string
+	}{
+		{HashEncodingHex, hex.EncodeToString(digest)},
+		{"", hex.EncodeToString(digest)},
+		{HashEncodingBase64, base64.StdEncoding.EncodeToString(digest)},
+		{HashEncodingBase64, base64.RawStdEncoding.EncodeToString(digest)},
+		{HashEncodingBase58, encodeBase58(digest)},
+		{HashEncodingBase58, encodeBase58(multihash)},
+		{HashEncodingAuto, hex.EncodeToString(digest)},
+		{HashEncodingAuto, base64.StdEncoding.EncodeToString(digest)},
+		{HashEncodingAuto, encodeBase58(digest)},
+	} {
+		decoded, err := tc.enc.Decode(tc.encoded)
+		require.NoError(err, tc.encoded)
+		require.Equal(digest, decoded, tc.encoded)
+	}
+
+	_, err = HashEncodingBase58.Decode("0OIl")
+	require.Error(err)
+	_, err = HashEncodingAuto.Decode("not a hash")
+	require.Error(err)
+	_, err = HashEncoding("base32").Decode("abc")
+	require.Error(err)
+
+	require.Equal([]byte{0, 0, 1}, must(decodeBase58("112")))
+}
+
+func must(b []byte, err error) []byte {
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// reencode replaces the revision hashes of r with their encoding using encode
+func reencode(r *api.Revision, encode func([]byte) string) {
+	var hashes []*string
+	for _, h := range []*string{
+		&r.Content.ContentHash,
+		&r.Metadata.MetadataHash,
+		&r.Metadata.VerificationHash,
+		&r.Metadata.PreviousVerificationHash,
+	} {
+		hashes = append(hashes, h)
+	}
+	if r.Signature != nil {
+		hashes = append(hashes, &r.Signature.SignatureHash)
+	}
+	if w := r.Witness; w != nil {
+		hashes = append(hashes, &w.WitnessHash, &w.DomainSnapshotGenesisHash, &w.MerkleRoot, &w.WitnessEventVerificationHash)
+		for _, n := range w.MerkleProof {
+			hashes = append(hashes, &n.LeftLeaf, &n.RightLeaf, &n.Successor)
+		}
+	}
+	for _, h := range hashes {
+		if *h != "" {
+			*h = encode(must(hex.DecodeString(*h)))
+		}
+	}
+}
+
+func TestWithHashEncoding(t *testing.T) {
+	for _, tc := range []struct {
+		enc    HashEncoding
+		encode func([]byte) string
+	}{
+		{HashEncodingHex, hex.EncodeToString},
+		{HashEncodingBase64, base64.StdEncoding.EncodeToString},
+		{HashEncodingBase58, encodeBase58},
+		{HashEncodingAuto, encodeBase58},
+	} {
+		t.Run(string(tc.enc), func(t *testing.T) {
+			require := require.New(t)
+			first, second, err := get1st2ndFixtureVerStructure()
+			require.NoError(err)
+			reencode(first, tc.encode)
+			reencode(second, tc.encode)
+			require.True(second.HasPreviousSignature())
+			require.True(second.HasPreviousWitness())
+
+			for _, r := range [][2]*api.Revision{{first, nil}, {second, first}} {
+				isCorrect, result := verifyRevision(r[0], r[1], GlobalDoVerifyMerkleProof,
+					WithOnChainChecks(false), WithHashEncoding(tc.enc))
+				require.NoError(result.Error)
+				require.True(isCorrect)
+				require.Equal(VERIFIED_VERIFICATION_STATUS, result.Status.Verification)
+				if r[0].Witness != nil {
+					require.Equal(NOT_CHECKED_STATUS, result.Status.Witness)
+					require.True(result.WitnessResult.WitnessEventVHMatches)
+				}
+			}
+			require.NotNil(first.Witness)
+			hash, err := ComputeVerificationHash(second, first, WithHashEncoding(tc.enc))
+			require.NoError(err)
+			require.Equal(tc.enc.normalize(second.Metadata.VerificationHash), hash)
+
+			// pins are decoded with the same encoding
+			pinned := tc.encode(must(hex.DecodeString(calculateContentHash(nil, first.Content))))
+			_, err = VerifyRevisionWithExpected(first, ExpectedHashes{ContentHash: pinned}, WithHashEncoding(tc.enc))
+			require.NoError(err)
+		})
+	}
+
+	// hashes that are not hex don't verify without the encoding
+	require := require.New(t)
+	first, _, err := get1st2ndFixtureVerStructure()
+	require.NoError(err)
+	reencode(first, encodeBase58)
+	_, result := verifyRevision(first, nil, GlobalDoVerifyMerkleProof, WithOnChainChecks(false))
+	require.EqualError(result.Error, "Metadata hash doesn't match")
+}
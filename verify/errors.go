@@ -0,0 +1,132 @@
+package verify
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors reported for the common verification failures. The errors of a
+// RevisionVerificationResult or ChainVerificationResult wrap them, so that
+// they can be told apart with errors.Is.
+var (
+	ErrContentHashMismatch      = errors.New("Content hash doesn't match")
+	ErrMetadataHashMismatch     = errors.New("Metadata hash doesn't match")
+	ErrVerificationHashMismatch = errors.New("Verification hash doesn't match")
+	ErrSignatureInvalid         = errors.New("Signature is invalid")
+	ErrWitnessMismatch          = errors.New("Witness doesn't match")
+	// ErrBrokenChain is wrapped by failures to link a revision to its
+	// previous revision
+	ErrBrokenChain = errors.New("Chain is broken")
+	// ErrTimestampOutOfOrder is reported for a revision that is older than
+	// its previous revision, which hints at reordered or replayed revisions
+	ErrTimestampOutOfOrder = errors.New("Timestamp is before the previous revision")
+	// ErrTimestampInFuture is reported for a revision dated later than the
+	// current time, allowing for clock skew of up to maxClockSkew
+	ErrTimestampInFuture = errors.New("Timestamp is in the future")
+	// ErrNoTrustAnchor is reported for a chain that is not anchored in any of
+	// the roots passed to WithIndependentRoots
+	ErrNoTrustAnchor = errors.New("Chain is not anchored in a trusted root")
+	// ErrWitnessPredatesRevision is reported for a witness transaction that
+	// was mined before the revision it witnesses was created, i.e. a
+	// backdated witness
+	ErrWitnessPredatesRevision = errors.New("Witness predates the revision")
+	// ErrMalformedTransactionHash is reported for a witness whose transaction
+	// hash is not a 32 byte hex hash
+	ErrMalformedTransactionHash = errors.New("Witness transaction hash is malformed")
+	// ErrWitnessNetworkMismatch is reported for a witness transaction that
+	// doesn't exist on the network the witness claims, but on another one
+	ErrWitnessNetworkMismatch = errors.New("Witness transaction is on another network")
+	// ErrChainTooHigh is reported by VerifyChain for a chain with more
+	// revisions than allowed by WithMaxChainHeight
+	ErrChainTooHigh = errors.New("Chain exceeds the maximum height")
+	// ErrGenesisMismatch is reported for a chain whose advertised genesis
+	// hash is not the verification hash of its genesis revision
+	ErrGenesisMismatch = errors.New("Genesis hash doesn't match the genesis revision")
+	// ErrDomainIdMismatch is reported for a chain with revisions of
+	// different domain ids, see CheckDomainId
+	ErrDomainIdMismatch = errors.New("Domain id differs within the chain")
+	// ErrWitnessMissing and ErrSignatureMissing are reported for revisions
+	// that are not witnessed or signed, if required by WithRequireWitness or
+	// WithRequireSignature
+	ErrWitnessMissing   = errors.New("Witness is missing")
+	ErrSignatureMissing = errors.New("Signature is missing")
+	// ErrUnsupportedSignature is reported for signatures of a format no
+	// SignatureVerifier is available for
+	ErrUnsupportedSignature = errors.New("Signature format is not supported")
+	// ErrUnsupportedAPIVersion is reported by VerifyChain for a server whose
+	// api version is not allowed by WithAllowedAPIVersions
+	ErrUnsupportedAPIVersion = errors.New("API version is not supported")
+	// ErrSignerNotAllowed is reported by VerifySignersAllowed for a revision
+	// signed by a wallet that is not allowed to sign
+	ErrSignerNotAllowed = errors.New("Signer is not allowed")
+)
+
+// verificationError is an error of the given kind with its own message
+type verificationError struct {
+	msg  string
+	kind error
+}
+
+func newVerificationError(kind error, format string, a ...interface{}) error {
+	return &verificationError{msg: fmt.Sprintf(format, a...), kind: kind}
+}
+
+func (e *verificationError) Error() string {
+	return e.msg
+}
+
+func (e *verificationError) Unwrap() error {
+	return e.kind
+}
+
+// Err returns nil if the revision verified successfully, or an error wrapping
+// the errors of every check that failed
+func (r *RevisionVerificationResult) Err() error {
+	if r.Error != nil {
+		return r.Error
+	}
+	var errs []error
+	switch {
+	case r.Reason == ReasonUnboundContent:
+		errs = append(errs, newVerificationError(ErrVerificationHashMismatch, "Verification hash doesn't bind the content hash to the metadata hash"))
+	case r.Status.Verification != VERIFIED_VERIFICATION_STATUS:
+		errs = append(errs, ErrVerificationHashMismatch)
+	}
+	if r.Status.Signature == "INVALID" {
+		// a SignatureError of another kind, e.g. ErrUnsupportedSignature,
+		// still reports the signature as invalid
+		if r.SignatureError == nil || !errors.Is(r.SignatureError, ErrSignatureInvalid) {
+			errs = append(errs, ErrSignatureInvalid)
+		}
+		if r.SignatureError != nil {
+			errs = append(errs, r.SignatureError)
+		}
+	}
+	if r.Status.Witness == "INVALID" {
+		errs = append(errs, ErrWitnessMismatch)
+	}
+	return errors.Join(errs...)
+}
+
+// Err returns nil if every revision of the chain verified successfully, or an
+// error wrapping the error of every revision that failed
+func (c *ChainVerificationResult) Err() error {
+	var errs []error
+	for _, r := range c.Revisions {
+		if err := r.Err(); err != nil {
+			errs = append(errs, fmt.Errorf("Revision %s: %w", r.VerificationHash, err))
+		}
+	}
+	if c.requireAnchor && c.TrustAnchor == "" {
+		errs = append(errs, ErrNoTrustAnchor)
+	}
+	if c.ActualGenesisHash != "" {
+		errs = append(errs, newVerificationError(ErrGenesisMismatch, "Genesis hash %s differs from the verification hash %s of the genesis revision",
+			c.GenesisHash, c.ActualGenesisHash))
+	}
+	for _, d := range c.DomainIdConflicts {
+		errs = append(errs, newVerificationError(ErrDomainIdMismatch, "Revision %s has domain id %s, other than the domain id %s of the chain",
+			d.VerificationHash, d.DomainId, d.ChainDomainId))
+	}
+	return errors.Join(errs...)
+}
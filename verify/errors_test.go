@@ -0,0 +1,101 @@
+package verify
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/inblockio/aqua-verifier-go/api"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerificationErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		tamper   func(first, second *api.Revision) *api.Revision
+		expected error
+	}{
+		{"content", func(first, second *api.Revision) *api.Revision {
+			second.Content.Content["main"] += "tampered"
+			return second
+		}, ErrContentHashMismatch},
+		{"metadata", func(first, second *api.Revision) *api.Revision {
+			second.Metadata.DomainId = "tampered"
+			return second
+		}, ErrMetadataHashMismatch},
+		{"verification", func(first, second *api.Revision) *api.Revision {
+			second.Metadata.VerificationHash = "tampered"
+			return second
+		}, ErrVerificationHashMismatch},
+		{"signature", func(first, second *api.Revision) *api.Revision {
+			first.Signature.WalletAddress = testContractWallet
+			return first
+		}, ErrSignatureInvalid},
+		{"witness", func(first, second *api.Revision) *api.Revision {
+			first.Witness.WitnessEventVerificationHash = "tampered"
+			return first
+		}, ErrWitnessMismatch},
+		{"previous signature", func(first, second *api.Revision) *api.Revision {
+			first.Signature.SignatureHash = "tampered"
+			return second
+		}, ErrBrokenChain},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+			first, second, err := get1st2ndFixtureVerStructure()
+			require.NoError(err)
+			r := tc.tamper(first, second)
+			var prev *api.Revision
+			if r == second {
+				prev = first
+			}
+			_, result := verifyRevision(r, prev, GlobalDoVerifyMerkleProof, WithOnChainChecks(false))
+			err = result.Err()
+			require.Error(err)
+			require.True(errors.Is(err, tc.expected), err.Error())
+			for _, other := range []error{ErrContentHashMismatch, ErrMetadataHashMismatch, ErrSignatureInvalid, ErrWitnessMismatch, ErrBrokenChain} {
+				if other != tc.expected {
+					require.False(errors.Is(err, other), other.Error())
+				}
+			}
+		})
+	}
+}
+
+func TestVerificationErrorsWrapped(t *testing.T) {
+	require := require.New(t)
+	data, err := jsonDecodeFixture(fixture)
+	require.NoError(err)
+	page := data.Pages[0]
+
+	result, err := VerifyHashChain(page, GlobalDoVerifyMerkleProof, -1, WithOnChainChecks(false))
+	require.NoError(err)
+	require.NoError(result.Err())
+	for _, r := range result.Revisions {
+		require.NoError(r.Err())
+	}
+
+	latest := page.Revisions[page.LatestVerificationHash]
+	latest.Content.Content["main"] = "tampered"
+	result, err = VerifyHashChain(page, GlobalDoVerifyMerkleProof, -1, WithOnChainChecks(false))
+	require.NoError(err)
+	require.True(errors.Is(result.Err(), ErrContentHashMismatch))
+	require.EqualError(result.Err(), "Revision "+page.LatestVerificationHash+": Content hash doesn't match")
+
+	first, _, err := get1st2ndFixtureVerStructure()
+	require.NoError(err)
+	_, err = VerifyHashChain(&api.HashChain{
+		HashChainInfo: api.HashChainInfo{LatestVerificationHash: "missing"},
+		Revisions:     map[string]*api.Revision{first.Metadata.VerificationHash: first},
+	}, GlobalDoVerifyMerkleProof, -1)
+	require.True(errors.Is(err, ErrBrokenChain))
+	require.EqualError(err, "Failure getting revision missing")
+
+	_, err = VerifyRevisionWithExpected(first, ExpectedHashes{ContentHash: "a", VerificationHash: "b"})
+	require.EqualError(err, "Pinned content, verification hash doesn't match")
+	require.True(errors.Is(err, ErrContentHashMismatch))
+	require.True(errors.Is(err, ErrVerificationHashMismatch))
+	require.False(errors.Is(err, ErrMetadataHashMismatch))
+
+	first.Context.HasPreviousSignature = true
+	require.True(errors.Is(VerifySignerContinuity([]*api.Revision{first}), ErrBrokenChain))
+}
@@ -0,0 +1,70 @@
+package verify
+
+import (
+	"errors"
+
+	"github.com/inblockio/aqua-verifier-go/api"
+)
+
+// ExplainContentHash returns the exact bytes the content hash of c is
+// calculated from, together with the resulting hash. The preimage is the
+// concatenation of the content slots ordered by their name, followed by the
+// salt, if any. The hash is computed with SHA3-512 unless another algorithm
+// is selected with WithHashRegistry.
+func ExplainContentHash(c *api.RevisionContent, opts ...Option) (preimage []byte, hash string) {
+	p := contentPreimage(c)
+	return []byte(p), newOptions(opts).hasher.sum(p)
+}
+
+// ExplainMetadataHash returns the exact bytes the metadata hash of m is
+// calculated from, together with the resulting hash. The preimage is the
+// concatenation of the domain id, the timestamp in the 20060102150405 layout
+// and the previous verification hash, which is empty for a genesis revision.
+// Like ExplainContentHash, the hash is computed with the algorithm selected
+// with WithHashRegistry.
+func ExplainMetadataHash(m *api.RevisionMetadata, opts ...Option) (preimage []byte, hash string) {
+	p := m.DomainId + m.Timestamp.String() + m.PreviousVerificationHash
+	return []byte(p), newOptions(opts).hasher.sum(p)
+}
+
+// ComputeVerificationHash calculates the verification hash of r from scratch,
+// without trusting any of the hashes stored in r or prev. The verification
+// hash is the hash of the concatenation of the content hash, the metadata hash
+// and the signature and witness hashes of the previous revision prev, which
+// are omitted when r doesn't commit to them. prev may be nil for a genesis
+// revision. The content, metadata and verification hashes are computed with
+// the algorithm selected with WithHashRegistry, from the hashes of r and prev
+// decoded with the encoding selected with WithHashEncoding.
+func ComputeVerificationHash(r, prev *api.Revision, opts ...Option) (string, error) {
+	if r.Content == nil || r.Metadata == nil {
+		return "", errors.New("Revision has no content or metadata")
+	}
+	if r.Metadata.PreviousVerificationHash != "" && prev == nil {
+		return "", errors.New("Revision has a previous revision, but none was provided")
+	}
+	o := newOptions(opts)
+	h := o.hasher
+	contentHash := calculateContentHash(h, r.Content)
+	metadataHash := calculateMetadataHash(h, r.Metadata.DomainId, r.Metadata.Timestamp.String(), o.hashEncoding.normalize(r.Metadata.PreviousVerificationHash))
+
+	signatureHash := ""
+	witnessHash := ""
+	if r.HasPreviousSignature() {
+		if prev == nil || prev.Signature == nil {
+			return "", newVerificationError(ErrBrokenChain, "Previous signature data not found")
+		}
+		signatureHash = calculateSignatureHash(prev.Signature.Signature, prev.Signature.PublicKey)
+	}
+	if r.HasPreviousWitness() {
+		if !prev.HasWitness() {
+			return "", newVerificationError(ErrBrokenChain, "Previous witness data not found")
+		}
+		w := o.hashEncoding.normalizeWitness(prev.Witness)
+		witnessHash = calculateWitnessHash(
+			w.DomainSnapshotGenesisHash,
+			w.MerkleRoot,
+			w.WitnessNetwork,
+			w.WitnessEventTransactionHash)
+	}
+	return calculateVerificationHash(h, contentHash, metadataHash, signatureHash, witnessHash), nil
+}
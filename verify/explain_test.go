@@ -0,0 +1,65 @@
+package verify
+
+import (
+	"testing"
+
+	"github.com/inblockio/aqua-verifier-go/api"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExplainHashes(t *testing.T) {
+	require := require.New(t)
+	first, second, err := get1st2ndFixtureVerStructure()
+	require.NoError(err)
+
+	preimage, hash := ExplainContentHash(first.Content)
+	var expected string
+	for _, key := range getSortedKeys(first.Content.Content) {
+		expected += first.Content.Content[key]
+	}
+	require.Equal(expected, string(preimage))
+	require.Equal(first.Content.ContentHash, hash)
+
+	first.Content.Salt = "salt"
+	preimage, hash = ExplainContentHash(first.Content)
+	require.Equal(expected+"salt", string(preimage))
+	require.Equal(getHashSum(expected+"salt"), hash)
+
+	preimage, hash = ExplainMetadataHash(first.Metadata)
+	require.Equal(first.Metadata.DomainId+first.Metadata.Timestamp.String(), string(preimage))
+	require.Len(first.Metadata.Timestamp.String(), len("20060102150405"))
+	require.Equal(first.Metadata.MetadataHash, hash)
+
+	preimage, hash = ExplainMetadataHash(second.Metadata)
+	require.Equal(second.Metadata.DomainId+second.Metadata.Timestamp.String()+first.Metadata.VerificationHash, string(preimage))
+	require.Equal(second.Metadata.MetadataHash, hash)
+}
+
+func TestComputeVerificationHash(t *testing.T) {
+	require := require.New(t)
+	set, err := getFixtureVerificationSet()
+	require.NoError(err)
+
+	var prev *api.Revision
+	for _, r := range set {
+		hash, err := ComputeVerificationHash(r, prev)
+		require.NoError(err)
+		require.Equal(r.Metadata.VerificationHash, hash)
+		prev = r
+	}
+
+	_, err = ComputeVerificationHash(set[1], nil)
+	require.Error(err)
+
+	// stored sub-hashes are not trusted
+	set[1].Content.ContentHash = "wrong"
+	set[0].Signature.SignatureHash = "wrong"
+	hash, err := ComputeVerificationHash(set[1], set[0])
+	require.NoError(err)
+	require.Equal(set[1].Metadata.VerificationHash, hash)
+
+	set[1].Content.Content["main"] += "tampered"
+	hash, err = ComputeVerificationHash(set[1], set[0])
+	require.NoError(err)
+	require.NotEqual(set[1].Metadata.VerificationHash, hash)
+}
@@ -0,0 +1,26 @@
+package verify
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/inblockio/aqua-verifier-go/api"
+)
+
+// VerifyExport verifies every page of an offline export written by
+// api.ExportChain without contacting the server the pages were exported from.
+func VerifyExport(r io.Reader, opts ...Option) ([]*ChainVerificationResult, error) {
+	data := &api.OfflineData{}
+	if err := json.NewDecoder(r).Decode(data); err != nil {
+		return nil, err
+	}
+	results := make([]*ChainVerificationResult, 0, len(data.Pages))
+	for _, page := range data.Pages {
+		result, err := VerifyHashChain(page, true, -1, opts...)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
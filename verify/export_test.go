@@ -0,0 +1,50 @@
+package verify
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/inblockio/aqua-verifier-go/api"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportVerifyExport(t *testing.T) {
+	require := require.New(t)
+	data, err := jsonDecodeFixture(fixture)
+	require.NoError(err)
+	s := newFixtureServer(data)
+	defer s.Close()
+	ap, err := api.NewAPI(s.URL, "")
+	require.NoError(err)
+
+	buf := new(bytes.Buffer)
+	require.NoError(ap.ExportChain(context.Background(), buf, "Main Page"))
+	// timestamps keep the protocol layout
+	require.Contains(buf.String(), `"time_stamp": "20220104075321"`)
+	require.Contains(buf.String(), `"siteInfo"`)
+	exported := buf.String()
+
+	page := data.Pages[0]
+	results, err := VerifyExport(buf, WithOnChainChecks(false))
+	require.NoError(err)
+	require.Len(results, 1)
+	result := results[0]
+	require.True(result.Valid())
+	require.Equal(page.GenesisHash, result.GenesisHash)
+	require.Len(result.Revisions, page.ChainHeight)
+	for _, r := range result.Revisions {
+		require.NoError(r.Error)
+		require.Equal(VERIFIED_VERIFICATION_STATUS, r.Status.Verification)
+	}
+
+	// the export decodes like the fixture it was served from
+	exportData, err := jsonDecodeFixture([]byte(exported))
+	require.NoError(err)
+	require.Equal(page.LatestVerificationHash, exportData.Pages[0].LatestVerificationHash)
+	require.Len(exportData.Pages[0].Revisions, len(page.Revisions))
+	require.Equal(page.SiteInfo, exportData.SiteInfo)
+
+	_, err = VerifyExport(bytes.NewReader(nil))
+	require.Error(err)
+}
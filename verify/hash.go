@@ -0,0 +1,89 @@
+package verify
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"hash"
+
+	"github.com/inblockio/aqua-verifier-go/api"
+	"golang.org/x/crypto/sha3"
+)
+
+// HashSHA3_512 identifies SHA3-512, the hash of the protocol up to api.Version
+const HashSHA3_512 = "sha3-512"
+
+// HashRegistry maps hash algorithm identifiers to the constructors of their
+// hash.Hash, and server api versions to the algorithm the content, metadata
+// and verification hashes of their revisions are computed with, so that
+// archives of chains served by different protocol versions can be verified.
+type HashRegistry struct {
+	algorithms map[string]func() hash.Hash
+	versions   map[string]string
+}
+
+// NewHashRegistry returns a HashRegistry holding HashSHA3_512, which is used
+// for all api versions that are not mapped to another algorithm with Use
+func NewHashRegistry() *HashRegistry {
+	return &HashRegistry{
+		algorithms: map[string]func() hash.Hash{HashSHA3_512: sha3.New512},
+		versions:   make(map[string]string),
+	}
+}
+
+// Register adds algorithm with the constructor of its hash, replacing the
+// constructor registered for algorithm before if there is one
+func (h *HashRegistry) Register(algorithm string, newHash func() hash.Hash) {
+	h.algorithms[algorithm] = newHash
+}
+
+// Use makes revisions served by apiVersion be hashed with algorithm, which
+// must have been registered
+func (h *HashRegistry) Use(apiVersion, algorithm string) error {
+	if _, ok := h.algorithms[algorithm]; !ok {
+		return fmt.Errorf("Unknown hash algorithm %s", algorithm)
+	}
+	h.versions[apiVersion] = algorithm
+	return nil
+}
+
+// Algorithm returns the algorithm revisions served by apiVersion are hashed
+// with
+func (h *HashRegistry) Algorithm(apiVersion string) string {
+	if algorithm, ok := h.versions[apiVersion]; ok {
+		return algorithm
+	}
+	return HashSHA3_512
+}
+
+func (h *HashRegistry) hasher(apiVersion string) hasher {
+	return hasher(h.algorithms[h.Algorithm(apiVersion)])
+}
+
+// withServerHashAlgorithm returns opts making the revisions served by ap be
+// hashed with the algorithm of the api version of its server, if
+// WithHashRegistry was given without an api version
+func withServerHashAlgorithm(ctx context.Context, ap api.AquaClient, opts []Option) ([]Option, error) {
+	o := newOptions(opts)
+	if o.hashRegistry == nil || o.hashAPIVersion != "" {
+		return opts, nil
+	}
+	s, err := serverInfo(ctx, ap)
+	if err != nil {
+		return nil, err
+	}
+	return append(opts[:len(opts):len(opts)], WithHashRegistry(o.hashRegistry, s.ApiVersion)), nil
+}
+
+// hasher computes the content, metadata and verification hashes of
+// revisions. The nil hasher uses SHA3-512 like getHashSum.
+type hasher func() hash.Hash
+
+func (h hasher) sum(content string) string {
+	if h == nil {
+		return getHashSum(content)
+	}
+	d := h()
+	d.Write([]byte(content))
+	return hex.EncodeToString(d.Sum(nil))
+}
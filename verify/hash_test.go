@@ -0,0 +1,125 @@
+package verify
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha512"
+	"encoding/json"
+	"testing"
+
+	"github.com/inblockio/aqua-verifier-go/api"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashRegistry(t *testing.T) {
+	require := require.New(t)
+	const sha2_512, newVersion = "sha2-512", "0.4.0"
+	registry := NewHashRegistry()
+	require.Error(registry.Use(newVersion, sha2_512))
+	registry.Register(sha2_512, sha512.New)
+	require.NoError(registry.Use(newVersion, sha2_512))
+	require.Equal(sha2_512, registry.Algorithm(newVersion))
+	require.Equal(HashSHA3_512, registry.Algorithm(api.Version))
+
+	// the same content hashes differently with both algorithms
+	content := &api.RevisionContent{Content: map[string]string{"main": "aqua"}}
+	sha2 := registry.hasher(newVersion)
+	require.Equal(getHashSum("aqua"), calculateContentHash(registry.hasher(api.Version), content))
+	require.NotEqual(calculateContentHash(nil, content), calculateContentHash(sha2, content))
+
+	// a revision hashed with SHA2-512 verifies as served by newVersion only
+	first, _, err := get1st2ndFixtureVerStructure()
+	require.NoError(err)
+	content.ContentHash = calculateContentHash(sha2, content)
+	metadata := *first.Metadata
+	metadata.MetadataHash = calculateMetadataHash(sha2, metadata.DomainId, metadata.Timestamp.String(), "")
+	metadata.VerificationHash = calculateVerificationHash(sha2, content.ContentHash, metadata.MetadataHash, "", "")
+	r := &api.Revision{Context: &api.VerificationContext{}, Content: content, Metadata: &metadata}
+
+	isCorrect, result := verifyRevision(r, nil, GlobalDoVerifyMerkleProof, WithOnChainChecks(false), WithHashRegistry(registry, newVersion))
+	require.True(isCorrect)
+	require.NoError(result.Err())
+	_, result = verifyRevision(r, nil, GlobalDoVerifyMerkleProof, WithOnChainChecks(false))
+	require.ErrorIs(result.Err(), ErrMetadataHashMismatch)
+
+	// revisions of older servers keep verifying with SHA3-512
+	isCorrect, _ = verifyRevision(first, nil, GlobalDoVerifyMerkleProof, WithOnChainChecks(false), WithHashRegistry(registry, api.Version))
+	require.True(isCorrect)
+	_, result = verifyRevision(first, nil, GlobalDoVerifyMerkleProof, WithOnChainChecks(false), WithHashRegistry(registry, newVersion))
+	require.ErrorIs(result.Err(), ErrMetadataHashMismatch)
+
+	// the explained and computed hashes use the algorithm too
+	_, hash := ExplainContentHash(content, WithHashRegistry(registry, newVersion))
+	require.Equal(content.ContentHash, hash)
+	_, hash = ExplainMetadataHash(&metadata, WithHashRegistry(registry, newVersion))
+	require.Equal(metadata.MetadataHash, hash)
+	hash, err = ComputeVerificationHash(r, nil, WithHashRegistry(registry, newVersion))
+	require.NoError(err)
+	require.Equal(metadata.VerificationHash, hash)
+	hash, err = ComputeVerificationHash(r, nil)
+	require.NoError(err)
+	require.NotEqual(metadata.VerificationHash, hash)
+
+	// without an api version, that of the server is used
+	page := &api.HashChain{
+		HashChainInfo: api.HashChainInfo{GenesisHash: metadata.VerificationHash, LatestVerificationHash: metadata.VerificationHash, Title: "Sha2"},
+		Revisions:     map[string]*api.Revision{metadata.VerificationHash: r},
+	}
+	ctx := context.Background()
+	backend := &fakeBackend{pages: []*api.HashChain{page}, calls: map[string]int{}}
+	newServer := versionBackend{backend, newVersion}
+	c, err := VerifyChain(ctx, newServer, "Sha2", true, -1, WithOnChainChecks(false), WithHashRegistry(registry, ""))
+	require.NoError(err)
+	require.NoError(c.Err())
+	_, result, err = GetVerifiedRevision(ctx, newServer, metadata.VerificationHash, WithHashRegistry(registry, ""))
+	require.NoError(err)
+	require.NoError(result.Err())
+	c, err = VerifyChain(ctx, backend, "Sha2", true, -1, WithOnChainChecks(false), WithHashRegistry(registry, ""))
+	require.NoError(err)
+	require.ErrorIs(c.Err(), ErrMetadataHashMismatch)
+}
+
+// versionBackend is a fakeBackend of a server with another api version
+type versionBackend struct {
+	*fakeBackend
+	version string
+}
+
+func (b versionBackend) GetServerInfoContext(ctx context.Context) (*api.ServerInfo, error) {
+	return &api.ServerInfo{ApiVersion: b.version}, nil
+}
+
+func TestGenesisMetadataHash(t *testing.T) {
+	require := require.New(t)
+	genesis, _, err := get1st2ndFixtureVerStructure()
+	require.NoError(err)
+	require.Empty(genesis.Metadata.PreviousVerificationHash)
+
+	hash, err := genesis.Metadata.ComputeHash()
+	require.NoError(err)
+	require.Equal(genesis.Metadata.MetadataHash, hash)
+	_, explained := ExplainMetadataHash(genesis.Metadata)
+	require.Equal(hash, explained)
+
+	// a previous verification hash served as null is empty too
+	data, err := json.Marshal(genesis.Metadata)
+	require.NoError(err)
+	data = bytes.Replace(data, []byte(`"previous_verification_hash":""`), []byte(`"previous_verification_hash":null`), 1)
+	require.Contains(string(data), `"previous_verification_hash":null`)
+	metadata := new(api.RevisionMetadata)
+	require.NoError(json.Unmarshal(data, metadata))
+	hash, err = metadata.ComputeHash()
+	require.NoError(err)
+	require.Equal(genesis.Metadata.MetadataHash, hash)
+
+	// unlike the literal string
+	metadata.PreviousVerificationHash = "null"
+	hash, err = metadata.ComputeHash()
+	require.NoError(err)
+	require.NotEqual(genesis.Metadata.MetadataHash, hash)
+
+	_, err = (&api.RevisionMetadata{Timestamp: genesis.Metadata.Timestamp}).ComputeHash()
+	require.Error(err)
+	_, err = (&api.RevisionMetadata{DomainId: genesis.Metadata.DomainId}).ComputeHash()
+	require.Error(err)
+}
@@ -0,0 +1,84 @@
+package verify
+
+import "encoding/json"
+
+// NORECORD_VERIFICATION_STATUS is the status of a chain without revisions in
+// the output of the JavaScript aqua-verifier
+const NORECORD_VERIFICATION_STATUS = "NORECORD"
+
+// jsChainResult is the JSON shape of the page verification details of the
+// JavaScript aqua-verifier
+type jsChainResult struct {
+	VerificationStatus string              `json:"verification_status"`
+	VerificationHashes []string            `json:"verification_hashes"`
+	RevisionDetails    []*jsRevisionResult `json:"revision_details"`
+}
+
+type jsRevisionResult struct {
+	VerificationHash string                      `json:"verification_hash"`
+	Status           *RevisionVerificationStatus `json:"status"`
+	// WitnessResult is an empty object for revisions without witness
+	WitnessResult interface{} `json:"witness_result"`
+	FileHash      string      `json:"file_hash"`
+	ErrorMessage  string      `json:"error_message"`
+}
+
+type jsWitnessResult struct {
+	WitnessHash                        string              `json:"witness_hash"`
+	TxHash                             string              `json:"tx_hash"`
+	WitnessNetwork                     string              `json:"witness_network"`
+	EtherscanResult                    string              `json:"etherscan_result"`
+	EtherscanErrorMessage              string              `json:"etherscan_error_message"`
+	ActualWitnessEventVerificationHash string              `json:"actual_witness_event_verification_hash"`
+	WitnessEventVHMatches              bool                `json:"witness_event_vh_matches"`
+	Extra                              *WitnessResultExtra `json:"extra"`
+	DoVerifyMerkleProof                bool                `json:"doVerifyMerkleProof"`
+	MerkleProofStatus                  string              `json:"merkle_proof_status"`
+}
+
+// MarshalJSON serializes the result in the shape of the page verification
+// details of the JavaScript aqua-verifier, so that consumers of its output
+// can consume either. Results only the Go verifier reports, such as signature
+// schemes, timings or trust anchors, are not part of that shape and are left
+// out; the revision results are available with their own JSON encoding.
+func (c *ChainVerificationResult) MarshalJSON() ([]byte, error) {
+	js := &jsChainResult{
+		VerificationStatus: NORECORD_VERIFICATION_STATUS,
+		VerificationHashes: make([]string, len(c.Revisions)),
+		RevisionDetails:    make([]*jsRevisionResult, len(c.Revisions)),
+	}
+	if len(c.Revisions) > 0 {
+		js.VerificationStatus = INVALID_VERIFICATION_STATUS
+		if c.Valid() {
+			js.VerificationStatus = VERIFIED_VERIFICATION_STATUS
+		}
+	}
+	for i, r := range c.Revisions {
+		js.VerificationHashes[i] = r.VerificationHash
+		details := &jsRevisionResult{
+			VerificationHash: r.VerificationHash,
+			Status:           r.Status,
+			WitnessResult:    struct{}{},
+			FileHash:         r.FileHash,
+		}
+		if w := r.WitnessResult; w != nil {
+			details.WitnessResult = &jsWitnessResult{
+				WitnessHash:                        w.WitnessHash,
+				TxHash:                             w.TxHash,
+				WitnessNetwork:                     w.WitnessNetwork,
+				EtherscanResult:                    w.EtherscanResult,
+				EtherscanErrorMessage:              w.EtherscanErrorMessage,
+				ActualWitnessEventVerificationHash: w.ActualWitnessEventVerificationHash,
+				WitnessEventVHMatches:              w.WitnessEventVHMatches,
+				Extra:                              w.Extra,
+				DoVerifyMerkleProof:                w.DoVerifyMerkleProof,
+				MerkleProofStatus:                  w.MerkleProofStatus,
+			}
+		}
+		if err := r.Err(); err != nil {
+			details.ErrorMessage = err.Error()
+		}
+		js.RevisionDetails[i] = details
+	}
+	return json.Marshal(js)
+}
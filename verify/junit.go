@@ -0,0 +1,74 @@
+package verify
+
+import (
+	"encoding/xml"
+	"io"
+	"sort"
+	"strings"
+)
+
+type junitTestSuite struct {
+	XMLName   xml.Name        `xml:"testsuite"`
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Error     *junitFailure `xml:"error,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes results, keyed by the name of the verified document, to w
+// as a JUnit XML test suite for the test reporters of CI pipelines. Every
+// document is a test case, in the order of their names, and an invalid chain
+// is reported as a failure whose message is the error of the first revision
+// that failed. A nil result is reported as an error, e.g. for a document
+// whose chain couldn't be fetched.
+func WriteJUnit(w io.Writer, results map[string]*ChainVerificationResult) error {
+	names := make([]string, 0, len(results))
+	for name := range results {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	suite := junitTestSuite{Name: "aqua-verification", Tests: len(names)}
+	for _, name := range names {
+		tc := junitTestCase{Name: name, ClassName: "aqua"}
+		switch c := results[name]; {
+		case c == nil:
+			tc.Error = &junitFailure{Message: "Chain not verified", Type: ERROR_VERIFICATION_STATUS}
+			suite.Errors++
+		case !c.Valid():
+			text := "Chain has no verified revisions"
+			if err := c.Err(); err != nil {
+				text = err.Error()
+			}
+			message, _, _ := strings.Cut(text, "\n")
+			tc.Failure = &junitFailure{Message: message, Type: INVALID_VERIFICATION_STATUS, Text: text}
+			suite.Failures++
+		}
+		suite.TestCases = append(suite.TestCases, tc)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suite); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,67 @@
+package verify
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/inblockio/aqua-verifier-go/api"
+)
+
+// VerifyInclusion checks that proof is the merkle path from leaf, the
+// verification hash of a witnessed revision, to the witnessed merkle root.
+// The proof is ordered like RevisionWitness.MerkleProof, from the node at the
+// deepest level up to the root. A proof that doesn't lead from leaf to root
+// returns false; an error is returned for an empty proof.
+func VerifyInclusion(leaf string, proof []*api.MerkleNode, root string) (bool, error) {
+	return VerifyInclusionBatch([]string{leaf}, [][]*api.MerkleNode{proof}, root)
+}
+
+// VerifyInclusionBatch checks that each of proofs is the merkle path from the
+// leaf at the same index to root, e.g. for the revisions of a domain that are
+// witnessed together. The nodes the proofs share, towards the root, are only
+// hashed once. It returns false if any leaf isn't included.
+func VerifyInclusionBatch(leaves []string, proofs [][]*api.MerkleNode, root string) (bool, error) {
+	if len(leaves) == 0 {
+		return false, errors.New("No leaves to verify")
+	}
+	if len(leaves) != len(proofs) {
+		return false, fmt.Errorf("Got %d leaves for %d merkle proofs", len(leaves), len(proofs))
+	}
+	for i, proof := range proofs {
+		if len(proof) == 0 {
+			return false, fmt.Errorf("Merkle proof of leaf %s is empty", leaves[i])
+		}
+		for _, node := range proof {
+			if node == nil {
+				return false, fmt.Errorf("Merkle proof of leaf %s has a missing node", leaves[i])
+			}
+		}
+	}
+	checked := map[api.MerkleNode]bool{}
+	for i, proof := range proofs {
+		if !verifyMerkleBranch(proof, leaves[i], root, checked) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// WitnessCovers checks that the witness event of w attests to the revision
+// with verification hash revisionVerificationHash: the witness event
+// verification hash must be the hash of the domain snapshot genesis hash and
+// the merkle root of w, and proof the merkle path from the verification hash
+// to that root. A nil proof uses the merkle proof of w. Like VerifyInclusion,
+// it returns false if the revision isn't covered; the error reports a witness
+// that doesn't match its own hashes or an empty proof.
+func WitnessCovers(w *api.RevisionWitness, revisionVerificationHash string, proof []*api.MerkleNode) (bool, error) {
+	if w == nil {
+		return false, errors.New("No witness")
+	}
+	if getHashSum(w.DomainSnapshotGenesisHash+w.MerkleRoot) != w.WitnessEventVerificationHash {
+		return false, newVerificationError(ErrWitnessMismatch, "Witness event verification hash doesn't match the merkle root")
+	}
+	if proof == nil {
+		proof = w.MerkleProof
+	}
+	return VerifyInclusion(revisionVerificationHash, proof, w.MerkleRoot)
+}
@@ -0,0 +1,83 @@
+package verify
+
+import (
+	"testing"
+
+	"github.com/inblockio/aqua-verifier-go/api"
+	"github.com/stretchr/testify/require"
+)
+
+// newMerkleTree returns the root of a two level merkle tree of the four
+// leaves and the proof of each leaf
+func newMerkleTree(leaves [4]string) (string, [][]*api.MerkleNode) {
+	left := &api.MerkleNode{Depth: 1, LeftLeaf: leaves[0], RightLeaf: leaves[1], Successor: getHashSum(leaves[0] + leaves[1])}
+	right := &api.MerkleNode{Depth: 1, LeftLeaf: leaves[2], RightLeaf: leaves[3], Successor: getHashSum(leaves[2] + leaves[3])}
+	top := &api.MerkleNode{Depth: 0, LeftLeaf: left.Successor, RightLeaf: right.Successor, Successor: getHashSum(left.Successor + right.Successor)}
+	return top.Successor, [][]*api.MerkleNode{{left, top}, {left, top}, {right, top}, {right, top}}
+}
+
+func TestVerifyInclusion(t *testing.T) {
+	require := require.New(t)
+	leaves := [4]string{getHashSum("a"), getHashSum("b"), getHashSum("c"), getHashSum("d")}
+	root, proofs := newMerkleTree(leaves)
+
+	for i, leaf := range leaves {
+		ok, err := VerifyInclusion(leaf, proofs[i], root)
+		require.NoError(err)
+		require.True(ok, i)
+	}
+	ok, err := VerifyInclusionBatch(leaves[:], proofs, root)
+	require.NoError(err)
+	require.True(ok)
+
+	// a leaf with the proof of another subtree
+	ok, err = VerifyInclusion(leaves[0], proofs[2], root)
+	require.NoError(err)
+	require.False(ok)
+	ok, err = VerifyInclusionBatch([]string{leaves[0], leaves[3]}, [][]*api.MerkleNode{proofs[0], proofs[0]}, root)
+	require.NoError(err)
+	require.False(ok)
+	// another root
+	ok, err = VerifyInclusionBatch(leaves[:], proofs, getHashSum("root"))
+	require.NoError(err)
+	require.False(ok)
+	// a tampered node shared by the proofs
+	tampered := *proofs[3][0]
+	tampered.RightLeaf = getHashSum("e")
+	ok, err = VerifyInclusionBatch(leaves[:], [][]*api.MerkleNode{proofs[0], proofs[1], proofs[2], {&tampered, proofs[3][1]}}, root)
+	require.NoError(err)
+	require.False(ok)
+
+	_, err = VerifyInclusion(leaves[0], nil, root)
+	require.EqualError(err, "Merkle proof of leaf "+leaves[0]+" is empty")
+	_, err = VerifyInclusionBatch(leaves[:], proofs[:3], root)
+	require.EqualError(err, "Got 4 leaves for 3 merkle proofs")
+	_, err = VerifyInclusionBatch(nil, nil, root)
+	require.Error(err)
+}
+
+func TestWitnessCovers(t *testing.T) {
+	require := require.New(t)
+	first, second, err := get1st2ndFixtureVerStructure()
+	require.NoError(err)
+	w := first.Witness
+
+	ok, err := WitnessCovers(w, first.Metadata.VerificationHash, nil)
+	require.NoError(err)
+	require.True(ok)
+	ok, err = WitnessCovers(w, first.Metadata.VerificationHash, w.MerkleProof)
+	require.NoError(err)
+	require.True(ok)
+	// the witness doesn't cover another revision
+	ok, err = WitnessCovers(w, second.Metadata.VerificationHash, nil)
+	require.NoError(err)
+	require.False(ok)
+
+	// a merkle root the witness event doesn't attest to
+	tampered := *w
+	tampered.MerkleRoot = getHashSum("root")
+	_, err = WitnessCovers(&tampered, first.Metadata.VerificationHash, nil)
+	require.ErrorIs(err, ErrWitnessMismatch)
+	_, err = WitnessCovers(nil, first.Metadata.VerificationHash, nil)
+	require.Error(err)
+}
@@ -0,0 +1,424 @@
+package verify
+
+import (
+	"context"
+	"time"
+
+	"github.com/inblockio/aqua-verifier-go/api"
+)
+
+// Option configures a verification
+type Option func(*options)
+
+type options struct {
+	contractSignatureChecker ContractSignatureChecker
+	onChain                  bool
+	observer                 func(*RevisionVerificationResult)
+	hashEncoding             HashEncoding
+	contentReconstructor     ContentReconstructor
+	contentNormalizer        func(string) string
+	witnessResolvers         map[string]api.WitnessResolver
+	witnessEvidence          map[string]api.WitnessEvidence
+	signatureVerifiers       map[string]SignatureVerifier
+	// independentRoots is non-nil if the chain must be anchored in one of
+	// the roots, see WithIndependentRoots
+	independentRoots     map[string]bool
+	progress             func(done, total int)
+	strict               bool
+	resultCache          *ResultCache
+	attachmentFetcher    AttachmentFetcher
+	witnessTimeTolerance time.Duration
+	hasher               hasher
+	hashAlgorithm        string
+	// hashRegistry is set by WithHashRegistry, without an api version if
+	// that of the server is to be used
+	hashRegistry      *HashRegistry
+	hashAPIVersion    string
+	ethSign           bool
+	maxChainHeight    int
+	clock             Clock
+	transclusionDepth int
+	mode              VerifyMode
+	checkpoint        *Checkpoint
+	requireWitness    bool
+	requireSignature  bool
+	// allowedAPIVersions is non-nil if the server must have one of the api
+	// versions, see WithAllowedAPIVersions
+	allowedAPIVersions map[string]bool
+	// rpcSlots bounds the concurrent witness transaction lookups, see
+	// WithRPCConcurrency
+	rpcSlots chan struct{}
+	// ctx is the context of the verification, see withContext
+	ctx context.Context
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{onChain: true, maxChainHeight: DefaultMaxChainHeight, clock: time.Now}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithContractSignatureChecker makes signatures that do not recover to the
+// wallet address of a revision be checked with c, so that revisions signed by
+// smart contract wallets (EIP-1271) can be verified.
+func WithContractSignatureChecker(c ContractSignatureChecker) Option {
+	return func(o *options) {
+		o.contractSignatureChecker = c
+	}
+}
+
+// WithOnChainChecks enables or disables the checks that need to contact a
+// blockchain: looking up witness transactions and checking contract wallet
+// signatures. They are enabled by default. Without them, witnesses are
+// reported as NOT_CHECKED rather than VALID.
+func WithOnChainChecks(enabled bool) Option {
+	return func(o *options) {
+		o.onChain = enabled
+	}
+}
+
+// VerifyMode selects which of the checks of signatures and witnesses run
+type VerifyMode int
+
+const (
+	// VerifyFull runs every check, it is the default and the zero value
+	VerifyFull VerifyMode = iota
+	// VerifyOnChain looks up witness transactions on chain, but doesn't
+	// recover the signers of signatures
+	VerifyOnChain
+	// VerifyStructural verifies the hashes of revisions, including the
+	// signature and witness hashes, and the links between them, without
+	// recovering the signers of signatures or looking up witness
+	// transactions, so that no requests are made besides fetching the
+	// revisions. Signatures and witnesses are reported as NOT_CHECKED
+	// rather than VALID.
+	VerifyStructural
+)
+
+// WithVerifyMode sets which of the checks of signatures and witnesses run,
+// see VerifyMode. VerifyStructural skips the on chain checks whatever
+// WithOnChainChecks is set to; in the other modes they run unless disabled
+// with WithOnChainChecks(false).
+func WithVerifyMode(m VerifyMode) Option {
+	return func(o *options) {
+		o.mode = m
+	}
+}
+
+// onChainChecks returns whether the checks that contact a blockchain run,
+// see WithOnChainChecks and WithVerifyMode
+func (o *options) onChainChecks() bool {
+	return o.onChain && o.mode != VerifyStructural
+}
+
+// WithObserver makes f be called with the result of every verified revision,
+// e.g. to export verification outcomes as metrics
+func WithObserver(f func(*RevisionVerificationResult)) Option {
+	return func(o *options) {
+		o.observer = f
+	}
+}
+
+// WithContentNormalizer makes every content slot of a revision be passed
+// through normalize before its content hash is computed, e.g. to strip
+// trailing whitespace or convert CRLF line endings that a server changed after
+// the content was hashed. This is a diagnostic aid and a workaround for server
+// quirks: with it, a valid content hash no longer proves that the content
+// served is exactly the content that was hashed, only that it is after
+// normalizing. Without it, the content is hashed as served.
+func WithContentNormalizer(normalize func(string) string) Option {
+	return func(o *options) {
+		o.contentNormalizer = normalize
+	}
+}
+
+// WithHashEncoding makes the content, metadata, verification and previous
+// verification hashes of revisions be decoded with e before they are hashed
+// or compared, for servers that don't serve them as hex.
+func WithHashEncoding(e HashEncoding) Option {
+	return func(o *options) {
+		o.hashEncoding = e
+	}
+}
+
+// WithContentReconstructor makes VerifyHashChain and VerifyChain rebuild the
+// full content of every revision with c, starting from the genesis revision,
+// for chains that serve their content as deltas. The content hashes are
+// verified against the full content.
+func WithContentReconstructor(c ContentReconstructor) Option {
+	return func(o *options) {
+		o.contentReconstructor = c
+	}
+}
+
+// WithWitnessResolver makes the transactions of witnesses on network be
+// looked up with r rather than by scraping etherscan, e.g. for witnesses on
+// other EVM chains or a permissioned chain.
+func WithWitnessResolver(network string, r api.WitnessResolver) Option {
+	return func(o *options) {
+		if o.witnessResolvers == nil {
+			o.witnessResolvers = make(map[string]api.WitnessResolver)
+		}
+		o.witnessResolvers[network] = r
+	}
+}
+
+// WithWitnessEvidence makes the witness transactions in evidence, keyed by
+// transaction hash, be checked against their imported evidence rather than
+// looked up on chain, e.g. in an air-gapped environment. Transactions
+// without evidence are still looked up with the resolver of their network.
+func WithWitnessEvidence(evidence map[string]api.WitnessEvidence) Option {
+	return func(o *options) {
+		if o.witnessEvidence == nil {
+			o.witnessEvidence = make(map[string]api.WitnessEvidence)
+		}
+		for txHash, e := range evidence {
+			o.witnessEvidence[txHash] = e
+		}
+	}
+}
+
+// WithStrict makes VerifyHashChain, VerifyChain and GetAllRevisions stop at
+// the first invalid revision rather than verifying the whole chain. The
+// result then ends with the invalid revision.
+func WithStrict(enabled bool) Option {
+	return func(o *options) {
+		o.strict = enabled
+	}
+}
+
+// WithAttachmentFetcher makes the files of revisions that are stored as
+// attachments rather than inline be fetched with f and checked against their
+// file hash. Without it such revisions fail verification, as their file can't
+// be checked.
+func WithAttachmentFetcher(f AttachmentFetcher) Option {
+	return func(o *options) {
+		o.attachmentFetcher = f
+	}
+}
+
+// WithResultCache makes revisions that verified successfully before, as
+// recorded in c, be reported valid without recomputing their hashes
+func WithResultCache(c *ResultCache) Option {
+	return func(o *options) {
+		o.resultCache = c
+	}
+}
+
+// WithSignatureVerifier makes signatures of format, e.g. did:key, be verified
+// with v, replacing the built in verifier for that format if there is one
+func WithSignatureVerifier(format string, v SignatureVerifier) Option {
+	return func(o *options) {
+		if o.signatureVerifiers == nil {
+			o.signatureVerifiers = make(map[string]SignatureVerifier)
+		}
+		o.signatureVerifiers[format] = v
+	}
+}
+
+// WithEthSign enables or disables accepting ethereum signatures over the raw
+// hash of the signed message, as made with eth_sign, besides personal_sign
+// signatures. It is disabled by default, as eth_sign signs an arbitrary hash
+// without the EIP-191 prefix that keeps signed messages from being valid
+// transactions. The scheme a signature matched is reported by
+// RevisionVerificationResult.SignatureScheme.
+func WithEthSign(enabled bool) Option {
+	return func(o *options) {
+		o.ethSign = enabled
+	}
+}
+
+// WithWitnessTimeTolerance sets how much earlier than the revision timestamp
+// the block of a witness transaction may be, to allow for clock skew between
+// the server and the chain. Block times are only checked for witnesses looked
+// up with a WitnessResolver that implements api.BlockTimeResolver. The
+// tolerance is zero by default.
+func WithWitnessTimeTolerance(d time.Duration) Option {
+	return func(o *options) {
+		o.witnessTimeTolerance = d
+	}
+}
+
+// WithHashRegistry makes the content, metadata and verification hashes of
+// revisions be computed with the algorithm r maps apiVersion to, where
+// apiVersion is the api version of the server that served the revisions, see
+// api.ServerInfo. With an empty apiVersion, the functions fetching revisions
+// from a server use the api version of its ServerInfo. By default the hashes
+// are computed with SHA3-512.
+func WithHashRegistry(r *HashRegistry, apiVersion string) Option {
+	return func(o *options) {
+		o.hashRegistry = r
+		o.hashAPIVersion = apiVersion
+		o.hashAlgorithm = r.Algorithm(apiVersion)
+		o.hasher = r.hasher(apiVersion)
+	}
+}
+
+// WithIndependentRoots makes VerifyHashChain and VerifyChain only accept a
+// chain that is anchored in one of roots, a set of hashes the caller obtained
+// independently of the server: witness merkle roots or witness event
+// verification hashes read from the blockchain, or pinned genesis
+// verification hashes.
+//
+// By default a chain is valid if it is internally consistent, i.e. every hash
+// recomputed from the served data matches, which a malicious server can
+// achieve for any chain it makes up. With independent roots, only the roots
+// are trusted and everything else is derived from the served data: a chain
+// is anchored if its genesis verification hash is one of roots, or if the
+// merkle proof of a witness leads from a revision's verification hash to one
+// of roots. The anchor covers the anchored revision and all revisions before
+// it, as every verification hash commits to the previous one, and is reported
+// by ChainVerificationResult.TrustAnchor and AnchoredRevisions. Revisions
+// newer than the anchored one are only linked to it, and a pinned genesis
+// hash just proves that the chain is the pinned page, not that its later
+// revisions are genuine.
+func WithIndependentRoots(roots []string) Option {
+	return func(o *options) {
+		o.independentRoots = make(map[string]bool, len(roots))
+		for _, root := range roots {
+			o.independentRoots[api.NormalizeHash(root)] = true
+		}
+	}
+}
+
+// WithProgress makes VerifyHashChain and VerifyChain call f after every
+// verified revision with the number of revisions verified so far and the
+// number of revisions to verify, which is the chain height unless the depth
+// is limited. f is called synchronously from the verifying goroutine, so it
+// should return quickly, e.g. by just redrawing a progress bar.
+func WithProgress(f func(done, total int)) Option {
+	return func(o *options) {
+		o.progress = f
+	}
+}
+
+// DefaultMaxChainHeight is the number of revisions VerifyChain verifies at
+// most unless changed with WithMaxChainHeight
+const DefaultMaxChainHeight = 100000
+
+// WithMaxChainHeight makes VerifyChain fail with ErrChainTooHigh for a chain
+// declared or found to have more than n revisions, after fetching at most n+1
+// of them, so that an untrusted server can't keep it busy with an endless
+// chain. Chains verified only up to a depth of at most n are not affected. A
+// limit of n <= 0 disables the check.
+func WithMaxChainHeight(n int) Option {
+	return func(o *options) {
+		o.maxChainHeight = n
+	}
+}
+
+// Clock returns the current time
+type Clock func() time.Time
+
+// WithClock makes the verification take the current time from c instead of
+// time.Now, e.g. to verify against a fixed time in tests. The current time is
+// used to reject revisions dated in the future and as the time a chain was
+// verified at.
+func WithClock(c Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+// WithTransclusionDepth makes VerifyTransclusions verify the whole chain of
+// every transcluded page up to the transcluded revision, and follow the pages
+// transcluded by the transcluded revisions up to depth levels deep, so that
+// every transitively transcluded revision is shown to be on a valid chain.
+// Revisions transcluding themselves, directly or through other pages, are
+// reported as cyclic instead of being followed.
+func WithTransclusionDepth(depth int) Option {
+	return func(o *options) {
+		o.transclusionDepth = depth
+	}
+}
+
+// WithCheckpoint makes VerifyChain resume an interrupted verification from
+// cp, see ChainVerificationResult.Checkpoint, verifying only the revisions
+// newer than the revision of cp. The checkpoint is rejected with
+// ErrBrokenChain unless its revision is a revision of the verified chain.
+// Reused signatures are only detected among the revisions newer than cp.
+func WithCheckpoint(cp *Checkpoint) Option {
+	return func(o *options) {
+		o.checkpoint = cp
+	}
+}
+
+// WithRequireWitness makes revisions that are not witnessed fail verification
+// with ErrWitnessMissing, for documents that must be witnessed by policy. By
+// default a missing witness is reported as MISSING and doesn't fail.
+func WithRequireWitness() Option {
+	return func(o *options) {
+		o.requireWitness = true
+	}
+}
+
+// WithRequireSignature makes revisions that are not signed fail verification
+// with ErrSignatureMissing, like WithRequireWitness for signatures
+func WithRequireSignature() Option {
+	return func(o *options) {
+		o.requireSignature = true
+	}
+}
+
+// WithAllowedAPIVersions makes VerifyChain refuse to verify against a server
+// whose api version is not one of versions, failing with
+// ErrUnsupportedAPIVersion, so that chains are not verified with the hashing
+// of another api version. The server info is fetched once per client.
+func WithAllowedAPIVersions(versions []string) Option {
+	return func(o *options) {
+		o.allowedAPIVersions = map[string]bool{}
+		for _, v := range versions {
+			o.allowedAPIVersions[v] = true
+		}
+	}
+}
+
+// WithRPCConcurrency bounds the witness transaction lookups that run at once
+// to n, independently of the requests to the Aqua server, so that verifying
+// many witnessed revisions concurrently, e.g. with VerifyTitles, doesn't get
+// rate limited by the RPC provider. The bound is shared by every verification
+// the returned Option is passed to. n < 1 leaves the lookups unbounded.
+func WithRPCConcurrency(n int) Option {
+	var slots chan struct{}
+	if n > 0 {
+		slots = make(chan struct{}, n)
+	}
+	return func(o *options) {
+		o.rpcSlots = slots
+	}
+}
+
+// acquireRPC waits for a free witness transaction lookup slot, see
+// WithRPCConcurrency, and returns the function releasing it. It returns the
+// error of the verification context if that is done first.
+func (o *options) acquireRPC() (func(), error) {
+	if o.rpcSlots == nil {
+		return func() {}, nil
+	}
+	select {
+	case o.rpcSlots <- struct{}{}:
+		return func() { <-o.rpcSlots }, nil
+	case <-o.context().Done():
+		return nil, o.context().Err()
+	}
+}
+
+// withContext makes the lookups of a verification, e.g. of witness
+// transactions, contract signatures and file attachments, use ctx. The
+// functions taking a context pass theirs with it.
+func withContext(ctx context.Context) Option {
+	return func(o *options) {
+		o.ctx = ctx
+	}
+}
+
+// context returns the context of the verification, context.Background() if
+// none was passed with withContext
+func (o *options) context() context.Context {
+	if o.ctx == nil {
+		return context.Background()
+	}
+	return o.ctx
+}
@@ -0,0 +1,124 @@
+package verify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/inblockio/aqua-verifier-go/api"
+)
+
+// MirrorDisagreement reports a mirror that failed to serve, or served
+// different data than the majority for, the hash chain info or a revision.
+type MirrorDisagreement struct {
+	// Mirror is the index of the mirror in the clients passed to
+	// VerifyChainQuorum
+	Mirror int `json:"mirror"`
+	// VerificationHash is the hash of the revision, or empty for the hash
+	// chain info
+	VerificationHash string `json:"verification_hash,omitempty"`
+	// Error is the error of the request if it failed
+	Error string `json:"error,omitempty"`
+}
+
+// VerifyChainQuorum fetches the hash chain info and every revision of the
+// chain identified by id_type and id from each of clients and only trusts
+// them if at least min clients, which must be a majority, served identical
+// data. The trusted chain is then verified like VerifyHashChain. Mirrors that
+// disagree with the majority are reported in the Disagreements of the result.
+// An error is returned if no quorum is reached.
+func VerifyChainQuorum(ctx context.Context, clients []api.AquaClient, id_type, id string, min int, opts ...Option) (*ChainVerificationResult, error) {
+	if min > len(clients) || min*2 <= len(clients) {
+		return nil, fmt.Errorf("Quorum of %d is not a majority of %d clients", min, len(clients))
+	}
+	var disagreements []MirrorDisagreement
+
+	v, err := quorum(len(clients), min, "", &disagreements, func(i int) (interface{}, error) {
+		return clients[i].GetHashChainInfoContext(ctx, id_type, id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	info := v.(*api.HashChainInfo)
+
+	data := &api.HashChain{HashChainInfo: *info, Revisions: make(map[string]*api.Revision)}
+	for cur := info.LatestVerificationHash; cur != ""; {
+		if _, ok := data.Revisions[cur]; ok {
+			return nil, fmt.Errorf("Revision %s is part of a cycle", cur)
+		}
+		v, err := quorum(len(clients), min, cur, &disagreements, func(i int) (interface{}, error) {
+			return clients[i].GetRevisionContext(ctx, cur)
+		})
+		if err != nil {
+			return nil, err
+		}
+		r := v.(*api.Revision)
+		if r.Metadata == nil {
+			return nil, fmt.Errorf("Revision %s has no metadata", cur)
+		}
+		data.Revisions[cur] = r
+		cur = r.Metadata.PreviousVerificationHash
+	}
+
+	// the quorum agreed on the data, so the server of any client tells how
+	// it is hashed
+	if opts, err = withServerHashAlgorithm(ctx, clients[0], opts); err != nil {
+		return nil, err
+	}
+	c, err := verifyHashChain(ctx, nil, data, true, -1, opts)
+	if err != nil {
+		return nil, err
+	}
+	c.Disagreements = disagreements
+	return c, nil
+}
+
+// quorum calls fetch for each of n clients, compares the JSON encoding of the
+// values they return and returns the value that at least min of them agree
+// on. Clients that fail or disagree are appended to disagreements.
+func quorum(n, min int, hash string, disagreements *[]MirrorDisagreement, fetch func(i int) (interface{}, error)) (interface{}, error) {
+	values := make([]interface{}, n)
+	encoded := make([]string, n)
+	errs := make([]error, n)
+	votes := make(map[string]int)
+	for i := 0; i < n; i++ {
+		values[i], errs[i] = fetch(i)
+		if errs[i] != nil {
+			continue
+		}
+		b, err := json.Marshal(values[i])
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		encoded[i] = string(b)
+		votes[encoded[i]]++
+	}
+
+	var majority string
+	for e, count := range votes {
+		if count > votes[majority] {
+			majority = e
+		}
+	}
+	var value interface{}
+	for i := 0; i < n; i++ {
+		if errs[i] == nil && encoded[i] == majority {
+			value = values[i]
+			continue
+		}
+		d := MirrorDisagreement{Mirror: i, VerificationHash: hash}
+		if errs[i] != nil {
+			d.Error = errs[i].Error()
+		}
+		*disagreements = append(*disagreements, d)
+	}
+	if votes[majority] < min {
+		what := "hash chain info"
+		if hash != "" {
+			what = "revision " + hash
+		}
+		return nil, fmt.Errorf("No quorum of %d mirrors for %s", min, what)
+	}
+	return value, nil
+}
@@ -0,0 +1,56 @@
+package verify
+
+import (
+	"context"
+	"testing"
+
+	"github.com/inblockio/aqua-verifier-go/api"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyChainQuorum(t *testing.T) {
+	require := require.New(t)
+	var clients []api.AquaClient
+	for i := 0; i < 3; i++ {
+		data, err := jsonDecodeFixture(fixture)
+		require.NoError(err)
+		if i == 2 {
+			page := data.Pages[0]
+			page.Revisions[page.LatestVerificationHash].Content.Content["main"] = "tampered"
+		}
+		s := newFixtureServer(data)
+		defer s.Close()
+		ap, err := api.NewAPI(s.URL, "")
+		require.NoError(err)
+		clients = append(clients, ap)
+	}
+	data, err := jsonDecodeFixture(fixture)
+	require.NoError(err)
+	page := data.Pages[0]
+
+	result, err := VerifyChainQuorum(context.Background(), clients, "title", page.Title, 2, WithOnChainChecks(false))
+	require.NoError(err)
+	require.True(result.Valid())
+	require.Equal(page.ChainHeight, result.Height)
+	require.Equal([]MirrorDisagreement{{Mirror: 2, VerificationHash: page.LatestVerificationHash}}, result.Disagreements)
+
+	// the tampered mirror prevents unanimity
+	_, err = VerifyChainQuorum(context.Background(), clients, "title", page.Title, 3)
+	require.EqualError(err, "No quorum of 3 mirrors for revision "+page.LatestVerificationHash)
+
+	// a mirror that is down is reported too
+	down, err := api.NewAPI("http://127.0.0.1:0", "")
+	require.NoError(err)
+	result, err = VerifyChainQuorum(context.Background(), append(clients[:2:2], down), "title", page.Title, 2, WithOnChainChecks(false))
+	require.NoError(err)
+	require.True(result.Valid())
+	require.Len(result.Disagreements, 1+page.ChainHeight)
+	require.Equal(2, result.Disagreements[0].Mirror)
+	require.Empty(result.Disagreements[0].VerificationHash)
+	require.NotEmpty(result.Disagreements[0].Error)
+
+	_, err = VerifyChainQuorum(context.Background(), clients, "title", page.Title, 1)
+	require.EqualError(err, "Quorum of 1 is not a majority of 3 clients")
+	_, err = VerifyChainQuorum(context.Background(), clients, "title", "Unknown", 2)
+	require.EqualError(err, "No quorum of 2 mirrors for hash chain info")
+}
@@ -0,0 +1,70 @@
+package verify
+
+import (
+	"html/template"
+	"io"
+	"time"
+
+	"github.com/inblockio/aqua-verifier-go/api"
+)
+
+// explorerURL returns the block explorer URL of a witness transaction, or ""
+// if the witness network is unknown
+func explorerURL(network, txHash string) string {
+	u, err := (&api.RevisionWitness{WitnessNetwork: network, WitnessEventTransactionHash: txHash}).ExplorerTxURL()
+	if err != nil {
+		return ""
+	}
+	return u
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"explorerURL": explorerURL,
+	"timestamp": func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.UTC().Format(time.RFC3339)
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Aqua verification report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; vertical-align: top; }
+.hash { font-family: monospace; word-break: break-all; max-width: 30em; }
+.valid { color: #080; }
+.invalid { color: #c00; }
+</style>
+</head>
+<body>
+<h1>Aqua verification report</h1>
+<p>Genesis hash: <span class="hash">{{.GenesisHash}}</span></p>
+<p>Revisions: {{len .Revisions}} of {{.Height}}</p>
+<p>Result: {{if .Valid}}<span class="valid">VALID</span>{{else}}<span class="invalid">INVALID</span>{{end}}</p>
+<table>
+<tr><th>#</th><th>Verification hash</th><th>Timestamp</th><th>Status</th><th>Signature</th><th>Signer</th><th>Witness</th><th>Error</th></tr>
+{{range $i, $r := .Revisions}}<tr>
+<td>{{$i}}</td>
+<td class="hash">{{$r.VerificationHash}}</td>
+<td>{{timestamp $r.Timestamp}}</td>
+<td>{{if $r.Valid}}<span class="valid">VALID</span>{{else}}<span class="invalid">INVALID</span>{{end}}</td>
+<td>{{$r.Status.Signature}}</td>
+<td class="hash">{{$r.Signer}}</td>
+<td>{{$r.Status.Witness}}{{with $r.WitnessResult}} {{.WitnessNetwork}} {{with explorerURL .WitnessNetwork .TxHash}}<a href="{{.}}">{{$r.WitnessResult.TxHash}}</a>{{else}}<span class="hash">{{.TxHash}}</span>{{end}}{{end}}</td>
+<td>{{with $r.Err}}{{.}}{{end}}</td>
+</tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// WriteHTMLReport writes a self-contained HTML page to w that lists every
+// revision of result with its verification status, signer, witness
+// transaction and timestamp.
+func WriteHTMLReport(w io.Writer, result *ChainVerificationResult) error {
+	return reportTemplate.Execute(w, result)
+}
@@ -0,0 +1,88 @@
+package verify
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var updateGolden = flag.Bool("update", false, "Update the golden files of the tests")
+
+const reportGolden = "test_fixtures/report.html.golden"
+
+func TestWriteHTMLReport(t *testing.T) {
+	require := require.New(t)
+	data, err := jsonDecodeFixture(fixture)
+	require.NoError(err)
+	page := data.Pages[0]
+	page.Revisions[page.LatestVerificationHash].Content.Content["main"] = "<tampered>"
+	result, err := VerifyHashChain(page, GlobalDoVerifyMerkleProof, -1, WithOnChainChecks(false))
+	require.NoError(err)
+
+	var b bytes.Buffer
+	require.NoError(WriteHTMLReport(&b, result))
+	if *updateGolden {
+		require.NoError(os.WriteFile(reportGolden, b.Bytes(), 0644))
+	}
+	golden, err := os.ReadFile(reportGolden)
+	require.NoError(err)
+	require.Equal(string(golden), b.String())
+}
+
+// resultGolden is the JSON encoding of the result of verifying the tampered
+// Main Page of the fixture
+const resultGolden = "test_fixtures/result.json.golden"
+
+func TestChainVerificationResultJSON(t *testing.T) {
+	require := require.New(t)
+	data, err := jsonDecodeFixture(fixture)
+	require.NoError(err)
+	page := data.Pages[0]
+	page.Revisions[page.LatestVerificationHash].Content.Content["main"] = "tampered"
+	result, err := VerifyHashChain(page, GlobalDoVerifyMerkleProof, -1, WithOnChainChecks(false))
+	require.NoError(err)
+
+	j, err := json.MarshalIndent(result, "", "  ")
+	require.NoError(err)
+	if *updateGolden {
+		require.NoError(os.WriteFile(resultGolden, j, 0644))
+	}
+	golden, err := os.ReadFile(resultGolden)
+	require.NoError(err)
+	require.JSONEq(string(golden), string(j))
+
+	j, err = json.Marshal(&ChainVerificationResult{})
+	require.NoError(err)
+	require.JSONEq(`{"verification_status":"NORECORD","verification_hashes":[],"revision_details":[]}`, string(j))
+}
+
+const junitGolden = "test_fixtures/junit.xml.golden"
+
+func TestWriteJUnit(t *testing.T) {
+	require := require.New(t)
+	data, err := jsonDecodeFixture(fixture)
+	require.NoError(err)
+	valid, err := VerifyHashChain(data.Pages[0], GlobalDoVerifyMerkleProof, -1, WithOnChainChecks(false))
+	require.NoError(err)
+	page := data.Pages[0]
+	page.Revisions[page.LatestVerificationHash].Content.Content["main"] = "<tampered>"
+	tampered, err := VerifyHashChain(page, GlobalDoVerifyMerkleProof, -1, WithOnChainChecks(false))
+	require.NoError(err)
+
+	var b bytes.Buffer
+	require.NoError(WriteJUnit(&b, map[string]*ChainVerificationResult{
+		"Main Page":     valid,
+		"Tampered Page": tampered,
+		"Missing Page":  nil,
+	}))
+	if *updateGolden {
+		require.NoError(os.WriteFile(junitGolden, b.Bytes(), 0644))
+	}
+	golden, err := os.ReadFile(junitGolden)
+	require.NoError(err)
+	require.Equal(string(golden), b.String())
+}
@@ -0,0 +1,71 @@
+package verify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/inblockio/aqua-verifier-go/api"
+)
+
+// newFixtureServer serves the pages of data over the data accounting api
+func newFixtureServer(data *api.OfflineData) *httptest.Server {
+	findRevision := func(hash string) (*api.HashChain, *api.Revision) {
+		for _, p := range data.Pages {
+			if r, ok := p.Revisions[hash]; ok {
+				return p, r
+			}
+		}
+		return nil, nil
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/data_accounting/get_server_info", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(&api.ServerInfo{ApiVersion: api.Version})
+	})
+	mux.HandleFunc("/data_accounting/get_hash_chain_info/title", func(w http.ResponseWriter, r *http.Request) {
+		id := strings.ReplaceAll(r.URL.Query().Get("identifier"), "_", " ")
+		for _, p := range data.Pages {
+			if strings.ReplaceAll(p.Title, "_", " ") == id {
+				json.NewEncoder(w).Encode(&p.HashChainInfo)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("/data_accounting/get_hash_chain_info/genesis_hash", func(w http.ResponseWriter, r *http.Request) {
+		for _, p := range data.Pages {
+			if p.GenesisHash == r.URL.Query().Get("identifier") {
+				json.NewEncoder(w).Encode(&p.HashChainInfo)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("/data_accounting/get_revision/", func(w http.ResponseWriter, r *http.Request) {
+		_, rev := findRevision(strings.TrimPrefix(r.URL.Path, "/data_accounting/get_revision/"))
+		if rev == nil {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(rev)
+	})
+	mux.HandleFunc("/data_accounting/get_revision_hashes/", func(w http.ResponseWriter, r *http.Request) {
+		hash := strings.TrimPrefix(r.URL.Path, "/data_accounting/get_revision_hashes/")
+		p, rev := findRevision(hash)
+		if rev == nil {
+			http.NotFound(w, r)
+			return
+		}
+		// the requested revision and all newer ones, oldest first
+		hashes := []string{}
+		for cur := p.LatestVerificationHash; cur != ""; cur = p.Revisions[cur].Metadata.PreviousVerificationHash {
+			hashes = append([]string{cur}, hashes...)
+			if cur == hash {
+				break
+			}
+		}
+		json.NewEncoder(w).Encode(hashes)
+	})
+	return httptest.NewServer(mux)
+}
@@ -0,0 +1,433 @@
+package verify
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/inblockio/aqua-verifier-go/api"
+)
+
+// Signature schemes a revision signature can be verified with
+const (
+	SIGNATURE_SCHEME_PERSONAL_SIGN = "personal_sign"
+	SIGNATURE_SCHEME_ETH_SIGN      = "eth_sign"
+	SIGNATURE_SCHEME_EIP1271       = "eip1271"
+)
+
+// Formats of revision signatures, told apart by the signer identity in the
+// wallet_address field: ethereum addresses or DIDs
+const (
+	SIGNATURE_FORMAT_ETHEREUM = "ethereum"
+	SIGNATURE_FORMAT_DID_KEY  = "did:key"
+)
+
+// SignatureVerifier verifies revision signatures of one format. Verify
+// reports whether sig is a valid signature of the hex encoded verification
+// hash by the identity in sig.WalletAddress, and returns an error if the
+// signature can't be checked at all.
+type SignatureVerifier interface {
+	Verify(ctx context.Context, verificationHash string, sig *api.RevisionSignature) (bool, error)
+}
+
+// EthereumSignatureVerifier verifies signatures by ethereum wallets by
+// recovering the signing address. Signatures are accepted over the EIP-191
+// personal_sign hash of the signed message, as made by MetaMask and ethers,
+// and unless PersonalSignOnly is set over its raw keccak256 hash, as made by
+// tooling that signs with eth_sign.
+type EthereumSignatureVerifier struct {
+	PersonalSignOnly bool
+}
+
+// Verify implements SignatureVerifier
+func (v EthereumSignatureVerifier) Verify(ctx context.Context, verificationHash string, sig *api.RevisionSignature) (bool, error) {
+	scheme, err := v.Scheme(verificationHash, sig)
+	return scheme != "", err
+}
+
+// Scheme returns the scheme sig is a signature of the hex encoded
+// verification hash by sig.WalletAddress with, SIGNATURE_SCHEME_PERSONAL_SIGN
+// or SIGNATURE_SCHEME_ETH_SIGN, or "" if it is not a signature by the wallet
+func (v EthereumSignatureVerifier) Scheme(verificationHash string, sig *api.RevisionSignature) (string, error) {
+	signature, err := hexutil.Decode(sig.Signature)
+	if err != nil {
+		return "", newVerificationError(ErrSignatureInvalid, "Malformed signature: %s", err)
+	}
+	wallet := strings.ToLower(sig.WalletAddress)
+	if recoverAddress(signatureMessageHash(verificationHash), signature) == wallet {
+		return SIGNATURE_SCHEME_PERSONAL_SIGN, nil
+	}
+	if !v.PersonalSignOnly && recoverAddress(rawSignatureMessageHash(verificationHash), signature) == wallet {
+		return SIGNATURE_SCHEME_ETH_SIGN, nil
+	}
+	return "", nil
+}
+
+// DIDKeySignatureVerifier is meant to verify signatures by did:key
+// identities. The signature encoding for them is not settled yet, so it
+// reports ErrUnsupportedSignature for every signature.
+type DIDKeySignatureVerifier struct{}
+
+// Verify implements SignatureVerifier
+func (DIDKeySignatureVerifier) Verify(ctx context.Context, verificationHash string, sig *api.RevisionSignature) (bool, error) {
+	return false, newVerificationError(ErrUnsupportedSignature, "Signatures by %s are not supported yet", sig.WalletAddress)
+}
+
+// signatureFormat returns the format of sig, derived from its signer: DIDs
+// have the format of their method, e.g. did:key, anything else is taken for
+// an ethereum address
+func signatureFormat(sig *api.RevisionSignature) string {
+	if !strings.HasPrefix(sig.WalletAddress, "did:") {
+		return SIGNATURE_FORMAT_ETHEREUM
+	}
+	if method, _, ok := strings.Cut(strings.TrimPrefix(sig.WalletAddress, "did:"), ":"); ok {
+		return "did:" + method
+	}
+	return sig.WalletAddress
+}
+
+// signatureVerifier returns the verifier for signatures of format, preferring
+// the ones passed to WithSignatureVerifier over the built in ones
+func (o *options) signatureVerifier(format string) SignatureVerifier {
+	if v, ok := o.signatureVerifiers[format]; ok {
+		return v
+	}
+	switch format {
+	case SIGNATURE_FORMAT_ETHEREUM:
+		return EthereumSignatureVerifier{PersonalSignOnly: !o.ethSign}
+	case SIGNATURE_FORMAT_DID_KEY:
+		return DIDKeySignatureVerifier{}
+	}
+	return nil
+}
+
+// ContractSignatureChecker reports whether signature is a valid signature of
+// hash by the smart contract wallet, i.e. whether the wallet's EIP-1271
+// isValidSignature returns the magic value. api.CheckEIP1271Signature
+// implements the check against an ethereum JSON-RPC endpoint.
+type ContractSignatureChecker func(ctx context.Context, wallet string, hash, signature []byte) (bool, error)
+
+// signatureResult holds the outcome of verifying the signature of a revision
+type signatureResult struct {
+	isCorrect bool
+	status    string
+	scheme    string
+	elapsed   time.Duration
+	// err is why an INVALID signature failed to verify
+	err error
+}
+
+func verifyCurrentSignature(r *api.Revision, o *options) *signatureResult {
+	if !r.HasSignature() {
+		return &signatureResult{isCorrect: true, status: "MISSING"}
+	}
+	return verifySignature(r.Signature, r.Metadata.VerificationHash, o)
+}
+
+// verifySignature verifies that sig is a signature of verificationHash by its
+// signer
+func verifySignature(sig *api.RevisionSignature, verificationHash string, o *options) *signatureResult {
+	start := time.Now()
+	result := &signatureResult{status: "INVALID"}
+	defer func() {
+		result.elapsed = time.Since(start)
+	}()
+
+	// a declared signature hash not matching the signature is rejected
+	// without recovering the signer
+	if sig.SignatureHash != "" {
+		if hash, err := sig.ComputeHash(); err != nil || hash != o.hashEncoding.normalize(sig.SignatureHash) {
+			result.err = newVerificationError(ErrSignatureInvalid, "Signature hash doesn't match")
+			return result
+		}
+	}
+	if o.mode != VerifyFull {
+		result.isCorrect, result.status = true, NOT_CHECKED_STATUS
+		return result
+	}
+	verificationHash = o.hashEncoding.normalize(verificationHash)
+	format := signatureFormat(sig)
+	verifier := o.signatureVerifier(format)
+	if verifier == nil {
+		result.err = newVerificationError(ErrUnsupportedSignature, "No verifier for signatures of format %s", format)
+		return result
+	}
+	var ok bool
+	var err error
+	scheme := format
+	if v, isEthereum := verifier.(EthereumSignatureVerifier); isEthereum {
+		// the scheme is found recovering the signer once
+		scheme, err = v.Scheme(verificationHash, sig)
+		ok = scheme != ""
+	} else {
+		ok, err = verifier.Verify(o.context(), verificationHash, sig)
+		if format == SIGNATURE_FORMAT_ETHEREUM {
+			scheme = SIGNATURE_SCHEME_PERSONAL_SIGN
+		}
+	}
+	if err == nil && ok {
+		result.isCorrect, result.status, result.scheme = true, "VALID", scheme
+		return result
+	}
+	result.err = err
+	if result.err == nil {
+		result.err = newVerificationError(ErrSignatureInvalid, "Signature is not by %s", sig.WalletAddress)
+	}
+	if format != SIGNATURE_FORMAT_ETHEREUM {
+		return result
+	}
+	hash := signatureMessageHash(verificationHash)
+	signature, err := hexutil.Decode(sig.Signature)
+	if err != nil {
+		return result
+	}
+	if o.onChainChecks() && o.contractSignatureChecker != nil {
+		ok, err := o.contractSignatureChecker(o.context(), sig.WalletAddress, hash, signature)
+		switch {
+		case err != nil:
+			result.err = fmt.Errorf("Failure checking the contract signature of %s: %w", sig.WalletAddress, err)
+		case ok:
+			result.isCorrect, result.status, result.scheme, result.err = true, "VALID", SIGNATURE_SCHEME_EIP1271, nil
+		}
+	}
+	return result
+}
+
+// signatureMessageHash returns the personal_sign hash of the message signers
+// sign for the given hex encoded hash
+func signatureMessageHash(hash string) []byte {
+	return accounts.TextHash([]byte(SignMessageForRevision(hash)))
+}
+
+// rawSignatureMessageHash returns the keccak256 hash of the message signers
+// sign for the given hex encoded hash, without the EIP-191 prefix
+func rawSignatureMessageHash(hash string) []byte {
+	return crypto.Keccak256([]byte(SignMessageForRevision(hash)))
+}
+
+// SignMessageForRevision returns the message a wallet displays and signs to
+// sign the revision with the given verification hash. It is the message
+// signatures are verified against, with or without the 0x prefix and in any
+// case of the hash.
+func SignMessageForRevision(verificationHash string) string {
+	return "I sign the following page verification_hash: [0x" + api.NormalizeHash(verificationHash) + "]"
+}
+
+// VerifyDetachedSignature checks a signature made over content before it was
+// committed to a revision. As such content has no metadata and therefore no
+// verification hash yet, the signer signs the content hash with the message
+// used for revision signatures. The content hash is calculated from the
+// content slots, including the transclusion hashes, and its salt; a content
+// hash set in content must match it. It returns false if sig is not a
+// signature by sig.WalletAddress, and an error if the content hash, the
+// signature hash or the signature itself is malformed.
+func VerifyDetachedSignature(content *api.RevisionContent, sig *api.RevisionSignature) (bool, error) {
+	contentHash := calculateContentHash(nil, content)
+	if content.ContentHash != "" && content.ContentHash != contentHash {
+		return false, ErrContentHashMismatch
+	}
+	if sig.SignatureHash != "" && sig.SignatureHash != calculateSignatureHash(sig.Signature, sig.PublicKey) {
+		return false, newVerificationError(ErrSignatureInvalid, "Signature hash doesn't match")
+	}
+	signature, err := hexutil.Decode(sig.Signature)
+	if err != nil {
+		return false, newVerificationError(ErrSignatureInvalid, "Malformed signature: %s", err)
+	}
+	if len(signature) != crypto.SignatureLength {
+		return false, newVerificationError(ErrSignatureInvalid, "Malformed signature: %d bytes instead of %d", len(signature), crypto.SignatureLength)
+	}
+	return recoverAddress(signatureMessageHash(contentHash), signature) == strings.ToLower(sig.WalletAddress), nil
+}
+
+// recoverAddress returns the lower case address that signed hash, or "" if no
+// address can be recovered from signature
+func recoverAddress(hash, signature []byte) string {
+	if len(signature) != crypto.SignatureLength {
+		return ""
+	}
+	sig := make([]byte, len(signature))
+	copy(sig, signature)
+	sig[crypto.RecoveryIDOffset] -= 27 // Transform yellow paper V from 27/28 to 0/1
+	sigPublicKey, err := crypto.Ecrecover(hash, sig)
+	if err != nil {
+		return ""
+	}
+	ecdsaPub, err := crypto.UnmarshalPubkey(sigPublicKey)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(crypto.PubkeyToAddress(*ecdsaPub).Hex())
+}
+
+// VerifySignatureWithKey checks that sig is a personal_sign signature of
+// message by the holder of pubKey, a compressed or uncompressed secp256k1
+// public key known out of band. The signature is checked against the key
+// rather than by recovering its signer, so neither the wallet address nor the
+// public key of sig are trusted. message is the signed message, see
+// SignMessageForRevision. It returns an error if the public key or the
+// signature is malformed.
+func VerifySignatureWithKey(sig *api.RevisionSignature, pubKey []byte, message string) (bool, error) {
+	signature, err := hexutil.Decode(sig.Signature)
+	if err != nil {
+		return false, newVerificationError(ErrSignatureInvalid, "Malformed signature: %s", err)
+	}
+	if len(signature) != crypto.SignatureLength {
+		return false, newVerificationError(ErrSignatureInvalid, "Malformed signature: %d bytes instead of %d", len(signature), crypto.SignatureLength)
+	}
+	var key *ecdsa.PublicKey
+	if len(pubKey) == 33 {
+		key, err = crypto.DecompressPubkey(pubKey)
+	} else {
+		key, err = crypto.UnmarshalPubkey(pubKey)
+	}
+	if err != nil {
+		return false, fmt.Errorf("Malformed public key: %w", err)
+	}
+	hash := accounts.TextHash([]byte(message))
+	return crypto.VerifySignature(crypto.FromECDSAPub(key), hash, signature[:crypto.RecoveryIDOffset]), nil
+}
+
+// VerifyAllSignatures checks that every signature of the co-signed revision r
+// is valid. It fails on the first invalid signature, naming its signer.
+func VerifyAllSignatures(r *api.Revision, opts ...Option) (bool, error) {
+	sigs := r.AllSignatures()
+	if len(sigs) == 0 {
+		return false, newVerificationError(ErrSignatureInvalid, "Revision %s is not signed", r.Metadata.VerificationHash)
+	}
+	o := newOptions(opts)
+	for i, sig := range sigs {
+		if sig == nil {
+			return false, newVerificationError(ErrSignatureInvalid, "Signature %d of revision %s is missing", i, r.Metadata.VerificationHash)
+		}
+		if !verifySignature(sig, r.Metadata.VerificationHash, o).isCorrect {
+			return false, newVerificationError(ErrSignatureInvalid, "Signature %d of revision %s by wallet %s is invalid", i, r.Metadata.VerificationHash, sig.WalletAddress)
+		}
+	}
+	return true, nil
+}
+
+// VerifySignersAllowed checks that every signature of revs is by one of the
+// allowed wallet addresses, compared case-insensitively. The signer is the
+// address recovered from the signature, which must be the wallet address of
+// the signature: a signature not by its wallet address is reported with
+// ErrSignatureInvalid, and the first signature by a wallet that is not allowed
+// with ErrSignerNotAllowed. A signed revision without metadata is reported
+// with ErrSignatureInvalid, and unsigned revisions are skipped. Like during
+// verification, eth_sign signatures are only accepted with WithEthSign.
+func VerifySignersAllowed(revs []*api.Revision, allowed []string, opts ...Option) error {
+	o := newOptions(opts)
+	wallets := map[string]bool{}
+	for _, w := range allowed {
+		wallets[strings.ToLower(w)] = true
+	}
+	for i, r := range revs {
+		sigs := r.AllSignatures()
+		if len(sigs) > 0 && r.Metadata == nil {
+			return newVerificationError(ErrSignatureInvalid, "Revision %d is signed but has no metadata", i)
+		}
+		for _, sig := range sigs {
+			signer, err := recoverSigner(r.Metadata.VerificationHash, sig, o.ethSign)
+			if err != nil {
+				return err
+			}
+			if signer == "" {
+				return newVerificationError(ErrSignatureInvalid, "Revision %s is not signed by wallet %s", r.Metadata.VerificationHash, sig.WalletAddress)
+			}
+			if !wallets[signer] {
+				return newVerificationError(ErrSignerNotAllowed, "Revision %s is signed by wallet %s, which is not allowed to sign", r.Metadata.VerificationHash, sig.WalletAddress)
+			}
+		}
+	}
+	return nil
+}
+
+// recoverSigner returns the lower case address that signed the hex encoded
+// verification hash with sig, with personal_sign or if ethSign is set with
+// eth_sign, or "" if that is not the wallet address of sig
+func recoverSigner(verificationHash string, sig *api.RevisionSignature, ethSign bool) (string, error) {
+	signature, err := hexutil.Decode(sig.Signature)
+	if err != nil {
+		return "", newVerificationError(ErrSignatureInvalid, "Malformed signature: %s", err)
+	}
+	wallet := strings.ToLower(sig.WalletAddress)
+	hashes := [][]byte{signatureMessageHash(verificationHash)}
+	if ethSign {
+		hashes = append(hashes, rawSignatureMessageHash(verificationHash))
+	}
+	for _, hash := range hashes {
+		if signer := recoverAddress(hash, signature); signer == wallet {
+			return signer, nil
+		}
+	}
+	return "", nil
+}
+
+// VerifySignerContinuity checks the signatures of revs, ordered from oldest to
+// newest: every signed revision must be signed by its wallet address, and the
+// has_previous_signature flag of every revision must be set exactly if the
+// previous revision is signed. A genesis revision must not claim a previous
+// signature.
+func VerifySignerContinuity(revs []*api.Revision, opts ...Option) error {
+	o := newOptions(opts)
+	signatures := signatureSet{}
+	for i, r := range revs {
+		if r.HasSignature() && verifyCurrentSignature(r, o).status != "VALID" {
+			return newVerificationError(ErrSignatureInvalid, "Revision %s is not signed by wallet %s", r.Metadata.VerificationHash, r.Signature.WalletAddress)
+		}
+		if err := signatures.add(r); err != nil {
+			return err
+		}
+		claimed := r.HasPreviousSignature()
+		var prevSigned bool
+		switch {
+		case i > 0:
+			prevSigned = revs[i-1].HasSignature()
+		case r.Metadata.PreviousVerificationHash != "":
+			// the previous revision is not part of revs
+			continue
+		}
+		if claimed && !prevSigned {
+			return newVerificationError(ErrBrokenChain, "Revision %s claims a previous signature, but the previous revision is not signed", r.Metadata.VerificationHash)
+		}
+		if !claimed && prevSigned {
+			return newVerificationError(ErrBrokenChain, "Revision %s doesn't claim the signature of the previous revision", r.Metadata.VerificationHash)
+		}
+	}
+	return nil
+}
+
+// signatureSet maps the signatures of revisions to the verification hash of
+// the revision they were first seen on
+type signatureSet map[string]string
+
+// add returns an error if the signature of r was already added for another
+// revision. A signature only signs a single verification hash, so a reused
+// signature was copied from another revision even if it verifies.
+func (s signatureSet) add(r *api.Revision) error {
+	if !r.HasSignature() {
+		return nil
+	}
+	signature := api.NormalizeHash(r.Signature.Signature)
+	if first, ok := s[signature]; ok && first != r.Metadata.VerificationHash {
+		return newVerificationError(ErrSignatureInvalid, "Signature of revision %s is reused from revision %s", r.Metadata.VerificationHash, first)
+	}
+	s[signature] = r.Metadata.VerificationHash
+	return nil
+}
+
+// withReusedSignature returns a copy of result with the signature marked
+// invalid by err, a reused signature reported by signatureSet.add. The result
+// itself may be cached for the revision on its own.
+func withReusedSignature(result *RevisionVerificationResult, err error) *RevisionVerificationResult {
+	reused := *result
+	reused.Status.Signature = "INVALID"
+	if reused.Error == nil {
+		reused.Error = err
+	}
+	return &reused
+}
@@ -0,0 +1,502 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/inblockio/aqua-verifier-go/api"
+	"github.com/stretchr/testify/require"
+)
+
+const testContractWallet = "0x45f59310add88e6d23ca58a0fa7a55bee6d2a611"
+
+func TestVerifySignatureSchemes(t *testing.T) {
+	require := require.New(t)
+	data, err := jsonDecodeFixture(fixture)
+	require.NoError(err)
+	page := data.Pages[0]
+
+	// The latest signed revision is signed by a contract wallet
+	contractSigned := page.Revisions[page.Revisions[page.LatestVerificationHash].Metadata.PreviousVerificationHash]
+	contractSigned.Signature.WalletAddress = testContractWallet
+	checker := func(ctx context.Context, wallet string, hash, signature []byte) (bool, error) {
+		return strings.ToLower(wallet) == testContractWallet, nil
+	}
+
+	result, err := VerifyHashChain(page, GlobalDoVerifyMerkleProof, -1, WithContractSignatureChecker(checker))
+	require.NoError(err)
+	require.Equal(map[string]int{
+		SIGNATURE_SCHEME_PERSONAL_SIGN: 3,
+		SIGNATURE_SCHEME_EIP1271:       1,
+	}, result.SignatureSchemes)
+	for _, r := range result.Revisions {
+		switch r.Status.Signature {
+		case "VALID":
+			require.NotEmpty(r.SignatureScheme)
+			require.NotZero(r.SignatureElapsed)
+		case "MISSING":
+			require.Empty(r.SignatureScheme)
+		default:
+			require.Fail("unexpected signature status", r.Status.Signature)
+		}
+	}
+	last := result.Revisions[len(result.Revisions)-2]
+	require.Equal(contractSigned.Metadata.VerificationHash, last.VerificationHash)
+	require.Equal(SIGNATURE_SCHEME_EIP1271, last.SignatureScheme)
+
+	// without a checker the contract wallet signature can't be verified
+	result, err = VerifyHashChain(page, GlobalDoVerifyMerkleProof, -1)
+	require.NoError(err)
+	require.Equal(map[string]int{SIGNATURE_SCHEME_PERSONAL_SIGN: 3}, result.SignatureSchemes)
+	require.Equal("INVALID", result.Revisions[len(result.Revisions)-2].Status.Signature)
+	require.ErrorIs(result.Revisions[len(result.Revisions)-2].SignatureError, ErrSignatureInvalid)
+
+	// a failing checker is reported
+	errRPC := errors.New("rpc down")
+	failing := func(ctx context.Context, wallet string, hash, signature []byte) (bool, error) {
+		return false, errRPC
+	}
+	result, err = VerifyHashChain(page, GlobalDoVerifyMerkleProof, -1, WithContractSignatureChecker(failing))
+	require.NoError(err)
+	require.ErrorIs(result.Revisions[len(result.Revisions)-2].Err(), errRPC)
+}
+
+func TestVerifySignerContinuity(t *testing.T) {
+	require := require.New(t)
+	data, err := jsonDecodeFixture(fixture)
+	require.NoError(err)
+	set, _, err := getVerificationSet(data.Pages[0], -1)
+	require.NoError(err)
+
+	require.NoError(VerifySignerContinuity(set))
+	// a partial chain can't be checked against the revision before it
+	require.NoError(VerifySignerContinuity(set[1:]))
+
+	// the third revision lies about having a previous signature
+	set[2].Context.HasPreviousSignature = true
+	require.EqualError(VerifySignerContinuity(set),
+		"Revision "+set[2].Metadata.VerificationHash+" claims a previous signature, but the previous revision is not signed")
+	set[2].Context.HasPreviousSignature = false
+
+	// and the second one denies the signature of the genesis revision
+	set[1].Context.HasPreviousSignature = false
+	require.EqualError(VerifySignerContinuity(set),
+		"Revision "+set[1].Metadata.VerificationHash+" doesn't claim the signature of the previous revision")
+	set[1].Context.HasPreviousSignature = true
+
+	set[0].Context.HasPreviousSignature = true
+	require.EqualError(VerifySignerContinuity(set[:1]),
+		"Revision "+set[0].Metadata.VerificationHash+" claims a previous signature, but the previous revision is not signed")
+	set[0].Context.HasPreviousSignature = false
+
+	set[3].Signature.WalletAddress = testContractWallet
+	require.EqualError(VerifySignerContinuity(set),
+		"Revision "+set[3].Metadata.VerificationHash+" is not signed by wallet "+testContractWallet)
+}
+
+func TestSignMessageForRevision(t *testing.T) {
+	require := require.New(t)
+	require.Equal("I sign the following page verification_hash: [0xab12]", SignMessageForRevision("0xAB12"))
+	first, _, err := get1st2ndFixtureVerStructure()
+	require.NoError(err)
+	hash := first.Metadata.VerificationHash
+	expected := "I sign the following page verification_hash: [0x" + hash + "]"
+	require.Equal(expected, SignMessageForRevision(hash))
+	require.Equal(expected, SignMessageForRevision("0x"+strings.ToUpper(hash)))
+
+	// the fixture's signature is a personal_sign signature of the message
+	signature, err := hexutil.Decode(first.Signature.Signature)
+	require.NoError(err)
+	require.Equal(strings.ToLower(first.Signature.WalletAddress),
+		recoverAddress(accounts.TextHash([]byte(SignMessageForRevision(hash))), signature))
+}
+
+func TestVerifySignatureReuse(t *testing.T) {
+	require := require.New(t)
+	data, err := jsonDecodeFixture(fixture)
+	require.NoError(err)
+	page := data.Pages[0]
+	set, _, err := getVerificationSet(page, -1)
+	require.NoError(err)
+	require.True(set[0].HasSignature())
+	require.True(set[3].HasSignature())
+
+	// the signature of the genesis revision is copied onto the fourth one,
+	// which a lenient verifier accepts
+	signature := *set[0].Signature
+	set[3].Signature = &signature
+	lenient := WithSignatureVerifier(SIGNATURE_FORMAT_ETHEREUM, &recordingVerifier{})
+	expected := "Signature of revision " + set[3].Metadata.VerificationHash + " is reused from revision " + set[0].Metadata.VerificationHash
+	require.EqualError(VerifySignerContinuity(set, lenient), expected)
+	// the verifier checking the message rejects the copy on its own
+	require.EqualError(VerifySignerContinuity(set),
+		"Revision "+set[3].Metadata.VerificationHash+" is not signed by wallet "+signature.WalletAddress)
+
+	cache := NewResultCache()
+	result, err := VerifyHashChain(page, GlobalDoVerifyMerkleProof, -1, lenient, WithOnChainChecks(false), WithResultCache(cache))
+	require.NoError(err)
+	require.Equal("VALID", result.Revisions[0].Status.Signature)
+	reused := result.Revisions[3]
+	require.Equal("INVALID", reused.Status.Signature)
+	require.EqualError(reused.Err(), expected)
+	require.ErrorIs(reused.Err(), ErrSignatureInvalid)
+	require.False(result.Valid())
+
+	// the cached result of the revision on its own is not affected
+	_, cached := verifyRevision(set[3], set[2], GlobalDoVerifyMerkleProof, lenient, WithOnChainChecks(false), WithResultCache(cache))
+	require.Equal("VALID", cached.Status.Signature)
+
+	// and streamed verifications detect the reuse as well
+	s := newFixtureServer(data)
+	defer s.Close()
+	ap, err := api.NewAPI(s.URL, "")
+	require.NoError(err)
+	var statuses []string
+	for r, err := range VerifyChainStream(context.Background(), ap, page.Title, GlobalDoVerifyMerkleProof, lenient, WithOnChainChecks(false)) {
+		require.NoError(err)
+		statuses = append(statuses, r.Status.Signature)
+	}
+	require.Equal("INVALID", statuses[3])
+}
+
+func TestVerifyDetachedSignature(t *testing.T) {
+	require := require.New(t)
+	key, err := crypto.HexToECDSA("4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318")
+	require.NoError(err)
+	wallet := crypto.PubkeyToAddress(key.PublicKey).Hex()
+
+	content := &api.RevisionContent{Content: map[string]string{
+		"main":                "Attested before it was committed",
+		"transclusion-hashes": `[{"dbkey":"Other_Page","ns":0,"verification_hash":"abc"}]`,
+	}}
+	sign := func(c *api.RevisionContent) *api.RevisionSignature {
+		signature, err := crypto.Sign(signatureMessageHash(calculateContentHash(nil, c)), key)
+		require.NoError(err)
+		signature[crypto.RecoveryIDOffset] += 27
+		sig := &api.RevisionSignature{
+			Signature:     hexutil.Encode(signature),
+			PublicKey:     hexutil.Encode(crypto.FromECDSAPub(&key.PublicKey)),
+			WalletAddress: wallet,
+		}
+		sig.SignatureHash = calculateSignatureHash(sig.Signature, sig.PublicKey)
+		return sig
+	}
+	sig := sign(content)
+
+	ok, err := VerifyDetachedSignature(content, sig)
+	require.NoError(err)
+	require.True(ok)
+
+	// the transcluded pages are part of the signed content
+	tampered := &api.RevisionContent{Content: map[string]string{"main": content.Content["main"]}}
+	ok, err = VerifyDetachedSignature(tampered, sig)
+	require.NoError(err)
+	require.False(ok)
+
+	// a stale content hash
+	tampered.ContentHash = calculateContentHash(nil, content)
+	_, err = VerifyDetachedSignature(tampered, sig)
+	require.True(errors.Is(err, ErrContentHashMismatch))
+
+	other := *sig
+	other.WalletAddress = testContractWallet
+	ok, err = VerifyDetachedSignature(content, &other)
+	require.NoError(err)
+	require.False(ok)
+
+	other = *sig
+	other.SignatureHash = "wrong"
+	_, err = VerifyDetachedSignature(content, &other)
+	require.EqualError(err, "Signature hash doesn't match")
+
+	other = *sig
+	other.Signature = "0x1234"
+	other.SignatureHash = ""
+	_, err = VerifyDetachedSignature(content, &other)
+	require.True(errors.Is(err, ErrSignatureInvalid))
+	require.EqualError(err, "Malformed signature: 2 bytes instead of 65")
+}
+
+func TestVerifySignatureConventions(t *testing.T) {
+	require := require.New(t)
+	key, err := crypto.HexToECDSA("4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318")
+	require.NoError(err)
+	first, _, err := get1st2ndFixtureVerStructure()
+	require.NoError(err)
+	verificationHash := first.Metadata.VerificationHash
+
+	// one signature of each kind over the same message
+	sign := func(hash []byte) *api.RevisionSignature {
+		signature, err := crypto.Sign(hash, key)
+		require.NoError(err)
+		signature[crypto.RecoveryIDOffset] += 27
+		return &api.RevisionSignature{
+			Signature:     hexutil.Encode(signature),
+			WalletAddress: crypto.PubkeyToAddress(key.PublicKey).Hex(),
+		}
+	}
+	personal := sign(signatureMessageHash(verificationHash))
+	raw := sign(rawSignatureMessageHash(verificationHash))
+	require.NotEqual(personal.Signature, raw.Signature)
+
+	for _, tc := range []struct {
+		sig          *api.RevisionSignature
+		expected     string
+		personalOnly bool
+	}{
+		{personal, SIGNATURE_SCHEME_PERSONAL_SIGN, true},
+		{raw, SIGNATURE_SCHEME_ETH_SIGN, false},
+	} {
+		scheme, err := EthereumSignatureVerifier{}.Scheme(verificationHash, tc.sig)
+		require.NoError(err)
+		require.Equal(tc.expected, scheme)
+		ok, err := EthereumSignatureVerifier{}.Verify(context.Background(), verificationHash, tc.sig)
+		require.NoError(err)
+		require.True(ok)
+
+		first.Signature = tc.sig
+		result := verifyCurrentSignature(first, newOptions([]Option{WithEthSign(true)}))
+		require.Equal("VALID", result.status)
+		require.Equal(tc.expected, result.scheme)
+
+		// eth_sign signatures are rejected by default
+		ok, err = EthereumSignatureVerifier{PersonalSignOnly: true}.Verify(context.Background(), verificationHash, tc.sig)
+		require.NoError(err)
+		require.Equal(tc.personalOnly, ok)
+		require.Equal(tc.personalOnly, verifyCurrentSignature(first, newOptions(nil)).isCorrect)
+		require.Equal(tc.personalOnly, verifyCurrentSignature(first, newOptions([]Option{WithEthSign(false)})).isCorrect)
+		err = VerifySignersAllowed([]*api.Revision{first}, []string{tc.sig.WalletAddress})
+		require.Equal(tc.personalOnly, err == nil)
+		require.NoError(VerifySignersAllowed([]*api.Revision{first}, []string{tc.sig.WalletAddress}, WithEthSign(true)))
+	}
+
+	// neither convention matches another message
+	scheme, err := EthereumSignatureVerifier{}.Scheme(first.Metadata.MetadataHash, raw)
+	require.NoError(err)
+	require.Empty(scheme)
+}
+
+func TestVerifySignatureHash(t *testing.T) {
+	require := require.New(t)
+	first, _, err := get1st2ndFixtureVerStructure()
+	require.NoError(err)
+	hash, err := first.Signature.ComputeHash()
+	require.NoError(err)
+	require.Equal(first.Signature.SignatureHash, hash)
+	require.True(verifyCurrentSignature(first, newOptions(nil)).isCorrect)
+
+	// the public key is not needed to recover the signer, only the signature
+	// hash binds it to the signature
+	signature := *first.Signature
+	first.Signature.PublicKey = "0x04tampered"
+	var checked bool
+	checker := func(ctx context.Context, wallet string, hash, signature []byte) (bool, error) {
+		checked = true
+		return true, nil
+	}
+	result := verifyCurrentSignature(first, newOptions([]Option{WithContractSignatureChecker(checker)}))
+	require.False(result.isCorrect)
+	require.Equal("INVALID", result.status)
+	require.ErrorIs(result.err, ErrSignatureInvalid)
+	require.False(checked)
+
+	// a tampered wallet address with the declared hash unchanged is rejected
+	*first.Signature = signature
+	first.Signature.WalletAddress = testContractWallet
+	require.False(verifyCurrentSignature(first, newOptions(nil)).isCorrect)
+
+	first.Signature.PublicKey = ""
+	_, err = first.Signature.ComputeHash()
+	require.Error(err)
+	require.False(verifyCurrentSignature(first, newOptions(nil)).isCorrect)
+}
+
+// recordingVerifier records the signers it was asked to verify
+type recordingVerifier struct {
+	next    SignatureVerifier
+	signers []string
+}
+
+func (v *recordingVerifier) Verify(ctx context.Context, verificationHash string, sig *api.RevisionSignature) (bool, error) {
+	v.signers = append(v.signers, sig.WalletAddress)
+	if v.next == nil {
+		return true, nil
+	}
+	return v.next.Verify(ctx, verificationHash, sig)
+}
+
+func TestSignatureVerifierDispatch(t *testing.T) {
+	require := require.New(t)
+	first, _, err := get1st2ndFixtureVerStructure()
+	require.NoError(err)
+	const didKey = "did:key:z6MkhaXgBZDvotDkL5257faiztiGiC2QtKLGpbnnEGta2doK"
+	const didWeb = "did:web:example.com"
+	require.Equal(SIGNATURE_FORMAT_ETHEREUM, signatureFormat(first.Signature))
+	require.Equal(SIGNATURE_FORMAT_DID_KEY, signatureFormat(&api.RevisionSignature{WalletAddress: didKey}))
+	require.Equal("did:web", signatureFormat(&api.RevisionSignature{WalletAddress: didWeb}))
+
+	ethereum := &recordingVerifier{next: EthereumSignatureVerifier{}}
+	did := &recordingVerifier{}
+	opts := []Option{WithSignatureVerifier(SIGNATURE_FORMAT_ETHEREUM, ethereum), WithSignatureVerifier("did:web", did)}
+	result := verifyCurrentSignature(first, newOptions(opts))
+	require.Equal("VALID", result.status)
+	require.Equal(SIGNATURE_SCHEME_PERSONAL_SIGN, result.scheme)
+	require.Equal([]string{first.Signature.WalletAddress}, ethereum.signers)
+	require.Empty(did.signers)
+
+	wallet := first.Signature.WalletAddress
+	first.Signature.WalletAddress = didWeb
+	result = verifyCurrentSignature(first, newOptions(opts))
+	require.Equal("VALID", result.status)
+	require.Equal("did:web", result.scheme)
+	require.Equal([]string{didWeb}, did.signers)
+	require.Len(ethereum.signers, 1)
+
+	// did:key signatures are not supported by the built in verifier yet
+	first.Signature.WalletAddress = didKey
+	result = verifyCurrentSignature(first, newOptions(opts))
+	require.Equal("INVALID", result.status)
+	require.ErrorIs(result.err, ErrUnsupportedSignature)
+	_, revision := verifyRevision(first, nil, GlobalDoVerifyMerkleProof, append(opts, WithOnChainChecks(false))...)
+	require.ErrorIs(revision.SignatureError, ErrUnsupportedSignature)
+	require.ErrorIs(revision.Err(), ErrUnsupportedSignature)
+	require.ErrorIs(revision.Err(), ErrSignatureInvalid)
+	_, err = DIDKeySignatureVerifier{}.Verify(context.Background(), first.Metadata.VerificationHash, first.Signature)
+	require.True(errors.Is(err, ErrUnsupportedSignature))
+	require.Len(did.signers, 1)
+
+	// and DID methods without a verifier are invalid
+	first.Signature.WalletAddress = didWeb
+	result = verifyCurrentSignature(first, newOptions(nil))
+	require.Equal("INVALID", result.status)
+	require.ErrorIs(result.err, ErrUnsupportedSignature)
+
+	first.Signature.WalletAddress = wallet
+	require.Equal("VALID", verifyCurrentSignature(first, newOptions(nil)).status)
+}
+
+func TestVerifySignatureWithKey(t *testing.T) {
+	require := require.New(t)
+	key, err := crypto.HexToECDSA("4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318")
+	require.NoError(err)
+	other, err := crypto.GenerateKey()
+	require.NoError(err)
+	message := SignMessageForRevision("9dab72c7635043452958c4cc2902f48ef7c4ae437058280197c6a2736ab9635f")
+	signature, err := crypto.Sign(accounts.TextHash([]byte(message)), key)
+	require.NoError(err)
+	signature[crypto.RecoveryIDOffset] += 27
+	// the embedded signer identity is not trusted
+	sig := &api.RevisionSignature{
+		Signature:     hexutil.Encode(signature),
+		PublicKey:     hexutil.Encode(crypto.FromECDSAPub(&other.PublicKey)),
+		WalletAddress: testContractWallet,
+	}
+
+	for _, pubKey := range [][]byte{crypto.FromECDSAPub(&key.PublicKey), crypto.CompressPubkey(&key.PublicKey)} {
+		ok, err := VerifySignatureWithKey(sig, pubKey, message)
+		require.NoError(err)
+		require.True(ok)
+	}
+	ok, err := VerifySignatureWithKey(sig, crypto.FromECDSAPub(&other.PublicKey), message)
+	require.NoError(err)
+	require.False(ok)
+	ok, err = VerifySignatureWithKey(sig, crypto.FromECDSAPub(&key.PublicKey), message+" tampered")
+	require.NoError(err)
+	require.False(ok)
+
+	_, err = VerifySignatureWithKey(sig, []byte{4, 1, 2, 3}, message)
+	require.Error(err)
+	_, err = VerifySignatureWithKey(&api.RevisionSignature{Signature: "0x0102"}, crypto.FromECDSAPub(&key.PublicKey), message)
+	require.True(errors.Is(err, ErrSignatureInvalid))
+}
+
+func TestVerifyAllSignatures(t *testing.T) {
+	require := require.New(t)
+	key, err := crypto.GenerateKey()
+	require.NoError(err)
+	first, _, err := get1st2ndFixtureVerStructure()
+	require.NoError(err)
+
+	signature, err := crypto.Sign(signatureMessageHash(first.Metadata.VerificationHash), key)
+	require.NoError(err)
+	signature[crypto.RecoveryIDOffset] += 27
+	cosigner := &api.RevisionSignature{
+		Signature:     hexutil.Encode(signature),
+		WalletAddress: crypto.PubkeyToAddress(key.PublicKey).Hex(),
+	}
+
+	ok, err := VerifyAllSignatures(first, WithOnChainChecks(false))
+	require.NoError(err)
+	require.True(ok)
+
+	first.Signatures = []*api.RevisionSignature{first.Signature, cosigner}
+	ok, err = VerifyAllSignatures(first, WithOnChainChecks(false))
+	require.NoError(err)
+	require.True(ok)
+
+	// a signature by another wallet than the declared one is invalid
+	forged := *cosigner
+	forged.WalletAddress = testContractWallet
+	first.Signatures = []*api.RevisionSignature{first.Signature, &forged}
+	ok, err = VerifyAllSignatures(first, WithOnChainChecks(false))
+	require.False(ok)
+	require.True(errors.Is(err, ErrSignatureInvalid))
+	require.Contains(err.Error(), "Signature 1 of revision")
+	require.Contains(err.Error(), testContractWallet)
+
+	first.Signature, first.Signatures = nil, nil
+	ok, err = VerifyAllSignatures(first)
+	require.False(ok)
+	require.True(errors.Is(err, ErrSignatureInvalid))
+}
+
+func TestVerifySignersAllowed(t *testing.T) {
+	require := require.New(t)
+	data, err := jsonDecodeFixture(fixture)
+	require.NoError(err)
+	set, _, err := getVerificationSet(data.Pages[0], -1)
+	require.NoError(err)
+	var signed *api.Revision
+	for _, r := range set {
+		if r.HasSignature() {
+			signed = r
+			break
+		}
+	}
+	require.NotNil(signed)
+	wallet := signed.Signature.WalletAddress
+
+	// addresses are compared case-insensitively
+	require.NoError(VerifySignersAllowed(set, []string{testContractWallet, strings.ToUpper(wallet)}))
+	require.NoError(VerifySignersAllowed(set, []string{strings.ToLower(wallet)}))
+
+	// a co-signer that is not allowed
+	key, err := crypto.GenerateKey()
+	require.NoError(err)
+	signature, err := crypto.Sign(signatureMessageHash(signed.Metadata.VerificationHash), key)
+	require.NoError(err)
+	signature[crypto.RecoveryIDOffset] += 27
+	cosigner := crypto.PubkeyToAddress(key.PublicKey).Hex()
+	signed.Signatures = []*api.RevisionSignature{signed.Signature, {Signature: hexutil.Encode(signature), WalletAddress: cosigner}}
+	err = VerifySignersAllowed(set, []string{wallet})
+	require.True(errors.Is(err, ErrSignerNotAllowed))
+	require.EqualError(err, "Revision "+signed.Metadata.VerificationHash+" is signed by wallet "+cosigner+", which is not allowed to sign")
+
+	// a signed revision without metadata fails instead of panicking
+	err = VerifySignersAllowed([]*api.Revision{{Signature: signed.Signature}}, []string{wallet})
+	require.True(errors.Is(err, ErrSignatureInvalid))
+	require.EqualError(err, "Revision 0 is signed but has no metadata")
+	require.NoError(VerifySignersAllowed(set, []string{wallet, cosigner}))
+	signed.Signatures = nil
+
+	require.True(errors.Is(VerifySignersAllowed(set, nil), ErrSignerNotAllowed))
+
+	// the declared wallet must be the signer
+	signed.Signature.WalletAddress = testContractWallet
+	require.True(errors.Is(VerifySignersAllowed(set, []string{testContractWallet}), ErrSignatureInvalid))
+}
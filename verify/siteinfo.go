@@ -0,0 +1,60 @@
+package verify
+
+import (
+	"reflect"
+
+	"github.com/inblockio/aqua-verifier-go/api"
+)
+
+// SiteInfoConflict is a page of an export whose site info differs from the
+// site info of the export. All pages of an export come from the same wiki, so
+// a conflict hints at data stitched together from different wikis.
+type SiteInfoConflict struct {
+	Title string `json:"title"`
+	// Fields are the json names of the differing fields, e.g. dbname
+	Fields []string `json:"fields"`
+}
+
+// CheckSiteInfo returns the pages of data whose site info differs from the
+// site info of the export, or from the site info of the first page that has
+// one if the export has none. Revisions don't carry site info, so it is
+// checked across the pages rather than the revisions of a chain. The
+// sitename, dbname, base and namespaces fields are compared; the generator
+// and case are not, as they change when the wiki is upgraded or
+// reconfigured. Pages without site info are skipped.
+func CheckSiteInfo(data *api.OfflineData) []SiteInfoConflict {
+	expected := data.SiteInfo
+	var conflicts []SiteInfoConflict
+	for _, p := range data.Pages {
+		if p.SiteInfo == nil {
+			continue
+		}
+		if expected == nil {
+			expected = p.SiteInfo
+			continue
+		}
+		if fields := siteInfoDiff(expected, p.SiteInfo); len(fields) > 0 {
+			conflicts = append(conflicts, SiteInfoConflict{Title: p.Title, Fields: fields})
+		}
+	}
+	return conflicts
+}
+
+// siteInfoDiff returns the json names of the compared fields of a and b that
+// differ
+func siteInfoDiff(a, b *api.SiteInfo) []string {
+	var fields []string
+	if a.SiteName != b.SiteName {
+		fields = append(fields, "sitename")
+	}
+	if a.DbName != b.DbName {
+		fields = append(fields, "dbname")
+	}
+	if a.Base != b.Base {
+		fields = append(fields, "base")
+	}
+	if !reflect.DeepEqual(a.Namespaces, b.Namespaces) {
+		fields = append(fields, "namespaces")
+	}
+	return fields
+}
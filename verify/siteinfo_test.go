@@ -0,0 +1,48 @@
+package verify
+
+import (
+	"testing"
+
+	"github.com/inblockio/aqua-verifier-go/api"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckSiteInfo(t *testing.T) {
+	require := require.New(t)
+	data, err := jsonDecodeFixture(fixture)
+	require.NoError(err)
+	require.NotNil(data.SiteInfo)
+	require.Empty(CheckSiteInfo(data))
+
+	site := func(dbname string, namespaces map[int]*api.Namespace) *api.SiteInfo {
+		return &api.SiteInfo{SiteName: "PKC", DbName: dbname, Base: "http://localhost:9352/index.php/Main_Page", Generator: "MediaWiki 1.37.1", Namespaces: namespaces}
+	}
+	namespaces := map[int]*api.Namespace{0: {Case: true, Title: ""}, 6942: {Case: true, Title: "Data Accounting"}}
+	upgraded := site("my_wiki", map[int]*api.Namespace{0: {Case: true, Title: ""}, 6942: {Case: true, Title: "Data Accounting"}})
+	upgraded.Generator = "MediaWiki 1.39.0"
+	consistent := &api.OfflineData{Pages: []*api.HashChain{
+		{HashChainInfo: api.HashChainInfo{Title: "A", SiteInfo: site("my_wiki", namespaces)}},
+		// pages may omit their site info
+		{HashChainInfo: api.HashChainInfo{Title: "B"}},
+		{HashChainInfo: api.HashChainInfo{Title: "C", SiteInfo: upgraded}},
+	}}
+	require.Empty(CheckSiteInfo(consistent))
+
+	// the first page with site info is the reference without an export site
+	// info
+	divergent := &api.OfflineData{Pages: []*api.HashChain{
+		{HashChainInfo: api.HashChainInfo{Title: "A"}},
+		{HashChainInfo: api.HashChainInfo{Title: "B", SiteInfo: site("my_wiki", namespaces)}},
+		{HashChainInfo: api.HashChainInfo{Title: "C", SiteInfo: site("other_wiki", map[int]*api.Namespace{0: {Case: true, Title: ""}})}},
+		{HashChainInfo: api.HashChainInfo{Title: "D", SiteInfo: site("my_wiki", namespaces)}},
+	}}
+	require.Equal([]SiteInfoConflict{{Title: "C", Fields: []string{"dbname", "namespaces"}}}, CheckSiteInfo(divergent))
+
+	// the site info of the export takes precedence
+	divergent.SiteInfo = site("other_wiki", namespaces)
+	require.Equal([]SiteInfoConflict{
+		{Title: "B", Fields: []string{"dbname"}},
+		{Title: "C", Fields: []string{"namespaces"}},
+		{Title: "D", Fields: []string{"dbname"}},
+	}, CheckSiteInfo(divergent))
+}
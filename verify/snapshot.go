@@ -0,0 +1,55 @@
+package verify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/inblockio/aqua-verifier-go/api"
+)
+
+// VerifyDomainSnapshot fetches the domain snapshot a witness refers to and
+// verifies its hash chain from the genesis revision on. The domain snapshot
+// is the page listing the verification hashes a witness event commits to with
+// its merkle root, so this links the witness of a single revision to the
+// snapshot of its domain. It returns whether the snapshot chain is valid, and
+// an error if the snapshot can't be fetched or is not the one referred to by
+// witness.
+func VerifyDomainSnapshot(ctx context.Context, ap api.AquaClient, witness *api.RevisionWitness, opts ...Option) (bool, error) {
+	if witness.DomainSnapshotGenesisHash == "" {
+		return false, fmt.Errorf("Witness event %d has no domain snapshot", witness.WitnessEventId)
+	}
+	data, err := api.FetchHashChain(ctx, ap, "genesis_hash", witness.DomainSnapshotGenesisHash, -1)
+	if err != nil {
+		return false, fmt.Errorf("Failure getting domain snapshot %s: %w", witness.DomainSnapshotGenesisHash, err)
+	}
+	switch {
+	case data.GenesisHash != witness.DomainSnapshotGenesisHash:
+		return false, newVerificationError(ErrWitnessMismatch, "Server returned domain snapshot %s instead of %s",
+			data.GenesisHash, witness.DomainSnapshotGenesisHash)
+	case witness.DomainSnapshotTitle != "" && !sameTitle(data.Title, witness.DomainSnapshotTitle):
+		return false, newVerificationError(ErrWitnessMismatch, "Domain snapshot is titled %s instead of %s",
+			data.Title, witness.DomainSnapshotTitle)
+	case witness.DomainId != "" && data.DomainId != witness.DomainId:
+		return false, newVerificationError(ErrWitnessMismatch, "Domain snapshot belongs to domain %s instead of %s",
+			data.DomainId, witness.DomainId)
+	}
+	if opts, err = withServerHashAlgorithm(ctx, ap, opts); err != nil {
+		return false, err
+	}
+	c, err := verifyHashChain(ctx, tracerOf(ap), data, true, -1, opts)
+	if err != nil {
+		return false, err
+	}
+	if len(c.Revisions) == 0 || c.Revisions[0].VerificationHash != witness.DomainSnapshotGenesisHash {
+		return false, newVerificationError(ErrBrokenChain, "Domain snapshot doesn't lead back to its genesis revision %s",
+			witness.DomainSnapshotGenesisHash)
+	}
+	return c.Valid(), nil
+}
+
+// sameTitle compares MediaWiki titles, which use spaces and underscores
+// interchangeably
+func sameTitle(a, b string) bool {
+	return strings.ReplaceAll(a, "_", " ") == strings.ReplaceAll(b, "_", " ")
+}
@@ -0,0 +1,57 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/inblockio/aqua-verifier-go/api"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyDomainSnapshot(t *testing.T) {
+	require := require.New(t)
+	data, err := jsonDecodeFixture(fixture)
+	require.NoError(err)
+	// the page stands in for the domain snapshot
+	snapshot := data.Pages[0]
+	ts := newFixtureServer(data)
+	defer ts.Close()
+	ap, err := api.NewAPI(ts.URL, "")
+	require.NoError(err)
+	ctx := context.Background()
+
+	witness := &api.RevisionWitness{
+		DomainId:                  snapshot.DomainId,
+		DomainSnapshotTitle:       snapshot.Title,
+		DomainSnapshotGenesisHash: snapshot.GenesisHash,
+	}
+	ok, err := VerifyDomainSnapshot(ctx, ap, witness, WithOnChainChecks(false))
+	require.NoError(err)
+	require.True(ok)
+
+	other := *witness
+	other.DomainSnapshotTitle = "Data Accounting:DomainSnapshot:other"
+	_, err = VerifyDomainSnapshot(ctx, ap, &other, WithOnChainChecks(false))
+	require.True(errors.Is(err, ErrWitnessMismatch))
+	require.EqualError(err, "Domain snapshot is titled "+snapshot.Title+" instead of Data Accounting:DomainSnapshot:other")
+
+	other = *witness
+	other.DomainId = "other"
+	_, err = VerifyDomainSnapshot(ctx, ap, &other, WithOnChainChecks(false))
+	require.True(errors.Is(err, ErrWitnessMismatch))
+
+	other = *witness
+	other.DomainSnapshotGenesisHash = "unknown"
+	_, err = VerifyDomainSnapshot(ctx, ap, &other, WithOnChainChecks(false))
+	require.Error(err)
+
+	_, err = VerifyDomainSnapshot(ctx, ap, &api.RevisionWitness{WitnessEventId: 1})
+	require.EqualError(err, "Witness event 1 has no domain snapshot")
+
+	// a tampered snapshot revision
+	snapshot.Revisions[snapshot.LatestVerificationHash].Content.Content["main"] += "tampered"
+	ok, err = VerifyDomainSnapshot(ctx, ap, witness, WithOnChainChecks(false))
+	require.NoError(err)
+	require.False(ok)
+}
@@ -0,0 +1,107 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/inblockio/aqua-verifier-go/api"
+)
+
+// RevisionStore is a local copy of hash chains, see SyncChain
+type RevisionStore interface {
+	// Has returns true if the store holds the revision with the given
+	// verification hash
+	Has(verification_hash string) bool
+	// Put adds a verified revision to the store
+	Put(r *api.Revision) error
+}
+
+// SyncChain adds the revisions of the page with the given title that are
+// newer than the newest revision in store. That revision is found walking back
+// from the latest revision, and only the revision hashes after it are listed.
+// Every new revision is verified before it is put into the store, and syncing
+// stops at the first revision that fails verification. It returns the number
+// of revisions added.
+func SyncChain(ctx context.Context, ap api.AquaClient, store RevisionStore, title string, opts ...Option) (added int, err error) {
+	info, err := ap.GetHashChainInfoContext(ctx, "title", title)
+	if err != nil {
+		return 0, err
+	}
+	if store.Has(info.LatestVerificationHash) {
+		return 0, nil
+	}
+	// fetch the new revisions from the latest back to the newest revision
+	// the store has, or the genesis revision
+	max := newOptions(opts).maxChainHeight
+	fetched := make(map[string]*api.Revision)
+	since := ""
+	for cur := info.LatestVerificationHash; cur != ""; {
+		if store.Has(cur) {
+			since = cur
+			break
+		}
+		if _, ok := fetched[cur]; ok {
+			return 0, newVerificationError(ErrBrokenChain, "Revision %s is part of a cycle", cur)
+		}
+		if max > 0 && len(fetched) >= max {
+			return 0, newVerificationError(ErrChainTooHigh, "Chain of %s has more than %d revisions", title, max)
+		}
+		r, err := ap.GetRevisionContext(ctx, cur)
+		if err == nil && r.Metadata == nil {
+			err = errors.New("Revision has no metadata")
+		}
+		if err != nil {
+			return 0, fmt.Errorf("Failure getting revision %s: %w", cur, err)
+		}
+		fetched[cur] = r
+		cur = r.Metadata.PreviousVerificationHash
+	}
+	first := since
+	if first == "" {
+		first = info.GenesisHash
+	}
+	hashes, err := ap.GetRevisionHashesContext(ctx, first)
+	if err != nil {
+		return 0, err
+	}
+	if len(hashes) == 0 || string(*hashes[0]) != first || string(*hashes[len(hashes)-1]) != info.LatestVerificationHash {
+		return 0, newVerificationError(ErrBrokenChain, "Revision hashes of %s don't lead from %s to the latest revision %s", title, first, info.LatestVerificationHash)
+	}
+	var prev *api.Revision
+	if since != "" {
+		// the newest stored revision links the new ones to the store
+		hashes = hashes[1:]
+		prev, err = ap.GetRevisionContext(ctx, since)
+		if err == nil && prev.Metadata == nil {
+			err = errors.New("Revision has no metadata")
+		}
+		if err != nil {
+			return 0, fmt.Errorf("Failure getting previous revision %s: %w", since, err)
+		}
+	}
+
+	if opts, err = withServerHashAlgorithm(ctx, ap, append(opts[:len(opts):len(opts)], withContext(ctx))); err != nil {
+		return 0, err
+	}
+	for _, h := range hashes {
+		hash := string(*h)
+		r, ok := fetched[hash]
+		if !ok {
+			return added, newVerificationError(ErrBrokenChain, "Revision %s is listed, but doesn't lead to the latest revision", hash)
+		}
+		if prev != nil && r.Metadata.PreviousVerificationHash != prev.Metadata.VerificationHash {
+			return added, newVerificationError(ErrBrokenChain, "Revision %s doesn't follow revision %s", hash, prev.Metadata.VerificationHash)
+		}
+		_, result := verifyRevision(r, prev, true, opts...)
+		if err := result.Err(); err != nil {
+			return added, fmt.Errorf("Revision %s: %w", hash, err)
+		}
+		if err := store.Put(r); err != nil {
+			return added, err
+		}
+		added++
+		prev = r
+	}
+	return added, nil
+}
@@ -0,0 +1,78 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/inblockio/aqua-verifier-go/api"
+	"github.com/stretchr/testify/require"
+)
+
+type memStore map[string]*api.Revision
+
+func (m memStore) Has(verification_hash string) bool {
+	_, ok := m[verification_hash]
+	return ok
+}
+
+func (m memStore) Put(r *api.Revision) error {
+	m[r.Metadata.VerificationHash] = r
+	return nil
+}
+
+func TestSyncChain(t *testing.T) {
+	require := require.New(t)
+	data, err := jsonDecodeFixture(fixture)
+	require.NoError(err)
+	s := newFixtureServer(data)
+	defer s.Close()
+	// the revisions the hashes were listed from
+	var listed []string
+	handler := s.Config.Handler
+	s.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hash, ok := strings.CutPrefix(r.URL.Path, "/data_accounting/get_revision_hashes/"); ok {
+			listed = append(listed, hash)
+		}
+		handler.ServeHTTP(w, r)
+	})
+	ap, err := api.NewAPI(s.URL, "")
+	require.NoError(err)
+	page := data.Pages[0]
+	ctx := context.Background()
+
+	// an empty store gets the whole chain
+	store := memStore{}
+	added, err := SyncChain(ctx, ap, store, page.Title, WithOnChainChecks(false))
+	require.NoError(err)
+	require.Equal(page.ChainHeight, added)
+	require.Len(store, page.ChainHeight)
+	require.Equal([]string{page.GenesisHash}, listed)
+
+	// an up to date store gets nothing
+	added, err = SyncChain(ctx, ap, store, page.Title, WithOnChainChecks(false))
+	require.NoError(err)
+	require.Zero(added)
+
+	// a store missing the last two revisions gets those
+	set, _, err := getVerificationSet(page, -1)
+	require.NoError(err)
+	delete(store, set[len(set)-1].Metadata.VerificationHash)
+	delete(store, set[len(set)-2].Metadata.VerificationHash)
+	added, err = SyncChain(ctx, ap, store, page.Title, WithOnChainChecks(false))
+	require.NoError(err)
+	require.Equal(2, added)
+	require.Len(store, page.ChainHeight)
+	// only the hashes after the newest stored revision are listed
+	require.Equal([]string{page.GenesisHash, set[len(set)-3].Metadata.VerificationHash}, listed)
+
+	// a revision that doesn't verify is not stored
+	delete(store, page.LatestVerificationHash)
+	page.Revisions[page.LatestVerificationHash].Content.Content["main"] = "tampered"
+	added, err = SyncChain(ctx, ap, store, page.Title, WithOnChainChecks(false))
+	require.Zero(added)
+	require.True(errors.Is(err, ErrContentHashMismatch))
+	require.False(store.Has(page.LatestVerificationHash))
+}
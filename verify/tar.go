@@ -0,0 +1,53 @@
+package verify
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/inblockio/aqua-verifier-go/api"
+)
+
+// VerifyChainTar verifies a hash chain archive written by api.ExportChainTar
+// like VerifyHashChain, without contacting the server the chain was exported
+// from. On-chain checks are disabled unless re-enabled with
+// WithOnChainChecks(true), so that the archive is verified offline.
+func VerifyChainTar(r io.Reader, doVerifyMerkleProof bool, opts ...Option) (*ChainVerificationResult, error) {
+	var info *api.HashChainInfo
+	revisions := make(map[string]*api.Revision)
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case hdr.Name == api.TarChainInfoName:
+			info = new(api.HashChainInfo)
+			if err := json.NewDecoder(tr).Decode(info); err != nil {
+				return nil, fmt.Errorf("%s: %w", hdr.Name, err)
+			}
+		case strings.HasPrefix(hdr.Name, api.TarRevisionsDir):
+			rev := new(api.Revision)
+			if err := json.NewDecoder(tr).Decode(rev); err != nil {
+				return nil, fmt.Errorf("%s: %w", hdr.Name, err)
+			}
+			if rev.Metadata == nil {
+				return nil, fmt.Errorf("%s: Revision has no metadata", hdr.Name)
+			}
+			revisions[rev.Metadata.VerificationHash] = rev
+		}
+	}
+	if info == nil {
+		return nil, errors.New("Archive has no " + api.TarChainInfoName)
+	}
+	opts = append([]Option{WithOnChainChecks(false)}, opts...)
+	return VerifyHashChain(&api.HashChain{HashChainInfo: *info, Revisions: revisions}, doVerifyMerkleProof, -1, opts...)
+}
@@ -0,0 +1,65 @@
+package verify
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/inblockio/aqua-verifier-go/api"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportVerifyChainTar(t *testing.T) {
+	require := require.New(t)
+	data, err := jsonDecodeFixture(fixture)
+	require.NoError(err)
+	s := newFixtureServer(data)
+	defer s.Close()
+	ap, err := api.NewAPI(s.URL, "")
+	require.NoError(err)
+
+	buf := new(bytes.Buffer)
+	require.NoError(ap.ExportChainTar(context.Background(), "title", "Main Page", buf))
+
+	// archives are reproducible
+	again := new(bytes.Buffer)
+	require.NoError(ap.ExportChainTar(context.Background(), "title", "Main Page", again))
+	require.Equal(buf.Bytes(), again.Bytes())
+
+	page := data.Pages[0]
+	archive := buf.Bytes()
+	result, err := VerifyChainTar(bytes.NewReader(archive), true)
+	require.NoError(err)
+	require.Equal(page.GenesisHash, result.GenesisHash)
+	require.Len(result.Revisions, page.ChainHeight)
+	require.Equal(page.GenesisHash, result.Revisions[0].VerificationHash)
+	require.Equal(page.LatestVerificationHash, result.Revisions[page.ChainHeight-1].VerificationHash)
+	for _, r := range result.Revisions {
+		require.NoError(r.Error)
+		require.True(r.Status.Content)
+		require.True(r.Status.Metadata)
+		require.Equal(VERIFIED_VERIFICATION_STATUS, r.Status.Verification)
+	}
+
+	// witness transactions are only looked up if on-chain checks are enabled
+	resolver := &contextResolver{fakeResolver: fakeResolver{}}
+	var opts []Option
+	for _, r := range page.Revisions {
+		if r.HasWitness() {
+			opts = append(opts, WithWitnessResolver(r.Witness.WitnessNetwork, resolver))
+			resolver.fakeResolver[r.Witness.WitnessEventTransactionHash] = r.Witness.WitnessEventVerificationHash
+		}
+	}
+	require.NotEmpty(opts)
+	result, err = VerifyChainTar(bytes.NewReader(archive), true, opts...)
+	require.NoError(err)
+	require.NoError(result.Err())
+	require.Empty(resolver.contexts)
+	result, err = VerifyChainTar(bytes.NewReader(archive), true, append(opts, WithOnChainChecks(true))...)
+	require.NoError(err)
+	require.NoError(result.Err())
+	require.NotEmpty(resolver.contexts)
+
+	_, err = VerifyChainTar(bytes.NewReader(nil), true)
+	require.EqualError(err, "Archive has no chain_info.json")
+}
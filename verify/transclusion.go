@@ -0,0 +1,129 @@
+package verify
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/inblockio/aqua-verifier-go/api"
+)
+
+// TransclusionResult holds the verification result of a page transcluded by
+// a revision
+type TransclusionResult struct {
+	api.Transclusion
+	// Revision is the result of verifying the transcluded revision with its
+	// previous revision, unless its chain is verified
+	Revision *RevisionVerificationResult `json:"revision,omitempty"`
+	// Chain is the result of verifying the chain of the transcluded page from
+	// its genesis revision up to the transcluded revision, see
+	// WithTransclusionDepth
+	Chain *ChainVerificationResult `json:"chain,omitempty"`
+	// Transclusions are the results of the pages transcluded by the
+	// transcluded revision, see WithTransclusionDepth
+	Transclusions []*TransclusionResult `json:"transclusions,omitempty"`
+	// Error reports why the transcluded revision couldn't be verified
+	Error error `json:"-"`
+}
+
+// Valid returns whether the transcluded revision and, if they were verified,
+// its chain and the pages it transcludes are valid. The transclusion of a page
+// that doesn't exist is valid.
+func (t *TransclusionResult) Valid() bool {
+	if t.Error != nil || (t.Revision != nil && !t.Revision.Valid()) || (t.Chain != nil && !t.Chain.Valid()) {
+		return false
+	}
+	for _, child := range t.Transclusions {
+		if !child.Valid() {
+			return false
+		}
+	}
+	return true
+}
+
+// VerifyTransclusions fetches the revisions transcluded by r from ap and
+// verifies them. By default every transcluded revision is verified with its
+// previous revision, like GetVerifiedRevision; with WithTransclusionDepth the
+// chains of the transcluded pages and the pages they transclude are verified
+// as well. Failures to fetch a transcluded revision are reported by its
+// result. The returned error reports malformed transclusion hashes of r.
+func VerifyTransclusions(ctx context.Context, ap api.AquaClient, r *api.Revision, opts ...Option) ([]*TransclusionResult, error) {
+	opts, err := withServerHashAlgorithm(ctx, ap, opts)
+	if err != nil {
+		return nil, err
+	}
+	o := newOptions(opts)
+	return verifyTransclusions(ctx, ap, r, o.transclusionDepth, map[string]bool{r.Metadata.VerificationHash: true}, o, opts)
+}
+
+// verifyTransclusions verifies the transclusions of r, following them depth
+// levels deep. path holds the verification hashes of the revisions that
+// transclude r, which are not followed again.
+func verifyTransclusions(ctx context.Context, ap api.AquaClient, r *api.Revision, depth int, path map[string]bool, o *options, opts []Option) ([]*TransclusionResult, error) {
+	transclusions, err := r.Content.ParseTransclusions()
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*TransclusionResult, len(transclusions))
+	for i, t := range transclusions {
+		result := &TransclusionResult{Transclusion: t}
+		results[i] = result
+		hash := api.NormalizeHash(t.VerificationHash)
+		switch {
+		case hash == "":
+			continue
+		case path[hash]:
+			result.Error = newVerificationError(ErrBrokenChain, "Transclusion of revision %s is cyclic", hash)
+			continue
+		case depth <= 0:
+			_, result.Revision, result.Error = GetVerifiedRevision(ctx, ap, hash, opts...)
+			continue
+		}
+		var latest *api.Revision
+		latest, result.Chain, result.Error = verifyChainUpTo(ctx, ap, t.DbKey, hash, t.GenesisHash, o, opts)
+		if result.Error != nil || depth == 1 {
+			continue
+		}
+		path[hash] = true
+		result.Transclusions, result.Error = verifyTransclusions(ctx, ap, latest, depth-1, path, o, opts)
+		delete(path, hash)
+	}
+	return results, nil
+}
+
+// verifyChainUpTo fetches the chain of the page with the given title from the
+// revision with verification hash latest back to its genesis revision, and
+// verifies it. If genesis is not empty, the chain must start at the revision
+// with that verification hash. It returns the revision latest and the result.
+func verifyChainUpTo(ctx context.Context, ap api.AquaClient, title, latest, genesis string, o *options, opts []Option) (*api.Revision, *ChainVerificationResult, error) {
+	data := &api.HashChain{
+		HashChainInfo: api.HashChainInfo{Title: title, LatestVerificationHash: latest},
+		Revisions:     make(map[string]*api.Revision),
+	}
+	for cur := latest; cur != ""; {
+		if _, ok := data.Revisions[cur]; ok {
+			return nil, nil, fmt.Errorf("Revision %s is part of a cycle", cur)
+		}
+		if o.maxChainHeight > 0 && len(data.Revisions) >= o.maxChainHeight {
+			return nil, nil, newVerificationError(ErrChainTooHigh, "Chain of %s has more than %d revisions", title, o.maxChainHeight)
+		}
+		r, err := ap.GetRevisionContext(ctx, cur)
+		if err == nil && r.Metadata == nil {
+			err = fmt.Errorf("Revision has no metadata")
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("Failure getting revision %s: %w", cur, err)
+		}
+		data.Revisions[cur] = r
+		data.GenesisHash = cur
+		cur = r.Metadata.PreviousVerificationHash
+	}
+	if genesis != "" && api.NormalizeHash(genesis) != api.NormalizeHash(data.GenesisHash) {
+		return nil, nil, newVerificationError(ErrBrokenChain, "Revision %s is not of the chain with genesis revision %s", latest, genesis)
+	}
+	c, err := verifyHashChain(ctx, tracerOf(ap), data, true, -1, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	c.setSourceOf(ap)
+	return data.Revisions[latest], c, nil
+}
@@ -0,0 +1,171 @@
+package verify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/inblockio/aqua-verifier-go/api"
+	"github.com/stretchr/testify/require"
+)
+
+// appendTransclusion appends a revision to page which transcludes the latest
+// revisions of the pages transcluded
+func appendTransclusion(require *require.Assertions, page *api.HashChain, transcluded ...*api.HashChain) *api.Revision {
+	transclusions := []api.Transclusion{}
+	for _, p := range transcluded {
+		transclusions = append(transclusions, api.Transclusion{DbKey: p.Title, VerificationHash: p.LatestVerificationHash})
+	}
+	return appendTransclusions(require, page, transclusions)
+}
+
+// appendTransclusions appends a revision to page with the given transclusions
+func appendTransclusions(require *require.Assertions, page *api.HashChain, transclusions []api.Transclusion) *api.Revision {
+	slot, err := json.Marshal(transclusions)
+	require.NoError(err)
+	content := &api.RevisionContent{Content: map[string]string{"main": "Revision of " + page.Title, "transclusion-hashes": string(slot)}}
+	_, content.ContentHash = ExplainContentHash(content)
+	metadata := &api.RevisionMetadata{
+		DomainId:                 "5e5a1ec586",
+		Timestamp:                api.Timestamp{Time: time.Date(2022, 1, 1, 0, 0, len(page.Revisions), 0, time.UTC)},
+		PreviousVerificationHash: page.LatestVerificationHash,
+	}
+	_, metadata.MetadataHash = ExplainMetadataHash(metadata)
+	r := &api.Revision{Context: &api.VerificationContext{}, Content: content, Metadata: metadata}
+	metadata.VerificationHash, err = ComputeVerificationHash(r, page.Revisions[page.LatestVerificationHash])
+	require.NoError(err)
+
+	if page.GenesisHash == "" {
+		page.GenesisHash = metadata.VerificationHash
+	}
+	page.LatestVerificationHash = metadata.VerificationHash
+	page.ChainHeight++
+	page.Revisions[metadata.VerificationHash] = r
+	return r
+}
+
+func newTransclusionPage(title string) *api.HashChain {
+	return &api.HashChain{HashChainInfo: api.HashChainInfo{Title: title}, Revisions: map[string]*api.Revision{}}
+}
+
+func TestVerifyTransclusions(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	// Main Page transcludes Template:A, which transcludes Template:B
+	b := newTransclusionPage("Template:B")
+	appendTransclusion(require, b)
+	appendTransclusion(require, b)
+	a := newTransclusionPage("Template:A")
+	appendTransclusion(require, a)
+	appendTransclusion(require, a, b)
+	main := newTransclusionPage("Main Page")
+	r := appendTransclusion(require, main, a, newTransclusionPage("Missing"))
+	backend := &fakeBackend{pages: []*api.HashChain{main, a, b}, calls: map[string]int{}}
+
+	// by default only the transcluded revisions are verified
+	results, err := VerifyTransclusions(ctx, backend, r, WithOnChainChecks(false))
+	require.NoError(err)
+	require.Len(results, 2)
+	require.Equal("Template:A", results[0].DbKey)
+	require.True(results[0].Valid())
+	require.NotNil(results[0].Revision)
+	require.Nil(results[0].Chain)
+	require.Empty(results[0].Transclusions)
+	require.Equal("Missing", results[1].DbKey)
+	require.True(results[1].Valid())
+	require.Nil(results[1].Revision)
+
+	// the chains of both levels of transclusions
+	results, err = VerifyTransclusions(ctx, backend, r, WithOnChainChecks(false), WithTransclusionDepth(2))
+	require.NoError(err)
+	require.True(results[0].Valid())
+	require.Nil(results[0].Revision)
+	require.Len(results[0].Chain.Revisions, 2)
+	require.Len(results[0].Transclusions, 1)
+	child := results[0].Transclusions[0]
+	require.Equal("Template:B", child.DbKey)
+	require.Len(child.Chain.Revisions, 2)
+	require.Empty(child.Transclusions)
+
+	// the depth limit
+	results, err = VerifyTransclusions(ctx, backend, r, WithOnChainChecks(false), WithTransclusionDepth(1))
+	require.NoError(err)
+	require.NotNil(results[0].Chain)
+	require.Empty(results[0].Transclusions)
+
+	// a tampered revision deep in the chain of a transcluded page
+	genesis := b.Revisions[b.GenesisHash]
+	saved := genesis.Content.Content["main"]
+	genesis.Content.Content["main"] = "tampered"
+	results, err = VerifyTransclusions(ctx, backend, r, WithOnChainChecks(false), WithTransclusionDepth(2))
+	require.NoError(err)
+	require.False(results[0].Valid())
+	require.True(results[0].Chain.Valid())
+	require.False(results[0].Transclusions[0].Chain.Valid())
+	genesis.Content.Content["main"] = saved
+
+	// a transcluded revision that can't be fetched
+	delete(a.Revisions, a.LatestVerificationHash)
+	results, err = VerifyTransclusions(ctx, backend, r, WithOnChainChecks(false), WithTransclusionDepth(2))
+	require.NoError(err)
+	require.False(results[0].Valid())
+	require.True(errors.Is(results[0].Error, errNotFound))
+}
+
+func TestVerifyTransclusionsGenesisHash(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	a := newTransclusionPage("Template:A")
+	appendTransclusion(require, a)
+	appendTransclusion(require, a)
+	b := newTransclusionPage("Template:B")
+	appendTransclusion(require, b)
+	main := newTransclusionPage("Main Page")
+	r := appendTransclusions(require, main, []api.Transclusion{
+		{DbKey: a.Title, GenesisHash: "0x" + strings.ToUpper(a.GenesisHash), VerificationHash: a.LatestVerificationHash},
+		// a revision of Template:A claimed to be of the chain of Template:B
+		{DbKey: b.Title, GenesisHash: b.GenesisHash, VerificationHash: a.LatestVerificationHash},
+	})
+	backend := &fakeBackend{pages: []*api.HashChain{main, a, b}, calls: map[string]int{}}
+
+	results, err := VerifyTransclusions(ctx, backend, r, WithOnChainChecks(false), WithTransclusionDepth(1))
+	require.NoError(err)
+	require.Len(results, 2)
+	require.True(results[0].Valid())
+	require.False(results[1].Valid())
+	require.True(errors.Is(results[1].Error, ErrBrokenChain))
+	require.EqualError(results[1].Error, "Revision "+a.LatestVerificationHash+" is not of the chain with genesis revision "+b.GenesisHash)
+}
+
+func TestVerifyTransclusionsCyclic(t *testing.T) {
+	require := require.New(t)
+	// Template:A transcludes Template:B, and a forged revision of Template:B
+	// transcludes Template:A back
+	b := newTransclusionPage("Template:B")
+	forged := appendTransclusion(require, b)
+	a := newTransclusionPage("Template:A")
+	r := appendTransclusion(require, a, b)
+	slot, err := json.Marshal([]api.Transclusion{{DbKey: a.Title, VerificationHash: a.LatestVerificationHash}})
+	require.NoError(err)
+	forged.Content.Content["transclusion-hashes"] = string(slot)
+	backend := &fakeBackend{pages: []*api.HashChain{a, b}, calls: map[string]int{}}
+
+	results, err := VerifyTransclusions(context.Background(), backend, r, WithOnChainChecks(false), WithTransclusionDepth(10))
+	require.NoError(err)
+	require.Len(results, 1)
+	require.False(results[0].Valid())
+	require.False(results[0].Chain.Valid())
+	require.Len(results[0].Transclusions, 1)
+	cyclic := results[0].Transclusions[0]
+	require.True(errors.Is(cyclic.Error, ErrBrokenChain))
+	require.EqualError(cyclic.Error, "Transclusion of revision "+a.LatestVerificationHash+" is cyclic")
+	require.Nil(cyclic.Chain)
+
+	// malformed transclusion hashes of the revision itself
+	r.Content.Content["transclusion-hashes"] = "{"
+	_, err = VerifyTransclusions(context.Background(), backend, r)
+	require.Error(err)
+}
@@ -1,6 +1,7 @@
 package verify
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
@@ -12,9 +13,6 @@ import (
 	"strings"
 	"time"
 
-	"github.com/ethereum/go-ethereum/accounts"
-	"github.com/ethereum/go-ethereum/common/hexutil"
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/inblockio/aqua-verifier-go/api"
 	"golang.org/x/crypto/sha3"
 )
@@ -32,6 +30,11 @@ const (
 	INVALID_VERIFICATION_STATUS  = "INVALID"
 	VERIFIED_VERIFICATION_STATUS = "VERIFIED"
 	ERROR_VERIFICATION_STATUS    = "ERROR"
+	// Signature and witness status of the checks skipped by VerifyMode, and
+	// witness status of a witness whose transaction was not looked up
+	NOT_CHECKED_STATUS = "NOT_CHECKED"
+	// EtherscanResult of a witness whose transaction was not looked up
+	ETHERSCAN_NOT_CHECKED = "NOT_CHECKED"
 	// https://stackoverflow.com/questions/9781218/how-to-change-node-jss-console-font-color
 	Reset    = "\x1b[0m"
 	Dim      = "\x1b[2m"
@@ -44,42 +47,90 @@ const (
 var Verbose bool
 
 type RevisionVerificationStatus struct {
-	Content      bool
-	Metadata     bool
-	Signature    string
-	Witness      string
-	Verification string
-	File         string
+	Content      bool   `json:"content"`
+	Metadata     bool   `json:"metadata"`
+	Signature    string `json:"signature"`
+	Witness      string `json:"witness"`
+	Verification string `json:"verification"`
+	File         string `json:"file"`
 }
 
 type RevisionVerificationResult struct {
+	VerificationHash string                      `json:"verification_hash"`
+	Status           *RevisionVerificationStatus `json:"status"`
+	WitnessResult    *WitnessResult              `json:"witness_result,omitempty"`
+	FileHash         string                      `json:"file_hash,omitempty"`
+	// Timestamp is the time the revision was created and Signer the wallet
+	// address the revision claims to be signed by
+	Timestamp time.Time `json:"timestamp"`
+	Signer    string    `json:"signer,omitempty"`
+	// PinMismatches lists the pinned hashes (content, metadata, verification)
+	// that did not match, see VerifyRevisionWithExpected.
+	PinMismatches []string `json:"pin_mismatches,omitempty"`
+	// ContentDiff is a unified diff of the main content against the expected
+	// content when the content hash doesn't match.
+	ContentDiff string `json:"content_diff,omitempty"`
+	// SignatureScheme is the scheme the signature was verified with and
+	// SignatureElapsed the time it took to verify the signature.
+	SignatureScheme  string        `json:"signature_scheme,omitempty"`
+	SignatureElapsed time.Duration `json:"signature_elapsed,omitempty"`
+	// SignatureError is why an INVALID signature failed to verify, e.g.
+	// ErrUnsupportedSignature or the error of a SignatureVerifier.
+	SignatureError error `json:"-"`
+	// Reason further classifies a failed verification
+	Reason  Reason        `json:"reason,omitempty"`
+	Error   error         `json:"-"`
+	Elapsed time.Duration `json:"elapsed"`
+}
+
+// Reason classifies why a revision failed to verify
+type Reason string
+
+const (
+	// ReasonSilentEdit means the content of a revision does not match its
+	// content hash, while the verification hash still commits to that content
+	// hash: the content was edited after the hashes were computed.
+	ReasonSilentEdit Reason = "SILENT_EDIT"
+	// ReasonUnboundContent means the content and the metadata of a revision
+	// each match their hashes, but the verification hash doesn't commit to
+	// both of them: the content and metadata blocks don't belong together,
+	// e.g. because a server served the content of another revision.
+	ReasonUnboundContent Reason = "UNBOUND_CONTENT"
+)
+
+// ExpectedHashes holds the hashes of a revision that are known out-of-band.
+// Empty fields are not checked.
+type ExpectedHashes struct {
+	ContentHash      string
+	MetadataHash     string
 	VerificationHash string
-	Status           *RevisionVerificationStatus
-	WitnessResult    *WitnessResult
-	FileHash         string
-	Error            error
-	Elapsed          time.Duration
+	// Content, if known, is diffed against the content of a revision whose
+	// content hash doesn't match.
+	Content *api.RevisionContent
+	// Previous, if known, is the previous revision, whose signature and
+	// witness hashes the verification hash commits to.
+	Previous *api.Revision
 }
 
 type WitnessResult struct {
-	WitnessHash                        string
-	TxHash                             string
-	WitnessNetwork                     string
-	EtherscanResult                    string
-	EtherscanErrorMessage              string
-	ActualWitnessEventVerificationHash string
-	WitnessEventVHMatches              bool
+	WitnessHash                        string `json:"witness_hash"`
+	TxHash                             string `json:"tx_hash"`
+	WitnessNetwork                     string `json:"witness_network"`
+	EtherscanResult                    string `json:"etherscan_result"`
+	EtherscanErrorMessage              string `json:"etherscan_error_message,omitempty"`
+	ActualWitnessEventVerificationHash string `json:"actual_witness_event_verification_hash"`
+	WitnessEventVHMatches              bool   `json:"witness_event_vh_matches"`
 	// `extra` is populated with useful info when the witness event verification
 	// doesn't match.
-	Extra               *WitnessResultExtra
-	DoVerifyMerkleProof bool
-	MerkleProofStatus   string
+	Extra               *WitnessResultExtra `json:"extra,omitempty"`
+	DoVerifyMerkleProof bool                `json:"do_verify_merkle_proof"`
+	MerkleProofStatus   string              `json:"merkle_proof_status,omitempty"`
 }
 
 type WitnessResultExtra struct {
-	DomainSnapshotGenesisHash    string
-	MerkleRoot                   string
-	WitnessEventVerificationHash string
+	DomainSnapshotGenesisHash    string `json:"domain_snapshot_genesis_hash"`
+	MerkleRoot                   string `json:"merkle_root"`
+	WitnessEventVerificationHash string `json:"witness_event_verification_hash"`
 }
 
 func VerifyData(fileName string, ignoreMerkleProof bool, depth int) bool {
@@ -235,14 +286,17 @@ func shortenHash(hash string) string {
 	return hash[:6] + "..." + hash[len(hash)-6:]
 }
 
+// sum512 is the hash function of all hashes, replaced by tests counting them
+var sum512 = sha3.Sum512
+
 func getHashSum(content string) string {
 	// XXX: do we want to encode the output in something human parsable such as base64 ?
-	s := sha3.Sum512([]byte(content))
+	s := sum512([]byte(content))
 	return hex.EncodeToString(s[:])
 }
 
-func calculateMetadataHash(domainId, timestamp, previousVerificationHash string) string {
-	return getHashSum(domainId + timestamp + previousVerificationHash)
+func calculateMetadataHash(h hasher, domainId, timestamp, previousVerificationHash string) string {
+	return h.sum(domainId + timestamp + previousVerificationHash)
 }
 
 func calculateSignatureHash(signature string, publicKey string) string {
@@ -254,8 +308,8 @@ func calculateWitnessHash(domain_snapshot_genesis_hash, merkle_root, witness_net
 	return getHashSum(domain_snapshot_genesis_hash + merkle_root + witness_network + witness_tx_hash)
 }
 
-func calculateVerificationHash(contentHash, metadataHash, signature_hash, witness_hash string) string {
-	return getHashSum(contentHash + metadataHash + signature_hash + witness_hash)
+func calculateVerificationHash(h hasher, contentHash, metadataHash, signature_hash, witness_hash string) string {
+	return h.sum(contentHash + metadataHash + signature_hash + witness_hash)
 }
 
 func checkAPIVersionCompatibility(ap *api.AquaProtocol) bool {
@@ -268,8 +322,109 @@ func checkAPIVersionCompatibility(ap *api.AquaProtocol) bool {
 	return false
 }
 
-func checkEtherScan(r *api.Revision) error {
-	return api.CheckEtherscan(r.Witness.WitnessNetwork, r.Witness.WitnessEventTransactionHash, r.Witness.WitnessEventVerificationHash)
+func checkEtherScan(ctx context.Context, r *api.Revision) error {
+	return api.CheckEtherscanContext(ctx, r.Witness.WitnessNetwork, r.Witness.WitnessEventTransactionHash, r.Witness.WitnessEventVerificationHash)
+}
+
+// checkWitnessTransaction checks that the witness transaction of r witnessed
+// its witness event verification hash, using its witness evidence if any, the
+// resolver registered for the witness network or etherscan otherwise
+func checkWitnessTransaction(r *api.Revision, o *options) error {
+	if e, ok := o.witnessEvidence[r.Witness.WitnessEventTransactionHash]; ok {
+		return checkWitnessEvidence(r, e, o.witnessTimeTolerance)
+	}
+	release, err := o.acquireRPC()
+	if err != nil {
+		return err
+	}
+	defer release()
+	resolver, ok := o.witnessResolvers[r.Witness.WitnessNetwork]
+	if !ok {
+		return checkEtherScan(o.context(), r)
+	}
+	witnessed, err := resolver.LookupMerkleRoot(o.context(), r.Witness.WitnessEventTransactionHash)
+	if errors.Is(err, api.ErrTransactionNotFound) {
+		if network := findTransactionNetwork(r, o); network != "" {
+			return newVerificationError(ErrWitnessNetworkMismatch, "Witness transaction %s is on network %s, not %s",
+				r.Witness.WitnessEventTransactionHash, network, r.Witness.WitnessNetwork)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(strings.TrimPrefix(witnessed, "0x"), r.Witness.WitnessEventVerificationHash) {
+		return errors.New("eventHash Does NOT match")
+	}
+	if btr, ok := resolver.(api.BlockTimeResolver); ok {
+		return checkWitnessTime(o.context(), r, btr, o.witnessTimeTolerance)
+	}
+	return nil
+}
+
+// checkWitnessEvidence checks the witness of r against the evidence of its
+// witness transaction
+func checkWitnessEvidence(r *api.Revision, e api.WitnessEvidence, tolerance time.Duration) error {
+	if e.Network != r.Witness.WitnessNetwork {
+		return newVerificationError(ErrWitnessNetworkMismatch, "Witness transaction %s is on network %s, not %s",
+			r.Witness.WitnessEventTransactionHash, e.Network, r.Witness.WitnessNetwork)
+	}
+	if !strings.EqualFold(strings.TrimPrefix(e.MerkleRoot, "0x"), r.Witness.WitnessEventVerificationHash) {
+		return errors.New("eventHash Does NOT match")
+	}
+	if !e.BlockTime.IsZero() {
+		return checkBlockTime(r, e.BlockTime, tolerance)
+	}
+	return nil
+}
+
+// findTransactionNetwork returns the network other than the claimed one whose
+// resolver knows the witness transaction of r, or "" if there is none
+func findTransactionNetwork(r *api.Revision, o *options) string {
+	networks := make([]string, 0, len(o.witnessResolvers))
+	for network := range o.witnessResolvers {
+		networks = append(networks, network)
+	}
+	sort.Strings(networks)
+	for _, network := range networks {
+		if network == r.Witness.WitnessNetwork {
+			continue
+		}
+		if _, err := o.witnessResolvers[network].LookupMerkleRoot(o.context(), r.Witness.WitnessEventTransactionHash); err == nil {
+			return network
+		}
+	}
+	return ""
+}
+
+// checkTransactionHash checks that txHash is a 0x prefixed 32 byte hex hash,
+// the format of transaction hashes on all witness networks
+func checkTransactionHash(txHash string) error {
+	digest, ok := strings.CutPrefix(txHash, "0x")
+	if _, err := hex.DecodeString(digest); !ok || err != nil || len(digest) != 64 {
+		return newVerificationError(ErrMalformedTransactionHash, "Witness transaction hash %q is not a 32 byte hex hash", txHash)
+	}
+	return nil
+}
+
+// checkWitnessTime checks that the witness transaction of r was not included
+// in a block before the revision was created, allowing for clock skew of up
+// to tolerance
+func checkWitnessTime(ctx context.Context, r *api.Revision, btr api.BlockTimeResolver, tolerance time.Duration) error {
+	blockTime, err := btr.LookupBlockTime(ctx, r.Witness.WitnessEventTransactionHash)
+	if err != nil {
+		return err
+	}
+	return checkBlockTime(r, blockTime, tolerance)
+}
+
+// checkBlockTime checks that blockTime, the time the witness transaction of r
+// was mined, isn't before the revision timestamp, allowing for tolerance
+func checkBlockTime(r *api.Revision, blockTime time.Time, tolerance time.Duration) error {
+	if blockTime.Add(tolerance).Before(r.Metadata.Timestamp.Time) {
+		return newVerificationError(ErrWitnessPredatesRevision, "Witness transaction was mined at %s, before the revision timestamp %s",
+			blockTime.Format(time.RFC3339), r.Metadata.Timestamp.Format(time.RFC3339))
+	}
+	return nil
 }
 
 func printWitnessInfo(result *RevisionVerificationResult) {
@@ -283,9 +438,14 @@ func printWitnessInfo(result *RevisionVerificationResult) {
 	wh := shortenHash(wr.WitnessHash)
 	witOut := space2 + "Witness event " + wh + " detected"
 	witOut += "\n" + space4 + "Transaction hash: " + wr.TxHash
+	if u := explorerURL(wr.WitnessNetwork, wr.TxHash); u != "" {
+		witOut += "\n" + space4 + "Explorer: " + u
+	}
 	suffix := " on " + wr.WitnessNetwork + " via etherscan.io"
 	if wr.EtherscanResult == "true" {
 		witOut += "\n" + space4 + CHECKMARK + WATCH + "Witness event verification hash has been verified" + suffix
+	} else if wr.EtherscanResult == ETHERSCAN_NOT_CHECKED {
+		witOut += "\n" + space4 + Dim + WARN + " Witness event verification hash not checked" + suffix + Reset
 	} else if wr.EtherscanResult == "false" {
 		witOut += cliRedify(
 			"\n" + space4 + CROSSMARK + WATCH + "Witness event verification hash does not match" + suffix,
@@ -367,6 +527,8 @@ func printRevisionInfo(result *RevisionVerificationResult, r *api.Revision) {
 		fmt.Printf("    %s%s Valid signature from wallet: %s\n", CHECKMARK, LOCKED_WITH_PEN, r.Signature.WalletAddress)
 	case "MISSING":
 		logDim(space4 + WARN + " Not signed")
+	case NOT_CHECKED_STATUS:
+		logDim(space4 + WARN + " Signature from wallet " + r.Signature.WalletAddress + " not checked")
 	case "INVALID":
 		logRed("    " + CROSSMARK + LOCKED_WITH_PEN + "Invalid signature\n")
 	}
@@ -390,15 +552,40 @@ func getSortedKeys(m map[string]string) []string {
 	return keys
 }
 
-func verifyContent(content *api.RevisionContent) bool {
+func calculateContentHash(h hasher, content *api.RevisionContent) string {
+	return h.sum(contentPreimage(content))
+}
+
+func contentPreimage(content *api.RevisionContent) string {
 	wholeContent := ""
 	// We sort the keys by alphabetical order, just the way it is done for
 	// canonical JSON.
 	for _, key := range getSortedKeys(content.Content) {
 		wholeContent += content.Content[key]
 	}
-	actualHash := getHashSum(wholeContent)
-	return content.ContentHash == actualHash
+	// A salt, if any, is hashed after the content so that identical content
+	// of different revisions doesn't hash to the same value.
+	wholeContent += content.Salt
+	return wholeContent
+}
+
+// normalizeContent returns a copy of content with every content slot passed
+// through normalize, or content itself if normalize is nil
+func normalizeContent(content *api.RevisionContent, normalize func(string) string) *api.RevisionContent {
+	if normalize == nil || content == nil {
+		return content
+	}
+	c := *content
+	c.Content = make(map[string]string, len(content.Content))
+	for k, v := range content.Content {
+		c.Content[k] = normalize(v)
+	}
+	return &c
+}
+
+func verifyContent(content *api.RevisionContent, enc HashEncoding, h hasher) bool {
+	actualHash := calculateContentHash(h, content)
+	return enc.normalize(content.ContentHash) == actualHash
 }
 
 func formatRevisionInfo2HTML(server *api.ServerInfo, detail *api.Revision) {
@@ -407,74 +594,150 @@ func formatRevisionInfo2HTML(server *api.ServerInfo, detail *api.Revision) {
 func formatPageInfo2HTML(serverUrl string, title string, status int, details string) {
 }
 
-func verifyRevisionMetadata(r *api.Revision) bool {
-	mh := calculateMetadataHash(r.Metadata.DomainId,
+// verifyRevisionMetadata recomputes the metadata hash of r. If the previous
+// revision is known, it is computed over the verification hash of prev rather
+// than the claimed previous verification hash, which proves that the link to
+// prev is bound by the metadata hash.
+func verifyRevisionMetadata(r *api.Revision, prev *api.Revision, enc HashEncoding, h hasher) bool {
+	prevHash := r.Metadata.PreviousVerificationHash
+	if prev != nil {
+		prevHash = prev.Metadata.VerificationHash
+	}
+	mh := calculateMetadataHash(h, r.Metadata.DomainId,
 		r.Metadata.Timestamp.String(),
-		r.Metadata.PreviousVerificationHash)
-	return mh == r.Metadata.MetadataHash
+		enc.normalize(prevHash))
+	return mh == enc.normalize(r.Metadata.MetadataHash)
 }
 
-func verifyFileContent(content *api.RevisionContent) (string, error) {
-	if content.File == nil {
+func verifyFileContent(content *api.RevisionContent, o *options) (string, error) {
+	fileContentHash, ok := content.Content["file_hash"]
+	if content.File == nil && !ok {
 		return "", nil
 	}
-	fileContentHash, ok := content.Content["file_hash"]
 	if !ok {
 		return "", errors.New("Revision contains a file, but no file content hash")
 	}
-	decoded, err := base64.StdEncoding.DecodeString(content.File.Data)
-	if err != nil {
-		return "", err
+	var data []byte
+	if content.File != nil && content.File.Data != "" {
+		decoded, err := base64.StdEncoding.DecodeString(content.File.Data)
+		if err != nil {
+			return "", err
+		}
+		data = decoded
+	} else {
+		// the file is stored as an attachment rather than inline
+		if o.attachmentFetcher == nil {
+			return "", errors.New("Revision contains a file attachment, but no attachment fetcher to check it")
+		}
+		fetched, err := o.attachmentFetcher(o.context(), content)
+		if err != nil {
+			return "", fmt.Errorf("Failure fetching file attachment: %w", err)
+		}
+		data = fetched
 	}
-	if actual := getHashSum(string(decoded[:])); actual != fileContentHash {
+	if actual := getHashSum(string(data)); actual != fileContentHash {
 		return "", errors.New("File content hash does not match")
 	}
 	return fileContentHash, nil
 }
 
-func verifyPreviousSignature(r *api.Revision, prev *api.Revision) error {
+// verifyPreviousLink checks that the previous verification hash of r is the
+// verification hash of prev
+func verifyPreviousLink(r *api.Revision, prev *api.Revision, enc HashEncoding) error {
+	if prev == nil {
+		return nil
+	}
+	if enc.normalize(r.PreviousVerificationHash()) != enc.normalize(prev.VerificationHash()) {
+		return newVerificationError(ErrBrokenChain, "Previous verification hash %s doesn't match the verification hash %s of the previous revision",
+			r.Metadata.PreviousVerificationHash, prev.Metadata.VerificationHash)
+	}
+	return nil
+}
+
+func verifyPreviousSignature(r *api.Revision, prev *api.Revision, enc HashEncoding) error {
 	// calculate and check prevSignatureHash from previous revision
-	if !r.Context.HasPreviousSignature {
+	if !r.HasPreviousSignature() {
 		return nil
 	}
 	if prev == nil {
-		return errors.New("Revision has previous signature, but no previous revision provided to validate")
+		return newVerificationError(ErrBrokenChain, "Revision has previous signature, but no previous revision provided to validate")
+	}
+	if prev.Signature == nil {
+		return newVerificationError(ErrBrokenChain, "Previous signature data not found")
 	}
 	prevSignature := prev.Signature.Signature
 	prevPublicKey := prev.Signature.PublicKey
 	prevSignatureHash := calculateSignatureHash(prevSignature, prevPublicKey)
-	if prevSignatureHash != prev.Signature.SignatureHash {
-		return errors.New("Previous signature hash doesn't match")
+	if prevSignatureHash != enc.normalize(prev.Signature.SignatureHash) {
+		return newVerificationError(ErrBrokenChain, "Previous signature hash doesn't match")
 	}
 	return nil
 }
 
-func verifyPreviousWitness(r *api.Revision, prev *api.Revision) error {
+func verifyTimestampOrder(r *api.Revision, prev *api.Revision) error {
+	// revisions edited within the same second have equal timestamps
+	if prev == nil || !r.Metadata.Timestamp.Before(prev.Metadata.Timestamp.Time) {
+		return nil
+	}
+	return newVerificationError(ErrTimestampOutOfOrder, "Timestamp %s is before the timestamp %s of the previous revision",
+		r.Metadata.Timestamp.String(), prev.Metadata.Timestamp.String())
+}
+
+// maxClockSkew is how much later than the current time a revision may be
+// dated, as the clocks of servers and verifiers differ
+const maxClockSkew = 5 * time.Minute
+
+func verifyTimestampNotInFuture(r *api.Revision, now time.Time) error {
+	if !r.Metadata.Timestamp.After(now.Add(maxClockSkew)) {
+		return nil
+	}
+	current := api.Timestamp{Time: now.UTC()}
+	return newVerificationError(ErrTimestampInFuture, "Timestamp %s is after the current time %s",
+		r.Metadata.Timestamp.String(), current.String())
+}
+
+func verifyPreviousWitness(r *api.Revision, prev *api.Revision, enc HashEncoding) error {
 	// calculate and check prevWitnessHash from previous revision
-	if !r.Context.HasPreviousWitness {
+	if !r.HasPreviousWitness() {
 		return nil
 	}
-	if prev.Witness == nil {
-		return errors.New("Previous witness data not found")
+	if !prev.HasWitness() {
+		return newVerificationError(ErrBrokenChain, "Previous witness data not found")
 	}
+	w := enc.normalizeWitness(prev.Witness)
 	prevWitnessHash := calculateWitnessHash(
-		prev.Witness.DomainSnapshotGenesisHash,
-		prev.Witness.MerkleRoot,
-		prev.Witness.WitnessNetwork,
-		prev.Witness.WitnessEventTransactionHash)
-	if prevWitnessHash != prev.Witness.WitnessHash {
-		return errors.New("Previous witness hash doesn't match")
+		w.DomainSnapshotGenesisHash,
+		w.MerkleRoot,
+		w.WitnessNetwork,
+		w.WitnessEventTransactionHash)
+	if prevWitnessHash != w.WitnessHash {
+		return newVerificationError(ErrBrokenChain, "Previous witness hash doesn't match")
 	}
 	return nil
 }
 
-func verifyMerkleIntegrity(merkleBranch []*api.MerkleNode, verificationHash string) bool {
+// verifyMerkleIntegrity checks that merkleBranch is the path from the leaf
+// verificationHash to merkleRoot. The leaves of a node are hashed in their
+// order, so siblings can't be swapped without changing the successor, and
+// the nodes must be ordered from the leaf at the deepest level, depth
+// len(merkleBranch)-1, up to the root at depth 0.
+func verifyMerkleIntegrity(merkleBranch []*api.MerkleNode, verificationHash, merkleRoot string) bool {
+	return verifyMerkleBranch(merkleBranch, verificationHash, merkleRoot, nil)
+}
+
+// verifyMerkleBranch is verifyMerkleIntegrity, skipping the hashing of the
+// nodes in checked, whose successors are known to match their leaves. The
+// nodes found to match are added to checked, if not nil.
+func verifyMerkleBranch(merkleBranch []*api.MerkleNode, verificationHash, merkleRoot string, checked map[api.MerkleNode]bool) bool {
 	if len(merkleBranch) == 0 {
 		return false
 	}
 
 	var prevSuccessor string
-	for _, node := range merkleBranch {
+	for i, node := range merkleBranch {
+		if node.Depth != len(merkleBranch)-1-i || node.WitnessEventId != merkleBranch[0].WitnessEventId {
+			return false
+		}
 		leaves := map[string]bool{
 			node.LeftLeaf:  true,
 			node.RightLeaf: true,
@@ -495,7 +758,11 @@ func verifyMerkleIntegrity(merkleBranch []*api.MerkleNode, verificationHash stri
 		}
 
 		var calculatedSuccessor string
-		if node.LeftLeaf == "" {
+		if checked[*node] {
+			calculatedSuccessor = node.Successor
+		} else if node.LeftLeaf == "" && node.RightLeaf == "" {
+			return false
+		} else if node.LeftLeaf == "" {
 			calculatedSuccessor = node.RightLeaf
 		} else if node.RightLeaf == "" {
 			calculatedSuccessor = node.LeftLeaf
@@ -507,18 +774,25 @@ func verifyMerkleIntegrity(merkleBranch []*api.MerkleNode, verificationHash stri
 			//console.log("Actual successor", node.successor)
 			return false
 		}
+		if checked != nil {
+			checked[*node] = true
+		}
 		prevSuccessor = node.Successor
 	}
-	return true
+	// a consistent path that leads elsewhere, e.g. with swapped siblings and
+	// recalculated successors, doesn't prove anything
+	return prevSuccessor == merkleRoot
 }
 
-func verifyWitness(r *api.Revision, doVerifyMerkleProof bool) (string, *WitnessResult) {
+func verifyWitness(r *api.Revision, doVerifyMerkleProof bool, o *options) (string, *WitnessResult) {
 	if r.Witness == nil {
 		return "MISSING", nil
 	}
+	// the hashes are compared and hashed as lower case hex
+	w := o.hashEncoding.normalizeWitness(r.Witness)
 
 	actualWitnessEventVerificationHash := getHashSum(
-		r.Witness.DomainSnapshotGenesisHash + r.Witness.MerkleRoot,
+		w.DomainSnapshotGenesisHash + w.MerkleRoot,
 	)
 
 	result := &WitnessResult{
@@ -538,11 +812,29 @@ func verifyWitness(r *api.Revision, doVerifyMerkleProof bool) (string, *WitnessR
 
 	// Do online lookup of transaction hash
 	etherScanResult := "true"
-	if err := checkEtherScan(r); err != nil {
+	if err := checkTransactionHash(r.Witness.WitnessEventTransactionHash); err != nil {
+		result.EtherscanResult = err.Error()
+		result.EtherscanErrorMessage = "Malformed transaction hash"
+		return "INVALID", result
+	}
+	// a witness whose fields don't match its hash is rejected without looking
+	// up the transaction
+	if hash, err := w.ComputeHash(); err != nil || hash != w.WitnessHash {
+		result.EtherscanResult = "Witness hash doesn't match"
+		result.EtherscanErrorMessage = "Witness hash doesn't match"
+		return "INVALID", result
+	}
+	if !o.onChainChecks() {
+		etherScanResult = ETHERSCAN_NOT_CHECKED
+	} else if err := checkWitnessTransaction(withWitness(r, w), o); err != nil {
 		etherScanResult = err.Error()
 		var errMsg string
 		if etherScanResult == "Transaction hash not found" {
 			errMsg = "Transaction hash not found"
+		} else if errors.Is(err, ErrWitnessPredatesRevision) {
+			errMsg = "Witness predates the revision"
+		} else if errors.Is(err, ErrWitnessNetworkMismatch) {
+			errMsg = "Transaction is on another network"
 		} else if strings.Contains(etherScanResult, "ENETUNREACH") {
 			errMsg = "Server is unreachable"
 		} else {
@@ -552,7 +844,7 @@ func verifyWitness(r *api.Revision, doVerifyMerkleProof bool) (string, *WitnessR
 	}
 	result.EtherscanResult = etherScanResult
 
-	if actualWitnessEventVerificationHash != r.Witness.WitnessEventVerificationHash {
+	if actualWitnessEventVerificationHash != w.WitnessEventVerificationHash {
 		result.WitnessEventVHMatches = false
 		result.Extra = &WitnessResultExtra{
 			DomainSnapshotGenesisHash:    r.Witness.DomainSnapshotGenesisHash,
@@ -565,12 +857,12 @@ func verifyWitness(r *api.Revision, doVerifyMerkleProof bool) (string, *WitnessR
 	if doVerifyMerkleProof {
 		// Only verify the witness merkle proof when verifyWitness is successful,
 		// because this step is expensive.
-		verificationHash := r.Metadata.VerificationHash
-		if verificationHash == r.Witness.DomainSnapshotGenesisHash {
+		verificationHash := o.hashEncoding.normalize(r.Metadata.VerificationHash)
+		if verificationHash == w.DomainSnapshotGenesisHash {
 			// Corner case when the page is a Domain Snapshot.
 			result.MerkleProofStatus = "DOMAIN_SNAPSHOT"
 		} else {
-			if merkleProofIsOK := verifyMerkleIntegrity(r.Witness.MerkleProof, verificationHash); merkleProofIsOK {
+			if merkleProofIsOK := verifyMerkleIntegrity(w.MerkleProof, verificationHash, w.MerkleRoot); merkleProofIsOK {
 				result.MerkleProofStatus = "VALID"
 			} else {
 				result.MerkleProofStatus = "INVALID"
@@ -578,70 +870,50 @@ func verifyWitness(r *api.Revision, doVerifyMerkleProof bool) (string, *WitnessR
 			}
 		}
 	}
-	if etherScanResult != "true" {
+	if etherScanResult != "true" && etherScanResult != ETHERSCAN_NOT_CHECKED {
 		return "INVALID", result
 	}
+	// a witness whose transaction wasn't looked up is not known to be valid
+	if o.mode == VerifyStructural || etherScanResult == ETHERSCAN_NOT_CHECKED {
+		return NOT_CHECKED_STATUS, result
+	}
 	return "VALID", result
 }
 
-func verifyCurrentSignature(r *api.Revision) (bool, string) {
-	if r.Signature == nil || r.Signature.Signature == "" {
-		return true, "MISSING"
-	}
-	verificationHash := r.Metadata.VerificationHash
-	paddedMessage := []byte("I sign the following page verification_hash: [0x" + verificationHash + "]")
-	signature, err := hexutil.Decode(r.Signature.Signature)
-	if err != nil {
-		return false, "INVALID"
-	}
-	signature[crypto.RecoveryIDOffset] -= 27 // Transform yellow paper V from 27/28 to 0/1
-	sigPublicKey, err := crypto.Ecrecover(accounts.TextHash(paddedMessage), signature)
-	if err != nil {
-		return false, "INVALID"
+// withWitness returns r with witness w, as a copy if w is not the witness of r
+func withWitness(r *api.Revision, w *api.RevisionWitness) *api.Revision {
+	if r.Witness == w {
+		return r
 	}
-	ecdsaPub, err := crypto.UnmarshalPubkey(sigPublicKey)
-	if err != nil {
-		return false, "INVALID"
-	}
-	sigAddress := crypto.PubkeyToAddress(*ecdsaPub).Hex()
-	if strings.ToLower(sigAddress) != strings.ToLower(r.Signature.WalletAddress) {
-		return false, "INVALID"
-	}
-	return true, "VALID"
+	c := *r
+	c.Witness = w
+	return &c
 }
 
-func verifyVerificationHash(r *api.Revision, prev *api.Revision) error {
+func verifyVerificationHash(r *api.Revision, prev *api.Revision, enc HashEncoding, h hasher) error {
 	// calculate verification hash
-	prevSignatureHash := ""
-	prevWitnessHash := ""
-	if prev != nil {
-		if prev.Signature != nil {
-			prevSignatureHash = prev.Signature.SignatureHash
-		}
-		if prev.Witness != nil {
-			prevWitnessHash = prev.Witness.WitnessHash
-		}
-	}
-	verificationHash := calculateVerificationHash(r.Content.ContentHash, r.Metadata.MetadataHash, prevSignatureHash, prevWitnessHash)
-	if verificationHash != r.Metadata.VerificationHash {
+	prevSignatureHash := enc.normalize(prev.SignatureHash())
+	prevWitnessHash := enc.normalize(prev.WitnessHash())
+	verificationHash := calculateVerificationHash(h, enc.normalize(r.Content.ContentHash), enc.normalize(r.Metadata.MetadataHash), prevSignatureHash, prevWitnessHash)
+	if verificationHash != enc.normalize(r.Metadata.VerificationHash) {
 		if Verbose {
 			fmt.Println("  Actual content hash: ", r.Content.ContentHash)
 			fmt.Println("  Actual metadata hash: ", r.Metadata.MetadataHash)
 			fmt.Println("  Actual signature hash: ", prevSignatureHash)
-			if r.Witness != nil {
+			if r.HasWitness() {
 				fmt.Println("  Witness event id: ", r.Witness.WitnessEventId)
 			}
-			if r.Context.HasPreviousSignature {
+			if r.HasPreviousSignature() {
 				fmt.Println("  HasPreviousSignature")
 			}
-			if r.Context.HasPreviousWitness {
+			if r.HasPreviousWitness() {
 				fmt.Println("  HasPreviousWitness")
 				fmt.Println("  Actual previous witness hash: ", prevWitnessHash)
 			}
 			fmt.Println("  Expected verification hash: ", r.Metadata.VerificationHash)
 			fmt.Println("  Actual verification hash: ", verificationHash)
 		}
-		return errors.New("Verification hash doesn't match")
+		return ErrVerificationHashMismatch
 	}
 	return nil
 }
@@ -666,17 +938,34 @@ func NewRevisionVerificationResult(verificationHash string) *RevisionVerificatio
 	return rvr
 }
 
-func verifyRevisionWithoutElapsed(r *api.Revision, prev *api.Revision, doVerifyMerkleProof bool) (bool, *RevisionVerificationResult) {
+func verifyRevisionWithoutElapsed(r *api.Revision, prev *api.Revision, doVerifyMerkleProof bool, o *options) (bool, *RevisionVerificationResult) {
 	result := NewRevisionVerificationResult(r.Metadata.VerificationHash)
+	result.Timestamp = r.Metadata.Timestamp.Time
+	if r.HasSignature() {
+		result.Signer = r.Signature.WalletAddress
+	}
 
-	if !verifyRevisionMetadata(r) {
-		result.Error = errors.New("Metadata hash doesn't match")
+	if err := verifyPreviousLink(r, prev, o.hashEncoding); err != nil {
+		result.Error = err
+		return false, result
+	}
+	if !verifyRevisionMetadata(r, prev, o.hashEncoding, o.hasher) {
+		result.Error = ErrMetadataHashMismatch
 		return false, result
 	}
 	// Mark metadata as correct
 	result.Status.Metadata = true
 
-	fileContentHash, err := verifyFileContent(r.Content)
+	err := verifyTimestampOrder(r, prev)
+	if err == nil {
+		err = verifyTimestampNotInFuture(r, o.clock())
+	}
+	if err != nil {
+		result.Error = err
+		return false, result
+	}
+
+	fileContentHash, err := verifyFileContent(r.Content, o)
 	if err != nil {
 		result.Error = err
 		result.Status.File = "INVALID"
@@ -687,53 +976,205 @@ func verifyRevisionWithoutElapsed(r *api.Revision, prev *api.Revision, doVerifyM
 		result.Status.File = "VERIFIED"
 	}
 
-	if !verifyContent(r.Content) {
-		result.Error = errors.New("Content hash doesn't match")
+	if !verifyContent(normalizeContent(r.Content, o.contentNormalizer), o.hashEncoding, o.hasher) {
+		result.Error = ErrContentHashMismatch
+		// The verification hash still commits to the stored content hash, so
+		// the content was edited without updating the hashes.
+		if verifyVerificationHash(r, prev, o.hashEncoding, o.hasher) == nil {
+			result.Reason = ReasonSilentEdit
+		}
 		return false, result
 	}
 	// Mark content as correct
 	result.Status.Content = true
 
-	err = verifyPreviousSignature(r, prev)
+	err = verifyPreviousSignature(r, prev, o.hashEncoding)
 	if err != nil {
 		result.Error = err
 		return false, result
 	}
 
-	err = verifyPreviousWitness(r, prev)
+	err = verifyPreviousWitness(r, prev, o.hashEncoding)
 	if err != nil {
 		result.Error = err
 		return false, result
 	}
 
-	witnessStatus, witnessResult := verifyWitness(r, doVerifyMerkleProof)
+	witnessStatus, witnessResult := verifyWitness(r, doVerifyMerkleProof, o)
 	result.Status.Witness = witnessStatus
 	result.WitnessResult = witnessResult
 	witnessIsCorrect := witnessStatus != "INVALID"
 
-	signatureIsCorrect, status := verifyCurrentSignature(r)
-	result.Status.Signature = status
+	sig := verifyCurrentSignature(r, o)
+	signatureIsCorrect := sig.isCorrect
+	result.Status.Signature = sig.status
+	result.SignatureScheme = sig.scheme
+	result.SignatureElapsed = sig.elapsed
+	result.SignatureError = sig.err
 
-	err = verifyVerificationHash(r, prev)
+	err = verifyVerificationHash(r, prev, o.hashEncoding, o.hasher)
 	if err != nil {
 		// TODO make this interface consistent with other error formatting.
 		result.Status.Verification = INVALID_VERIFICATION_STATUS
+		// the content and metadata hashes were checked above, so it is
+		// their binding that fails
+		result.Reason = ReasonUnboundContent
 		return false, result
 	}
 	result.Status.Verification = VERIFIED_VERIFICATION_STATUS
 
+	if err := verifyRequiredParts(r, o); err != nil {
+		result.Error = err
+		return false, result
+	}
 	return signatureIsCorrect && witnessIsCorrect, result
 }
 
-func verifyRevision(r *api.Revision, prev *api.Revision, doVerifyMerkleProof bool) (bool, *RevisionVerificationResult) {
+// verifyRequiredParts checks that r is witnessed and signed if required by
+// WithRequireWitness and WithRequireSignature
+func verifyRequiredParts(r *api.Revision, o *options) error {
+	if o.requireWitness && !r.HasWitness() {
+		return newVerificationError(ErrWitnessMissing, "Revision is not witnessed, but a witness is required")
+	}
+	if o.requireSignature && !r.HasSignature() {
+		return newVerificationError(ErrSignatureMissing, "Revision is not signed, but a signature is required")
+	}
+	return nil
+}
+
+// VerifyRevisionWithExpected verifies the content, metadata and verification
+// hashes of a revision and checks them against the pinned values in expected.
+// This defends against a server returning internally consistent but
+// substituted data. The pinned hashes are decoded with the HashEncoding of
+// opts, same as the served ones.
+//
+// A revision committing to the signature or witness of its previous revision
+// needs expected.Previous to compute its verification hash. Without it, the
+// Verification status of the result is NOT_CHECKED and only a pinned
+// verification hash is compared. A nil error is returned only if every hash
+// that could be computed matches.
+func VerifyRevisionWithExpected(r *api.Revision, expected ExpectedHashes, opts ...Option) (*RevisionVerificationResult, error) {
+	result := NewRevisionVerificationResult(r.Metadata.VerificationHash)
+	o := newOptions(opts)
+	enc := o.hashEncoding
+
+	if !verifyRevisionMetadata(r, nil, enc, o.hasher) {
+		result.Error = ErrMetadataHashMismatch
+		return result, result.Error
+	}
+	result.Status.Metadata = true
+
+	if !verifyContent(normalizeContent(r.Content, o.contentNormalizer), enc, o.hasher) {
+		result.Error = ErrContentHashMismatch
+		if expected.Content != nil {
+			result.ContentDiff = DiffContent(expected.Content, r.Content)
+		}
+		return result, result.Error
+	}
+	result.Status.Content = true
+
+	if prev := expected.Previous; prev != nil {
+		result.Error = verifyPreviousLink(r, prev, enc)
+		if result.Error == nil {
+			result.Error = verifyPreviousSignature(r, prev, enc)
+		}
+		if result.Error == nil {
+			result.Error = verifyPreviousWitness(r, prev, enc)
+		}
+		if result.Error != nil {
+			return result, result.Error
+		}
+	}
+	if expected.Previous == nil && (r.HasPreviousSignature() || r.HasPreviousWitness()) {
+		result.Status.Verification = NOT_CHECKED_STATUS
+	} else if err := verifyVerificationHash(r, expected.Previous, enc, o.hasher); err != nil {
+		result.Error = err
+		return result, result.Error
+	} else {
+		result.Status.Verification = VERIFIED_VERIFICATION_STATUS
+	}
+
+	pins := []struct {
+		name, expected, actual string
+		err                    error
+	}{
+		{"content", expected.ContentHash, r.Content.ContentHash, ErrContentHashMismatch},
+		{"metadata", expected.MetadataHash, r.Metadata.MetadataHash, ErrMetadataHashMismatch},
+		{"verification", expected.VerificationHash, r.Metadata.VerificationHash, ErrVerificationHashMismatch},
+	}
+	var errs []error
+	for _, p := range pins {
+		if p.expected != "" && enc.normalize(p.expected) != enc.normalize(p.actual) {
+			result.PinMismatches = append(result.PinMismatches, p.name)
+			errs = append(errs, p.err)
+		}
+	}
+	if len(result.PinMismatches) > 0 {
+		if expected.Content != nil && result.PinMismatches[0] == "content" {
+			result.ContentDiff = DiffContent(expected.Content, r.Content)
+		}
+		result.Error = newVerificationError(errors.Join(errs...), "Pinned %s hash doesn't match", strings.Join(result.PinMismatches, ", "))
+		return result, result.Error
+	}
+	return result, nil
+}
+
+// VerifyRevisionJSON decodes a revision from its JSON encoding, e.g. as
+// received from a message queue, and verifies it offline, without looking up
+// witness transactions or contract signatures. As the previous revision is not
+// known, revisions committing to a previous signature or witness fail to
+// verify. It returns an error for malformed JSON, and the result together with
+// its Err if the revision doesn't verify.
+func VerifyRevisionJSON(data []byte, opts ...Option) (*RevisionVerificationResult, error) {
+	var r api.Revision
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	if r.Metadata == nil || r.Content == nil {
+		return nil, errors.New("Revision has no metadata or content")
+	}
+	_, result := verifyRevision(&r, nil, true, append(opts, WithOnChainChecks(false))...)
+	return result, result.Err()
+}
+
+func verifyRevision(r *api.Revision, prev *api.Revision, doVerifyMerkleProof bool, opts ...Option) (bool, *RevisionVerificationResult) {
 	// Wrap verifyRevisionWithoutElapsed so that it contains elapsed info.
 	elapsedStart := time.Now()
-	isCorrect, result := verifyRevisionWithoutElapsed(r, prev, doVerifyMerkleProof)
+	o := newOptions(opts)
+	if err := checkRevisionParts(r, prev); err != nil {
+		result := NewRevisionVerificationResult(r.VerificationHash())
+		result.Error = err
+		return false, result
+	}
+	result := o.resultCache.get(r, prev, doVerifyMerkleProof, o)
+	isCorrect := result != nil
+	if result == nil {
+		isCorrect, result = verifyRevisionWithoutElapsed(r, prev, doVerifyMerkleProof, o)
+		if isCorrect {
+			o.resultCache.put(r, prev, doVerifyMerkleProof, o, result)
+		}
+	}
 	elapsed := time.Since(elapsedStart)
 	result.Elapsed = elapsed
+	if o.observer != nil {
+		o.observer(result)
+	}
 	return isCorrect, result
 }
 
+// checkRevisionParts checks that r and prev have the parts verification
+// needs, so that revisions missing them fail verification instead of
+// panicking. Signatures, witnesses and the verification context are optional.
+func checkRevisionParts(r, prev *api.Revision) error {
+	if r == nil || r.Metadata == nil || r.Content == nil {
+		return errors.New("Revision has no metadata or content")
+	}
+	if prev != nil && prev.Metadata == nil {
+		return newVerificationError(ErrBrokenChain, "Previous revision has no metadata")
+	}
+	return nil
+}
+
 func calculateStatus(count, totalLength int) {
 }
 
@@ -741,19 +1182,24 @@ func getVerificationSet(data *api.HashChain, depth int) ([]*api.Revision, int, e
 	var height int
 	if depth == -1 || depth > len(data.Revisions) {
 		height = len(data.Revisions)
-	} else if depth < len(data.Revisions) {
+	} else {
 		height = depth
 	}
 
 	// follow the revisions height deep, and order revisions by oldest to newest:
 	verificationSet := make([]*api.Revision, height)
 	cur := data.LatestVerificationHash
+	visited := make(map[string]bool, height)
 
 	for i := 0; i < height; i++ {
 		r, ok := data.Revisions[cur]
 		if !ok {
-			return nil, height, fmt.Errorf("Failure getting revision %s", cur)
+			return nil, height, newVerificationError(ErrBrokenChain, "Failure getting revision %s", cur)
+		}
+		if visited[cur] {
+			return nil, height, newVerificationError(ErrBrokenChain, "Revision %s occurs more than once in the chain", cur)
 		}
+		visited[cur] = true
 		verificationSet[height-i-1] = r
 		cur = r.Metadata.PreviousVerificationHash
 	}
@@ -2,8 +2,12 @@ package verify
 
 import (
 	"encoding/json"
+	"errors"
 	"io/ioutil"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/inblockio/aqua-verifier-go/api"
 	"github.com/stretchr/testify/require"
@@ -206,3 +210,512 @@ func TestInvalidVerificationHash(t *testing.T) {
 	require.False(isCorrect)
 	require.Equal(result.Status.Verification, "INVALID")
 }
+
+func TestVerifyRevisionWithExpected(t *testing.T) {
+	require := require.New(t)
+	first, _, err := get1st2ndFixtureVerStructure()
+	require.NoError(err)
+
+	expected := ExpectedHashes{
+		ContentHash:      first.Content.ContentHash,
+		MetadataHash:     first.Metadata.MetadataHash,
+		VerificationHash: first.Metadata.VerificationHash,
+	}
+	result, err := VerifyRevisionWithExpected(first, expected)
+	require.NoError(err)
+	require.Empty(result.PinMismatches)
+	require.True(result.Status.Content)
+	require.True(result.Status.Metadata)
+
+	// Only pinned hashes are checked
+	result, err = VerifyRevisionWithExpected(first, ExpectedHashes{})
+	require.NoError(err)
+	require.Empty(result.PinMismatches)
+
+	expected.ContentHash = "wrong"
+	expected.VerificationHash = "wrong"
+	result, err = VerifyRevisionWithExpected(first, expected)
+	require.EqualError(err, "Pinned content, verification hash doesn't match")
+	require.Equal([]string{"content", "verification"}, result.PinMismatches)
+	require.Equal(VERIFIED_VERIFICATION_STATUS, result.Status.Verification)
+
+	// a substituted verification hash doesn't verify, even when pinned
+	substituted := *first
+	metadata := *first.Metadata
+	metadata.VerificationHash = "wrong"
+	substituted.Metadata = &metadata
+	result, err = VerifyRevisionWithExpected(&substituted, ExpectedHashes{VerificationHash: "wrong"})
+	require.ErrorIs(err, ErrVerificationHashMismatch)
+	require.Equal(INVALID_VERIFICATION_STATUS, result.Status.Verification)
+}
+
+func TestVerifyRevisionWithExpectedPrevious(t *testing.T) {
+	require := require.New(t)
+	first, second, err := get1st2ndFixtureVerStructure()
+	require.NoError(err)
+	require.True(second.HasPreviousSignature())
+
+	// without the previous revision the verification hash can't be computed
+	result, err := VerifyRevisionWithExpected(second, ExpectedHashes{VerificationHash: second.Metadata.VerificationHash})
+	require.NoError(err)
+	require.Equal(NOT_CHECKED_STATUS, result.Status.Verification)
+
+	result, err = VerifyRevisionWithExpected(second, ExpectedHashes{Previous: first})
+	require.NoError(err)
+	require.Equal(VERIFIED_VERIFICATION_STATUS, result.Status.Verification)
+
+	// the previous revision must be the one the revision follows
+	_, err = VerifyRevisionWithExpected(second, ExpectedHashes{Previous: second})
+	require.ErrorIs(err, ErrBrokenChain)
+	signature := *first.Signature
+	signature.Signature = "forged"
+	forged := *first
+	forged.Signature = &signature
+	_, err = VerifyRevisionWithExpected(second, ExpectedHashes{Previous: &forged})
+	require.ErrorIs(err, ErrBrokenChain)
+}
+
+func TestSaltedContent(t *testing.T) {
+	require := require.New(t)
+	first, _, err := get1st2ndFixtureVerStructure()
+	require.NoError(err)
+	require.True(verifyContent(first.Content, HashEncodingHex, nil))
+
+	salted := *first.Content
+	salted.Salt = "c2FsdA"
+	salted.ContentHash = getHashSum(salted.Content["main"] + salted.Content["transclusion-hashes"] + salted.Salt)
+	require.True(verifyContent(&salted, HashEncodingHex, nil))
+
+	// hashing without the salt fails
+	salted.Salt = ""
+	require.False(verifyContent(&salted, HashEncodingHex, nil))
+}
+
+func TestWithContentNormalizer(t *testing.T) {
+	require := require.New(t)
+	first, second, err := get1st2ndFixtureVerStructure()
+	require.NoError(err)
+	crlf := func(s string) string { return strings.ReplaceAll(s, "\r\n", "\n") }
+
+	// the server serves the content with CRLF line endings
+	second.Content.Content["main"] = strings.ReplaceAll(second.Content.Content["main"], "\n", "\r\n")
+	isCorrect, result := verifyRevision(second, first, GlobalDoVerifyMerkleProof, WithOnChainChecks(false))
+	require.False(isCorrect)
+	require.ErrorIs(result.Err(), ErrContentHashMismatch)
+	require.False(VerifyContentHash(second.Content))
+
+	isCorrect, result = verifyRevision(second, first, GlobalDoVerifyMerkleProof, WithOnChainChecks(false), WithContentNormalizer(crlf))
+	require.True(isCorrect)
+	require.NoError(result.Err())
+	require.True(VerifyContentHash(second.Content, WithContentNormalizer(crlf)))
+	// the served content is left as is
+	require.Contains(second.Content.Content["main"], "\r\n")
+
+	// content changes that the normalizer doesn't undo still fail
+	second.Content.Content["main"] += " edited"
+	isCorrect, _ = verifyRevision(second, first, GlobalDoVerifyMerkleProof, WithOnChainChecks(false), WithContentNormalizer(crlf))
+	require.False(isCorrect)
+}
+
+func TestSilentEdit(t *testing.T) {
+	require := require.New(t)
+	first, second, err := get1st2ndFixtureVerStructure()
+	require.NoError(err)
+
+	// content edited post-hoc, hashes left stale
+	second.Content.Content["main"] += " edited"
+	isCorrect, result := verifyRevision(second, first, GlobalDoVerifyMerkleProof)
+	require.False(isCorrect)
+	require.EqualError(result.Error, "Content hash doesn't match")
+	require.Equal(ReasonSilentEdit, result.Reason)
+
+	// the content hash was updated too, but not the verification hash
+	second.Content.ContentHash = calculateContentHash(nil, second.Content)
+	isCorrect, result = verifyRevision(second, first, GlobalDoVerifyMerkleProof)
+	require.False(isCorrect)
+	require.NoError(result.Error)
+	require.Equal(INVALID_VERIFICATION_STATUS, result.Status.Verification)
+	require.Equal(ReasonUnboundContent, result.Reason)
+
+	// a content hash not committed to by the verification hash is not a silent edit
+	second.Content.Content["main"] += " again"
+	isCorrect, result = verifyRevision(second, first, GlobalDoVerifyMerkleProof)
+	require.False(isCorrect)
+	require.EqualError(result.Error, "Content hash doesn't match")
+	require.Empty(result.Reason)
+}
+
+func TestTimestampOrder(t *testing.T) {
+	require := require.New(t)
+	first, second, err := get1st2ndFixtureVerStructure()
+	require.NoError(err)
+
+	// rewrite the timestamp of second keeping its hashes consistent
+	setTimestamp := func(ts time.Time) {
+		second.Metadata.Timestamp = api.Timestamp{Time: ts}
+		_, second.Metadata.MetadataHash = ExplainMetadataHash(second.Metadata)
+		second.Metadata.VerificationHash, err = ComputeVerificationHash(second, first)
+		require.NoError(err)
+	}
+
+	setTimestamp(first.Metadata.Timestamp.Add(-time.Second))
+	isCorrect, result := verifyRevision(second, first, GlobalDoVerifyMerkleProof, WithOnChainChecks(false))
+	require.False(isCorrect)
+	require.True(errors.Is(result.Error, ErrTimestampOutOfOrder))
+	require.EqualError(result.Error, "Timestamp "+second.Metadata.Timestamp.String()+
+		" is before the timestamp "+first.Metadata.Timestamp.String()+" of the previous revision")
+
+	// edits within the same second are in order
+	setTimestamp(first.Metadata.Timestamp.Time)
+	_, result = verifyRevision(second, first, GlobalDoVerifyMerkleProof, WithOnChainChecks(false))
+	require.NoError(result.Error)
+	require.Equal(VERIFIED_VERIFICATION_STATUS, result.Status.Verification)
+}
+
+func TestTimestampInFuture(t *testing.T) {
+	require := require.New(t)
+	data, err := jsonDecodeFixture(fixture)
+	require.NoError(err)
+	page := data.Pages[0]
+	genesis := page.Revisions[page.GenesisHash]
+	created := genesis.Metadata.Timestamp.Time
+	clock := func(now time.Time) Option {
+		return WithClock(func() time.Time { return now })
+	}
+
+	_, result := verifyRevision(genesis, nil, GlobalDoVerifyMerkleProof, WithOnChainChecks(false), clock(created))
+	require.NoError(result.Err())
+	// clocks may be a bit off
+	_, result = verifyRevision(genesis, nil, GlobalDoVerifyMerkleProof, WithOnChainChecks(false), clock(created.Add(-maxClockSkew)))
+	require.NoError(result.Err())
+
+	now := created.Add(-time.Hour)
+	isCorrect, result := verifyRevision(genesis, nil, GlobalDoVerifyMerkleProof, WithOnChainChecks(false), clock(now))
+	require.False(isCorrect)
+	require.True(errors.Is(result.Err(), ErrTimestampInFuture))
+	require.EqualError(result.Error, "Timestamp "+genesis.Metadata.Timestamp.String()+
+		" is after the current time "+now.UTC().Format("20060102150405"))
+
+	// chains are verified at the time of the clock
+	verifiedAt := page.Revisions[page.LatestVerificationHash].Metadata.Timestamp.Add(time.Hour)
+	c, err := VerifyHashChain(page, GlobalDoVerifyMerkleProof, -1, WithOnChainChecks(false), clock(verifiedAt))
+	require.NoError(err)
+	require.NoError(c.Err())
+	require.Equal(verifiedAt.UTC(), c.VerifiedAt)
+	c, err = VerifyHashChain(page, GlobalDoVerifyMerkleProof, -1, WithOnChainChecks(false), clock(created))
+	require.NoError(err)
+	require.True(errors.Is(c.Err(), ErrTimestampInFuture))
+}
+
+func TestPreviousLink(t *testing.T) {
+	require := require.New(t)
+	first, second, err := get1st2ndFixtureVerStructure()
+	require.NoError(err)
+	_, result := verifyRevision(second, first, GlobalDoVerifyMerkleProof, WithOnChainChecks(false))
+	require.NoError(result.Err())
+
+	// the previous verification hash matches, but the metadata hash was
+	// computed as if second had no previous revision
+	unbound := *second.Metadata
+	unbound.MetadataHash = calculateMetadataHash(nil, unbound.DomainId, unbound.Timestamp.String(), "")
+	r := *second
+	r.Metadata = &unbound
+	unbound.VerificationHash, err = ComputeVerificationHash(&r, first)
+	require.NoError(err)
+	require.Equal(first.Metadata.VerificationHash, unbound.PreviousVerificationHash)
+	_, result = verifyRevision(&r, first, GlobalDoVerifyMerkleProof, WithOnChainChecks(false))
+	require.True(errors.Is(result.Err(), ErrMetadataHashMismatch))
+
+	// a self-consistent revision linking to another previous revision
+	relinked := *second.Metadata
+	relinked.PreviousVerificationHash = strings.Repeat("ab", 64)
+	_, relinked.MetadataHash = ExplainMetadataHash(&relinked)
+	r.Metadata = &relinked
+	relinked.VerificationHash, err = ComputeVerificationHash(&r, first)
+	require.NoError(err)
+	require.True(verifyRevisionMetadata(&r, nil, HashEncodingHex, nil))
+	_, result = verifyRevision(&r, first, GlobalDoVerifyMerkleProof, WithOnChainChecks(false))
+	require.True(errors.Is(result.Err(), ErrBrokenChain))
+	require.EqualError(result.Err(), "Previous verification hash "+relinked.PreviousVerificationHash+
+		" doesn't match the verification hash "+first.Metadata.VerificationHash+" of the previous revision")
+}
+
+func TestPrefixedHashes(t *testing.T) {
+	require := require.New(t)
+	// a server formatting its hashes as upper case with a 0x prefix
+	prefixed := regexp.MustCompile(`"([0-9a-f]{128})"`).ReplaceAllFunc(fixture, func(b []byte) []byte {
+		return []byte(`"0x` + strings.ToUpper(string(b[1:len(b)-1])) + `"`)
+	})
+	require.NotEqual(fixture, prefixed)
+	data, err := jsonDecodeFixture(prefixed)
+	require.NoError(err)
+	result, err := VerifyHashChain(data.Pages[0], GlobalDoVerifyMerkleProof, -1, WithOnChainChecks(false))
+	require.NoError(err)
+	require.NoError(result.Err())
+}
+
+func TestMerkleProofOrdering(t *testing.T) {
+	require := require.New(t)
+	first, _, err := get1st2ndFixtureVerStructure()
+	require.NoError(err)
+	vh := first.Metadata.VerificationHash
+	root := first.Witness.MerkleRoot
+	proof := func() []*api.MerkleNode {
+		p := make([]*api.MerkleNode, len(first.Witness.MerkleProof))
+		for i, n := range first.Witness.MerkleProof {
+			node := *n
+			p[i] = &node
+		}
+		return p
+	}
+	// successors recalculates the successors of p from its leaves
+	successors := func(p []*api.MerkleNode) {
+		for i, n := range p {
+			if i > 0 {
+				if n.LeftLeaf == first.Witness.MerkleProof[i-1].Successor {
+					n.LeftLeaf = p[i-1].Successor
+				} else {
+					n.RightLeaf = p[i-1].Successor
+				}
+			}
+			n.Successor = getHashSum(n.LeftLeaf + n.RightLeaf)
+		}
+	}
+	require.True(verifyMerkleIntegrity(proof(), vh, root))
+
+	// swapped siblings
+	swapped := proof()
+	swapped[0].LeftLeaf, swapped[0].RightLeaf = swapped[0].RightLeaf, swapped[0].LeftLeaf
+	require.False(verifyMerkleIntegrity(swapped, vh, root))
+	// with consistently recalculated successors
+	successors(swapped)
+	require.False(verifyMerkleIntegrity(swapped, vh, root))
+
+	// a claimed depth that doesn't match the position of a node
+	deep := proof()
+	deep[0].Depth++
+	require.False(verifyMerkleIntegrity(deep, vh, root))
+
+	// nodes out of order
+	reordered := proof()
+	reordered[0], reordered[1] = reordered[1], reordered[0]
+	reordered[0].Depth, reordered[1].Depth = reordered[1].Depth, reordered[0].Depth
+	require.False(verifyMerkleIntegrity(reordered, vh, root))
+
+	// a proof of another witness event
+	other := proof()
+	other[1].WitnessEventId++
+	require.False(verifyMerkleIntegrity(other, vh, root))
+
+	// a truncated proof doesn't lead to the root
+	require.False(verifyMerkleIntegrity(proof()[:2], vh, root))
+}
+
+func TestVerifyRevisionJSON(t *testing.T) {
+	require := require.New(t)
+	first, second, err := get1st2ndFixtureVerStructure()
+	require.NoError(err)
+	data, err := json.Marshal(first)
+	require.NoError(err)
+
+	result, err := VerifyRevisionJSON(data)
+	require.NoError(err)
+	require.Equal(first.Metadata.VerificationHash, result.VerificationHash)
+	require.Equal(VERIFIED_VERIFICATION_STATUS, result.Status.Verification)
+	require.Equal(NOT_CHECKED_STATUS, result.Status.Witness)
+	require.Equal(ETHERSCAN_NOT_CHECKED, result.WitnessResult.EtherscanResult)
+
+	first.Content.Content["main"] += "tampered"
+	data, err = json.Marshal(first)
+	require.NoError(err)
+	result, err = VerifyRevisionJSON(data)
+	require.True(errors.Is(err, ErrContentHashMismatch))
+	require.NotNil(result)
+
+	// the previous revision is needed to verify the second one
+	data, err = json.Marshal(second)
+	require.NoError(err)
+	_, err = VerifyRevisionJSON(data)
+	require.True(errors.Is(err, ErrBrokenChain), err)
+
+	for _, malformed := range []string{"", "{", `{"metadata":"hash"}`, `{"content":{}}`, "null"} {
+		result, err = VerifyRevisionJSON([]byte(malformed))
+		require.Error(err, malformed)
+		require.Nil(result, malformed)
+	}
+}
+
+func TestWithVerifyMode(t *testing.T) {
+	require := require.New(t)
+	first, _, err := get1st2ndFixtureVerStructure()
+	require.NoError(err)
+	network := first.Witness.WitnessNetwork
+	resolver := fakeResolver{first.Witness.WitnessEventTransactionHash: first.Witness.WitnessEventVerificationHash}
+	wrong := fakeResolver{first.Witness.WitnessEventTransactionHash: "wrong"}
+	verify := func(mode VerifyMode, r api.WitnessResolver) *RevisionVerificationResult {
+		_, result := verifyRevision(first, nil, true, WithWitnessResolver(network, r), WithVerifyMode(mode))
+		return result
+	}
+
+	result := verify(VerifyStructural, wrong)
+	require.True(result.Valid())
+	require.Equal(NOT_CHECKED_STATUS, result.Status.Signature)
+	require.Equal(NOT_CHECKED_STATUS, result.Status.Witness)
+	require.Equal(ETHERSCAN_NOT_CHECKED, result.WitnessResult.EtherscanResult)
+	require.Equal("VALID", result.WitnessResult.MerkleProofStatus)
+
+	result = verify(VerifyOnChain, resolver)
+	require.True(result.Valid())
+	require.Equal(NOT_CHECKED_STATUS, result.Status.Signature)
+	require.Equal("VALID", result.Status.Witness)
+	require.False(verify(VerifyOnChain, wrong).Valid())
+
+	result = verify(VerifyFull, resolver)
+	require.True(result.Valid())
+	require.Equal("VALID", result.Status.Signature)
+	require.Equal("VALID", result.Status.Witness)
+
+	// a signature by another wallet only fails when signers are recovered
+	wallet := first.Signature.WalletAddress
+	first.Signature.WalletAddress = testContractWallet
+	require.Equal(NOT_CHECKED_STATUS, verify(VerifyStructural, resolver).Status.Signature)
+	require.Equal(NOT_CHECKED_STATUS, verify(VerifyOnChain, resolver).Status.Signature)
+	require.Equal("INVALID", verify(VerifyFull, resolver).Status.Signature)
+	first.Signature.WalletAddress = wallet
+
+	// the zero value runs every check
+	require.Equal(VerifyFull, VerifyMode(0))
+	require.Equal(VerifyFull, newOptions(nil).mode)
+
+	// disabled on chain checks stay disabled whatever the order of the
+	// options
+	for _, opts := range [][]Option{
+		{WithOnChainChecks(false), WithVerifyMode(VerifyOnChain)},
+		{WithVerifyMode(VerifyOnChain), WithOnChainChecks(false)},
+	} {
+		_, result = verifyRevision(first, nil, true, append(opts, WithWitnessResolver(network, wrong))...)
+		require.True(result.Valid())
+		require.Equal(ETHERSCAN_NOT_CHECKED, result.WitnessResult.EtherscanResult)
+	}
+	require.False(newOptions([]Option{WithOnChainChecks(true), WithVerifyMode(VerifyStructural)}).onChainChecks())
+
+	// signatures that are not checked are not taken for continuous
+	require.NoError(VerifySignerContinuity([]*api.Revision{first}))
+	require.True(errors.Is(VerifySignerContinuity([]*api.Revision{first}, WithVerifyMode(VerifyStructural)), ErrSignatureInvalid))
+
+	// while hashes not matching fail in every mode
+	first.Signature.SignatureHash = "tampered"
+	first.Witness.MerkleRoot = "tampered"
+	for _, mode := range []VerifyMode{VerifyStructural, VerifyOnChain, VerifyFull} {
+		result = verify(mode, resolver)
+		require.Equal("INVALID", result.Status.Signature)
+		require.Equal("INVALID", result.Status.Witness)
+	}
+}
+
+func TestVerifyRevisionMissingParts(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		remove func(r *api.Revision)
+		err    string
+	}{
+		{"context", func(r *api.Revision) { r.Context = nil }, ""},
+		{"signature", func(r *api.Revision) { r.Signature = nil }, ""},
+		{"witness", func(r *api.Revision) { r.Witness = nil }, ""},
+		{"content", func(r *api.Revision) { r.Content = nil }, "Revision has no metadata or content"},
+		{"metadata", func(r *api.Revision) { r.Metadata = nil }, "Revision has no metadata or content"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+			first, _, err := get1st2ndFixtureVerStructure()
+			require.NoError(err)
+			tc.remove(first)
+			isCorrect, result := verifyRevision(first, nil, GlobalDoVerifyMerkleProof, WithOnChainChecks(false))
+			if tc.err == "" {
+				require.True(isCorrect)
+				require.NoError(result.Err())
+			} else {
+				require.False(isCorrect)
+				require.EqualError(result.Err(), tc.err)
+			}
+		})
+	}
+
+	// a revision claiming a signature of a previous revision that has none
+	require := require.New(t)
+	first, second, err := get1st2ndFixtureVerStructure()
+	require.NoError(err)
+	require.True(second.HasPreviousSignature())
+	first.Signature = nil
+	_, result := verifyRevision(second, first, GlobalDoVerifyMerkleProof, WithOnChainChecks(false))
+	require.True(errors.Is(result.Err(), ErrBrokenChain))
+	require.EqualError(result.Err(), "Previous signature data not found")
+
+	first.Metadata = nil
+	_, result = verifyRevision(second, first, GlobalDoVerifyMerkleProof, WithOnChainChecks(false))
+	require.True(errors.Is(result.Err(), ErrBrokenChain))
+}
+
+func TestUnboundContent(t *testing.T) {
+	require := require.New(t)
+	set, err := getFixtureVerificationSet()
+	require.NoError(err)
+	first, second := set[0], set[1]
+
+	// the content of another revision is consistent with its content hash,
+	// and the metadata with its metadata hash, but they don't belong together
+	second.Content = set[2].Content
+	require.True(verifyContent(second.Content, HashEncodingHex, nil))
+	isCorrect, result := verifyRevision(second, first, GlobalDoVerifyMerkleProof, WithOnChainChecks(false))
+	require.False(isCorrect)
+	require.True(result.Status.Content)
+	require.True(result.Status.Metadata)
+	require.Equal(INVALID_VERIFICATION_STATUS, result.Status.Verification)
+	require.Equal(ReasonUnboundContent, result.Reason)
+	require.True(errors.Is(result.Err(), ErrVerificationHashMismatch))
+	require.EqualError(result.Err(), "Verification hash doesn't bind the content hash to the metadata hash")
+}
+
+func TestWithRequireWitnessSignature(t *testing.T) {
+	require := require.New(t)
+	data, err := jsonDecodeFixture(fixture)
+	require.NoError(err)
+	page := data.Pages[0]
+
+	invalid := func(result *ChainVerificationResult) []int {
+		var failed []int
+		for i, r := range result.Revisions {
+			if !r.Valid() {
+				failed = append(failed, i)
+			}
+		}
+		return failed
+	}
+	result, err := VerifyHashChain(page, GlobalDoVerifyMerkleProof, -1, WithOnChainChecks(false))
+	require.NoError(err)
+	require.True(result.Valid())
+
+	// only the genesis revision is witnessed
+	result, err = VerifyHashChain(page, GlobalDoVerifyMerkleProof, -1, WithOnChainChecks(false), WithRequireWitness())
+	require.NoError(err)
+	require.Equal([]int{1, 2, 3, 4, 5, 6}, invalid(result))
+	r := result.Revisions[1]
+	require.Equal("MISSING", r.Status.Witness)
+	require.True(errors.Is(r.Err(), ErrWitnessMissing))
+	require.EqualError(r.Err(), "Revision is not witnessed, but a witness is required")
+
+	result, err = VerifyHashChain(page, GlobalDoVerifyMerkleProof, -1, WithOnChainChecks(false), WithRequireSignature())
+	require.NoError(err)
+	require.Equal([]int{1, 2, 6}, invalid(result))
+	require.True(errors.Is(result.Revisions[6].Err(), ErrSignatureMissing))
+	require.False(errors.Is(result.Revisions[6].Err(), ErrWitnessMissing))
+
+	// a single revision
+	first, second, err := get1st2ndFixtureVerStructure()
+	require.NoError(err)
+	isCorrect, _ := verifyRevision(first, nil, GlobalDoVerifyMerkleProof, WithOnChainChecks(false), WithRequireWitness(), WithRequireSignature())
+	require.True(isCorrect)
+	isCorrect, result2 := verifyRevision(second, first, GlobalDoVerifyMerkleProof, WithOnChainChecks(false), WithRequireSignature())
+	require.False(isCorrect)
+	require.True(errors.Is(result2.Err(), ErrSignatureMissing))
+}
@@ -0,0 +1,335 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/inblockio/aqua-verifier-go/api"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeResolver knows the hashes witnessed by transactions
+type fakeResolver map[string]string
+
+func (f fakeResolver) LookupMerkleRoot(ctx context.Context, txHash string) (string, error) {
+	root, ok := f[txHash]
+	if !ok {
+		return "", api.ErrTransactionNotFound
+	}
+	return root, nil
+}
+
+// fakeBlockTimeResolver also knows when the transactions were mined
+type fakeBlockTimeResolver struct {
+	fakeResolver
+	blockTimes map[string]time.Time
+}
+
+func (f fakeBlockTimeResolver) LookupBlockTime(ctx context.Context, txHash string) (time.Time, error) {
+	t, ok := f.blockTimes[txHash]
+	if !ok {
+		return time.Time{}, api.ErrTransactionNotFound
+	}
+	return t, nil
+}
+
+func TestWithWitnessResolver(t *testing.T) {
+	require := require.New(t)
+	first, _, err := get1st2ndFixtureVerStructure()
+	require.NoError(err)
+	network := first.Witness.WitnessNetwork
+	tx := first.Witness.WitnessEventTransactionHash
+
+	resolver := fakeResolver{tx: "0x" + first.Witness.WitnessEventVerificationHash}
+	_, result := verifyRevision(first, nil, true, WithWitnessResolver(network, resolver))
+	require.Equal("VALID", result.Status.Witness)
+	require.Equal("true", result.WitnessResult.EtherscanResult)
+
+	resolver[tx] = "wrong"
+	_, result = verifyRevision(first, nil, true, WithWitnessResolver(network, resolver))
+	require.Equal("INVALID", result.Status.Witness)
+	require.Equal("eventHash Does NOT match", result.WitnessResult.EtherscanResult)
+
+	delete(resolver, tx)
+	_, result = verifyRevision(first, nil, true, WithWitnessResolver(network, resolver))
+	require.Equal("INVALID", result.Status.Witness)
+	require.Equal("Transaction hash not found", result.WitnessResult.EtherscanErrorMessage)
+
+	// a witness on a private chain is looked up with its own resolver
+	resolver[tx] = first.Witness.WitnessEventVerificationHash
+	first.Witness.WitnessNetwork = "private"
+	first.Witness.WitnessHash, err = first.Witness.ComputeHash()
+	require.NoError(err)
+	_, result = verifyRevision(first, nil, true,
+		WithWitnessResolver(network, fakeResolver{}), WithWitnessResolver("private", resolver))
+	require.Equal("VALID", result.Status.Witness)
+}
+
+func TestWitnessBlockTime(t *testing.T) {
+	require := require.New(t)
+	first, _, err := get1st2ndFixtureVerStructure()
+	require.NoError(err)
+	network := first.Witness.WitnessNetwork
+	tx := first.Witness.WitnessEventTransactionHash
+	created := first.Metadata.Timestamp.Time
+	resolver := fakeBlockTimeResolver{
+		fakeResolver: fakeResolver{tx: first.Witness.WitnessEventVerificationHash},
+		blockTimes:   map[string]time.Time{tx: created.Add(time.Minute)},
+	}
+
+	_, result := verifyRevision(first, nil, true, WithWitnessResolver(network, resolver))
+	require.Equal("VALID", result.Status.Witness)
+
+	// the witness claims to be older than the revision
+	resolver.blockTimes[tx] = created.Add(-time.Hour)
+	_, result = verifyRevision(first, nil, true, WithWitnessResolver(network, resolver))
+	require.Equal("INVALID", result.Status.Witness)
+	require.Equal("Witness predates the revision", result.WitnessResult.EtherscanErrorMessage)
+	require.Equal("Witness transaction was mined at "+created.Add(-time.Hour).Format(time.RFC3339)+
+		", before the revision timestamp "+created.Format(time.RFC3339), result.WitnessResult.EtherscanResult)
+	require.True(errors.Is(result.Err(), ErrWitnessMismatch))
+
+	// unless the clocks are allowed to be that far apart
+	_, result = verifyRevision(first, nil, true, WithWitnessResolver(network, resolver), WithWitnessTimeTolerance(2*time.Hour))
+	require.Equal("VALID", result.Status.Witness)
+	_, result = verifyRevision(first, nil, true, WithWitnessResolver(network, resolver), WithWitnessTimeTolerance(time.Hour))
+	require.Equal("VALID", result.Status.Witness)
+	_, result = verifyRevision(first, nil, true, WithWitnessResolver(network, resolver), WithWitnessTimeTolerance(time.Hour-time.Second))
+	require.Equal("INVALID", result.Status.Witness)
+
+	// resolvers without block times don't check them
+	_, result = verifyRevision(first, nil, true, WithWitnessResolver(network, resolver.fakeResolver))
+	require.Equal("VALID", result.Status.Witness)
+}
+
+func TestWithWitnessEvidence(t *testing.T) {
+	require := require.New(t)
+	first, _, err := get1st2ndFixtureVerStructure()
+	require.NoError(err)
+	network := first.Witness.WitnessNetwork
+	tx := first.Witness.WitnessEventTransactionHash
+	created := first.Metadata.Timestamp.Time
+	evidence := map[string]api.WitnessEvidence{tx: {
+		Network:    network,
+		MerkleRoot: "0x" + first.Witness.WitnessEventVerificationHash,
+		BlockTime:  created.Add(time.Minute),
+	}}
+
+	// the evidence is consulted before the resolver, which doesn't know the
+	// transaction
+	_, result := verifyRevision(first, nil, true, WithWitnessEvidence(evidence), WithWitnessResolver(network, fakeResolver{}))
+	require.Equal("VALID", result.Status.Witness)
+	require.Equal("true", result.WitnessResult.EtherscanResult)
+
+	for _, tc := range []struct {
+		name     string
+		evidence api.WitnessEvidence
+		expected string
+	}{
+		{"root", api.WitnessEvidence{Network: network, MerkleRoot: "wrong"}, "Online lookup failed"},
+		{"network", api.WitnessEvidence{Network: "other", MerkleRoot: first.Witness.WitnessEventVerificationHash}, "Transaction is on another network"},
+		{"block time", api.WitnessEvidence{Network: network, MerkleRoot: first.Witness.WitnessEventVerificationHash, BlockTime: created.Add(-time.Hour)}, "Witness predates the revision"},
+	} {
+		_, result = verifyRevision(first, nil, true, WithWitnessEvidence(map[string]api.WitnessEvidence{tx: tc.evidence}),
+			WithWitnessResolver(network, fakeResolver{tx: first.Witness.WitnessEventVerificationHash}))
+		require.Equal("INVALID", result.Status.Witness, tc.name)
+		require.Equal(tc.expected, result.WitnessResult.EtherscanErrorMessage, tc.name)
+		require.True(errors.Is(result.Err(), ErrWitnessMismatch), tc.name)
+	}
+
+	// transactions without evidence are looked up with the resolver
+	_, result = verifyRevision(first, nil, true, WithWitnessEvidence(map[string]api.WitnessEvidence{"0xother": {}}),
+		WithWitnessResolver(network, fakeResolver{tx: first.Witness.WitnessEventVerificationHash}))
+	require.Equal("VALID", result.Status.Witness)
+}
+
+func TestWitnessTransactionConsistency(t *testing.T) {
+	require := require.New(t)
+	first, _, err := get1st2ndFixtureVerStructure()
+	require.NoError(err)
+	tx := first.Witness.WitnessEventTransactionHash
+	require.NoError(checkTransactionHash(tx))
+
+	for _, malformed := range []string{"", "0x17cb", tx[2:], tx + "00", "0x" + strings.Repeat("zz", 32), "0X" + tx[2:]} {
+		err := checkTransactionHash(malformed)
+		require.True(errors.Is(err, ErrMalformedTransactionHash), malformed)
+		first.Witness.WitnessEventTransactionHash = malformed
+		_, result := verifyRevision(first, nil, true, WithOnChainChecks(false))
+		require.Equal("INVALID", result.Status.Witness)
+		require.Equal("Malformed transaction hash", result.WitnessResult.EtherscanErrorMessage)
+		require.True(errors.Is(result.Err(), ErrWitnessMismatch))
+	}
+	first.Witness.WitnessEventTransactionHash = tx
+
+	// the transaction exists, but on mainnet rather than the claimed network
+	witnessed := "0x" + first.Witness.WitnessEventVerificationHash
+	claimed := fakeResolver{}
+	opts := []Option{
+		WithWitnessResolver(first.Witness.WitnessNetwork, claimed),
+		WithWitnessResolver("mainnet", fakeResolver{tx: witnessed}),
+	}
+	_, result := verifyRevision(first, nil, true, opts...)
+	require.Equal("INVALID", result.Status.Witness)
+	require.Equal("Transaction is on another network", result.WitnessResult.EtherscanErrorMessage)
+	require.Equal("Witness transaction "+tx+" is on network mainnet, not goerli", result.WitnessResult.EtherscanResult)
+
+	claimed[tx] = witnessed
+	_, result = verifyRevision(first, nil, true, opts...)
+	require.Equal("VALID", result.Status.Witness)
+
+	// a transaction unknown on every network is just not found
+	delete(claimed, tx)
+	_, result = verifyRevision(first, nil, true, WithWitnessResolver(first.Witness.WitnessNetwork, claimed), WithWitnessResolver("mainnet", fakeResolver{}))
+	require.Equal("Transaction hash not found", result.WitnessResult.EtherscanErrorMessage)
+}
+
+func TestWitnessHash(t *testing.T) {
+	require := require.New(t)
+	first, _, err := get1st2ndFixtureVerStructure()
+	require.NoError(err)
+	hash, err := first.Witness.ComputeHash()
+	require.NoError(err)
+	require.Equal(first.Witness.WitnessHash, hash)
+	_, result := verifyRevision(first, nil, true, WithOnChainChecks(false))
+	require.Equal(NOT_CHECKED_STATUS, result.Status.Witness)
+
+	otherTx := "0x" + strings.Repeat("ab", 32)
+	for name, tamper := range map[string]func(w *api.RevisionWitness){
+		"genesis hash": func(w *api.RevisionWitness) { w.DomainSnapshotGenesisHash += "00" },
+		"merkle root":  func(w *api.RevisionWitness) { w.MerkleRoot += "00" },
+		"network":      func(w *api.RevisionWitness) { w.WitnessNetwork = "mainnet" },
+		"transaction":  func(w *api.RevisionWitness) { w.WitnessEventTransactionHash = otherTx },
+	} {
+		first, _, err := get1st2ndFixtureVerStructure()
+		require.NoError(err)
+		tamper(first.Witness)
+		// the mismatch is found without a lookup
+		_, result := verifyRevision(first, nil, true, WithWitnessResolver(first.Witness.WitnessNetwork, fakeResolver{}))
+		require.Equal("INVALID", result.Status.Witness, name)
+		require.Equal("Witness hash doesn't match", result.WitnessResult.EtherscanErrorMessage, name)
+		require.True(errors.Is(result.Err(), ErrWitnessMismatch), name)
+	}
+
+	first.Witness.MerkleRoot = ""
+	_, err = first.Witness.ComputeHash()
+	require.Error(err)
+}
+
+// concurrencyResolver records the most lookups that ran at once
+type concurrencyResolver struct {
+	fakeResolver
+	running, max atomic.Int32
+}
+
+func (c *concurrencyResolver) LookupMerkleRoot(ctx context.Context, txHash string) (string, error) {
+	n := c.running.Add(1)
+	defer c.running.Add(-1)
+	for {
+		max := c.max.Load()
+		if n <= max || c.max.CompareAndSwap(max, n) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+	return c.fakeResolver.LookupMerkleRoot(ctx, txHash)
+}
+
+func TestWithRPCConcurrency(t *testing.T) {
+	for _, limit := range []int{1, 3} {
+		t.Run(strconv.Itoa(limit), func(t *testing.T) {
+			require := require.New(t)
+			first, _, err := get1st2ndFixtureVerStructure()
+			require.NoError(err)
+			resolver := &concurrencyResolver{fakeResolver: fakeResolver{
+				first.Witness.WitnessEventTransactionHash: first.Witness.WitnessEventVerificationHash,
+			}}
+			opts := []Option{WithWitnessResolver(first.Witness.WitnessNetwork, resolver), WithRPCConcurrency(limit)}
+			errs := make([]error, 12)
+			var wg sync.WaitGroup
+			for i := range errs {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_, result := verifyRevision(first, nil, GlobalDoVerifyMerkleProof, opts...)
+					errs[i] = result.Err()
+				}()
+			}
+			wg.Wait()
+			for _, err := range errs {
+				require.NoError(err)
+			}
+			require.EqualValues(limit, resolver.max.Load())
+		})
+	}
+}
+
+// contextResolver records the contexts it looks transactions up with
+type contextResolver struct {
+	fakeResolver
+	mu       sync.Mutex
+	contexts []context.Context
+}
+
+func (c *contextResolver) LookupMerkleRoot(ctx context.Context, txHash string) (string, error) {
+	c.mu.Lock()
+	c.contexts = append(c.contexts, ctx)
+	c.mu.Unlock()
+	return c.fakeResolver.LookupMerkleRoot(ctx, txHash)
+}
+
+func TestVerificationContext(t *testing.T) {
+	require := require.New(t)
+	data, err := jsonDecodeFixture(fixture)
+	require.NoError(err)
+	page := data.Pages[0]
+	resolvers := map[string]*contextResolver{}
+	var opts []Option
+	for _, r := range page.Revisions {
+		if !r.HasWitness() {
+			continue
+		}
+		network := r.Witness.WitnessNetwork
+		if resolvers[network] == nil {
+			resolvers[network] = &contextResolver{fakeResolver: fakeResolver{}}
+			opts = append(opts, WithWitnessResolver(network, resolvers[network]))
+		}
+		resolvers[network].fakeResolver[r.Witness.WitnessEventTransactionHash] = r.Witness.WitnessEventVerificationHash
+	}
+	require.NotEmpty(resolvers)
+	backend := &fakeBackend{pages: data.Pages, calls: map[string]int{}}
+
+	// the lookups are made with the context of VerifyChain
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, "verify")
+	c, err := VerifyChain(ctx, backend, page.Title, true, -1, opts...)
+	require.NoError(err)
+	require.NoError(c.Err())
+	for _, resolver := range resolvers {
+		require.NotEmpty(resolver.contexts)
+		for _, lookup := range resolver.contexts {
+			require.Equal("verify", lookup.Value(key{}))
+		}
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = VerifyChain(cancelled, backend, page.Title, true, -1, opts...)
+	require.ErrorIs(err, context.Canceled)
+
+	// waiting for a lookup slot stops with the context
+	rpc := WithRPCConcurrency(1)
+	release, err := newOptions([]Option{rpc}).acquireRPC()
+	require.NoError(err)
+	_, err = newOptions([]Option{rpc, withContext(cancelled)}).acquireRPC()
+	require.ErrorIs(err, context.Canceled)
+	release()
+	release, err = newOptions([]Option{rpc}).acquireRPC()
+	require.NoError(err)
+	release()
+}